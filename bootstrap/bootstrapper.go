@@ -1,13 +1,18 @@
 package bootstrap
 
 import (
+	"github.com/go-xorm/xorm"
 	"github.com/gorilla/securecookie"
 	"github.com/iralance/go-lottery/conf"
 	"github.com/iralance/go-lottery/cron"
+	"github.com/iralance/go-lottery/dao"
+	"github.com/iralance/go-lottery/datasource"
+	"github.com/iralance/go-lottery/services"
 	"github.com/kataras/iris/v12"
 	"github.com/kataras/iris/v12/middleware/logger"
 	"github.com/kataras/iris/v12/middleware/recover"
 	"github.com/kataras/iris/v12/sessions"
+	"log"
 	"time"
 )
 
@@ -19,6 +24,11 @@ type Bootstrapper struct {
 	AppOwner     string
 	AppSpawnDate time.Time
 	Sessions     *sessions.Sessions
+	// 数据库和缓存，没有指定的时候使用默认的mysql主库和redis
+	Engine *xorm.Engine
+	Cache  datasource.Cache
+	// 接口的访问频率限制，没有指定的时候使用conf.RateLimits
+	RateLimits []conf.RateLimit
 }
 
 func New(appName, appOwner string, cfgs ...Configurator) *Bootstrapper {
@@ -91,9 +101,15 @@ func (b *Bootstrapper) Configure(cs ...Configurator) {
 func (b *Bootstrapper) setupCron() {
 	// 服务类应用
 	if conf.RunningCrontabService {
-		cron.ConfigueAppOneCron()
+		giftService := services.NewGiftService(dao.NewGiftDao(b.Engine), b.Cache)
+		fulfillService := services.NewFulfillService(dao.NewFulfillDao(b.Engine))
+		resultService := services.NewResultService(dao.NewResultDao(b.Engine))
+		codeService := services.NewCodeService(dao.NewCodeDao(b.Engine))
+		codeGenService := services.NewCodeGenService(dao.NewCodeGenDao(b.Engine))
+		rewardDispatcher := services.NewRewardDispatcher(conf.RewardWebhook, b.Cache, resultService)
+		cron.ConfigueAppOneCron(b.Cache, giftService, fulfillService, resultService, codeService, codeGenService, rewardDispatcher)
 	}
-	cron.ConfigueAppAllCron()
+	cron.ConfigueAppAllCron(b.Cache)
 }
 
 const (
@@ -107,6 +123,19 @@ const (
 //
 // Returns itself.
 func (b *Bootstrapper) Bootstrap() *Bootstrapper {
+	// 密钥不在代码中，缺少的时候不能启动
+	if err := conf.LoadSecrets(); err != nil {
+		log.Fatal("bootstrap.Bootstrap LoadSecrets error ", err)
+	}
+	if b.Engine == nil {
+		b.Engine = datasource.InstanceDbMaster()
+	}
+	if b.Cache == nil {
+		b.Cache = datasource.InstanceCache()
+	}
+	if b.RateLimits == nil {
+		b.RateLimits = conf.RateLimits
+	}
 	b.SetupViews("./views")
 	b.SetupSessions(24*time.Hour,
 		[]byte("the-big-and-secret-fash-key-here"),
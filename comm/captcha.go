@@ -0,0 +1,69 @@
+package comm
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"time"
+)
+
+// 5x7点阵的数字字体，每一行的低5位表示一行的像素
+var captchaFont = [10][7]uint8{
+	{0x0e, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0e}, // 0
+	{0x04, 0x0c, 0x04, 0x04, 0x04, 0x04, 0x0e}, // 1
+	{0x0e, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1f}, // 2
+	{0x1f, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0e}, // 3
+	{0x02, 0x06, 0x0a, 0x12, 0x1f, 0x02, 0x02}, // 4
+	{0x1f, 0x10, 0x1e, 0x01, 0x01, 0x11, 0x0e}, // 5
+	{0x06, 0x08, 0x10, 0x1e, 0x11, 0x11, 0x0e}, // 6
+	{0x1f, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08}, // 7
+	{0x0e, 0x11, 0x11, 0x0e, 0x11, 0x11, 0x0e}, // 8
+	{0x0e, 0x11, 0x11, 0x0f, 0x01, 0x02, 0x0c}, // 9
+}
+
+const captchaScale = 4
+const captchaPadding = 8
+
+// 生成数字验证码的PNG图片，字符位置和颜色随机，并且加上干扰点
+func CaptchaImage(digits string) []byte {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	charW := 6 * captchaScale
+	width := len(digits)*charW + captchaPadding*2
+	height := 7*captchaScale + captchaPadding*2
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{245, 245, 245, 255})
+		}
+	}
+	for i, ch := range digits {
+		if ch < '0' || ch > '9' {
+			continue
+		}
+		c := color.RGBA{uint8(r.Intn(120)), uint8(r.Intn(120)), uint8(r.Intn(120)), 255}
+		left := captchaPadding + i*charW + r.Intn(3) - 1
+		top := captchaPadding + r.Intn(captchaPadding) - captchaPadding/2
+		for row, bits := range captchaFont[ch-'0'] {
+			for col := 0; col < 5; col++ {
+				if bits&(1<<uint(4-col)) == 0 {
+					continue
+				}
+				for dx := 0; dx < captchaScale; dx++ {
+					for dy := 0; dy < captchaScale; dy++ {
+						img.Set(left+col*captchaScale+dx, top+row*captchaScale+dy, c)
+					}
+				}
+			}
+		}
+	}
+	// 干扰点
+	for i := 0; i < width*height/8; i++ {
+		img.Set(r.Intn(width), r.Intn(height),
+			color.RGBA{uint8(r.Intn(256)), uint8(r.Intn(256)), uint8(r.Intn(256)), 255})
+	}
+	buf := &bytes.Buffer{}
+	png.Encode(buf, img)
+	return buf.Bytes()
+}
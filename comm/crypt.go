@@ -0,0 +1,78 @@
+package comm
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/iralance/go-lottery/conf"
+	"io"
+)
+
+// 优惠券编码加密，AES-GCM，每次使用随机的nonce
+// 返回，密文（base64编码，nonce在前面），使用的密钥版本
+// 没有配置密钥的时候返回错误，不能保存编码
+func CodeEncrypt(plain string) (string, int, error) {
+	if conf.CodeHashKey == "" {
+		return "", 0, errors.New("code hash key not set")
+	}
+	keyId := conf.CodeKeyCurrent
+	gcm, err := codeCipher(keyId)
+	if err != nil {
+		return "", 0, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", 0, err
+	}
+	data := gcm.Seal(nonce, nonce, []byte(plain), nil)
+	return base64.StdEncoding.EncodeToString(data), keyId, nil
+}
+
+// 使用加密时的密钥版本解密优惠券编码
+func CodeDecrypt(text string, keyId int) (string, error) {
+	gcm, err := codeCipher(keyId)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// 优惠券编码的查找值，HMAC-SHA256，同一个编码的结果总是一样
+func CodeHash(plain string) string {
+	mac := hmac.New(sha256.New, []byte(conf.CodeHashKey))
+	mac.Write([]byte(plain))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func codeCipher(keyId int) (cipher.AEAD, error) {
+	str, ok := conf.CodeKeys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("code key %d not found", keyId)
+	}
+	key, err := base64.StdEncoding.DecodeString(str)
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("code key %d must be 32 bytes in base64", keyId)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,105 @@
+package comm
+
+import (
+	"testing"
+
+	"github.com/iralance/go-lottery/conf"
+)
+
+// 测试使用的密钥，测试结束之后恢复原来的配置
+func setCodeKeys(t *testing.T, keys map[int]string, current int, hashKey string) {
+	oldKeys, oldCurrent, oldHashKey := conf.CodeKeys, conf.CodeKeyCurrent, conf.CodeHashKey
+	conf.CodeKeys, conf.CodeKeyCurrent, conf.CodeHashKey = keys, current, hashKey
+	t.Cleanup(func() {
+		conf.CodeKeys, conf.CodeKeyCurrent, conf.CodeHashKey = oldKeys, oldCurrent, oldHashKey
+	})
+}
+
+const (
+	testCodeKey1 = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+	testCodeKey2 = "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+)
+
+func TestCodeEncrypt(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    map[int]string
+		current int
+		plain   string
+		wantErr bool
+	}{
+		{"当前密钥", map[int]string{1: testCodeKey1}, 1, "CODE-0001", false},
+		{"轮换之后的密钥", map[int]string{1: testCodeKey1, 2: testCodeKey2}, 2, "CODE-0002", false},
+		{"空的编码", map[int]string{1: testCodeKey1}, 1, "", false},
+		{"中文编码", map[int]string{1: testCodeKey1}, 1, "券码-张三", false},
+		{"当前密钥不存在", map[int]string{1: testCodeKey1}, 2, "CODE-0003", true},
+		{"密钥长度不对", map[int]string{1: "c2hvcnQ="}, 1, "CODE-0004", true},
+		{"密钥不是base64", map[int]string{1: "not base64!"}, 1, "CODE-0005", true},
+	}
+	for _, tt := range tests {
+		setCodeKeys(t, tt.keys, tt.current, "hash-key")
+		text, keyId, err := CodeEncrypt(tt.plain)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: CodeEncrypt(%q) error = %v, wantErr %v", tt.name, tt.plain, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if keyId != tt.current || text == tt.plain {
+			t.Errorf("%s: CodeEncrypt(%q) = %q, %d", tt.name, tt.plain, text, keyId)
+		}
+		plain, err := CodeDecrypt(text, keyId)
+		if err != nil || plain != tt.plain {
+			t.Errorf("%s: CodeDecrypt = %q, %v, want %q", tt.name, plain, err, tt.plain)
+		}
+	}
+	// 没有HMAC密钥的时候不能加密，否则保存之后查找不到
+	setCodeKeys(t, map[int]string{1: testCodeKey1}, 1, "")
+	if _, _, err := CodeEncrypt("CODE-0006"); err == nil {
+		t.Errorf("CodeEncrypt without hash key, want error")
+	}
+}
+
+func TestCodeDecrypt(t *testing.T) {
+	setCodeKeys(t, map[int]string{1: testCodeKey1, 2: testCodeKey2}, 1, "hash-key")
+	text, keyId, err := CodeEncrypt("CODE-0001")
+	if err != nil {
+		t.Fatal("CodeEncrypt error", err)
+	}
+	// 每次加密使用随机的nonce，密文不同
+	if again, _, _ := CodeEncrypt("CODE-0001"); again == text {
+		t.Errorf("CodeEncrypt returned the same ciphertext twice")
+	}
+	tests := []struct {
+		name  string
+		text  string
+		keyId int
+	}{
+		{"使用其他版本的密钥", text, 2},
+		{"密钥版本不存在", text, 3},
+		{"不是base64", "not base64!", keyId},
+		{"密文太短", "AAAA", keyId},
+		{"密文被修改", text[:len(text)-4] + "AAAA", keyId},
+	}
+	for _, tt := range tests {
+		if plain, err := CodeDecrypt(tt.text, tt.keyId); err == nil {
+			t.Errorf("%s: CodeDecrypt = %q, want error", tt.name, plain)
+		}
+	}
+}
+
+func TestCodeHash(t *testing.T) {
+	setCodeKeys(t, map[int]string{1: testCodeKey1}, 1, "hash-key")
+	hash := CodeHash("CODE-0001")
+	if len(hash) != 64 || hash != CodeHash("CODE-0001") {
+		t.Errorf("CodeHash = %q, want the same 64 hex chars every time", hash)
+	}
+	if hash == CodeHash("CODE-0002") {
+		t.Errorf("CodeHash is the same for different codes")
+	}
+	conf.CodeHashKey = "other-key"
+	if hash == CodeHash("CODE-0001") {
+		t.Errorf("CodeHash is the same for different keys")
+	}
+}
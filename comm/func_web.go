@@ -8,14 +8,131 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
 	"net"
 )
 
 // 得到客户端IP地址
+// 请求来自可信的代理服务器时，依次使用 Forwarded、X-Forwarded-For、X-Real-IP 中的地址
 func ClientIP(request *http.Request) string {
-	host, _, _ := net.SplitHostPort(request.RemoteAddr)
-	return host
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		host = request.RemoteAddr
+	}
+	ip := parseHeaderIp(host)
+	if ip == "" {
+		return host
+	}
+	proxies := trustedProxies()
+	if !isTrustedProxy(proxies, ip) {
+		return ip
+	}
+	// 从右往左，跳过可信的代理，第一个不可信的地址就是客户端
+	for _, chain := range [][]string{forwardedFor(request), forwardedXff(request)} {
+		if len(chain) == 0 {
+			continue
+		}
+		for i := len(chain) - 1; i >= 0; i-- {
+			if chain[i] == "" {
+				// 无法识别的地址，不能继续向前信任
+				break
+			}
+			ip = chain[i]
+			if !isTrustedProxy(proxies, ip) {
+				break
+			}
+		}
+		return ip
+	}
+	if realIp := parseHeaderIp(request.Header.Get("X-Real-IP")); realIp != "" {
+		return realIp
+	}
+	return ip
+}
+
+func trustedProxies() []*net.IPNet {
+	proxies := make([]*net.IPNet, 0, len(conf.TrustedProxies))
+	for _, str := range conf.TrustedProxies {
+		ipNet, err := ParseIpNet(str)
+		if err != nil {
+			log.Println("func_web.trustedProxies ParseIpNet ", str, ", error=", err)
+			continue
+		}
+		proxies = append(proxies, ipNet)
+	}
+	return proxies
+}
+
+func isTrustedProxy(proxies []*net.IPNet, ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, ipNet := range proxies {
+		if ipNet.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// X-Forwarded-For: client, proxy1, proxy2
+func forwardedXff(request *http.Request) []string {
+	chain := make([]string, 0)
+	for _, value := range request.Header.Values("X-Forwarded-For") {
+		for _, str := range strings.Split(value, ",") {
+			chain = append(chain, parseHeaderIp(str))
+		}
+	}
+	return chain
+}
+
+// Forwarded: for=192.0.2.60;proto=http, for="[2001:db8:cafe::17]:4711"
+func forwardedFor(request *http.Request) []string {
+	chain := make([]string, 0)
+	for _, value := range request.Header.Values("Forwarded") {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+				if len(kv) == 2 && strings.EqualFold(kv[0], "for") {
+					chain = append(chain, parseHeaderIp(kv[1]))
+				}
+			}
+		}
+	}
+	return chain
+}
+
+// 头信息中的地址可能带有引号、端口、方括号，返回标准化的IP，无法识别的时候返回空字符串
+func parseHeaderIp(str string) string {
+	str = strings.Trim(strings.TrimSpace(str), "\"")
+	if host, _, err := net.SplitHostPort(str); err == nil {
+		str = host
+	}
+	str = strings.TrimSuffix(strings.TrimPrefix(str, "["), "]")
+	return NormalizeIp(str)
+}
+
+// 设备标识，客户端通过请求头或者参数传递
+const DeviceHeader = "X-Device-Id"
+
+// 得到客户端的设备标识，只保留字母、数字和-_，最长64个字符
+func ClientDevice(request *http.Request) string {
+	device := request.Header.Get(DeviceHeader)
+	if device == "" {
+		device = request.URL.Query().Get("device_id")
+	}
+	device = strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '-' || r == '_' {
+			return r
+		}
+		return -1
+	}, device)
+	if len(device) > 64 {
+		device = device[:64]
+	}
+	return device
 }
 
 // 跳转URL
@@ -1,12 +1,8 @@
 package comm
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
 	"crypto/md5"
-	"encoding/base64"
 	"encoding/binary"
-	"errors"
 	"fmt"
 	"github.com/iralance/go-lottery/conf"
 	"math/rand"
@@ -60,41 +56,40 @@ func CreateSign(str string) string {
 	return sign
 }
 
-// 对一个字符串进行加密
-func encrypt(key, text []byte) ([]byte, error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, err
+// 用户名打码，保留前面prefix个和后面suffix个字符
+// 用户名太短的时候减少保留的字符，至少要隐藏一个字符
+func MaskUsername(name string, prefix, suffix int) string {
+	r := []rune(name)
+	if len(r) == 0 {
+		return ""
 	}
-	b := base64.StdEncoding.EncodeToString(text)
-	ciphertext := make([]byte, aes.BlockSize+len(b))
-	iv := ciphertext[:aes.BlockSize]
-	//if _, err := io.ReadFull(rand.Reader, iv); err != nil {
-	//	return nil, err
-	//}
-	cfb := cipher.NewCFBEncrypter(block, iv)
-	cfb.XORKeyStream(ciphertext[aes.BlockSize:], []byte(b))
-	return ciphertext, nil
+	for prefix+suffix >= len(r) {
+		if suffix >= prefix {
+			suffix--
+		} else {
+			prefix--
+		}
+	}
+	return string(r[:prefix]) + "***" + string(r[len(r)-suffix:])
 }
 
-// 对一个字符串进行解密
-func decrypt(key, text []byte) ([]byte, error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, err
-	}
-	if len(text) < aes.BlockSize {
-		return nil, errors.New("ciphertext too short")
+// 表格软件会当作公式执行的开头字符
+const csvFormulaChars = "=+-@\t\r"
+
+// 导出CSV的单元格，以公式字符开头的内容前面加上单引号，Excel打开的时候不会当作公式执行
+func CsvEscape(str string) string {
+	if str != "" && strings.ContainsRune(csvFormulaChars, rune(str[0])) {
+		return "'" + str
 	}
-	iv := text[:aes.BlockSize]
-	text = text[aes.BlockSize:]
-	cfb := cipher.NewCFBDecrypter(block, iv)
-	cfb.XORKeyStream(text, text)
-	data, err := base64.StdEncoding.DecodeString(string(text))
-	if err != nil {
-		return nil, err
+	return str
+}
+
+// 导入CSV的单元格，去掉CsvEscape添加的单引号
+func CsvUnescape(str string) string {
+	if len(str) > 1 && str[0] == '\'' && strings.ContainsRune(csvFormulaChars, rune(str[1])) {
+		return str[1:]
 	}
-	return data, nil
+	return str
 }
 
 // addslashes() 函数返回在预定义字符之前添加反斜杠的字符串。
@@ -136,9 +131,9 @@ func Ip4toInt(ip string) int64 {
 	bits := strings.Split(ip, ".")
 	if len(bits) == 4 {
 		b0, _ := strconv.Atoi(bits[0])
-		b1, _ := strconv.Atoi(bits[0])
-		b2, _ := strconv.Atoi(bits[0])
-		b3, _ := strconv.Atoi(bits[0])
+		b1, _ := strconv.Atoi(bits[1])
+		b2, _ := strconv.Atoi(bits[2])
+		b3, _ := strconv.Atoi(bits[3])
 		var sum int64
 		sum += int64(b0) << 24
 		sum += int64(b1) << 16
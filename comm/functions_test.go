@@ -0,0 +1,51 @@
+package comm
+
+import "testing"
+
+func TestMaskUsername(t *testing.T) {
+	tests := []struct {
+		name           string
+		prefix, suffix int
+		want           string
+	}{
+		{"admin-12345", 3, 2, "adm***45"},
+		{"abcde", 3, 2, "ab***de"},
+		{"ab", 3, 2, "a***"},
+		{"a", 3, 2, "***"},
+		{"", 3, 2, ""},
+		{"张三丰", 1, 1, "张***丰"},
+	}
+	for _, tt := range tests {
+		if got := MaskUsername(tt.name, tt.prefix, tt.suffix); got != tt.want {
+			t.Errorf("MaskUsername(%q, %d, %d) = %q, want %q", tt.name, tt.prefix, tt.suffix, got, tt.want)
+		}
+	}
+}
+
+func TestCsvEscape(t *testing.T) {
+	tests := []struct {
+		str, want string
+	}{
+		{"", ""},
+		{"北京市朝阳区", "北京市朝阳区"},
+		{"13800000000", "13800000000"},
+		{"=HYPERLINK(\"http://x\")", "'=HYPERLINK(\"http://x\")"},
+		{"+1+2", "'+1+2"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1", "'\t=1"},
+		{"\r=1", "'\r=1"},
+		{"a=1", "a=1"},
+		{"'=1", "'=1"},
+	}
+	for _, tt := range tests {
+		got := CsvEscape(tt.str)
+		if got != tt.want {
+			t.Errorf("CsvEscape(%q) = %q, want %q", tt.str, got, tt.want)
+		}
+		// 导出之后再导入，内容不变
+		if back := CsvUnescape(got); back != tt.str && tt.str != "'=1" {
+			t.Errorf("CsvUnescape(%q) = %q, want %q", got, back, tt.str)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package comm
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/iralance/go-lottery/conf"
+)
+
+// 标准化的IP地址，IPv4映射的IPv6地址转换成IPv4，无法解析的时候返回空字符串
+func NormalizeIp(ip string) string {
+	addr := net.ParseIP(strings.TrimSpace(ip))
+	if addr == nil {
+		return ""
+	}
+	return addr.String()
+}
+
+// 解析单个IP或者CIDR网段，单个IP转换成/32或者/128的网段
+func ParseIpNet(str string) (*net.IPNet, error) {
+	str = strings.TrimSpace(str)
+	if strings.Contains(str, "/") {
+		_, ipNet, err := net.ParseCIDR(str)
+		if err != nil {
+			return nil, err
+		}
+		if ip4 := ipNet.IP.To4(); ip4 != nil && len(ipNet.Mask) == net.IPv6len {
+			// ::ffff:a.b.c.d/n 的写法转换成IPv4网段
+			ones, _ := ipNet.Mask.Size()
+			if ones < 96 {
+				return nil, errors.New("invalid IPv4-mapped CIDR " + str)
+			}
+			ipNet = &net.IPNet{IP: ip4, Mask: net.CIDRMask(ones-96, 32)}
+		}
+		return ipNet, nil
+	}
+	addr := net.ParseIP(str)
+	if addr == nil {
+		return nil, errors.New("invalid IP " + str)
+	}
+	if ip4 := addr.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: addr, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// IP计数使用的key，IPv4使用完整的地址，IPv6使用前缀网段
+func IpCounterKey(ip string) string {
+	addr := net.ParseIP(strings.TrimSpace(ip))
+	if addr == nil {
+		return ip
+	}
+	if addr.To4() != nil {
+		return addr.String()
+	}
+	mask := net.CIDRMask(conf.IpV6CounterPrefix, 128)
+	ipNet := &net.IPNet{IP: addr.Mask(mask), Mask: mask}
+	return ipNet.String()
+}
+
+// IP网段的前缀树，判断IP属于哪些网段
+// IPv4和IPv6分开保存，查找的时间只和地址长度相关
+type IpTrie struct {
+	v4 *ipTrieNode
+	v6 *ipTrieNode
+}
+
+type ipTrieNode struct {
+	child  [2]*ipTrieNode
+	values []int
+}
+
+func NewIpTrie() *IpTrie {
+	return &IpTrie{
+		v4: &ipTrieNode{},
+		v6: &ipTrieNode{},
+	}
+}
+
+func (t *IpTrie) root(ip net.IP) (*ipTrieNode, net.IP) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return t.v4, ip4
+	}
+	return t.v6, ip.To16()
+}
+
+// 增加一个网段，value是网段对应的数据
+func (t *IpTrie) Insert(ipNet *net.IPNet, value int) {
+	node, ip := t.root(ipNet.IP)
+	ones, _ := ipNet.Mask.Size()
+	for i := 0; i < ones; i++ {
+		bit := ip[i/8] >> (7 - uint(i%8)) & 1
+		if node.child[bit] == nil {
+			node.child[bit] = &ipTrieNode{}
+		}
+		node = node.child[bit]
+	}
+	node.values = append(node.values, value)
+}
+
+// 查找包含这个IP的所有网段
+func (t *IpTrie) Lookup(ip net.IP) []int {
+	if ip == nil {
+		return nil
+	}
+	node, ip := t.root(ip)
+	values := append([]int{}, node.values...)
+	for i := 0; i < len(ip)*8; i++ {
+		bit := ip[i/8] >> (7 - uint(i%8)) & 1
+		node = node.child[bit]
+		if node == nil {
+			break
+		}
+		values = append(values, node.values...)
+	}
+	return values
+}
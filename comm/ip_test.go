@@ -0,0 +1,83 @@
+package comm
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParseIpNet(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"10.1.2.3", "10.1.2.3/32"},
+		{" 10.1.2.0/24 ", "10.1.2.0/24"},
+		{"::ffff:10.1.0.0/112", "10.1.0.0/16"},
+		{"2001:db8::1", "2001:db8::1/128"},
+		{"2001:db8::/32", "2001:db8::/32"},
+	}
+	for _, tt := range tests {
+		ipNet, err := ParseIpNet(tt.in)
+		if err != nil {
+			t.Errorf("ParseIpNet(%q) error=%v", tt.in, err)
+			continue
+		}
+		if ipNet.String() != tt.want {
+			t.Errorf("ParseIpNet(%q) = %s, want %s", tt.in, ipNet, tt.want)
+		}
+	}
+	for _, in := range []string{"", "10.1.2", "10.1.2.0/33"} {
+		if _, err := ParseIpNet(in); err == nil {
+			t.Errorf("ParseIpNet(%q) want error", in)
+		}
+	}
+}
+
+func TestIpCounterKey(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"10.1.2.3", "10.1.2.3"},
+		{"::ffff:10.1.2.3", "10.1.2.3"},
+		{"2001:db8:0:1::1", "2001:db8:0:1::/64"},
+		{"2001:db8:0:1:ffff::2", "2001:db8:0:1::/64"},
+		{"bad", "bad"},
+	}
+	for _, tt := range tests {
+		if got := IpCounterKey(tt.in); got != tt.want {
+			t.Errorf("IpCounterKey(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIpTrie(t *testing.T) {
+	trie := NewIpTrie()
+	for i, cidr := range []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.3", "2001:db8::/32", "0.0.0.0/0"} {
+		ipNet, err := ParseIpNet(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trie.Insert(ipNet, i+1)
+	}
+	tests := []struct {
+		ip   string
+		want []int
+	}{
+		{"10.1.2.3", []int{5, 1, 2, 3}},
+		{"10.1.9.9", []int{5, 1, 2}},
+		{"10.2.0.1", []int{5, 1}},
+		{"192.168.1.1", []int{5}},
+		{"2001:db8::1", []int{4}},
+		{"2001:db9::1", []int{}},
+	}
+	for _, tt := range tests {
+		if got := trie.Lookup(net.ParseIP(tt.ip)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Lookup(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+	if got := trie.Lookup(nil); got != nil {
+		t.Errorf("Lookup(nil) = %v, want nil", got)
+	}
+}
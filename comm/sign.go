@@ -0,0 +1,24 @@
+package comm
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// 对外接口的HMAC签名，签名内容是 timestamp.body
+func HmacSign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// 验证签名，使用固定时间的比较，没有配置密钥的时候都不通过
+func HmacVerify(secret, timestamp string, body []byte, sign string) bool {
+	if secret == "" {
+		return false
+	}
+	return hmac.Equal([]byte(HmacSign(secret, timestamp, body)), []byte(sign))
+}
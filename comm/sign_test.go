@@ -0,0 +1,28 @@
+package comm
+
+import "testing"
+
+func TestHmacVerify(t *testing.T) {
+	body := []byte(`{"code":"A1"}`)
+	sign := HmacSign("secret", "1700000000", body)
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      []byte
+		sign      string
+		want      bool
+	}{
+		{"正确的签名", "secret", "1700000000", body, sign, true},
+		{"密钥不同", "other", "1700000000", body, sign, false},
+		{"时间戳不同", "secret", "1700000001", body, sign, false},
+		{"内容不同", "secret", "1700000000", []byte(`{"code":"A2"}`), sign, false},
+		{"没有签名", "secret", "1700000000", body, "", false},
+		{"没有配置密钥", "", "1700000000", body, HmacSign("", "1700000000", body), false},
+	}
+	for _, tt := range tests {
+		if got := HmacVerify(tt.secret, tt.timestamp, tt.body, tt.sign); got != tt.want {
+			t.Errorf("%s: HmacVerify = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -5,6 +5,206 @@ import "time"
 const UserPrizeMax = 3000 // 用户每天最多抽奖次数
 const IpPrizeMax = 30000  // 同一个IP每天最多抽奖次数
 const IpLimitMax = 300000 // 同一个IP每天最多抽奖次数
+
+// 用户查看自己的中奖记录、抽奖记录，每页的数量
+const UserListPageSize = 20
+
+// 一次抽奖多次，最多的次数
+const LuckyBatchMax = 10
+
+// 一次抽奖的次数达到N的时候，至少获得一个某类型的奖品，-1表示不保底
+var LuckyBatchGuaranteeNum = 10
+var LuckyBatchGuaranteeGtype = -1
+
+// 额外抽奖机会的来源，每天免费的次数用完之后使用
+const ChanceSourceCheckin = 1 // 每日签到
+const ChanceSourceInvite = 2  // 邀请用户
+const ChanceSourceShare = 3   // 分享
+const ChanceSourceEvent = 4   // 外部活动接口发放
+
+// 每个来源一次获得的抽奖次数
+var ChanceGrantNum = map[int]int{
+	ChanceSourceCheckin: 1,
+	ChanceSourceInvite:  2,
+	ChanceSourceShare:   1,
+}
+
+var ChanceExpireDays = 7    // 额外抽奖机会的有效期，天
+var ChanceInviteDayMax = 10 // 每天最多通过邀请获得抽奖机会的次数
+var ChanceEventMax = 100    // 外部活动一次最多发放的抽奖次数
+
+// 外部活动发放抽奖机会的接口，请求需要使用这个密钥做HMAC签名，启动的时候读取，见LoadSecrets
+var ChancePartnerSecret = ""
+
+// 积分流水的类型
+const PointActionEarn = 1   // 获得
+const PointActionSpend = 2  // 抽奖消耗
+const PointActionRefund = 3 // 抽奖失败退回
+
+// 每个活动每次抽奖消耗的积分，0表示免费抽奖，抽奖的时候使用campaign参数选择活动
+// 需要积分的活动，抽奖之前扣除积分，不再使用每天免费的次数
+var DrawPointsCost = map[string]int{
+	"": 0,
+}
+
+// 接口的访问频率限制，Window秒内最多Limit次请求
+// Key是限制的维度：ip、uid、device
+type RateLimit struct {
+	Path   string
+	Key    string
+	Limit  int
+	Window int
+}
+
+var RateLimits = []RateLimit{
+	{Path: "/lucky", Key: "uid", Limit: 5, Window: 1},
+	{Path: "/lucky", Key: "device", Limit: 5, Window: 1},
+	{Path: "/lucky", Key: "ip", Limit: 100, Window: 1},
+	// 一次抽奖多次，路径需要完全相同才会限制
+	{Path: "/lucky/batch", Key: "uid", Limit: 1, Window: 1},
+	{Path: "/lucky/batch", Key: "device", Limit: 1, Window: 1},
+	{Path: "/lucky/batch", Key: "ip", Limit: 20, Window: 1},
+	{Path: "/gifts", Key: "ip", Limit: 100, Window: 1},
+	{Path: "/newprize", Key: "ip", Limit: 100, Window: 1},
+}
+
+// 中奖记录的审核状态，同时也是审核记录中的操作
+const ReviewNone = 0          // 没有审核
+const ReviewFlagged = 1       // 标记，等待审核
+const ReviewInvestigating = 2 // 调查中
+const ReviewConfirmed = 3     // 确认作弊
+const ReviewRevoked = 4       // 已经撤销奖品
+const ReviewCleared = 5       // 审核通过，没有作弊
+
+// 中奖记录的状态
+const ResultStatusNormal = 0  // 正常
+const ResultStatusDeleted = 1 // 删除
+const ResultStatusCheat = 2   // 作弊
+
+// 实物奖品的发货状态
+const FulfillPendingAddress = 1 // 等待填写地址
+const FulfillReady = 2          // 等待发货
+const FulfillShipped = 3        // 已发货
+const FulfillDelivered = 4      // 已签收
+const FulfillExpired = 5        // 超时没有填写地址，已过期
+
+// 中奖之后填写收货地址的期限，单位：天
+var FulfillAddressDays = 7
+
+// 优惠券编码的状态
+const CodeStatusNormal = 0   // 正常，等待发放
+const CodeStatusVoid = 1     // 作废，没有发放就过期的也作废
+const CodeStatusIssued = 2   // 已发放
+const CodeStatusRedeemed = 3 // 已核销
+const CodeStatusExpired = 4  // 发放之后没有核销，已过期
+
+// 优惠券编码导入，每批写入的数量
+var CodeImportBatch = 500
+
+// 上传的文件超过这个大小的时候在后台导入，单位：字节
+var CodeImportAsyncSize int64 = 1 << 20
+
+// 导入任务的进度保留时间，单位：秒
+var CodeImportJobExpire = 86400
+
+// 系统生成优惠券编码的默认规则，去掉了容易混淆的0、O、1、I
+var CodeGenAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+var CodeGenLength = 12
+
+// 一次最多生成的优惠券数量
+var CodeGenMax = 100000
+
+// 优惠券编码加密保存的密钥，key是密钥版本，value是base64编码的32字节密钥，启动的时候读取，见LoadSecrets
+// 新的编码使用CodeKeyCurrent加密，轮换之后旧版本的编码都重新加密了才能删除旧的密钥
+var CodeKeys = map[int]string{}
+var CodeKeyCurrent = 0
+
+// 查找优惠券编码使用的HMAC密钥，修改之后所有编码的code_hash和缓存都需要重新生成
+var CodeHashKey = ""
+
+// 合作方核销优惠券的接口，请求需要使用这个密钥做HMAC签名
+var CodePartnerSecret = ""
+
+// 核销请求的时间戳允许的误差，单位：秒
+var CodeRedeemSkew = 300
+
+// 虚拟币奖品的发放状态
+const DeliverNone = 0    // 不需要发放
+const DeliverPending = 1 // 发放中
+const DeliverDone = 2    // 已到账
+const DeliverDead = 3    // 多次重试都失败，等待人工处理
+
+// 虚拟币奖品通过webhook通知钱包服务入账
+// Url为空的时候不发放，Secret用来做HMAC签名，Url和Secret启动的时候读取，见LoadSecrets
+type Webhook struct {
+	Url        string
+	Secret     string
+	Timeout    time.Duration
+	MaxTries   int
+	RetryDelay time.Duration // 第一次重试的间隔，之后每次翻倍
+}
+
+var RewardWebhook = Webhook{
+	Url:        "",
+	Secret:     "",
+	Timeout:    5 * time.Second,
+	MaxTries:   5,
+	RetryDelay: 2 * time.Second,
+}
+
+// 中奖动态的发布订阅频道，以及保留的最新中奖记录数量
+const WinnerChannel = "lottery_winners"
+const WinnerRecentSize = 50
+
+// 中奖榜单中的用户名打码，保留前面和后面的字符数，例如 adm***45
+var WinnerMaskPrefix = 3
+var WinnerMaskSuffix = 2
+
+// 可以在公开的中奖榜单中展示的奖品类型，虚拟券或者实物奖
+var WinnerPublicGtypes = map[int]bool{
+	GtypeCodeDiff:  true,
+	GtypeGiftSmall: true,
+	GtypeGiftLarge: true,
+}
+
+// 风控规则要求验证的时候使用的验证方式，pow 计算工作量证明，image 图片验证码
+var ChallengeType = "pow"
+
+const ChallengePowBits = 18   // 工作量证明需要的前导0的位数
+const ChallengeImageLen = 5   // 图片验证码的数字个数
+const ChallengeExpire = 300   // 验证的有效期，秒
+const ChallengePassTime = 600 // 完成验证之后，多长时间内不需要再次验证，秒
+
+// 用户、IP、设备之间的关联关系，保存的时长，秒
+const LinkExpire = 30 * 86400
+
+// 关联风险分，每多一个关联的对象增加的分数
+const LinkScoreDeviceUid = 20 // 同一个设备上的其他用户
+const LinkScoreUidDevice = 10 // 同一个用户的其他设备
+const LinkScoreUidIp = 2      // 同一个用户的其他IP
+const LinkScoreIpUid = 1      // 同一个IP上的其他用户
+
+// 每一项关联最多增加的分数，NAT、负载均衡后面同一个IP上的用户很多，不能只靠这一项就超过阈值
+const LinkScoreDeviceUidMax = 200
+const LinkScoreUidDeviceMax = 100
+const LinkScoreUidIpMax = 40
+const LinkScoreIpUidMax = 40
+
+// IPv6的用户通常会分配到一整个/64的网段，按照这个长度的前缀统计
+const IpV6CounterPrefix = 64
+
+// 用户中奖次数的限制，0表示不限制
+var UserGiftWinMax = 0 // 同一个奖品，每个用户最多中奖次数
+var UserDayWinMax = 0  // 每个用户每天最多中奖次数
+var UserWeekWinMax = 0 // 每个用户每周最多中奖次数
+
+// 没有中奖时发放的安慰奖，每个用户每天最多获得的次数，0表示不限制
+var UserDayConsolationMax = 3
+
+// 每种类型的奖品，每个用户最多中奖次数，不限定周期
+// 实物大奖默认使用冷却规则，中奖之后30天内不能再获得
+var UserGtypeWinMax = map[int]int{}
+
 // 定义24小时的奖品分配权重
 var PrizeDataRandomDayTime = [100]int{
 	// 24 * 3 = 72   平均3%的机会
@@ -42,9 +242,36 @@ const GtypeCodeDiff = 2  // 虚拟券，不同的码
 const GtypeGiftSmall = 3 // 实物小奖
 const GtypeGiftLarge = 4 // 实物大奖
 
+// 风控规则的类型
+const RuleTypeWinCooldown = 1 // 获得某类奖品之后，冷却N天
+const RuleTypeIpUsers = 2     // 同一个IP今天参与的用户数超过K
+const RuleTypeIpLucky = 3     // 同一个IP今天的抽奖次数超过K
+const RuleTypeUserLucky = 4   // 用户今天的抽奖次数超过K
+const RuleTypeLinkScore = 5   // 用户、IP、设备关联的风险分超过K，拒绝的时候不计算同一个IP上的其他用户
+
+// 风控规则的处理方式，数值越大越严格
+const RuleActionFlag = 1      // 标记中奖记录
+const RuleActionDowngrade = 2 // 降级，只能获得小奖
+const RuleActionCaptcha = 3   // 需要验证之后才能抽奖
+const RuleActionDeny = 4      // 拒绝抽奖
+
+// 连续没有中奖之后的保底规则的处理方式
+const PityActionBoost = 1     // 每多一次没有中奖，提高获得某类奖品的概率
+const PityActionGuarantee = 2 // 必定获得某类奖品
+
+// 连续没有中奖次数的统计区间，后台展示用户的分布
+var PityLossBuckets = []int{0, 10, 20, 30, 50, 100}
+
+// 用户连续没有中奖的次数，多少天没有抽奖之后过期
+const PityLossExpireDays = 30
+
 const SysTimeform = "2006-01-02 15:04:05"
 const SysTimeformShort = "2006-01-02"
 
+// 可信的代理服务器网段，只有来自这些地址的请求才会使用
+// Forwarded、X-Forwarded-For、X-Real-IP 中的客户端IP
+var TrustedProxies = []string{}
+
 // 是否需要启动全局计划任务服务
 var RunningCrontabService = false
 
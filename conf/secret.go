@@ -0,0 +1,142 @@
+package conf
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 密钥不保存在代码中，启动的时候从环境变量读取
+// 也可以使用LOTTERY_SECRET_FILE指定一个文件，每行一个 名称=值，环境变量优先
+//
+//	LOTTERY_CODE_KEYS              优惠券编码加密的密钥，版本:base64编码的32字节密钥，多个用逗号分隔，例如 1:xxx,2:yyy
+//	LOTTERY_CODE_KEY_CURRENT       新的编码使用的密钥版本，默认是最大的版本
+//	LOTTERY_CODE_HASH_KEY          查找优惠券编码使用的HMAC密钥
+//	LOTTERY_CODE_PARTNER_SECRET    合作方核销优惠券的签名密钥
+//	LOTTERY_CHANCE_PARTNER_SECRET  外部活动发放抽奖机会的签名密钥
+//	LOTTERY_WALLET_WEBHOOK_URL     钱包服务的入账地址，为空的时候不发放虚拟币
+//	LOTTERY_WALLET_WEBHOOK_SECRET  通知钱包服务的签名密钥，设置了入账地址的时候必须设置
+const SecretFileEnv = "LOTTERY_SECRET_FILE"
+
+// 读取密钥，已经设置的配置只在环境变量或者文件中有值的时候才覆盖
+// 读取之后还缺少密钥的时候返回错误，应用不能启动
+func LoadSecrets() error {
+	values, err := readSecretFile(os.Getenv(SecretFileEnv))
+	if err != nil {
+		return err
+	}
+	get := func(name string) string {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+		return values[name]
+	}
+	if value := get("LOTTERY_CODE_KEYS"); value != "" {
+		keys, err := parseCodeKeys(value)
+		if err != nil {
+			return err
+		}
+		CodeKeys = keys
+		CodeKeyCurrent = 0
+		for keyId := range keys {
+			if keyId > CodeKeyCurrent {
+				CodeKeyCurrent = keyId
+			}
+		}
+	}
+	if value := get("LOTTERY_CODE_KEY_CURRENT"); value != "" {
+		keyId, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("LOTTERY_CODE_KEY_CURRENT %q is not a number", value)
+		}
+		CodeKeyCurrent = keyId
+	}
+	setSecret(&CodeHashKey, get("LOTTERY_CODE_HASH_KEY"))
+	setSecret(&CodePartnerSecret, get("LOTTERY_CODE_PARTNER_SECRET"))
+	setSecret(&ChancePartnerSecret, get("LOTTERY_CHANCE_PARTNER_SECRET"))
+	setSecret(&RewardWebhook.Url, get("LOTTERY_WALLET_WEBHOOK_URL"))
+	setSecret(&RewardWebhook.Secret, get("LOTTERY_WALLET_WEBHOOK_SECRET"))
+	return CheckSecrets()
+}
+
+// 缺少的密钥，全部列出来，方便一次配置好
+func CheckSecrets() error {
+	missing := make([]string, 0)
+	if _, ok := CodeKeys[CodeKeyCurrent]; !ok {
+		missing = append(missing, "LOTTERY_CODE_KEYS")
+	}
+	if CodeHashKey == "" {
+		missing = append(missing, "LOTTERY_CODE_HASH_KEY")
+	}
+	if CodePartnerSecret == "" {
+		missing = append(missing, "LOTTERY_CODE_PARTNER_SECRET")
+	}
+	if ChancePartnerSecret == "" {
+		missing = append(missing, "LOTTERY_CHANCE_PARTNER_SECRET")
+	}
+	if RewardWebhook.Url != "" && RewardWebhook.Secret == "" {
+		missing = append(missing, "LOTTERY_WALLET_WEBHOOK_SECRET")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing secrets: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func setSecret(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
+
+// 密钥文件，每行一个 名称=值，空行和#开头的行忽略
+func readSecretFile(name string) (map[string]string, error) {
+	values := make(map[string]string)
+	if name == "" {
+		return values, nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pos := strings.Index(line, "=")
+		if pos < 1 {
+			return nil, fmt.Errorf("secret file %s: invalid line %q", name, line)
+		}
+		values[strings.TrimSpace(line[:pos])] = strings.TrimSpace(line[pos+1:])
+	}
+	return values, scanner.Err()
+}
+
+// 版本:密钥，多个用逗号分隔
+func parseCodeKeys(value string) (map[int]string, error) {
+	keys := make(map[int]string)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		pos := strings.Index(item, ":")
+		if pos < 1 {
+			return nil, fmt.Errorf("LOTTERY_CODE_KEYS: invalid key %q", item)
+		}
+		keyId, err := strconv.Atoi(item[:pos])
+		if err != nil || keyId <= 0 {
+			return nil, fmt.Errorf("LOTTERY_CODE_KEYS: invalid key version %q", item[:pos])
+		}
+		keys[keyId] = item[pos+1:]
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("LOTTERY_CODE_KEYS is empty")
+	}
+	return keys, nil
+}
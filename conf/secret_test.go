@@ -0,0 +1,102 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 每个测试之前清空密钥，测试结束之后恢复原来的配置
+func resetSecrets(t *testing.T) {
+	keys, current, hashKey := CodeKeys, CodeKeyCurrent, CodeHashKey
+	codeSecret, chanceSecret, webhook := CodePartnerSecret, ChancePartnerSecret, RewardWebhook
+	t.Cleanup(func() {
+		CodeKeys, CodeKeyCurrent, CodeHashKey = keys, current, hashKey
+		CodePartnerSecret, ChancePartnerSecret, RewardWebhook = codeSecret, chanceSecret, webhook
+	})
+	CodeKeys, CodeKeyCurrent, CodeHashKey = map[int]string{}, 0, ""
+	CodePartnerSecret, ChancePartnerSecret = "", ""
+	RewardWebhook.Url, RewardWebhook.Secret = "", ""
+}
+
+func TestLoadSecrets(t *testing.T) {
+	all := map[string]string{
+		"LOTTERY_CODE_KEYS":             "1:a2V5MQ==, 3:a2V5Mw==",
+		"LOTTERY_CODE_HASH_KEY":         "hash-key",
+		"LOTTERY_CODE_PARTNER_SECRET":   "code-secret",
+		"LOTTERY_CHANCE_PARTNER_SECRET": "chance-secret",
+	}
+	without := func(name string) map[string]string {
+		env := make(map[string]string)
+		for k, v := range all {
+			if k != name {
+				env[k] = v
+			}
+		}
+		return env
+	}
+	with := func(name, value string) map[string]string {
+		env := without("")
+		env[name] = value
+		return env
+	}
+	tests := []struct {
+		name    string
+		env     map[string]string
+		file    string
+		wantErr string
+		current int
+	}{
+		{name: "全部设置", env: all, current: 3},
+		{name: "没有设置", env: nil, wantErr: "LOTTERY_CODE_KEYS, LOTTERY_CODE_HASH_KEY, LOTTERY_CODE_PARTNER_SECRET, LOTTERY_CHANCE_PARTNER_SECRET"},
+		{name: "缺少加密密钥", env: without("LOTTERY_CODE_KEYS"), wantErr: "missing secrets: LOTTERY_CODE_KEYS"},
+		{name: "缺少HMAC密钥", env: without("LOTTERY_CODE_HASH_KEY"), wantErr: "missing secrets: LOTTERY_CODE_HASH_KEY"},
+		{name: "缺少核销密钥", env: without("LOTTERY_CODE_PARTNER_SECRET"), wantErr: "missing secrets: LOTTERY_CODE_PARTNER_SECRET"},
+		{name: "缺少抽奖机会密钥", env: without("LOTTERY_CHANCE_PARTNER_SECRET"), wantErr: "missing secrets: LOTTERY_CHANCE_PARTNER_SECRET"},
+		{name: "指定当前的密钥版本", env: with("LOTTERY_CODE_KEY_CURRENT", "1"), current: 1},
+		{name: "当前的密钥版本不存在", env: with("LOTTERY_CODE_KEY_CURRENT", "2"), wantErr: "missing secrets: LOTTERY_CODE_KEYS"},
+		{name: "密钥版本格式不对", env: with("LOTTERY_CODE_KEYS", "a2V5MQ=="), wantErr: "invalid key"},
+		{name: "入账地址没有签名密钥", env: with("LOTTERY_WALLET_WEBHOOK_URL", "http://wallet"), wantErr: "missing secrets: LOTTERY_WALLET_WEBHOOK_SECRET"},
+		{
+			name:    "从文件读取，环境变量优先",
+			env:     map[string]string{"LOTTERY_CODE_HASH_KEY": "hash-key"},
+			file:    "# 注释\nLOTTERY_CODE_KEYS=2:a2V5Mg==\nLOTTERY_CODE_HASH_KEY=file-hash-key\n\nLOTTERY_CODE_PARTNER_SECRET = code-secret\nLOTTERY_CHANCE_PARTNER_SECRET=chance-secret\n",
+			current: 2,
+		},
+		{name: "文件格式不对", env: all, file: "LOTTERY_CODE_KEYS\n", wantErr: "invalid line"},
+	}
+	names := []string{"LOTTERY_CODE_KEYS", "LOTTERY_CODE_KEY_CURRENT", "LOTTERY_CODE_HASH_KEY", "LOTTERY_CODE_PARTNER_SECRET",
+		"LOTTERY_CHANCE_PARTNER_SECRET", "LOTTERY_WALLET_WEBHOOK_URL", "LOTTERY_WALLET_WEBHOOK_SECRET", SecretFileEnv}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetSecrets(t)
+			for _, name := range names {
+				t.Setenv(name, tt.env[name])
+			}
+			if tt.file != "" {
+				name := filepath.Join(t.TempDir(), "secrets")
+				if err := os.WriteFile(name, []byte(tt.file), 0600); err != nil {
+					t.Fatal(err)
+				}
+				t.Setenv(SecretFileEnv, name)
+			}
+			err := LoadSecrets()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadSecrets error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadSecrets error = %v", err)
+			}
+			if CodeKeyCurrent != tt.current || CodeKeys[CodeKeyCurrent] == "" {
+				t.Errorf("CodeKeyCurrent = %d, CodeKeys = %v, want current %d", CodeKeyCurrent, CodeKeys, tt.current)
+			}
+			if CodeHashKey != "hash-key" || CodePartnerSecret != "code-secret" || ChancePartnerSecret != "chance-secret" {
+				t.Errorf("secrets = %q %q %q", CodeHashKey, CodePartnerSecret, ChancePartnerSecret)
+			}
+		})
+	}
+}
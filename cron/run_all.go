@@ -1,9 +1,18 @@
 package cron
 
+import (
+	"github.com/iralance/go-lottery/datasource"
+	utils "github.com/iralance/go-lottery/uitls"
+)
+
 /**
  * 需要每个应用服务器都要运行的服务
  * 单个应用的服务
  */
-func ConfigueAppAllCron() {
-
+func ConfigueAppAllCron(cache datasource.Cache) {
+	// 每天零点，用户和IP的今日参与次数归零
+	utils.ResetGroupUserList(cache)
+	utils.ResetGroupIpList(cache)
+	// 本地开发测试的时候，每次重新启动，奖品池自动归零
+	utils.ResetGiftPool(cache)
 }
@@ -2,6 +2,8 @@ package cron
 
 import (
 	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/datasource"
 	"github.com/iralance/go-lottery/services"
 	utils "github.com/iralance/go-lottery/uitls"
 	"log"
@@ -12,17 +14,27 @@ import (
  * 只需要一个应用运行的服务
  * 全局的服务
  */
-func ConfigueAppOneCron() {
+func ConfigueAppOneCron(cache datasource.Cache, giftService services.GiftService,
+	fulfillService services.FulfillService, resultService services.ResultService,
+	codeService services.CodeService, codeGenService services.CodeGenService,
+	rewardDispatcher services.RewardDispatcher) {
 	// 每5分钟执行一次，奖品的发奖计划到期的时候，需要重新生成发奖计划
-	go resetAllGiftPrizeData()
+	go resetAllGiftPrizeData(cache, giftService)
 	// 每分钟执行一次，根据发奖计划，把奖品数量放入奖品池
-	go distributionAllGiftPool()
+	go distributionAllGiftPool(cache, giftService)
+	// 每小时执行一次，超过期限没有填写收货地址的实物奖品过期
+	go expireFulfillPending(fulfillService)
+	// 每5分钟执行一次，发放中断的虚拟币奖品重新发放
+	go resumeRewardDispatch(resultService, rewardDispatcher)
+	// 每小时执行一次，过了有效期的优惠券作废或者过期
+	go expireAllCodes(cache, codeService)
+	// 每分钟执行一次，剩余编码不足的优惠券自动补充
+	go topupAllCodes(cache, giftService, codeService, codeGenService)
 }
 
 // 重置所有奖品的发奖计划
 // 每5分钟执行一次
-func resetAllGiftPrizeData() {
-	giftService := services.NewGiftService()
+func resetAllGiftPrizeData(cache datasource.Cache, giftService services.GiftService) {
 	list := giftService.GetAll(false)
 	nowTime := comm.NowUnix()
 	for _, giftInfo := range list {
@@ -30,7 +42,7 @@ func resetAllGiftPrizeData() {
 			(giftInfo.PrizeData == "" || giftInfo.PrizeEnd <= nowTime) {
 			// 立即执行
 			log.Println("crontab start utils.ResetGiftPrizeData giftInfo=", giftInfo)
-			utils.ResetGiftPrizeData(&giftInfo, giftService)
+			utils.ResetGiftPrizeData(cache, &giftInfo, giftService)
 			// 预加载缓存数据
 			giftService.GetAll(true)
 			log.Println("crontab end utils.ResetGiftPrizeData giftInfo")
@@ -38,16 +50,63 @@ func resetAllGiftPrizeData() {
 	}
 
 	// 每5分钟执行一次
-	time.AfterFunc(5*time.Minute, resetAllGiftPrizeData)
+	time.AfterFunc(5*time.Minute, func() { resetAllGiftPrizeData(cache, giftService) })
 }
 
 // 根据发奖计划，把奖品数量放入奖品池
 // 每分钟执行一次
-func distributionAllGiftPool() {
+func distributionAllGiftPool(cache datasource.Cache, giftService services.GiftService) {
 	log.Println("crontab start utils.DistributionGiftPool")
-	num := utils.DistributionGiftPool()
+	num := utils.DistributionGiftPool(cache, giftService)
 	log.Println("crontab end utils.DistributionGiftPool, num=", num)
 
 	// 每分钟执行一次
-	time.AfterFunc(time.Minute, distributionAllGiftPool)
+	time.AfterFunc(time.Minute, func() { distributionAllGiftPool(cache, giftService) })
+}
+
+// 超过期限没有填写收货地址的实物奖品过期
+// 每小时执行一次
+func expireFulfillPending(fulfillService services.FulfillService) {
+	before := comm.NowUnix() - conf.FulfillAddressDays*86400
+	num := fulfillService.ExpirePending(before)
+	log.Println("crontab fulfillService.ExpirePending, num=", num)
+
+	// 每小时执行一次
+	time.AfterFunc(time.Hour, func() { expireFulfillPending(fulfillService) })
+}
+
+// 应用重启等原因中断的虚拟币发放，重新发放
+// 每5分钟执行一次
+func resumeRewardDispatch(resultService services.ResultService, rewardDispatcher services.RewardDispatcher) {
+	// 超过10分钟还在发放中，认为发放已经中断
+	before := comm.NowUnix() - 600
+	list := resultService.SearchByDeliver(conf.DeliverPending, before, 100)
+	for i := range list {
+		rewardDispatcher.Dispatch(&list[i])
+	}
+	log.Println("crontab resumeRewardDispatch, num=", len(list))
+
+	// 每5分钟执行一次
+	time.AfterFunc(5*time.Minute, func() { resumeRewardDispatch(resultService, rewardDispatcher) })
+}
+
+// 过了有效期的优惠券作废或者过期
+// 每小时执行一次
+func expireAllCodes(cache datasource.Cache, codeService services.CodeService) {
+	num := utils.ExpireCodes(cache, codeService)
+	log.Println("crontab utils.ExpireCodes, num=", num)
+
+	// 每小时执行一次
+	time.AfterFunc(time.Hour, func() { expireAllCodes(cache, codeService) })
+}
+
+// 剩余编码少于阈值的优惠券，按照生成规则自动补充
+// 每分钟执行一次
+func topupAllCodes(cache datasource.Cache, giftService services.GiftService,
+	codeService services.CodeService, codeGenService services.CodeGenService) {
+	num := utils.TopupCodes(cache, giftService, codeService, codeGenService)
+	log.Println("crontab utils.TopupCodes, num=", num)
+
+	// 每分钟执行一次
+	time.AfterFunc(time.Minute, func() { topupAllCodes(cache, giftService, codeService, codeGenService) })
 }
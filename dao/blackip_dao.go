@@ -6,17 +6,29 @@ import (
 	"log"
 )
 
-type BlackipDao struct {
+type BlackipDao interface {
+	Get(id int) *models.LtBlackip
+	GetAll(page, size int) []models.LtBlackip
+	CountAll() int64
+	Search(ip string) []models.LtBlackip
+	Delete(id int) error
+	Update(data *models.LtBlackip, columns []string) error
+	Create(data *models.LtBlackip) (int64, error)
+	GetByIp(ip string) *models.LtBlackip
+	GetAllCidr() []models.LtBlackip
+}
+
+type blackipDao struct {
 	engine *xorm.Engine
 }
 
-func NewBlackipDao(engine *xorm.Engine) *BlackipDao {
-	return &BlackipDao{
+func NewBlackipDao(engine *xorm.Engine) BlackipDao {
+	return &blackipDao{
 		engine: engine,
 	}
 }
 
-func (d *BlackipDao) Get(id int) *models.LtBlackip {
+func (d *blackipDao) Get(id int) *models.LtBlackip {
 	data := &models.LtBlackip{Id: id}
 	ok, err := d.engine.Get(data)
 	if ok && err == nil {
@@ -25,7 +37,7 @@ func (d *BlackipDao) Get(id int) *models.LtBlackip {
 	return nil
 }
 
-func (d *BlackipDao) GetAll(page, size int) []models.LtBlackip {
+func (d *blackipDao) GetAll(page, size int) []models.LtBlackip {
 	offset := (page - 1) * size
 	dataList := make([]models.LtBlackip, 0)
 	err := d.engine.
@@ -39,7 +51,7 @@ func (d *BlackipDao) GetAll(page, size int) []models.LtBlackip {
 	return dataList
 }
 
-func (d *BlackipDao) CountAll() int64 {
+func (d *blackipDao) CountAll() int64 {
 	num, err := d.engine.Count(&models.LtBlackip{})
 	if err != nil {
 		return 0
@@ -47,7 +59,7 @@ func (d *BlackipDao) CountAll() int64 {
 	return num
 }
 
-func (d *BlackipDao) Search(ip string) []models.LtBlackip {
+func (d *blackipDao) Search(ip string) []models.LtBlackip {
 	datalist := make([]models.LtBlackip, 0)
 	err := d.engine.
 		Where("ip=?", ip).
@@ -60,23 +72,23 @@ func (d *BlackipDao) Search(ip string) []models.LtBlackip {
 	}
 }
 
-func (d *BlackipDao) Delete(id int) error {
+func (d *blackipDao) Delete(id int) error {
 	data := &models.LtBlackip{Id: id}
 	_, err := d.engine.Id(data.Id).
 		Update(data)
 	return err
 }
 
-func (d *BlackipDao) Update(data *models.LtBlackip, columns []string) error {
+func (d *blackipDao) Update(data *models.LtBlackip, columns []string) error {
 	_, err := d.engine.Id(data.Id).MustCols(columns...).Update(data)
 	return err
 }
 
-func (d *BlackipDao) Create(data *models.LtBlackip) (int64, error) {
+func (d *blackipDao) Create(data *models.LtBlackip) (int64, error) {
 	return d.engine.Insert(data)
 }
 
-func (d *BlackipDao) GetByIp(ip string) *models.LtBlackip {
+func (d *blackipDao) GetByIp(ip string) *models.LtBlackip {
 	data := &models.LtBlackip{}
 	ok, err := d.engine.Desc("id").Where("ip = ?", ip).Get(data)
 	if ok && err == nil {
@@ -84,3 +96,16 @@ func (d *BlackipDao) GetByIp(ip string) *models.LtBlackip {
 	}
 	return nil
 }
+
+// 全部的CIDR网段黑名单
+func (d *blackipDao) GetAllCidr() []models.LtBlackip {
+	datalist := make([]models.LtBlackip, 0)
+	err := d.engine.
+		Where("ip like ?", "%/%").
+		Desc("id").
+		Find(&datalist)
+	if err != nil {
+		log.Println("blackip_dao.GetAllCidr error=", err)
+	}
+	return datalist
+}
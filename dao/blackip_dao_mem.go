@@ -0,0 +1,112 @@
+package dao
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/iralance/go-lottery/models"
+)
+
+// 内存版本的IP黑名单数据，用于单元测试
+type blackipMemDao struct {
+	mu     sync.RWMutex
+	rows   map[int]*models.LtBlackip
+	lastId int
+}
+
+func NewBlackipMemDao() BlackipDao {
+	return &blackipMemDao{
+		rows: make(map[int]*models.LtBlackip),
+	}
+}
+
+func (d *blackipMemDao) Get(id int) *models.LtBlackip {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if data, ok := d.rows[id]; ok {
+		copied := *data
+		return &copied
+	}
+	return nil
+}
+
+func (d *blackipMemDao) GetAll(page, size int) []models.LtBlackip {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	all := d.filter(func(data *models.LtBlackip) bool { return true })
+	start, end := memPage(len(all), page, size)
+	return all[start:end]
+}
+
+func (d *blackipMemDao) CountAll() int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return int64(len(d.rows))
+}
+
+func (d *blackipMemDao) Search(ip string) []models.LtBlackip {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.filter(func(data *models.LtBlackip) bool { return data.Ip == ip })
+}
+
+func (d *blackipMemDao) Delete(id int) error {
+	return nil
+}
+
+func (d *blackipMemDao) Update(data *models.LtBlackip, columns []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if row, ok := d.rows[data.Id]; ok {
+		memUpdate(row, data, columns)
+	}
+	return nil
+}
+
+func (d *blackipMemDao) Create(data *models.LtBlackip) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if data.Id <= 0 {
+		data.Id = d.lastId + 1
+	} else if _, ok := d.rows[data.Id]; ok {
+		return 0, errors.New("blackip_dao_mem.Create duplicate id")
+	}
+	if data.Id > d.lastId {
+		d.lastId = data.Id
+	}
+	copied := *data
+	d.rows[data.Id] = &copied
+	return 1, nil
+}
+
+func (d *blackipMemDao) GetByIp(ip string) *models.LtBlackip {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	list := d.filter(func(data *models.LtBlackip) bool { return data.Ip == ip })
+	if len(list) > 0 {
+		return &list[0]
+	}
+	return nil
+}
+
+func (d *blackipMemDao) GetAllCidr() []models.LtBlackip {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.filter(func(data *models.LtBlackip) bool { return strings.Contains(data.Ip, "/") })
+}
+
+// 按照id倒序，返回满足条件的数据
+func (d *blackipMemDao) filter(fn func(data *models.LtBlackip) bool) []models.LtBlackip {
+	ids := make([]int, 0, len(d.rows))
+	for id := range d.rows {
+		ids = append(ids, id)
+	}
+	datalist := make([]models.LtBlackip, 0)
+	for _, id := range memIdsDesc(ids) {
+		if fn(d.rows[id]) {
+			datalist = append(datalist, *d.rows[id])
+		}
+	}
+	return datalist
+}
@@ -0,0 +1,80 @@
+package dao
+
+import (
+	"github.com/go-xorm/xorm"
+	"github.com/iralance/go-lottery/models"
+	"log"
+)
+
+type ChanceDao interface {
+	GetByRef(source int, ref string) *models.LtChance
+	SearchValid(uid, now int) []models.LtChance
+	CountBySource(uid, source, since int) int64
+	Create(data *models.LtChance) (int64, error)
+	Consume(id, now int) (bool, error)
+}
+
+type chanceDao struct {
+	engine *xorm.Engine
+}
+
+func NewChanceDao(engine *xorm.Engine) ChanceDao {
+	return &chanceDao{
+		engine: engine,
+	}
+}
+
+func (d *chanceDao) GetByRef(source int, ref string) *models.LtChance {
+	data := &models.LtChance{}
+	ok, err := d.engine.
+		Where("source=?", source).
+		And("ref=?", ref).
+		Get(data)
+	if ok && err == nil {
+		return data
+	}
+	return nil
+}
+
+// 用户还没有用完并且没有过期的抽奖机会，先到期的排在前面
+func (d *chanceDao) SearchValid(uid, now int) []models.LtChance {
+	datalist := make([]models.LtChance, 0)
+	err := d.engine.
+		Where("uid=?", uid).
+		And("left_num>?", 0).
+		And("expire>?", now).
+		Asc("expire", "id").
+		Find(&datalist)
+	if err != nil {
+		log.Println("chance_dao.SearchValid error=", err)
+	}
+	return datalist
+}
+
+// 用户从某个时间开始，通过某个来源获得抽奖机会的次数
+func (d *chanceDao) CountBySource(uid, source, since int) int64 {
+	num, err := d.engine.
+		Where("uid=?", uid).
+		And("source=?", source).
+		And("sys_created>=?", since).
+		Count(&models.LtChance{})
+	if err != nil {
+		return 0
+	}
+	return num
+}
+
+func (d *chanceDao) Create(data *models.LtChance) (int64, error) {
+	return d.engine.Insert(data)
+}
+
+// 使用一次抽奖机会，剩余次数和有效期在同一个更新中判断，不会超用
+func (d *chanceDao) Consume(id, now int) (bool, error) {
+	r, err := d.engine.Id(id).
+		Decr("left_num", 1).
+		Where("left_num>?", 0).
+		And("expire>?", now).
+		Cols("sys_updated").
+		Update(&models.LtChance{SysUpdated: now})
+	return r > 0, err
+}
@@ -0,0 +1,104 @@
+package dao
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/iralance/go-lottery/models"
+)
+
+// 内存版本的抽奖机会，用于单元测试
+type chanceMemDao struct {
+	mu     sync.RWMutex
+	rows   map[int]*models.LtChance
+	lastId int
+}
+
+func NewChanceMemDao() ChanceDao {
+	return &chanceMemDao{
+		rows: make(map[int]*models.LtChance),
+	}
+}
+
+func (d *chanceMemDao) GetByRef(source int, ref string) *models.LtChance {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	list := d.filter(func(data *models.LtChance) bool { return data.Source == source && data.Ref == ref })
+	if len(list) > 0 {
+		return &list[0]
+	}
+	return nil
+}
+
+func (d *chanceMemDao) SearchValid(uid, now int) []models.LtChance {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	list := d.filter(func(data *models.LtChance) bool {
+		return data.Uid == uid && data.LeftNum > 0 && data.Expire > now
+	})
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Expire != list[j].Expire {
+			return list[i].Expire < list[j].Expire
+		}
+		return list[i].Id < list[j].Id
+	})
+	return list
+}
+
+func (d *chanceMemDao) CountBySource(uid, source, since int) int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	list := d.filter(func(data *models.LtChance) bool {
+		return data.Uid == uid && data.Source == source && data.SysCreated >= since
+	})
+	return int64(len(list))
+}
+
+func (d *chanceMemDao) Create(data *models.LtChance) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, row := range d.rows {
+		if row.Source == data.Source && row.Ref == data.Ref {
+			return 0, errors.New("chance_dao_mem.Create duplicate source_ref")
+		}
+	}
+	if data.Id <= 0 {
+		data.Id = d.lastId + 1
+	} else if _, ok := d.rows[data.Id]; ok {
+		return 0, errors.New("chance_dao_mem.Create duplicate id")
+	}
+	if data.Id > d.lastId {
+		d.lastId = data.Id
+	}
+	copied := *data
+	d.rows[data.Id] = &copied
+	return 1, nil
+}
+
+func (d *chanceMemDao) Consume(id, now int) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	row, ok := d.rows[id]
+	if !ok || row.LeftNum <= 0 || row.Expire <= now {
+		return false, nil
+	}
+	row.LeftNum--
+	row.SysUpdated = now
+	return true, nil
+}
+
+// 按照id倒序，返回满足条件的数据
+func (d *chanceMemDao) filter(fn func(data *models.LtChance) bool) []models.LtChance {
+	ids := make([]int, 0, len(d.rows))
+	for id := range d.rows {
+		ids = append(ids, id)
+	}
+	datalist := make([]models.LtChance, 0)
+	for _, id := range memIdsDesc(ids) {
+		if fn(d.rows[id]) {
+			datalist = append(datalist, *d.rows[id])
+		}
+	}
+	return datalist
+}
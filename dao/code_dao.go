@@ -2,21 +2,46 @@ package dao
 
 import (
 	"github.com/go-xorm/xorm"
+	"github.com/iralance/go-lottery/conf"
 	"github.com/iralance/go-lottery/models"
 	"log"
 )
 
-type CodeDao struct {
+type CodeDao interface {
+	Get(id int) *models.LtCode
+	GetAll(page, size int) []models.LtCode
+	CountAll() int64
+	CountByGift(giftId int) int64
+	Search(giftId int) []models.LtCode
+	Delete(id int) error
+	Update(data *models.LtCode, columns []string) error
+	Create(data *models.LtCode) (int64, error)
+	NextUsingCode(giftId, codeId int) *models.LtCode
+	GetByCode(code string) *models.LtCode
+	GetByHash(hash string) *models.LtCode
+	SearchPage(giftId, status, page, size int) []models.LtCode
+	CountPage(giftId, status int) int64
+	CountGroupStatus(giftId int) map[int]int64
+	SearchExpired(now, size int) []models.LtCode
+	UpdateStatus(data *models.LtCode, fromStatus []int, columns []string) (bool, error)
+	SearchExistCodes(codes []string) []string
+	SearchExistHashes(hashes []string) []string
+	SearchOldKey(keyId, lastId, size int) []models.LtCode
+	CountOldKey(keyId int) int64
+	CreateBatch(datalist []models.LtCode) (int64, error)
+}
+
+type codeDao struct {
 	engine *xorm.Engine
 }
 
-func NewCodeDao(engine *xorm.Engine) *CodeDao {
-	return &CodeDao{
+func NewCodeDao(engine *xorm.Engine) CodeDao {
+	return &codeDao{
 		engine: engine,
 	}
 }
 
-func (d *CodeDao) Get(id int) *models.LtCode {
+func (d *codeDao) Get(id int) *models.LtCode {
 	data := &models.LtCode{Id: id}
 	ok, err := d.engine.Get(data)
 	if ok && err == nil {
@@ -25,7 +50,7 @@ func (d *CodeDao) Get(id int) *models.LtCode {
 	return nil
 }
 
-func (d *CodeDao) GetAll(page, size int) []models.LtCode {
+func (d *codeDao) GetAll(page, size int) []models.LtCode {
 	offset := (page - 1) * size
 	dataList := make([]models.LtCode, 0)
 	err := d.engine.
@@ -39,7 +64,7 @@ func (d *CodeDao) GetAll(page, size int) []models.LtCode {
 	return dataList
 }
 
-func (d *CodeDao) CountAll() int64 {
+func (d *codeDao) CountAll() int64 {
 	num, err := d.engine.Count(&models.LtCode{})
 	if err != nil {
 		return 0
@@ -47,7 +72,7 @@ func (d *CodeDao) CountAll() int64 {
 	return num
 }
 
-func (d *CodeDao) CountByGift(giftId int) int64 {
+func (d *codeDao) CountByGift(giftId int) int64 {
 	num, err := d.engine.
 		Where("gift_id=?", giftId).
 		Count(&models.LtCode{})
@@ -58,7 +83,7 @@ func (d *CodeDao) CountByGift(giftId int) int64 {
 	}
 }
 
-func (d *CodeDao) Search(giftId int) []models.LtCode {
+func (d *codeDao) Search(giftId int) []models.LtCode {
 	datalist := make([]models.LtCode, 0)
 	err := d.engine.
 		Where("gift_id=?", giftId).
@@ -71,24 +96,24 @@ func (d *CodeDao) Search(giftId int) []models.LtCode {
 	}
 }
 
-func (d *CodeDao) Delete(id int) error {
+func (d *codeDao) Delete(id int) error {
 	data := &models.LtCode{Id: id, SysStatus: 1}
 	_, err := d.engine.Id(data.Id).
 		Update(data)
 	return err
 }
 
-func (d *CodeDao) Update(data *models.LtCode, columns []string) error {
+func (d *codeDao) Update(data *models.LtCode, columns []string) error {
 	_, err := d.engine.Id(data.Id).MustCols(columns...).Update(data)
 	return err
 }
 
-func (d *CodeDao) Create(data *models.LtCode) (int64, error) {
+func (d *codeDao) Create(data *models.LtCode) (int64, error) {
 	return d.engine.Insert(data)
 }
 
 // 找到下一个可用的最小的优惠券
-func (d *CodeDao) NextUsingCode(giftId, codeId int) *models.LtCode {
+func (d *codeDao) NextUsingCode(giftId, codeId int) *models.LtCode {
 	datalist := make([]models.LtCode, 0)
 	err := d.engine.Where("gift_id=?", giftId).
 		Where("sys_status=?", 0).
@@ -102,9 +127,176 @@ func (d *CodeDao) NextUsingCode(giftId, codeId int) *models.LtCode {
 	}
 }
 
-// 根据唯一的code来更新
-func (d *CodeDao) UpdateByCode(data *models.LtCode, columns []string) error {
-	_, err := d.engine.Where("code=?", data.Code).
-		MustCols(columns...).Update(data)
-	return err
+// 没有加密的旧数据，根据明文的code查找
+func (d *codeDao) GetByCode(code string) *models.LtCode {
+	data := &models.LtCode{}
+	ok, err := d.engine.Where("code=?", code).And("key_id=0").Get(data)
+	if ok && err == nil {
+		return data
+	}
+	return nil
+}
+
+// 根据编码的HMAC查找
+func (d *codeDao) GetByHash(hash string) *models.LtCode {
+	data := &models.LtCode{CodeHash: hash}
+	ok, err := d.engine.Get(data)
+	if ok && err == nil {
+		return data
+	}
+	return nil
+}
+
+// 分页查询，giftId为0的时候不区分奖品，status小于0的时候不区分状态
+func (d *codeDao) SearchPage(giftId, status, page, size int) []models.LtCode {
+	offset := (page - 1) * size
+	datalist := make([]models.LtCode, 0)
+	err := d.pageSession(giftId, status).
+		Desc("id").
+		Limit(size, offset).
+		Find(&datalist)
+	if err != nil {
+		log.Println("code_dao.SearchPage error=", err)
+	}
+	return datalist
+}
+
+func (d *codeDao) CountPage(giftId, status int) int64 {
+	num, err := d.pageSession(giftId, status).Count(&models.LtCode{})
+	if err != nil {
+		log.Println("code_dao.CountPage error=", err)
+		return 0
+	}
+	return num
+}
+
+func (d *codeDao) pageSession(giftId, status int) *xorm.Session {
+	session := d.engine.Where("1=1")
+	if giftId > 0 {
+		session = session.And("gift_id=?", giftId)
+	}
+	if status >= 0 {
+		session = session.And("sys_status=?", status)
+	}
+	return session
+}
+
+// 一个奖品每种状态的编码数量，一次查询完成
+func (d *codeDao) CountGroupStatus(giftId int) map[int]int64 {
+	rows := make([]struct {
+		SysStatus int
+		Num       int64
+	}, 0)
+	err := d.engine.Table(&models.LtCode{}).
+		Select("sys_status, count(*) as num").
+		Where("gift_id=?", giftId).
+		GroupBy("sys_status").
+		Find(&rows)
+	nums := make(map[int]int64)
+	if err != nil {
+		log.Println("code_dao.CountGroupStatus error=", err)
+		return nums
+	}
+	for _, row := range rows {
+		nums[row.SysStatus] = row.Num
+	}
+	return nums
+}
+
+// 已经过了有效期，还没有发放或者发放了没有核销的优惠券
+func (d *codeDao) SearchExpired(now, size int) []models.LtCode {
+	datalist := make([]models.LtCode, 0)
+	err := d.engine.
+		In("sys_status", conf.CodeStatusNormal, conf.CodeStatusIssued).
+		And("valid_to>0").
+		And("valid_to<?", now).
+		Asc("id").
+		Limit(size).
+		Find(&datalist)
+	if err != nil {
+		log.Println("code_dao.SearchExpired error=", err)
+	}
+	return datalist
+}
+
+// 状态是fromStatus中的一个时才更新，避免并发的核销和过期互相覆盖
+func (d *codeDao) UpdateStatus(data *models.LtCode, fromStatus []int, columns []string) (bool, error) {
+	rows, err := d.engine.Id(data.Id).
+		In("sys_status", fromStatus).
+		MustCols(columns...).
+		Update(data)
+	return rows > 0, err
+}
+
+// 数据库中已经存在的没有加密的旧编码
+func (d *codeDao) SearchExistCodes(codes []string) []string {
+	exists := make([]string, 0)
+	if len(codes) == 0 {
+		return exists
+	}
+	err := d.engine.Table(&models.LtCode{}).
+		In("code", codes).
+		And("key_id=0").
+		Cols("code").
+		Find(&exists)
+	if err != nil {
+		log.Println("code_dao.SearchExistCodes error=", err)
+	}
+	return exists
+}
+
+// 数据库中已经存在的编码HMAC
+func (d *codeDao) SearchExistHashes(hashes []string) []string {
+	exists := make([]string, 0)
+	if len(hashes) == 0 {
+		return exists
+	}
+	err := d.engine.Table(&models.LtCode{}).
+		In("code_hash", hashes).
+		Cols("code_hash").
+		Find(&exists)
+	if err != nil {
+		log.Println("code_dao.SearchExistHashes error=", err)
+	}
+	return exists
+}
+
+// 不是使用keyId密钥加密的编码，按照id正序分批读取
+func (d *codeDao) SearchOldKey(keyId, lastId, size int) []models.LtCode {
+	datalist := make([]models.LtCode, 0)
+	err := d.engine.
+		Where("key_id<>?", keyId).
+		And("id>?", lastId).
+		Asc("id").
+		Limit(size).
+		Find(&datalist)
+	if err != nil {
+		log.Println("code_dao.SearchOldKey error=", err)
+	}
+	return datalist
+}
+
+func (d *codeDao) CountOldKey(keyId int) int64 {
+	num, err := d.engine.
+		Where("key_id<>?", keyId).
+		Count(&models.LtCode{})
+	if err != nil {
+		return 0
+	}
+	return num
+}
+
+// 批量导入，在一个事务中完成，全部成功或者全部失败
+func (d *codeDao) CreateBatch(datalist []models.LtCode) (int64, error) {
+	session := d.engine.NewSession()
+	defer session.Close()
+	if err := session.Begin(); err != nil {
+		return 0, err
+	}
+	num, err := session.Insert(&datalist)
+	if err != nil {
+		session.Rollback()
+		return 0, err
+	}
+	return num, session.Commit()
 }
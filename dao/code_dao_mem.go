@@ -0,0 +1,265 @@
+package dao
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+)
+
+// 内存版本的优惠券数据，用于单元测试
+type codeMemDao struct {
+	mu     sync.RWMutex
+	rows   map[int]*models.LtCode
+	lastId int
+}
+
+func NewCodeMemDao() CodeDao {
+	return &codeMemDao{
+		rows: make(map[int]*models.LtCode),
+	}
+}
+
+func (d *codeMemDao) Get(id int) *models.LtCode {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if data, ok := d.rows[id]; ok {
+		copied := *data
+		return &copied
+	}
+	return nil
+}
+
+func (d *codeMemDao) GetAll(page, size int) []models.LtCode {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	all := d.filter(func(data *models.LtCode) bool { return true })
+	start, end := memPage(len(all), page, size)
+	return all[start:end]
+}
+
+func (d *codeMemDao) CountAll() int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return int64(len(d.rows))
+}
+
+func (d *codeMemDao) CountByGift(giftId int) int64 {
+	return int64(len(d.Search(giftId)))
+}
+
+func (d *codeMemDao) Search(giftId int) []models.LtCode {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.filter(func(data *models.LtCode) bool { return data.GiftId == giftId })
+}
+
+func (d *codeMemDao) Delete(id int) error {
+	return d.Update(&models.LtCode{Id: id, SysStatus: 1}, nil)
+}
+
+func (d *codeMemDao) Update(data *models.LtCode, columns []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if row, ok := d.rows[data.Id]; ok {
+		memUpdate(row, data, columns)
+	}
+	return nil
+}
+
+func (d *codeMemDao) Create(data *models.LtCode) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if data.Id <= 0 {
+		data.Id = d.lastId + 1
+	} else if _, ok := d.rows[data.Id]; ok {
+		return 0, errors.New("code_dao_mem.Create duplicate id")
+	}
+	// 和数据表一样，code_hash是唯一索引
+	for _, row := range d.rows {
+		if row.CodeHash == data.CodeHash {
+			return 0, errors.New("code_dao_mem.Create duplicate code_hash")
+		}
+	}
+	if data.Id > d.lastId {
+		d.lastId = data.Id
+	}
+	copied := *data
+	d.rows[data.Id] = &copied
+	return 1, nil
+}
+
+// 找到下一个可用的最小的优惠券
+func (d *codeMemDao) NextUsingCode(giftId, codeId int) *models.LtCode {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var next *models.LtCode
+	for _, data := range d.rows {
+		if data.GiftId == giftId && data.SysStatus == 0 && data.Id > codeId &&
+			(next == nil || data.Id < next.Id) {
+			next = data
+		}
+	}
+	if next == nil {
+		return nil
+	}
+	copied := *next
+	return &copied
+}
+
+// 没有加密的旧数据，根据明文的code查找
+func (d *codeMemDao) GetByCode(code string) *models.LtCode {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	list := d.filter(func(data *models.LtCode) bool { return data.Code == code && data.KeyId == 0 })
+	if len(list) > 0 {
+		return &list[0]
+	}
+	return nil
+}
+
+func (d *codeMemDao) GetByHash(hash string) *models.LtCode {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	list := d.filter(func(data *models.LtCode) bool { return data.CodeHash == hash })
+	if len(list) > 0 {
+		return &list[0]
+	}
+	return nil
+}
+
+func (d *codeMemDao) SearchPage(giftId, status, page, size int) []models.LtCode {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	all := d.filter(func(data *models.LtCode) bool { return memCodeMatch(data, giftId, status) })
+	start, end := memPage(len(all), page, size)
+	return all[start:end]
+}
+
+func (d *codeMemDao) CountPage(giftId, status int) int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	all := d.filter(func(data *models.LtCode) bool { return memCodeMatch(data, giftId, status) })
+	return int64(len(all))
+}
+
+func (d *codeMemDao) CountGroupStatus(giftId int) map[int]int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	nums := make(map[int]int64)
+	for _, row := range d.rows {
+		if row.GiftId == giftId {
+			nums[row.SysStatus]++
+		}
+	}
+	return nums
+}
+
+// giftId为0的时候不区分奖品，status小于0的时候不区分状态
+func memCodeMatch(data *models.LtCode, giftId, status int) bool {
+	return (giftId <= 0 || data.GiftId == giftId) && (status < 0 || data.SysStatus == status)
+}
+
+func (d *codeMemDao) SearchExpired(now, size int) []models.LtCode {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	list := d.filter(func(data *models.LtCode) bool {
+		return (data.SysStatus == conf.CodeStatusNormal || data.SysStatus == conf.CodeStatusIssued) && data.ValidTo > 0 && data.ValidTo < now
+	})
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	_, end := memPage(len(list), 1, size)
+	return list[:end]
+}
+
+func (d *codeMemDao) UpdateStatus(data *models.LtCode, fromStatus []int, columns []string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	row, ok := d.rows[data.Id]
+	if !ok {
+		return false, nil
+	}
+	for _, status := range fromStatus {
+		if row.SysStatus == status {
+			memUpdate(row, data, columns)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (d *codeMemDao) SearchExistCodes(codes []string) []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	in := make(map[string]bool)
+	for _, code := range codes {
+		in[code] = true
+	}
+	exists := make([]string, 0)
+	for _, row := range d.rows {
+		if row.KeyId == 0 && in[row.Code] {
+			exists = append(exists, row.Code)
+		}
+	}
+	return exists
+}
+
+func (d *codeMemDao) SearchExistHashes(hashes []string) []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	in := make(map[string]bool)
+	for _, hash := range hashes {
+		in[hash] = true
+	}
+	exists := make([]string, 0)
+	for _, row := range d.rows {
+		if row.CodeHash != "" && in[row.CodeHash] {
+			exists = append(exists, row.CodeHash)
+		}
+	}
+	return exists
+}
+
+func (d *codeMemDao) SearchOldKey(keyId, lastId, size int) []models.LtCode {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	list := d.filter(func(data *models.LtCode) bool { return data.KeyId != keyId && data.Id > lastId })
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	_, end := memPage(len(list), 1, size)
+	return list[:end]
+}
+
+func (d *codeMemDao) CountOldKey(keyId int) int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	list := d.filter(func(data *models.LtCode) bool { return data.KeyId != keyId })
+	return int64(len(list))
+}
+
+func (d *codeMemDao) CreateBatch(datalist []models.LtCode) (int64, error) {
+	for i := range datalist {
+		if _, err := d.Create(&datalist[i]); err != nil {
+			return int64(i), err
+		}
+	}
+	return int64(len(datalist)), nil
+}
+
+// 按照id倒序，返回满足条件的数据
+func (d *codeMemDao) filter(fn func(data *models.LtCode) bool) []models.LtCode {
+	ids := make([]int, 0, len(d.rows))
+	for id := range d.rows {
+		ids = append(ids, id)
+	}
+	datalist := make([]models.LtCode, 0)
+	for _, id := range memIdsDesc(ids) {
+		if fn(d.rows[id]) {
+			datalist = append(datalist, *d.rows[id])
+		}
+	}
+	return datalist
+}
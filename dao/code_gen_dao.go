@@ -0,0 +1,53 @@
+package dao
+
+import (
+	"github.com/go-xorm/xorm"
+	"github.com/iralance/go-lottery/models"
+	"log"
+)
+
+type CodeGenDao interface {
+	GetByGift(giftId int) *models.LtCodeGen
+	GetAll() []models.LtCodeGen
+	Create(data *models.LtCodeGen) (int64, error)
+	Update(data *models.LtCodeGen, columns []string) error
+}
+
+type codeGenDao struct {
+	engine *xorm.Engine
+}
+
+func NewCodeGenDao(engine *xorm.Engine) CodeGenDao {
+	return &codeGenDao{
+		engine: engine,
+	}
+}
+
+func (d *codeGenDao) GetByGift(giftId int) *models.LtCodeGen {
+	data := &models.LtCodeGen{GiftId: giftId}
+	ok, err := d.engine.Get(data)
+	if ok && err == nil {
+		return data
+	}
+	return nil
+}
+
+func (d *codeGenDao) GetAll() []models.LtCodeGen {
+	datalist := make([]models.LtCodeGen, 0)
+	err := d.engine.
+		Asc("gift_id").
+		Find(&datalist)
+	if err != nil {
+		log.Println("code_gen_dao.GetAll error=", err)
+	}
+	return datalist
+}
+
+func (d *codeGenDao) Create(data *models.LtCodeGen) (int64, error) {
+	return d.engine.Insert(data)
+}
+
+func (d *codeGenDao) Update(data *models.LtCodeGen, columns []string) error {
+	_, err := d.engine.Id(data.Id).MustCols(columns...).Update(data)
+	return err
+}
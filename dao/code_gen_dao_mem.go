@@ -0,0 +1,76 @@
+package dao
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/iralance/go-lottery/models"
+)
+
+// 内存版本的优惠券生成规则，用于单元测试
+type codeGenMemDao struct {
+	mu     sync.RWMutex
+	rows   map[int]*models.LtCodeGen
+	lastId int
+}
+
+func NewCodeGenMemDao() CodeGenDao {
+	return &codeGenMemDao{
+		rows: make(map[int]*models.LtCodeGen),
+	}
+}
+
+func (d *codeGenMemDao) GetByGift(giftId int) *models.LtCodeGen {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, row := range d.rows {
+		if row.GiftId == giftId {
+			copied := *row
+			return &copied
+		}
+	}
+	return nil
+}
+
+func (d *codeGenMemDao) GetAll() []models.LtCodeGen {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	datalist := make([]models.LtCodeGen, 0, len(d.rows))
+	for _, row := range d.rows {
+		datalist = append(datalist, *row)
+	}
+	// 按照奖品ID正序
+	sort.Slice(datalist, func(i, j int) bool { return datalist[i].GiftId < datalist[j].GiftId })
+	return datalist
+}
+
+func (d *codeGenMemDao) Create(data *models.LtCodeGen) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, row := range d.rows {
+		if row.GiftId == data.GiftId {
+			return 0, errors.New("code_gen_dao_mem.Create duplicate gift_id")
+		}
+	}
+	if data.Id <= 0 {
+		data.Id = d.lastId + 1
+	} else if _, ok := d.rows[data.Id]; ok {
+		return 0, errors.New("code_gen_dao_mem.Create duplicate id")
+	}
+	if data.Id > d.lastId {
+		d.lastId = data.Id
+	}
+	copied := *data
+	d.rows[data.Id] = &copied
+	return 1, nil
+}
+
+func (d *codeGenMemDao) Update(data *models.LtCodeGen, columns []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if row, ok := d.rows[data.Id]; ok {
+		memUpdate(row, data, columns)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package dao
+
+import (
+	"github.com/go-xorm/xorm"
+	"github.com/iralance/go-lottery/models"
+	"log"
+)
+
+type DrawLogDao interface {
+	SearchByUser(uid, page, size int) []models.LtDrawLog
+	CountByUser(uid int) int64
+	Create(data *models.LtDrawLog) (int64, error)
+	CreateBatch(datalist []models.LtDrawLog) (int64, error)
+}
+
+type drawLogDao struct {
+	engine *xorm.Engine
+}
+
+func NewDrawLogDao(engine *xorm.Engine) DrawLogDao {
+	return &drawLogDao{
+		engine: engine,
+	}
+}
+
+// 用户的抽奖记录，按照时间倒序
+func (d *drawLogDao) SearchByUser(uid, page, size int) []models.LtDrawLog {
+	offset := (page - 1) * size
+	datalist := make([]models.LtDrawLog, 0)
+	err := d.engine.
+		Where("uid=?", uid).
+		Desc("id").
+		Limit(size, offset).
+		Find(&datalist)
+	if err != nil {
+		log.Println("draw_log_dao.SearchByUser error=", err)
+	}
+	return datalist
+}
+
+func (d *drawLogDao) CountByUser(uid int) int64 {
+	num, err := d.engine.
+		Where("uid=?", uid).
+		Count(&models.LtDrawLog{})
+	if err != nil {
+		return 0
+	}
+	return num
+}
+
+func (d *drawLogDao) Create(data *models.LtDrawLog) (int64, error) {
+	return d.engine.Insert(data)
+}
+
+func (d *drawLogDao) CreateBatch(datalist []models.LtDrawLog) (int64, error) {
+	return d.engine.Insert(&datalist)
+}
@@ -0,0 +1,76 @@
+package dao
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/iralance/go-lottery/models"
+)
+
+// 内存版本的抽奖记录，用于单元测试
+type drawLogMemDao struct {
+	mu     sync.RWMutex
+	rows   map[int]*models.LtDrawLog
+	lastId int
+}
+
+func NewDrawLogMemDao() DrawLogDao {
+	return &drawLogMemDao{
+		rows: make(map[int]*models.LtDrawLog),
+	}
+}
+
+func (d *drawLogMemDao) SearchByUser(uid, page, size int) []models.LtDrawLog {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	all := d.filter(func(data *models.LtDrawLog) bool { return data.Uid == uid })
+	start, end := memPage(len(all), page, size)
+	return all[start:end]
+}
+
+func (d *drawLogMemDao) CountByUser(uid int) int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	all := d.filter(func(data *models.LtDrawLog) bool { return data.Uid == uid })
+	return int64(len(all))
+}
+
+func (d *drawLogMemDao) Create(data *models.LtDrawLog) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if data.Id <= 0 {
+		data.Id = d.lastId + 1
+	} else if _, ok := d.rows[data.Id]; ok {
+		return 0, errors.New("draw_log_dao_mem.Create duplicate id")
+	}
+	if data.Id > d.lastId {
+		d.lastId = data.Id
+	}
+	copied := *data
+	d.rows[data.Id] = &copied
+	return 1, nil
+}
+
+func (d *drawLogMemDao) CreateBatch(datalist []models.LtDrawLog) (int64, error) {
+	for i := range datalist {
+		if _, err := d.Create(&datalist[i]); err != nil {
+			return int64(i), err
+		}
+	}
+	return int64(len(datalist)), nil
+}
+
+// 按照id倒序，返回满足条件的数据
+func (d *drawLogMemDao) filter(fn func(data *models.LtDrawLog) bool) []models.LtDrawLog {
+	ids := make([]int, 0, len(d.rows))
+	for id := range d.rows {
+		ids = append(ids, id)
+	}
+	datalist := make([]models.LtDrawLog, 0)
+	for _, id := range memIdsDesc(ids) {
+		if fn(d.rows[id]) {
+			datalist = append(datalist, *d.rows[id])
+		}
+	}
+	return datalist
+}
@@ -0,0 +1,114 @@
+package dao
+
+import (
+	"github.com/go-xorm/xorm"
+	"github.com/iralance/go-lottery/models"
+	"log"
+)
+
+type FulfillDao interface {
+	Get(id int) *models.LtFulfill
+	GetByResult(resultId int) *models.LtFulfill
+	GetAll(status, page, size int) []models.LtFulfill
+	CountAll(status int) int64
+	SearchByUser(uid int) []models.LtFulfill
+	SearchExpired(status, before int) []models.LtFulfill
+	Create(data *models.LtFulfill) (int64, error)
+	UpdateStatus(data *models.LtFulfill, fromStatus []int, columns []string) (bool, error)
+}
+
+type fulfillDao struct {
+	engine *xorm.Engine
+}
+
+func NewFulfillDao(engine *xorm.Engine) FulfillDao {
+	return &fulfillDao{
+		engine: engine,
+	}
+}
+
+func (d *fulfillDao) Get(id int) *models.LtFulfill {
+	data := &models.LtFulfill{Id: id}
+	ok, err := d.engine.Get(data)
+	if ok && err == nil {
+		return data
+	}
+	return nil
+}
+
+func (d *fulfillDao) GetByResult(resultId int) *models.LtFulfill {
+	data := &models.LtFulfill{ResultId: resultId}
+	ok, err := d.engine.Get(data)
+	if ok && err == nil {
+		return data
+	}
+	return nil
+}
+
+// status为0的时候不区分状态
+func (d *fulfillDao) GetAll(status, page, size int) []models.LtFulfill {
+	offset := (page - 1) * size
+	datalist := make([]models.LtFulfill, 0)
+	session := d.engine.Desc("id")
+	if status > 0 {
+		session = session.Where("status=?", status)
+	}
+	err := session.Limit(size, offset).Find(&datalist)
+	if err != nil {
+		log.Println("fulfill_dao.GetAll error=", err)
+	}
+	return datalist
+}
+
+func (d *fulfillDao) CountAll(status int) int64 {
+	var num int64
+	var err error
+	if status > 0 {
+		num, err = d.engine.Where("status=?", status).Count(&models.LtFulfill{})
+	} else {
+		num, err = d.engine.Count(&models.LtFulfill{})
+	}
+	if err != nil {
+		return 0
+	}
+	return num
+}
+
+func (d *fulfillDao) SearchByUser(uid int) []models.LtFulfill {
+	datalist := make([]models.LtFulfill, 0)
+	err := d.engine.
+		Where("uid=?", uid).
+		Desc("id").
+		Find(&datalist)
+	if err != nil {
+		log.Println("fulfill_dao.SearchByUser error=", err)
+	}
+	return datalist
+}
+
+// 状态是status并且创建时间在before之前的发货记录
+func (d *fulfillDao) SearchExpired(status, before int) []models.LtFulfill {
+	datalist := make([]models.LtFulfill, 0)
+	err := d.engine.
+		Where("status=?", status).
+		And("sys_created<?", before).
+		Asc("id").
+		Find(&datalist)
+	if err != nil {
+		log.Println("fulfill_dao.SearchExpired error=", err)
+	}
+	return datalist
+}
+
+func (d *fulfillDao) Create(data *models.LtFulfill) (int64, error) {
+	return d.engine.Insert(data)
+}
+
+// 发货状态是fromStatus中的一个时才更新，避免状态倒退
+func (d *fulfillDao) UpdateStatus(data *models.LtFulfill, fromStatus []int, columns []string) (bool, error) {
+	rows, err := d.engine.Id(data.Id).
+		In("status", fromStatus).
+		MustCols(columns...).
+		Update(data)
+	return rows > 0, err
+}
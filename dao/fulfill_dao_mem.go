@@ -0,0 +1,126 @@
+package dao
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/iralance/go-lottery/models"
+)
+
+// 内存版本的发货记录，用于单元测试
+type fulfillMemDao struct {
+	mu     sync.RWMutex
+	rows   map[int]*models.LtFulfill
+	lastId int
+}
+
+func NewFulfillMemDao() FulfillDao {
+	return &fulfillMemDao{
+		rows: make(map[int]*models.LtFulfill),
+	}
+}
+
+func (d *fulfillMemDao) Get(id int) *models.LtFulfill {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if data, ok := d.rows[id]; ok {
+		copied := *data
+		return &copied
+	}
+	return nil
+}
+
+func (d *fulfillMemDao) GetByResult(resultId int) *models.LtFulfill {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	list := d.filter(func(data *models.LtFulfill) bool { return data.ResultId == resultId })
+	if len(list) > 0 {
+		return &list[0]
+	}
+	return nil
+}
+
+func (d *fulfillMemDao) GetAll(status, page, size int) []models.LtFulfill {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	all := d.filter(func(data *models.LtFulfill) bool { return status <= 0 || data.Status == status })
+	start, end := memPage(len(all), page, size)
+	return all[start:end]
+}
+
+func (d *fulfillMemDao) CountAll(status int) int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	all := d.filter(func(data *models.LtFulfill) bool { return status <= 0 || data.Status == status })
+	return int64(len(all))
+}
+
+func (d *fulfillMemDao) SearchByUser(uid int) []models.LtFulfill {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.filter(func(data *models.LtFulfill) bool { return data.Uid == uid })
+}
+
+func (d *fulfillMemDao) SearchExpired(status, before int) []models.LtFulfill {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	list := d.filter(func(data *models.LtFulfill) bool {
+		return data.Status == status && data.SysCreated < before
+	})
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	return list
+}
+
+func (d *fulfillMemDao) Create(data *models.LtFulfill) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, row := range d.rows {
+		if row.ResultId == data.ResultId {
+			return 0, errors.New("fulfill_dao_mem.Create duplicate result_id")
+		}
+	}
+	if data.Id <= 0 {
+		data.Id = d.lastId + 1
+	} else if _, ok := d.rows[data.Id]; ok {
+		return 0, errors.New("fulfill_dao_mem.Create duplicate id")
+	}
+	if data.Id > d.lastId {
+		d.lastId = data.Id
+	}
+	copied := *data
+	d.rows[data.Id] = &copied
+	return 1, nil
+}
+
+func (d *fulfillMemDao) UpdateStatus(data *models.LtFulfill, fromStatus []int, columns []string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	row, ok := d.rows[data.Id]
+	if !ok {
+		return false, nil
+	}
+	for _, status := range fromStatus {
+		if row.Status == status {
+			memUpdate(row, data, columns)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// 按照id倒序，返回满足条件的数据
+func (d *fulfillMemDao) filter(fn func(data *models.LtFulfill) bool) []models.LtFulfill {
+	ids := make([]int, 0, len(d.rows))
+	for id := range d.rows {
+		ids = append(ids, id)
+	}
+	datalist := make([]models.LtFulfill, 0)
+	for _, id := range memIdsDesc(ids) {
+		if fn(d.rows[id]) {
+			datalist = append(datalist, *d.rows[id])
+		}
+	}
+	return datalist
+}
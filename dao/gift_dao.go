@@ -7,17 +7,29 @@ import (
 	"log"
 )
 
-type GiftDao struct {
+type GiftDao interface {
+	Get(id int) *models.LtGift
+	GetAll() []models.LtGift
+	CountAll() int64
+	Delete(id int) error
+	Update(data *models.LtGift, columns []string) error
+	Create(data *models.LtGift) (int64, error)
+	GetAllUse() []models.LtGift
+	IncrLeftNum(id, num int) (int64, error)
+	DecrLeftNum(id, num int) (int64, error)
+}
+
+type giftDao struct {
 	engine *xorm.Engine
 }
 
-func NewGiftDao(engine *xorm.Engine) *GiftDao {
-	return &GiftDao{
+func NewGiftDao(engine *xorm.Engine) GiftDao {
+	return &giftDao{
 		engine: engine,
 	}
 }
 
-func (d *GiftDao) Get(id int) *models.LtGift {
+func (d *giftDao) Get(id int) *models.LtGift {
 	data := &models.LtGift{Id: id}
 	ok, err := d.engine.Get(data)
 	if ok && err == nil {
@@ -26,7 +38,7 @@ func (d *GiftDao) Get(id int) *models.LtGift {
 	return nil
 }
 
-func (d *GiftDao) GetAll() []models.LtGift {
+func (d *giftDao) GetAll() []models.LtGift {
 	dataList := make([]models.LtGift, 0)
 	err := d.engine.Asc("sys_status").
 		Asc("displayorder").
@@ -38,7 +50,7 @@ func (d *GiftDao) GetAll() []models.LtGift {
 	return dataList
 }
 
-func (d *GiftDao) CountAll() int64 {
+func (d *giftDao) CountAll() int64 {
 	num, err := d.engine.Count(&models.LtGift{})
 	if err != nil {
 		return 0
@@ -46,26 +58,26 @@ func (d *GiftDao) CountAll() int64 {
 	return num
 }
 
-func (d *GiftDao) Delete(id int) error {
+func (d *giftDao) Delete(id int) error {
 	data := &models.LtGift{Id: id, SysStatus: 1}
 	_, err := d.engine.Id(data.Id).
 		Update(data)
 	return err
 }
 
-func (d *GiftDao) Update(data *models.LtGift, columns []string) error {
+func (d *giftDao) Update(data *models.LtGift, columns []string) error {
 	_, err := d.engine.Id(data.Id).MustCols(columns...).Update(data)
 	return err
 }
 
-func (d *GiftDao) Create(data *models.LtGift) (int64, error) {
+func (d *giftDao) Create(data *models.LtGift) (int64, error) {
 	return d.engine.Insert(data)
 }
 
 // 获取到当前可以获取的奖品列表
 // 有奖品限定，状态正常，时间期间内
 // gtype倒序， displayorder正序
-func (d *GiftDao) GetAllUse() []models.LtGift {
+func (d *giftDao) GetAllUse() []models.LtGift {
 	now := comm.NowUnix()
 	datalist := make([]models.LtGift, 0)
 	err := d.engine.
@@ -85,7 +97,7 @@ func (d *GiftDao) GetAllUse() []models.LtGift {
 	}
 }
 
-func (d *GiftDao) IncrLeftNum(id, num int) (int64, error) {
+func (d *giftDao) IncrLeftNum(id, num int) (int64, error) {
 	r, err := d.engine.Id(id).
 		Incr("left_num", num).
 		//Where("left_num=?", num).
@@ -93,7 +105,7 @@ func (d *GiftDao) IncrLeftNum(id, num int) (int64, error) {
 	return r, err
 }
 
-func (d *GiftDao) DecrLeftNum(id, num int) (int64, error) {
+func (d *giftDao) DecrLeftNum(id, num int) (int64, error) {
 	r, err := d.engine.Id(id).
 		Decr("left_num", num).
 		Where("left_num>=?", num).
@@ -0,0 +1,140 @@
+package dao
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/models"
+)
+
+// 内存版本的奖品数据，用于单元测试
+type giftMemDao struct {
+	mu     sync.RWMutex
+	rows   map[int]*models.LtGift
+	lastId int
+}
+
+func NewGiftMemDao() GiftDao {
+	return &giftMemDao{
+		rows: make(map[int]*models.LtGift),
+	}
+}
+
+func (d *giftMemDao) Get(id int) *models.LtGift {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if data, ok := d.rows[id]; ok {
+		copied := *data
+		return &copied
+	}
+	return nil
+}
+
+func (d *giftMemDao) GetAll() []models.LtGift {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	datalist := d.list()
+	sort.SliceStable(datalist, func(i, j int) bool {
+		if datalist[i].SysStatus != datalist[j].SysStatus {
+			return datalist[i].SysStatus < datalist[j].SysStatus
+		}
+		return datalist[i].Displayorder < datalist[j].Displayorder
+	})
+	return datalist
+}
+
+func (d *giftMemDao) CountAll() int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return int64(len(d.rows))
+}
+
+func (d *giftMemDao) Delete(id int) error {
+	return d.Update(&models.LtGift{Id: id, SysStatus: 1}, nil)
+}
+
+func (d *giftMemDao) Update(data *models.LtGift, columns []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if row, ok := d.rows[data.Id]; ok {
+		memUpdate(row, data, columns)
+	}
+	return nil
+}
+
+func (d *giftMemDao) Create(data *models.LtGift) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if data.Id <= 0 {
+		data.Id = d.lastId + 1
+	} else if _, ok := d.rows[data.Id]; ok {
+		return 0, errors.New("gift_dao_mem.Create duplicate id")
+	}
+	if data.Id > d.lastId {
+		d.lastId = data.Id
+	}
+	copied := *data
+	d.rows[data.Id] = &copied
+	return 1, nil
+}
+
+// 获取到当前可以获取的奖品列表
+// 有奖品限定，状态正常，时间期间内
+// gtype倒序， displayorder正序
+func (d *giftMemDao) GetAllUse() []models.LtGift {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	now := comm.NowUnix()
+	datalist := make([]models.LtGift, 0)
+	for _, data := range d.list() {
+		if data.PrizeNum >= 0 && data.SysStatus == 0 &&
+			data.TimeBegin <= now && data.TimeEnd >= now {
+			datalist = append(datalist, data)
+		}
+	}
+	sort.SliceStable(datalist, func(i, j int) bool {
+		if datalist[i].Gtype != datalist[j].Gtype {
+			return datalist[i].Gtype > datalist[j].Gtype
+		}
+		return datalist[i].Displayorder < datalist[j].Displayorder
+	})
+	return datalist
+}
+
+func (d *giftMemDao) IncrLeftNum(id, num int) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	row, ok := d.rows[id]
+	if !ok {
+		return 0, nil
+	}
+	row.LeftNum += num
+	return 1, nil
+}
+
+func (d *giftMemDao) DecrLeftNum(id, num int) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	row, ok := d.rows[id]
+	if !ok || row.LeftNum < num {
+		return 0, nil
+	}
+	row.LeftNum -= num
+	return 1, nil
+}
+
+// 按照id正序的全部数据
+func (d *giftMemDao) list() []models.LtGift {
+	ids := make([]int, 0, len(d.rows))
+	for id := range d.rows {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	datalist := make([]models.LtGift, 0, len(ids))
+	for _, id := range ids {
+		datalist = append(datalist, *d.rows[id])
+	}
+	return datalist
+}
@@ -0,0 +1,54 @@
+package dao
+
+import (
+	"reflect"
+	"sort"
+
+	"xorm.io/core"
+)
+
+var memMapper = core.SnakeMapper{}
+
+// 内存版本的数据更新，规则与xorm的Update一致
+// 非零值的字段会被更新，columns中指定的字段即使是零值也会被更新
+func memUpdate(dst, src interface{}, columns []string) {
+	must := make(map[string]bool)
+	for _, col := range columns {
+		must[col] = true
+	}
+	dv := reflect.ValueOf(dst).Elem()
+	sv := reflect.ValueOf(src).Elem()
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		name := st.Field(i).Name
+		if name == "Id" {
+			continue
+		}
+		f := sv.Field(i)
+		if must[memMapper.Obj2Table(name)] || !f.IsZero() {
+			dv.Field(i).Set(f)
+		}
+	}
+}
+
+// 分页计算，返回数据切片的开始、结束位置
+func memPage(total, page, size int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	start := (page - 1) * size
+	if start > total {
+		start = total
+	}
+	end := start + size
+	if end > total || size < 1 {
+		end = total
+	}
+	return start, end
+}
+
+// 所有的id，倒序
+func memIdsDesc(ids []int) []int {
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+	return ids
+}
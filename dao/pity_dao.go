@@ -0,0 +1,71 @@
+package dao
+
+import (
+	"github.com/go-xorm/xorm"
+	"github.com/iralance/go-lottery/models"
+	"log"
+)
+
+type PityDao interface {
+	Get(id int) *models.LtPity
+	GetAll() []models.LtPity
+	CountAll() int64
+	Delete(id int) error
+	Update(data *models.LtPity, columns []string) error
+	Create(data *models.LtPity) (int64, error)
+}
+
+type pityDao struct {
+	engine *xorm.Engine
+}
+
+func NewPityDao(engine *xorm.Engine) PityDao {
+	return &pityDao{
+		engine: engine,
+	}
+}
+
+func (d *pityDao) Get(id int) *models.LtPity {
+	data := &models.LtPity{Id: id}
+	ok, err := d.engine.Get(data)
+	if ok && err == nil {
+		return data
+	}
+	return nil
+}
+
+func (d *pityDao) GetAll() []models.LtPity {
+	dataList := make([]models.LtPity, 0)
+	err := d.engine.Asc("sys_status").
+		Asc("id").
+		Find(&dataList)
+	if err != nil {
+		log.Println("pity_dao.GetAll error=", err)
+		return dataList
+	}
+	return dataList
+}
+
+func (d *pityDao) CountAll() int64 {
+	num, err := d.engine.Count(&models.LtPity{})
+	if err != nil {
+		return 0
+	}
+	return num
+}
+
+func (d *pityDao) Delete(id int) error {
+	data := &models.LtPity{Id: id, SysStatus: 1}
+	_, err := d.engine.Id(data.Id).
+		Update(data)
+	return err
+}
+
+func (d *pityDao) Update(data *models.LtPity, columns []string) error {
+	_, err := d.engine.Id(data.Id).MustCols(columns...).Update(data)
+	return err
+}
+
+func (d *pityDao) Create(data *models.LtPity) (int64, error) {
+	return d.engine.Insert(data)
+}
@@ -0,0 +1,83 @@
+package dao
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/iralance/go-lottery/models"
+)
+
+// 内存版本的保底规则数据，用于单元测试
+type pityMemDao struct {
+	mu     sync.RWMutex
+	rows   map[int]*models.LtPity
+	lastId int
+}
+
+func NewPityMemDao() PityDao {
+	return &pityMemDao{
+		rows: make(map[int]*models.LtPity),
+	}
+}
+
+func (d *pityMemDao) Get(id int) *models.LtPity {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if data, ok := d.rows[id]; ok {
+		copied := *data
+		return &copied
+	}
+	return nil
+}
+
+func (d *pityMemDao) GetAll() []models.LtPity {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	datalist := make([]models.LtPity, 0, len(d.rows))
+	for _, data := range d.rows {
+		datalist = append(datalist, *data)
+	}
+	sort.Slice(datalist, func(i, j int) bool {
+		if datalist[i].SysStatus != datalist[j].SysStatus {
+			return datalist[i].SysStatus < datalist[j].SysStatus
+		}
+		return datalist[i].Id < datalist[j].Id
+	})
+	return datalist
+}
+
+func (d *pityMemDao) CountAll() int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return int64(len(d.rows))
+}
+
+func (d *pityMemDao) Delete(id int) error {
+	return d.Update(&models.LtPity{Id: id, SysStatus: 1}, nil)
+}
+
+func (d *pityMemDao) Update(data *models.LtPity, columns []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if row, ok := d.rows[data.Id]; ok {
+		memUpdate(row, data, columns)
+	}
+	return nil
+}
+
+func (d *pityMemDao) Create(data *models.LtPity) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if data.Id <= 0 {
+		data.Id = d.lastId + 1
+	} else if _, ok := d.rows[data.Id]; ok {
+		return 0, errors.New("pity_dao_mem.Create duplicate id")
+	}
+	if data.Id > d.lastId {
+		d.lastId = data.Id
+	}
+	copied := *data
+	d.rows[data.Id] = &copied
+	return 1, nil
+}
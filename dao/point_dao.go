@@ -0,0 +1,146 @@
+package dao
+
+import (
+	"github.com/go-xorm/xorm"
+	"github.com/iralance/go-lottery/models"
+	"log"
+)
+
+type PointDao interface {
+	Get(uid int) *models.LtPoint
+	GetAll(page, size int) []models.LtPoint
+	CountAll() int64
+	GetLogByRef(action int, ref string) *models.LtPointLog
+	SearchLog(uid, page, size int) []models.LtPointLog
+	CountLog(uid int) int64
+	Change(data *models.LtPointLog) (bool, error)
+}
+
+type pointDao struct {
+	engine *xorm.Engine
+}
+
+func NewPointDao(engine *xorm.Engine) PointDao {
+	return &pointDao{
+		engine: engine,
+	}
+}
+
+func (d *pointDao) Get(uid int) *models.LtPoint {
+	data := &models.LtPoint{}
+	ok, err := d.engine.Where("uid=?", uid).Get(data)
+	if ok && err == nil {
+		return data
+	}
+	return nil
+}
+
+func (d *pointDao) GetAll(page, size int) []models.LtPoint {
+	offset := (page - 1) * size
+	datalist := make([]models.LtPoint, 0)
+	err := d.engine.
+		Desc("sys_updated", "id").
+		Limit(size, offset).
+		Find(&datalist)
+	if err != nil {
+		log.Println("point_dao.GetAll error=", err)
+	}
+	return datalist
+}
+
+func (d *pointDao) CountAll() int64 {
+	num, err := d.engine.Count(&models.LtPoint{})
+	if err != nil {
+		return 0
+	}
+	return num
+}
+
+func (d *pointDao) GetLogByRef(action int, ref string) *models.LtPointLog {
+	data := &models.LtPointLog{}
+	ok, err := d.engine.
+		Where("action=?", action).
+		And("ref=?", ref).
+		Get(data)
+	if ok && err == nil {
+		return data
+	}
+	return nil
+}
+
+// 积分流水，按照时间倒序，uid为0的时候是全部用户
+func (d *pointDao) SearchLog(uid, page, size int) []models.LtPointLog {
+	offset := (page - 1) * size
+	datalist := make([]models.LtPointLog, 0)
+	session := d.engine.Desc("id").Limit(size, offset)
+	if uid > 0 {
+		session = session.Where("uid=?", uid)
+	}
+	if err := session.Find(&datalist); err != nil {
+		log.Println("point_dao.SearchLog error=", err)
+	}
+	return datalist
+}
+
+func (d *pointDao) CountLog(uid int) int64 {
+	session := d.engine.NewSession()
+	defer session.Close()
+	if uid > 0 {
+		session = session.Where("uid=?", uid)
+	}
+	num, err := session.Count(&models.LtPointLog{})
+	if err != nil {
+		return 0
+	}
+	return num
+}
+
+// 修改积分余额并且写入流水，在一个事务中完成
+// 扣除积分的时候余额不足返回false，余额和流水都不修改
+func (d *pointDao) Change(data *models.LtPointLog) (bool, error) {
+	session := d.engine.NewSession()
+	defer session.Close()
+	if err := session.Begin(); err != nil {
+		return false, err
+	}
+	session.Where("uid=?", data.Uid)
+	if data.Num < 0 {
+		session.And("balance>=?", -data.Num)
+	}
+	rows, err := session.
+		Incr("balance", data.Num).
+		Cols("sys_updated").
+		Update(&models.LtPoint{SysUpdated: data.SysCreated})
+	if err != nil {
+		session.Rollback()
+		return false, err
+	}
+	if rows == 0 {
+		if data.Num < 0 {
+			session.Rollback()
+			return false, nil
+		}
+		// 第一次获得积分，新建账户
+		_, err = session.Insert(&models.LtPoint{
+			Uid:        data.Uid,
+			Balance:    data.Num,
+			SysCreated: data.SysCreated,
+			SysUpdated: data.SysCreated,
+		})
+		if err != nil {
+			session.Rollback()
+			return false, err
+		}
+	}
+	account := &models.LtPoint{}
+	if _, err = session.Where("uid=?", data.Uid).Get(account); err != nil {
+		session.Rollback()
+		return false, err
+	}
+	data.Balance = account.Balance
+	if _, err = session.Insert(data); err != nil {
+		session.Rollback()
+		return false, err
+	}
+	return true, session.Commit()
+}
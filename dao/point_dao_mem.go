@@ -0,0 +1,128 @@
+package dao
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/iralance/go-lottery/models"
+)
+
+// 内存版本的积分账户和流水，用于单元测试
+type pointMemDao struct {
+	mu        sync.RWMutex
+	accounts  map[int]*models.LtPoint
+	rows      map[int]*models.LtPointLog
+	lastId    int
+	accountId int
+}
+
+func NewPointMemDao() PointDao {
+	return &pointMemDao{
+		accounts: make(map[int]*models.LtPoint),
+		rows:     make(map[int]*models.LtPointLog),
+	}
+}
+
+func (d *pointMemDao) Get(uid int) *models.LtPoint {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if data, ok := d.accounts[uid]; ok {
+		copied := *data
+		return &copied
+	}
+	return nil
+}
+
+func (d *pointMemDao) GetAll(page, size int) []models.LtPoint {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	all := make([]models.LtPoint, 0, len(d.accounts))
+	for _, data := range d.accounts {
+		all = append(all, *data)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].SysUpdated != all[j].SysUpdated {
+			return all[i].SysUpdated > all[j].SysUpdated
+		}
+		return all[i].Id > all[j].Id
+	})
+	start, end := memPage(len(all), page, size)
+	return all[start:end]
+}
+
+func (d *pointMemDao) CountAll() int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return int64(len(d.accounts))
+}
+
+func (d *pointMemDao) GetLogByRef(action int, ref string) *models.LtPointLog {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	list := d.filter(func(data *models.LtPointLog) bool { return data.Action == action && data.Ref == ref })
+	if len(list) > 0 {
+		return &list[0]
+	}
+	return nil
+}
+
+func (d *pointMemDao) SearchLog(uid, page, size int) []models.LtPointLog {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	all := d.filter(func(data *models.LtPointLog) bool { return uid <= 0 || data.Uid == uid })
+	start, end := memPage(len(all), page, size)
+	return all[start:end]
+}
+
+func (d *pointMemDao) CountLog(uid int) int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	all := d.filter(func(data *models.LtPointLog) bool { return uid <= 0 || data.Uid == uid })
+	return int64(len(all))
+}
+
+func (d *pointMemDao) Change(data *models.LtPointLog) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, row := range d.rows {
+		if row.Action == data.Action && row.Ref == data.Ref {
+			return false, errors.New("point_dao_mem.Change duplicate action_ref")
+		}
+	}
+	account, ok := d.accounts[data.Uid]
+	if !ok {
+		if data.Num < 0 {
+			return false, nil
+		}
+		d.accountId++
+		account = &models.LtPoint{Id: d.accountId, Uid: data.Uid, SysCreated: data.SysCreated}
+		d.accounts[data.Uid] = account
+	}
+	if account.Balance+data.Num < 0 {
+		return false, nil
+	}
+	account.Balance += data.Num
+	account.SysUpdated = data.SysCreated
+	data.Balance = account.Balance
+	d.lastId++
+	data.Id = d.lastId
+	copied := *data
+	d.rows[data.Id] = &copied
+	return true, nil
+}
+
+// 按照id倒序，返回满足条件的数据
+func (d *pointMemDao) filter(fn func(data *models.LtPointLog) bool) []models.LtPointLog {
+	ids := make([]int, 0, len(d.rows))
+	for id := range d.rows {
+		ids = append(ids, id)
+	}
+	datalist := make([]models.LtPointLog, 0)
+	for _, id := range memIdsDesc(ids) {
+		if fn(d.rows[id]) {
+			datalist = append(datalist, *d.rows[id])
+		}
+	}
+	return datalist
+}
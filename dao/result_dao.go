@@ -1,22 +1,45 @@
 package dao
 
 import (
+	"fmt"
 	"github.com/go-xorm/xorm"
 	"github.com/iralance/go-lottery/models"
 	"log"
 )
 
-type ResultDao struct {
+type ResultDao interface {
+	Get(id int) *models.LtResult
+	GetAll(page, size int) []models.LtResult
+	CountAll() int64
+	GetNewPrize(size int, giftIds []int) []models.LtResult
+	SearchByGift(giftId, page, size int) []models.LtResult
+	SearchByUser(uid, page, size int) []models.LtResult
+	CountByGift(giftId int) int64
+	CountByUser(uid int) int64
+	CountGroupPity() map[int]int64
+	CountByUserGift(uid, giftId int) int64
+	CountByUserGtype(uid, gtype int) int64
+	CountByUserSince(uid, since int) int64
+	Delete(id int) error
+	Update(data *models.LtResult, columns []string) error
+	Create(data *models.LtResult) (int64, error)
+	CreateBatch(datalist []models.LtResult) (int64, error)
+	UpdateReview(data *models.LtResult, fromStatus []int, columns []string) (bool, error)
+	UpdateDeliver(data *models.LtResult, fromStatus []int, columns []string) (bool, error)
+	SearchByDeliver(status, before, size int) []models.LtResult
+}
+
+type resultDao struct {
 	engine *xorm.Engine
 }
 
-func NewResultDao(engine *xorm.Engine) *ResultDao {
-	return &ResultDao{
+func NewResultDao(engine *xorm.Engine) ResultDao {
+	return &resultDao{
 		engine: engine,
 	}
 }
 
-func (d *ResultDao) Get(id int) *models.LtResult {
+func (d *resultDao) Get(id int) *models.LtResult {
 	data := &models.LtResult{Id: id}
 	ok, err := d.engine.Get(data)
 	if ok && err == nil {
@@ -25,7 +48,7 @@ func (d *ResultDao) Get(id int) *models.LtResult {
 	return nil
 }
 
-func (d *ResultDao) GetAll(page, size int) []models.LtResult {
+func (d *resultDao) GetAll(page, size int) []models.LtResult {
 	offset := (page - 1) * size
 	dataList := make([]models.LtResult, 0)
 	err := d.engine.
@@ -39,7 +62,7 @@ func (d *ResultDao) GetAll(page, size int) []models.LtResult {
 	return dataList
 }
 
-func (d *ResultDao) CountAll() int64 {
+func (d *resultDao) CountAll() int64 {
 	num, err := d.engine.Count(&models.LtResult{})
 	if err != nil {
 		return 0
@@ -47,7 +70,7 @@ func (d *ResultDao) CountAll() int64 {
 	return num
 }
 
-func (d *ResultDao) GetNewPrize(size int, giftIds []int) []models.LtResult {
+func (d *resultDao) GetNewPrize(size int, giftIds []int) []models.LtResult {
 	datalist := make([]models.LtResult, 0)
 	err := d.engine.
 		In("gift_id", giftIds).
@@ -61,7 +84,7 @@ func (d *ResultDao) GetNewPrize(size int, giftIds []int) []models.LtResult {
 	}
 }
 
-func (d *ResultDao) SearchByGift(giftId, page, size int) []models.LtResult {
+func (d *resultDao) SearchByGift(giftId, page, size int) []models.LtResult {
 	offset := (page - 1) * size
 	datalist := make([]models.LtResult, 0)
 	err := d.engine.
@@ -76,7 +99,7 @@ func (d *ResultDao) SearchByGift(giftId, page, size int) []models.LtResult {
 	}
 }
 
-func (d *ResultDao) SearchByUser(uid, page, size int) []models.LtResult {
+func (d *resultDao) SearchByUser(uid, page, size int) []models.LtResult {
 	offset := (page - 1) * size
 	datalist := make([]models.LtResult, 0)
 	err := d.engine.
@@ -91,7 +114,7 @@ func (d *ResultDao) SearchByUser(uid, page, size int) []models.LtResult {
 	}
 }
 
-func (d *ResultDao) CountByGift(giftId int) int64 {
+func (d *resultDao) CountByGift(giftId int) int64 {
 	num, err := d.engine.
 		Where("gift_id=?", giftId).
 		Count(&models.LtResult{})
@@ -102,7 +125,7 @@ func (d *ResultDao) CountByGift(giftId int) int64 {
 	}
 }
 
-func (d *ResultDao) CountByUser(uid int) int64 {
+func (d *resultDao) CountByUser(uid int) int64 {
 	num, err := d.engine.
 		Where("uid=?", uid).
 		Count(&models.LtResult{})
@@ -113,18 +136,142 @@ func (d *ResultDao) CountByUser(uid int) int64 {
 	}
 }
 
-func (d *ResultDao) Delete(id int) error {
+// 每个保底规则触发的中奖次数，一次查询完成
+func (d *resultDao) CountGroupPity() map[int]int64 {
+	rows := make([]struct {
+		PityId int
+		Num    int64
+	}, 0)
+	err := d.engine.Table(&models.LtResult{}).
+		Select("pity_id, count(*) as num").
+		Where("pity_id>?", 0).
+		GroupBy("pity_id").
+		Find(&rows)
+	nums := make(map[int]int64)
+	if err != nil {
+		log.Println("result_dao.CountGroupPity error=", err)
+		return nums
+	}
+	for _, row := range rows {
+		nums[row.PityId] = row.Num
+	}
+	return nums
+}
+
+// 用户在某个奖品上的中奖次数，删除的记录和安慰奖不计算
+func (d *resultDao) CountByUserGift(uid, giftId int) int64 {
+	num, err := d.engine.
+		Where("uid=?", uid).
+		Where("gift_id=?", giftId).
+		Where("sys_status<>?", 1).
+		Where("fallback=?", 0).
+		Count(&models.LtResult{})
+	if err != nil {
+		return 0
+	} else {
+		return num
+	}
+}
+
+// 用户在某类奖品上的中奖次数，删除的记录和安慰奖不计算
+func (d *resultDao) CountByUserGtype(uid, gtype int) int64 {
+	num, err := d.engine.
+		Where("uid=?", uid).
+		Where("gift_type=?", gtype).
+		Where("sys_status<>?", 1).
+		Where("fallback=?", 0).
+		Count(&models.LtResult{})
+	if err != nil {
+		return 0
+	} else {
+		return num
+	}
+}
+
+// 用户从某个时间开始的中奖次数，删除的记录和安慰奖不计算
+func (d *resultDao) CountByUserSince(uid, since int) int64 {
+	num, err := d.engine.
+		Where("uid=?", uid).
+		Where("sys_created>=?", since).
+		Where("sys_status<>?", 1).
+		Where("fallback=?", 0).
+		Count(&models.LtResult{})
+	if err != nil {
+		return 0
+	} else {
+		return num
+	}
+}
+
+func (d *resultDao) Delete(id int) error {
 	data := &models.LtResult{Id: id, SysStatus: 1}
 	_, err := d.engine.Id(data.Id).
 		Update(data)
 	return err
 }
 
-func (d *ResultDao) Update(data *models.LtResult, columns []string) error {
+func (d *resultDao) Update(data *models.LtResult, columns []string) error {
 	_, err := d.engine.Id(data.Id).MustCols(columns...).Update(data)
 	return err
 }
 
-func (d *ResultDao) Create(data *models.LtResult) (int64, error) {
+func (d *resultDao) Create(data *models.LtResult) (int64, error) {
 	return d.engine.Insert(data)
 }
+
+// 批量新增，在一个事务中逐条写入，全部成功或者全部失败
+// 中奖记录后续还需要使用id，逐条写入的时候每条记录都能得到自己的id
+func (d *resultDao) CreateBatch(datalist []models.LtResult) (int64, error) {
+	if len(datalist) == 0 {
+		return 0, nil
+	}
+	session := d.engine.NewSession()
+	defer session.Close()
+	if err := session.Begin(); err != nil {
+		return 0, err
+	}
+	for i := range datalist {
+		num, err := session.Insert(&datalist[i])
+		if err == nil && (num != 1 || datalist[i].Id <= 0) {
+			err = fmt.Errorf("result_dao.CreateBatch insert rows=%d, id=%d", num, datalist[i].Id)
+		}
+		if err != nil {
+			session.Rollback()
+			return 0, err
+		}
+	}
+	return int64(len(datalist)), session.Commit()
+}
+
+// 审核状态是fromStatus中的一个时才更新，避免同一个操作重复执行
+func (d *resultDao) UpdateReview(data *models.LtResult, fromStatus []int, columns []string) (bool, error) {
+	rows, err := d.engine.Id(data.Id).
+		In("review_status", fromStatus).
+		MustCols(columns...).
+		Update(data)
+	return rows > 0, err
+}
+
+// 发放状态是fromStatus中的一个时才更新，同一个中奖记录不会重复发放
+func (d *resultDao) UpdateDeliver(data *models.LtResult, fromStatus []int, columns []string) (bool, error) {
+	rows, err := d.engine.Id(data.Id).
+		In("deliver_status", fromStatus).
+		MustCols(columns...).
+		Update(data)
+	return rows > 0, err
+}
+
+// 发放状态是status，并且最后一次发放在before之前的中奖记录
+func (d *resultDao) SearchByDeliver(status, before, size int) []models.LtResult {
+	datalist := make([]models.LtResult, 0)
+	err := d.engine.
+		Where("deliver_status=?", status).
+		And("deliver_time<?", before).
+		Asc("id").
+		Limit(size).
+		Find(&datalist)
+	if err != nil {
+		log.Println("result_dao.SearchByDeliver error=", err)
+	}
+	return datalist
+}
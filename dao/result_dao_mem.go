@@ -0,0 +1,219 @@
+package dao
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/iralance/go-lottery/models"
+)
+
+// 内存版本的中奖记录，用于单元测试
+type resultMemDao struct {
+	mu     sync.RWMutex
+	rows   map[int]*models.LtResult
+	lastId int
+}
+
+func NewResultMemDao() ResultDao {
+	return &resultMemDao{
+		rows: make(map[int]*models.LtResult),
+	}
+}
+
+func (d *resultMemDao) Get(id int) *models.LtResult {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if data, ok := d.rows[id]; ok {
+		copied := *data
+		return &copied
+	}
+	return nil
+}
+
+func (d *resultMemDao) GetAll(page, size int) []models.LtResult {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	all := d.filter(func(data *models.LtResult) bool { return true })
+	start, end := memPage(len(all), page, size)
+	return all[start:end]
+}
+
+func (d *resultMemDao) CountAll() int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return int64(len(d.rows))
+}
+
+func (d *resultMemDao) GetNewPrize(size int, giftIds []int) []models.LtResult {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	in := make(map[int]bool)
+	for _, id := range giftIds {
+		in[id] = true
+	}
+	all := d.filter(func(data *models.LtResult) bool { return in[data.GiftId] })
+	_, end := memPage(len(all), 1, size)
+	return all[:end]
+}
+
+func (d *resultMemDao) SearchByGift(giftId, page, size int) []models.LtResult {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	all := d.filter(func(data *models.LtResult) bool { return data.GiftId == giftId })
+	start, end := memPage(len(all), page, size)
+	return all[start:end]
+}
+
+func (d *resultMemDao) SearchByUser(uid, page, size int) []models.LtResult {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	all := d.filter(func(data *models.LtResult) bool { return data.Uid == uid })
+	start, end := memPage(len(all), page, size)
+	return all[start:end]
+}
+
+func (d *resultMemDao) CountByGift(giftId int) int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return int64(len(d.filter(func(data *models.LtResult) bool { return data.GiftId == giftId })))
+}
+
+func (d *resultMemDao) CountByUser(uid int) int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return int64(len(d.filter(func(data *models.LtResult) bool { return data.Uid == uid })))
+}
+
+func (d *resultMemDao) CountGroupPity() map[int]int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	nums := make(map[int]int64)
+	for _, row := range d.rows {
+		if row.PityId > 0 {
+			nums[row.PityId]++
+		}
+	}
+	return nums
+}
+
+func (d *resultMemDao) CountByUserGift(uid, giftId int) int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return int64(len(d.filter(func(data *models.LtResult) bool {
+		return data.Uid == uid && data.GiftId == giftId && data.SysStatus != 1 && data.Fallback == 0
+	})))
+}
+
+func (d *resultMemDao) CountByUserGtype(uid, gtype int) int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return int64(len(d.filter(func(data *models.LtResult) bool {
+		return data.Uid == uid && data.GiftType == gtype && data.SysStatus != 1 && data.Fallback == 0
+	})))
+}
+
+func (d *resultMemDao) CountByUserSince(uid, since int) int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return int64(len(d.filter(func(data *models.LtResult) bool {
+		return data.Uid == uid && data.SysCreated >= since && data.SysStatus != 1 && data.Fallback == 0
+	})))
+}
+
+func (d *resultMemDao) Delete(id int) error {
+	return d.Update(&models.LtResult{Id: id, SysStatus: 1}, nil)
+}
+
+func (d *resultMemDao) Update(data *models.LtResult, columns []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if row, ok := d.rows[data.Id]; ok {
+		memUpdate(row, data, columns)
+	}
+	return nil
+}
+
+func (d *resultMemDao) UpdateReview(data *models.LtResult, fromStatus []int, columns []string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	row, ok := d.rows[data.Id]
+	if !ok {
+		return false, nil
+	}
+	for _, status := range fromStatus {
+		if row.ReviewStatus == status {
+			memUpdate(row, data, columns)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (d *resultMemDao) UpdateDeliver(data *models.LtResult, fromStatus []int, columns []string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	row, ok := d.rows[data.Id]
+	if !ok {
+		return false, nil
+	}
+	for _, status := range fromStatus {
+		if row.DeliverStatus == status {
+			memUpdate(row, data, columns)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (d *resultMemDao) SearchByDeliver(status, before, size int) []models.LtResult {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	list := d.filter(func(data *models.LtResult) bool {
+		return data.DeliverStatus == status && data.DeliverTime < before
+	})
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	_, end := memPage(len(list), 1, size)
+	return list[:end]
+}
+
+func (d *resultMemDao) Create(data *models.LtResult) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if data.Id <= 0 {
+		data.Id = d.lastId + 1
+	} else if _, ok := d.rows[data.Id]; ok {
+		return 0, errors.New("result_dao_mem.Create duplicate id")
+	}
+	if data.Id > d.lastId {
+		d.lastId = data.Id
+	}
+	copied := *data
+	d.rows[data.Id] = &copied
+	return 1, nil
+}
+
+func (d *resultMemDao) CreateBatch(datalist []models.LtResult) (int64, error) {
+	for i := range datalist {
+		if _, err := d.Create(&datalist[i]); err != nil {
+			return int64(i), err
+		}
+	}
+	return int64(len(datalist)), nil
+}
+
+// 按照id倒序，返回满足条件的数据
+func (d *resultMemDao) filter(fn func(data *models.LtResult) bool) []models.LtResult {
+	ids := make([]int, 0, len(d.rows))
+	for id := range d.rows {
+		ids = append(ids, id)
+	}
+	datalist := make([]models.LtResult, 0)
+	for _, id := range memIdsDesc(ids) {
+		if fn(d.rows[id]) {
+			datalist = append(datalist, *d.rows[id])
+		}
+	}
+	return datalist
+}
@@ -0,0 +1,62 @@
+package dao
+
+import (
+	"github.com/go-xorm/xorm"
+	"github.com/iralance/go-lottery/models"
+	"log"
+)
+
+type ReviewDao interface {
+	GetAll(page, size int) []models.LtReview
+	CountAll() int64
+	SearchByResult(resultId int) []models.LtReview
+	Create(data *models.LtReview) (int64, error)
+}
+
+type reviewDao struct {
+	engine *xorm.Engine
+}
+
+func NewReviewDao(engine *xorm.Engine) ReviewDao {
+	return &reviewDao{
+		engine: engine,
+	}
+}
+
+func (d *reviewDao) GetAll(page, size int) []models.LtReview {
+	offset := (page - 1) * size
+	datalist := make([]models.LtReview, 0)
+	err := d.engine.
+		Desc("id").
+		Limit(size, offset).
+		Find(&datalist)
+	if err != nil {
+		log.Println("review_dao.GetAll error=", err)
+	}
+	return datalist
+}
+
+func (d *reviewDao) CountAll() int64 {
+	num, err := d.engine.Count(&models.LtReview{})
+	if err != nil {
+		return 0
+	}
+	return num
+}
+
+// 中奖记录的审核历史，按照时间正序
+func (d *reviewDao) SearchByResult(resultId int) []models.LtReview {
+	datalist := make([]models.LtReview, 0)
+	err := d.engine.
+		Where("result_id=?", resultId).
+		Asc("id").
+		Find(&datalist)
+	if err != nil {
+		log.Println("review_dao.SearchByResult error=", err)
+	}
+	return datalist
+}
+
+func (d *reviewDao) Create(data *models.LtReview) (int64, error) {
+	return d.engine.Insert(data)
+}
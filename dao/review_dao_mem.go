@@ -0,0 +1,77 @@
+package dao
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/iralance/go-lottery/models"
+)
+
+// 内存版本的审核记录，用于单元测试
+type reviewMemDao struct {
+	mu     sync.RWMutex
+	rows   map[int]*models.LtReview
+	lastId int
+}
+
+func NewReviewMemDao() ReviewDao {
+	return &reviewMemDao{
+		rows: make(map[int]*models.LtReview),
+	}
+}
+
+func (d *reviewMemDao) GetAll(page, size int) []models.LtReview {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	all := d.filter(func(data *models.LtReview) bool { return true })
+	start, end := memPage(len(all), page, size)
+	return all[start:end]
+}
+
+func (d *reviewMemDao) CountAll() int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return int64(len(d.rows))
+}
+
+func (d *reviewMemDao) SearchByResult(resultId int) []models.LtReview {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	list := d.filter(func(data *models.LtReview) bool { return data.ResultId == resultId })
+	// 按照时间正序
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	return list
+}
+
+func (d *reviewMemDao) Create(data *models.LtReview) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if data.Id <= 0 {
+		data.Id = d.lastId + 1
+	} else if _, ok := d.rows[data.Id]; ok {
+		return 0, errors.New("review_dao_mem.Create duplicate id")
+	}
+	if data.Id > d.lastId {
+		d.lastId = data.Id
+	}
+	copied := *data
+	d.rows[data.Id] = &copied
+	return 1, nil
+}
+
+// 按照id倒序，返回满足条件的数据
+func (d *reviewMemDao) filter(fn func(data *models.LtReview) bool) []models.LtReview {
+	ids := make([]int, 0, len(d.rows))
+	for id := range d.rows {
+		ids = append(ids, id)
+	}
+	datalist := make([]models.LtReview, 0)
+	for _, id := range memIdsDesc(ids) {
+		if fn(d.rows[id]) {
+			datalist = append(datalist, *d.rows[id])
+		}
+	}
+	return datalist
+}
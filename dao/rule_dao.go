@@ -0,0 +1,71 @@
+package dao
+
+import (
+	"github.com/go-xorm/xorm"
+	"github.com/iralance/go-lottery/models"
+	"log"
+)
+
+type RuleDao interface {
+	Get(id int) *models.LtRule
+	GetAll() []models.LtRule
+	CountAll() int64
+	Delete(id int) error
+	Update(data *models.LtRule, columns []string) error
+	Create(data *models.LtRule) (int64, error)
+}
+
+type ruleDao struct {
+	engine *xorm.Engine
+}
+
+func NewRuleDao(engine *xorm.Engine) RuleDao {
+	return &ruleDao{
+		engine: engine,
+	}
+}
+
+func (d *ruleDao) Get(id int) *models.LtRule {
+	data := &models.LtRule{Id: id}
+	ok, err := d.engine.Get(data)
+	if ok && err == nil {
+		return data
+	}
+	return nil
+}
+
+func (d *ruleDao) GetAll() []models.LtRule {
+	dataList := make([]models.LtRule, 0)
+	err := d.engine.Asc("sys_status").
+		Asc("id").
+		Find(&dataList)
+	if err != nil {
+		log.Println("rule_dao.GetAll error=", err)
+		return dataList
+	}
+	return dataList
+}
+
+func (d *ruleDao) CountAll() int64 {
+	num, err := d.engine.Count(&models.LtRule{})
+	if err != nil {
+		return 0
+	}
+	return num
+}
+
+func (d *ruleDao) Delete(id int) error {
+	data := &models.LtRule{Id: id, SysStatus: 1}
+	_, err := d.engine.Id(data.Id).
+		Update(data)
+	return err
+}
+
+func (d *ruleDao) Update(data *models.LtRule, columns []string) error {
+	_, err := d.engine.Id(data.Id).MustCols(columns...).Update(data)
+	return err
+}
+
+func (d *ruleDao) Create(data *models.LtRule) (int64, error) {
+	return d.engine.Insert(data)
+}
@@ -0,0 +1,83 @@
+package dao
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/iralance/go-lottery/models"
+)
+
+// 内存版本的风控规则数据，用于单元测试
+type ruleMemDao struct {
+	mu     sync.RWMutex
+	rows   map[int]*models.LtRule
+	lastId int
+}
+
+func NewRuleMemDao() RuleDao {
+	return &ruleMemDao{
+		rows: make(map[int]*models.LtRule),
+	}
+}
+
+func (d *ruleMemDao) Get(id int) *models.LtRule {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if data, ok := d.rows[id]; ok {
+		copied := *data
+		return &copied
+	}
+	return nil
+}
+
+func (d *ruleMemDao) GetAll() []models.LtRule {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	datalist := make([]models.LtRule, 0, len(d.rows))
+	for _, data := range d.rows {
+		datalist = append(datalist, *data)
+	}
+	sort.Slice(datalist, func(i, j int) bool {
+		if datalist[i].SysStatus != datalist[j].SysStatus {
+			return datalist[i].SysStatus < datalist[j].SysStatus
+		}
+		return datalist[i].Id < datalist[j].Id
+	})
+	return datalist
+}
+
+func (d *ruleMemDao) CountAll() int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return int64(len(d.rows))
+}
+
+func (d *ruleMemDao) Delete(id int) error {
+	return d.Update(&models.LtRule{Id: id, SysStatus: 1}, nil)
+}
+
+func (d *ruleMemDao) Update(data *models.LtRule, columns []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if row, ok := d.rows[data.Id]; ok {
+		memUpdate(row, data, columns)
+	}
+	return nil
+}
+
+func (d *ruleMemDao) Create(data *models.LtRule) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if data.Id <= 0 {
+		data.Id = d.lastId + 1
+	} else if _, ok := d.rows[data.Id]; ok {
+		return 0, errors.New("rule_dao_mem.Create duplicate id")
+	}
+	if data.Id > d.lastId {
+		d.lastId = data.Id
+	}
+	copied := *data
+	d.rows[data.Id] = &copied
+	return 1, nil
+}
@@ -6,17 +6,26 @@ import (
 	"log"
 )
 
-type UserDao struct {
+type UserDao interface {
+	Get(id int) *models.LtUser
+	GetAll(page, size int) []models.LtUser
+	CountAll() int64
+	Delete(id int) error
+	Update(data *models.LtUser, columns []string) error
+	Create(data *models.LtUser) (int64, error)
+}
+
+type userDao struct {
 	engine *xorm.Engine
 }
 
-func NewUserDao(engine *xorm.Engine) *UserDao {
-	return &UserDao{
+func NewUserDao(engine *xorm.Engine) UserDao {
+	return &userDao{
 		engine: engine,
 	}
 }
 
-func (d *UserDao) Get(id int) *models.LtUser {
+func (d *userDao) Get(id int) *models.LtUser {
 	data := &models.LtUser{Id: id}
 	ok, err := d.engine.Get(data)
 	if ok && err == nil {
@@ -25,7 +34,7 @@ func (d *UserDao) Get(id int) *models.LtUser {
 	return nil
 }
 
-func (d *UserDao) GetAll(page, size int) []models.LtUser {
+func (d *userDao) GetAll(page, size int) []models.LtUser {
 	offset := (page - 1) * size
 	dataList := make([]models.LtUser, 0)
 	err := d.engine.
@@ -39,7 +48,7 @@ func (d *UserDao) GetAll(page, size int) []models.LtUser {
 	return dataList
 }
 
-func (d *UserDao) CountAll() int64 {
+func (d *userDao) CountAll() int64 {
 	num, err := d.engine.Count(&models.LtUser{})
 	if err != nil {
 		return 0
@@ -47,18 +56,18 @@ func (d *UserDao) CountAll() int64 {
 	return num
 }
 
-func (d *UserDao) Delete(id int) error {
+func (d *userDao) Delete(id int) error {
 	data := &models.LtUser{Id: id}
 	_, err := d.engine.Id(data.Id).
 		Update(data)
 	return err
 }
 
-func (d *UserDao) Update(data *models.LtUser, columns []string) error {
+func (d *userDao) Update(data *models.LtUser, columns []string) error {
 	_, err := d.engine.Id(data.Id).MustCols(columns...).Update(data)
 	return err
 }
 
-func (d *UserDao) Create(data *models.LtUser) (int64, error) {
+func (d *userDao) Create(data *models.LtUser) (int64, error) {
 	return d.engine.Insert(data)
 }
@@ -0,0 +1,81 @@
+package dao
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/iralance/go-lottery/models"
+)
+
+// 内存版本的用户数据，用于单元测试
+type userMemDao struct {
+	mu     sync.RWMutex
+	rows   map[int]*models.LtUser
+	lastId int
+}
+
+func NewUserMemDao() UserDao {
+	return &userMemDao{
+		rows: make(map[int]*models.LtUser),
+	}
+}
+
+func (d *userMemDao) Get(id int) *models.LtUser {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if data, ok := d.rows[id]; ok {
+		copied := *data
+		return &copied
+	}
+	return nil
+}
+
+func (d *userMemDao) GetAll(page, size int) []models.LtUser {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	ids := make([]int, 0, len(d.rows))
+	for id := range d.rows {
+		ids = append(ids, id)
+	}
+	all := make([]models.LtUser, 0, len(ids))
+	for _, id := range memIdsDesc(ids) {
+		all = append(all, *d.rows[id])
+	}
+	start, end := memPage(len(all), page, size)
+	return all[start:end]
+}
+
+func (d *userMemDao) CountAll() int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return int64(len(d.rows))
+}
+
+func (d *userMemDao) Delete(id int) error {
+	return nil
+}
+
+func (d *userMemDao) Update(data *models.LtUser, columns []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if row, ok := d.rows[data.Id]; ok {
+		memUpdate(row, data, columns)
+	}
+	return nil
+}
+
+func (d *userMemDao) Create(data *models.LtUser) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if data.Id <= 0 {
+		data.Id = d.lastId + 1
+	} else if _, ok := d.rows[data.Id]; ok {
+		return 0, errors.New("user_dao_mem.Create duplicate id")
+	}
+	if data.Id > d.lastId {
+		d.lastId = data.Id
+	}
+	copied := *data
+	d.rows[data.Id] = &copied
+	return 1, nil
+}
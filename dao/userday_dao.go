@@ -6,17 +6,28 @@ import (
 	"log"
 )
 
-type UserdayDao struct {
+type UserdayDao interface {
+	Get(id int) *models.LtUserday
+	GetAll(page, size int) []models.LtUserday
+	CountAll() int64
+	Search(uid, day int) []models.LtUserday
+	Count(uid, day int) int
+	Delete(id int) error
+	Update(data *models.LtUserday, columns []string) error
+	Create(data *models.LtUserday) (int64, error)
+}
+
+type userdayDao struct {
 	engine *xorm.Engine
 }
 
-func NewUserdayDao(engine *xorm.Engine) *UserdayDao {
-	return &UserdayDao{
+func NewUserdayDao(engine *xorm.Engine) UserdayDao {
+	return &userdayDao{
 		engine: engine,
 	}
 }
 
-func (d *UserdayDao) Get(id int) *models.LtUserday {
+func (d *userdayDao) Get(id int) *models.LtUserday {
 	data := &models.LtUserday{Id: id}
 	ok, err := d.engine.Get(data)
 	if ok && err == nil {
@@ -25,7 +36,7 @@ func (d *UserdayDao) Get(id int) *models.LtUserday {
 	return nil
 }
 
-func (d *UserdayDao) GetAll(page, size int) []models.LtUserday {
+func (d *userdayDao) GetAll(page, size int) []models.LtUserday {
 	offset := (page - 1) * size
 	dataList := make([]models.LtUserday, 0)
 	err := d.engine.
@@ -39,7 +50,7 @@ func (d *UserdayDao) GetAll(page, size int) []models.LtUserday {
 	return dataList
 }
 
-func (d *UserdayDao) CountAll() int64 {
+func (d *userdayDao) CountAll() int64 {
 	num, err := d.engine.Count(&models.LtUserday{})
 	if err != nil {
 		return 0
@@ -47,7 +58,7 @@ func (d *UserdayDao) CountAll() int64 {
 	return num
 }
 
-func (d *UserdayDao) Search(uid, day int) []models.LtUserday {
+func (d *userdayDao) Search(uid, day int) []models.LtUserday {
 	datalist := make([]models.LtUserday, 0)
 	err := d.engine.
 		Where("uid=?", uid).
@@ -61,7 +72,7 @@ func (d *UserdayDao) Search(uid, day int) []models.LtUserday {
 	}
 }
 
-func (d *UserdayDao) Count(uid, day int) int {
+func (d *userdayDao) Count(uid, day int) int {
 	info := &models.LtUserday{}
 	ok, err := d.engine.
 		Where("uid=?", uid).
@@ -74,18 +85,18 @@ func (d *UserdayDao) Count(uid, day int) int {
 	}
 }
 
-func (d *UserdayDao) Delete(id int) error {
+func (d *userdayDao) Delete(id int) error {
 	data := &models.LtUserday{Id: id}
 	_, err := d.engine.Id(data.Id).
 		Update(data)
 	return err
 }
 
-func (d *UserdayDao) Update(data *models.LtUserday, columns []string) error {
+func (d *userdayDao) Update(data *models.LtUserday, columns []string) error {
 	_, err := d.engine.Id(data.Id).MustCols(columns...).Update(data)
 	return err
 }
 
-func (d *UserdayDao) Create(data *models.LtUserday) (int64, error) {
+func (d *userdayDao) Create(data *models.LtUserday) (int64, error) {
 	return d.engine.Insert(data)
 }
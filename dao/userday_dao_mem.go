@@ -0,0 +1,103 @@
+package dao
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/iralance/go-lottery/models"
+)
+
+// 内存版本的用户每日参与次数，用于单元测试
+type userdayMemDao struct {
+	mu     sync.RWMutex
+	rows   map[int]*models.LtUserday
+	lastId int
+}
+
+func NewUserdayMemDao() UserdayDao {
+	return &userdayMemDao{
+		rows: make(map[int]*models.LtUserday),
+	}
+}
+
+func (d *userdayMemDao) Get(id int) *models.LtUserday {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if data, ok := d.rows[id]; ok {
+		copied := *data
+		return &copied
+	}
+	return nil
+}
+
+func (d *userdayMemDao) GetAll(page, size int) []models.LtUserday {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	all := d.filter(func(data *models.LtUserday) bool { return true })
+	start, end := memPage(len(all), page, size)
+	return all[start:end]
+}
+
+func (d *userdayMemDao) CountAll() int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return int64(len(d.rows))
+}
+
+func (d *userdayMemDao) Search(uid, day int) []models.LtUserday {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.filter(func(data *models.LtUserday) bool { return data.Uid == uid && data.Day == day })
+}
+
+func (d *userdayMemDao) Count(uid, day int) int {
+	list := d.Search(uid, day)
+	if len(list) < 1 {
+		return 0
+	}
+	return list[len(list)-1].Num
+}
+
+func (d *userdayMemDao) Delete(id int) error {
+	return nil
+}
+
+func (d *userdayMemDao) Update(data *models.LtUserday, columns []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if row, ok := d.rows[data.Id]; ok {
+		memUpdate(row, data, columns)
+	}
+	return nil
+}
+
+func (d *userdayMemDao) Create(data *models.LtUserday) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if data.Id <= 0 {
+		data.Id = d.lastId + 1
+	} else if _, ok := d.rows[data.Id]; ok {
+		return 0, errors.New("userday_dao_mem.Create duplicate id")
+	}
+	if data.Id > d.lastId {
+		d.lastId = data.Id
+	}
+	copied := *data
+	d.rows[data.Id] = &copied
+	return 1, nil
+}
+
+// 按照id倒序，返回满足条件的数据
+func (d *userdayMemDao) filter(fn func(data *models.LtUserday) bool) []models.LtUserday {
+	ids := make([]int, 0, len(d.rows))
+	for id := range d.rows {
+		ids = append(ids, id)
+	}
+	datalist := make([]models.LtUserday, 0)
+	for _, id := range memIdsDesc(ids) {
+		if fn(d.rows[id]) {
+			datalist = append(datalist, *d.rows[id])
+		}
+	}
+	return datalist
+}
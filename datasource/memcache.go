@@ -0,0 +1,524 @@
+package datasource
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errMemWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+var errMemNotInteger = errors.New("ERR value is not an integer or out of range")
+var errMemSyntax = errors.New("ERR syntax error")
+
+type memEntry struct {
+	value    interface{} // string, map[string]string, map[string]struct{}, []string
+	expireAt time.Time
+}
+
+// 内存版本的缓存，实现了抽奖中用到的redis命令
+// 返回值的类型与redigo保持一致，方便redis.String等函数直接使用
+type MemCache struct {
+	mu   sync.Mutex
+	data map[string]*memEntry
+	subs map[string][]chan []byte
+}
+
+func NewMemCache() *MemCache {
+	return &MemCache{
+		data: make(map[string]*memEntry),
+		subs: make(map[string][]chan []byte),
+	}
+}
+
+func (m *MemCache) Do(commandName string, args ...interface{}) (reply interface{}, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	argv := make([]string, len(args))
+	for i, arg := range args {
+		argv[i] = memArgString(arg)
+	}
+	cmd := strings.ToUpper(commandName)
+	switch cmd {
+	case "PING":
+		return "PONG", nil
+	case "GET":
+		if len(argv) != 1 {
+			return nil, memArgErr(cmd)
+		}
+		return m.get(argv[0])
+	case "SET":
+		return m.setString(argv)
+	case "DEL":
+		var n int64
+		for _, key := range argv {
+			if m.lookup(key) != nil {
+				delete(m.data, key)
+				n++
+			}
+		}
+		return n, nil
+	case "EXISTS":
+		var n int64
+		for _, key := range argv {
+			if m.lookup(key) != nil {
+				n++
+			}
+		}
+		return n, nil
+	case "EXPIRE":
+		if len(argv) != 2 {
+			return nil, memArgErr(cmd)
+		}
+		sec, err := strconv.ParseInt(argv[1], 10, 64)
+		if err != nil {
+			return nil, errMemNotInteger
+		}
+		e := m.lookup(argv[0])
+		if e == nil {
+			return int64(0), nil
+		}
+		e.expireAt = time.Now().Add(time.Duration(sec) * time.Second)
+		return int64(1), nil
+	case "TTL":
+		if len(argv) != 1 {
+			return nil, memArgErr(cmd)
+		}
+		e := m.lookup(argv[0])
+		if e == nil {
+			return int64(-2), nil
+		}
+		if e.expireAt.IsZero() {
+			return int64(-1), nil
+		}
+		return int64(time.Until(e.expireAt).Seconds() + 0.5), nil
+	case "INCR", "INCRBY", "DECR", "DECRBY":
+		return m.incr(cmd, argv)
+	case "RENAME":
+		if len(argv) != 2 {
+			return nil, memArgErr(cmd)
+		}
+		e := m.lookup(argv[0])
+		if e == nil {
+			return nil, errors.New("ERR no such key")
+		}
+		delete(m.data, argv[0])
+		m.data[argv[1]] = e
+		return "OK", nil
+	case "HGET", "HMGET", "HSET", "HMSET", "HGETALL", "HINCRBY", "HDEL", "HLEN", "HEXISTS":
+		return m.hashCmd(cmd, argv)
+	case "SADD", "SREM", "SPOP", "SCARD", "SISMEMBER", "SMEMBERS":
+		return m.setCmd(cmd, argv)
+	case "LPUSH", "LTRIM", "LRANGE", "LLEN":
+		return m.listCmd(cmd, argv)
+	case "PUBLISH":
+		if len(argv) != 2 {
+			return nil, memArgErr(cmd)
+		}
+		var n int64
+		for _, ch := range m.subs[argv[0]] {
+			// 订阅者处理不过来的时候丢弃消息
+			select {
+			case ch <- []byte(argv[1]):
+				n++
+			default:
+			}
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("ERR unknown command '%s'", commandName)
+}
+
+// 找到一个没有过期的key
+func (m *MemCache) lookup(key string) *memEntry {
+	e, ok := m.data[key]
+	if !ok {
+		return nil
+	}
+	if !e.expireAt.IsZero() && !time.Now().Before(e.expireAt) {
+		delete(m.data, key)
+		return nil
+	}
+	return e
+}
+
+func (m *MemCache) get(key string) (interface{}, error) {
+	e := m.lookup(key)
+	if e == nil {
+		return nil, nil
+	}
+	str, ok := e.value.(string)
+	if !ok {
+		return nil, errMemWrongType
+	}
+	return []byte(str), nil
+}
+
+// SET key value [EX seconds] [PX milliseconds] [NX|XX]
+func (m *MemCache) setString(argv []string) (interface{}, error) {
+	if len(argv) < 2 {
+		return nil, memArgErr("SET")
+	}
+	key, value := argv[0], argv[1]
+	var expireAt time.Time
+	nx, xx := false, false
+	for i := 2; i < len(argv); i++ {
+		switch strings.ToUpper(argv[i]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "EX", "PX":
+			if i+1 >= len(argv) {
+				return nil, errMemSyntax
+			}
+			n, err := strconv.ParseInt(argv[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				return nil, errMemNotInteger
+			}
+			unit := time.Second
+			if strings.ToUpper(argv[i]) == "PX" {
+				unit = time.Millisecond
+			}
+			expireAt = time.Now().Add(time.Duration(n) * unit)
+			i++
+		default:
+			return nil, errMemSyntax
+		}
+	}
+	exists := m.lookup(key) != nil
+	if (nx && exists) || (xx && !exists) {
+		return nil, nil
+	}
+	m.data[key] = &memEntry{value: value, expireAt: expireAt}
+	return "OK", nil
+}
+
+func (m *MemCache) incr(cmd string, argv []string) (interface{}, error) {
+	var delta int64 = 1
+	switch cmd {
+	case "INCRBY", "DECRBY":
+		if len(argv) != 2 {
+			return nil, memArgErr(cmd)
+		}
+		n, err := strconv.ParseInt(argv[1], 10, 64)
+		if err != nil {
+			return nil, errMemNotInteger
+		}
+		delta = n
+	default:
+		if len(argv) != 1 {
+			return nil, memArgErr(cmd)
+		}
+	}
+	if cmd == "DECR" || cmd == "DECRBY" {
+		delta = -delta
+	}
+	e := m.lookup(argv[0])
+	var num int64
+	if e != nil {
+		str, ok := e.value.(string)
+		if !ok {
+			return nil, errMemWrongType
+		}
+		n, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return nil, errMemNotInteger
+		}
+		num = n
+	} else {
+		e = &memEntry{}
+		m.data[argv[0]] = e
+	}
+	num += delta
+	e.value = strconv.FormatInt(num, 10)
+	return num, nil
+}
+
+func (m *MemCache) hashCmd(cmd string, argv []string) (interface{}, error) {
+	if len(argv) < 1 {
+		return nil, memArgErr(cmd)
+	}
+	key := argv[0]
+	var h map[string]string
+	e := m.lookup(key)
+	if e != nil {
+		v, ok := e.value.(map[string]string)
+		if !ok {
+			return nil, errMemWrongType
+		}
+		h = v
+	}
+	// 写入的时候，不存在就创建
+	create := func() map[string]string {
+		if h == nil {
+			h = make(map[string]string)
+			m.data[key] = &memEntry{value: h}
+		}
+		return h
+	}
+	switch cmd {
+	case "HGET":
+		if len(argv) != 2 {
+			return nil, memArgErr(cmd)
+		}
+		v, ok := h[argv[1]]
+		if !ok {
+			return nil, nil
+		}
+		return []byte(v), nil
+	case "HMGET":
+		if len(argv) < 2 {
+			return nil, memArgErr(cmd)
+		}
+		rs := make([]interface{}, len(argv)-1)
+		for i, field := range argv[1:] {
+			if v, ok := h[field]; ok {
+				rs[i] = []byte(v)
+			}
+		}
+		return rs, nil
+	case "HSET", "HMSET":
+		if len(argv) < 3 || len(argv)%2 != 1 {
+			return nil, memArgErr(cmd)
+		}
+		create()
+		var n int64
+		for i := 1; i < len(argv); i += 2 {
+			if _, ok := h[argv[i]]; !ok {
+				n++
+			}
+			h[argv[i]] = argv[i+1]
+		}
+		if cmd == "HMSET" {
+			return "OK", nil
+		}
+		return n, nil
+	case "HGETALL":
+		rs := make([]interface{}, 0, len(h)*2)
+		for k, v := range h {
+			rs = append(rs, []byte(k), []byte(v))
+		}
+		return rs, nil
+	case "HINCRBY":
+		if len(argv) != 3 {
+			return nil, memArgErr(cmd)
+		}
+		delta, err := strconv.ParseInt(argv[2], 10, 64)
+		if err != nil {
+			return nil, errMemNotInteger
+		}
+		create()
+		var num int64
+		if v, ok := h[argv[1]]; ok {
+			num, err = strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, errors.New("ERR hash value is not an integer")
+			}
+		}
+		num += delta
+		h[argv[1]] = strconv.FormatInt(num, 10)
+		return num, nil
+	case "HDEL":
+		var n int64
+		for _, field := range argv[1:] {
+			if _, ok := h[field]; ok {
+				delete(h, field)
+				n++
+			}
+		}
+		if h != nil && len(h) == 0 {
+			delete(m.data, key)
+		}
+		return n, nil
+	case "HLEN":
+		return int64(len(h)), nil
+	case "HEXISTS":
+		if len(argv) != 2 {
+			return nil, memArgErr(cmd)
+		}
+		if _, ok := h[argv[1]]; ok {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	}
+	return nil, memArgErr(cmd)
+}
+
+func (m *MemCache) setCmd(cmd string, argv []string) (interface{}, error) {
+	if len(argv) < 1 {
+		return nil, memArgErr(cmd)
+	}
+	key := argv[0]
+	var s map[string]struct{}
+	e := m.lookup(key)
+	if e != nil {
+		v, ok := e.value.(map[string]struct{})
+		if !ok {
+			return nil, errMemWrongType
+		}
+		s = v
+	}
+	switch cmd {
+	case "SADD":
+		if len(argv) < 2 {
+			return nil, memArgErr(cmd)
+		}
+		if s == nil {
+			s = make(map[string]struct{})
+			m.data[key] = &memEntry{value: s}
+		}
+		var n int64
+		for _, member := range argv[1:] {
+			if _, ok := s[member]; !ok {
+				s[member] = struct{}{}
+				n++
+			}
+		}
+		return n, nil
+	case "SREM":
+		var n int64
+		for _, member := range argv[1:] {
+			if _, ok := s[member]; ok {
+				delete(s, member)
+				n++
+			}
+		}
+		if s != nil && len(s) == 0 {
+			delete(m.data, key)
+		}
+		return n, nil
+	case "SPOP":
+		// map的遍历顺序是随机的，正好满足SPOP的语义
+		for member := range s {
+			delete(s, member)
+			if len(s) == 0 {
+				delete(m.data, key)
+			}
+			return []byte(member), nil
+		}
+		return nil, nil
+	case "SCARD":
+		return int64(len(s)), nil
+	case "SISMEMBER":
+		if len(argv) != 2 {
+			return nil, memArgErr(cmd)
+		}
+		if _, ok := s[argv[1]]; ok {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case "SMEMBERS":
+		rs := make([]interface{}, 0, len(s))
+		for member := range s {
+			rs = append(rs, []byte(member))
+		}
+		return rs, nil
+	}
+	return nil, memArgErr(cmd)
+}
+
+func (m *MemCache) listCmd(cmd string, argv []string) (interface{}, error) {
+	if len(argv) < 1 {
+		return nil, memArgErr(cmd)
+	}
+	key := argv[0]
+	var l []string
+	e := m.lookup(key)
+	if e != nil {
+		v, ok := e.value.([]string)
+		if !ok {
+			return nil, errMemWrongType
+		}
+		l = v
+	}
+	switch cmd {
+	case "LPUSH":
+		if len(argv) < 2 {
+			return nil, memArgErr(cmd)
+		}
+		for _, member := range argv[1:] {
+			l = append([]string{member}, l...)
+		}
+		if e == nil {
+			e = &memEntry{}
+			m.data[key] = e
+		}
+		e.value = l
+		return int64(len(l)), nil
+	case "LLEN":
+		return int64(len(l)), nil
+	case "LTRIM", "LRANGE":
+		if len(argv) != 3 {
+			return nil, memArgErr(cmd)
+		}
+		start, err1 := strconv.Atoi(argv[1])
+		stop, err2 := strconv.Atoi(argv[2])
+		if err1 != nil || err2 != nil {
+			return nil, errMemNotInteger
+		}
+		start, stop = memListRange(len(l), start, stop)
+		if cmd == "LRANGE" {
+			rs := make([]interface{}, 0)
+			for i := start; i <= stop; i++ {
+				rs = append(rs, []byte(l[i]))
+			}
+			return rs, nil
+		}
+		if start > stop {
+			delete(m.data, key)
+		} else if e != nil {
+			e.value = append([]string{}, l[start:stop+1]...)
+		}
+		return "OK", nil
+	}
+	return nil, memArgErr(cmd)
+}
+
+// 列表的下标范围，负数从后往前数，规则与redis一致
+func memListRange(n, start, stop int) (int, int) {
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	return start, stop
+}
+
+func memArgErr(cmd string) error {
+	return fmt.Errorf("ERR wrong number of arguments for '%s' command", strings.ToLower(cmd))
+}
+
+// 参数转换成字符串，规则与redigo写入参数的时候一致
+func memArgString(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(arg)
+}
@@ -0,0 +1,67 @@
+package datasource
+
+import (
+	"github.com/gomodule/redigo/redis"
+)
+
+// 发布订阅，发布直接使用Do("PUBLISH")，订阅需要单独的连接
+type Subscriber interface {
+	// 订阅频道，收到消息的时候调用fn
+	// 一直阻塞，直到连接出错或者stop被关闭
+	Subscribe(channel string, stop <-chan struct{}, fn func(data []byte)) error
+}
+
+func (rds *RedisConn) Subscribe(channel string, stop <-chan struct{}, fn func(data []byte)) error {
+	conn := redis.PubSubConn{Conn: rds.pool.Get()}
+	defer conn.Close()
+	if err := conn.Subscribe(channel); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+			conn.Unsubscribe(channel)
+		case <-done:
+		}
+	}()
+	for {
+		switch v := conn.Receive().(type) {
+		case redis.Message:
+			fn(v.Data)
+		case redis.Subscription:
+			if v.Kind == "unsubscribe" && v.Count == 0 {
+				return nil
+			}
+		case error:
+			return v
+		}
+	}
+}
+
+func (m *MemCache) Subscribe(channel string, stop <-chan struct{}, fn func(data []byte)) error {
+	ch := make(chan []byte, 100)
+	m.mu.Lock()
+	m.subs[channel] = append(m.subs[channel], ch)
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		list := m.subs[channel]
+		for i := range list {
+			if list[i] == ch {
+				m.subs[channel] = append(list[:i], list[i+1:]...)
+				break
+			}
+		}
+	}()
+	for {
+		select {
+		case data := <-ch:
+			fn(data)
+		case <-stop:
+			return nil
+		}
+	}
+}
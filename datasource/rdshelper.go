@@ -12,6 +12,11 @@ import (
 var rdsLock sync.Mutex
 var cacheInstance *RedisConn
 
+// 缓存的抽象，redis和内存实现都只需要提供一个Do命令
+type Cache interface {
+	Do(commandName string, args ...interface{}) (reply interface{}, err error)
+}
+
 type RedisConn struct {
 	pool      *redis.Pool
 	showDebug bool
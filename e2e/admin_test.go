@@ -0,0 +1,136 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/iralance/go-lottery/conf"
+)
+
+func testAdminRequiresAuth(h *harness) error {
+	status, _, err := h.newClient().get("/admin")
+	if err != nil {
+		return err
+	}
+	if status != http.StatusUnauthorized {
+		return fmt.Errorf("GET /admin without auth status=%d", status)
+	}
+	return nil
+}
+
+func testAdminPages(h *harness) error {
+	gift, err := h.seedGift("coupon", conf.GtypeCodeDiff, 5, "0-9999")
+	if err != nil {
+		return err
+	}
+	if err = h.seedCodes(gift.Id, 5); err != nil {
+		return err
+	}
+	admin := h.newAdmin()
+	for _, path := range []string{
+		"/admin", "/admin/gift", "/admin/gift/edit", fmt.Sprintf("/admin/gift/edit?id=%d", gift.Id),
+		"/admin/code", fmt.Sprintf("/admin/code?gift_id=%d", gift.Id),
+		"/admin/result", "/admin/user", "/admin/blackip", "/admin/rule", "/admin/rule/edit",
+		"/admin/point", "/admin/point/log", "/admin/pity", "/admin/pity/edit",
+	} {
+		status, body, err := admin.get(path)
+		if err != nil {
+			return err
+		}
+		if status != http.StatusOK {
+			return fmt.Errorf("GET %s status=%d body=%s", path, status, body)
+		}
+	}
+	return nil
+}
+
+func testAdminCodeImport(h *harness) error {
+	gift, err := h.seedGift("coupon", conf.GtypeCodeDiff, 5, "0-9999")
+	if err != nil {
+		return err
+	}
+	admin := h.newAdmin()
+	form := url.Values{"codes": {"A001\nA002\n\nA003\n"}}
+	status, body, err := admin.post(fmt.Sprintf("/admin/code/import?gift_id=%d", gift.Id), form)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK || !strings.Contains(string(body), "成功导入 3 条") {
+		return fmt.Errorf("import status=%d body=%s", status, body)
+	}
+	if num := h.ServiceCode.CountByGift(gift.Id); num != 3 {
+		return fmt.Errorf("%d codes in db after import, want 3", num)
+	}
+	// 缓存丢失之后，可以从数据库重新整理
+	h.cache.Do("DEL", fmt.Sprintf("gift_code_%d", gift.Id))
+	status, body, err = admin.get(fmt.Sprintf("/admin/code/recache?id=%d", gift.Id))
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK || !strings.Contains(string(body), "sucNum=3") {
+		return fmt.Errorf("recache status=%d body=%s", status, body)
+	}
+	cacheNum, _ := h.cache.Do("SCARD", fmt.Sprintf("gift_code_%d", gift.Id))
+	if cacheNum.(int64) != 3 {
+		return fmt.Errorf("gift_code set has %d codes after recache, want 3", cacheNum)
+	}
+	return nil
+}
+
+func testAdminGiftCreate(h *harness) error {
+	form := url.Values{
+		"title":        {"created by admin"},
+		"prize_num":    {"10"},
+		"prize_code":   {"0-99"},
+		"prize_time":   {"0"},
+		"displayorder": {"1"},
+		"gtype":        {fmt.Sprint(conf.GtypeGiftSmall)},
+		"time_begin":   {"2020-01-01 00:00:00"},
+		"time_end":     {"2099-01-01 00:00:00"},
+	}
+	status, _, err := h.newAdmin().post("/admin/gift/save", form)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusFound && status != http.StatusSeeOther {
+		return fmt.Errorf("save gift status=%d", status)
+	}
+	list := h.ServiceGift.GetAll(false)
+	if len(list) != 1 || list[0].Title != "created by admin" || list[0].LeftNum != 10 {
+		return fmt.Errorf("gifts after admin save = %+v", list)
+	}
+	if pool := h.poolNum(list[0].Id); pool != 10 {
+		return fmt.Errorf("gift pool=%d after admin save, want 10", pool)
+	}
+	return nil
+}
+
+func testAdminResultCheat(h *harness) error {
+	if _, err := h.seedGift("small", conf.GtypeGiftSmall, 0, "0-9999"); err != nil {
+		return err
+	}
+	c := h.newClient()
+	if err := c.login(); err != nil {
+		return err
+	}
+	rs, err := c.lucky()
+	if err != nil {
+		return err
+	}
+	if rs.Code != 0 {
+		return fmt.Errorf("lucky code=%d msg=%s, want a prize", rs.Code, rs.Msg)
+	}
+	list := h.ServiceResult.GetAll(1, 10)
+	if len(list) != 1 {
+		return fmt.Errorf("%d results, want 1", len(list))
+	}
+	if _, _, err = h.newAdmin().get(fmt.Sprintf("/admin/result/cheat?id=%d", list[0].Id)); err != nil {
+		return err
+	}
+	if info := h.ServiceResult.Get(list[0].Id); info.SysStatus != conf.ResultStatusCheat {
+		return fmt.Errorf("result sys_status=%d after cheat, want 2", info.SysStatus)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package e2e
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+	utils "github.com/iralance/go-lottery/uitls"
+)
+
+// 一次抽奖多次，中奖记录一次写入，达到次数的时候保底获得某类型的奖品
+func testBatchDraws(h *harness) error {
+	defer func(gtype int) { conf.LuckyBatchGuaranteeGtype = gtype }(conf.LuckyBatchGuaranteeGtype)
+	conf.LuckyBatchGuaranteeGtype = conf.GtypeGiftSmall
+	defer delete(conf.DrawPointsCost, "paid")
+	conf.DrawPointsCost["paid"] = 10
+	if _, err := h.seedGift("coin", conf.GtypeVirtual, 0, "0-4999"); err != nil {
+		return err
+	}
+	small, err := h.seedGift("small", conf.GtypeGiftSmall, 1, "0-0")
+	if err != nil {
+		return err
+	}
+	c := h.newClient()
+	if err = c.login(); err != nil {
+		return err
+	}
+	type batchResult struct {
+		Code     int                   `json:"code"`
+		Msg      string                `json:"msg"`
+		DrawList []models.ObjLuckyDraw `json:"draw_list"`
+	}
+	batch := func(params url.Values) (*batchResult, error) {
+		rs := &batchResult{}
+		err := c.getJSON("/lucky/batch?"+params.Encode(), rs)
+		return rs, err
+	}
+
+	rs, err := batch(url.Values{"n": {fmt.Sprint(conf.LuckyBatchMax + 1)}})
+	if err != nil {
+		return err
+	}
+	if rs.Code != 111 {
+		return fmt.Errorf("batch n=%d code=%d, want 111", conf.LuckyBatchMax+1, rs.Code)
+	}
+	if rs, err = batch(url.Values{"n": {"10"}}); err != nil {
+		return err
+	}
+	if rs.Code != 0 || len(rs.DrawList) != 10 {
+		return fmt.Errorf("batch code=%d msg=%s draws=%d", rs.Code, rs.Msg, len(rs.DrawList))
+	}
+	wins, smalls := 0, 0
+	for _, draw := range rs.DrawList {
+		if draw.Code == 0 {
+			wins++
+			if draw.Gift.Id == small.Id {
+				smalls++
+			}
+		}
+	}
+	if smalls != 1 || h.poolNum(small.Id) != 0 {
+		return fmt.Errorf("batch small gifts=%d pool=%d, want 1 and 0", smalls, h.poolNum(small.Id))
+	}
+	if num := h.ServiceResult.CountByUser(c.uid()); num != int64(wins) {
+		return fmt.Errorf("results after batch = %d, want %d", num, wins)
+	}
+	draws := struct {
+		Total int `json:"total"`
+	}{}
+	if err = c.getJSON("/mydraws", &draws); err != nil {
+		return err
+	}
+	if draws.Total != 10 {
+		return fmt.Errorf("mydraws total after batch = %d, want 10", draws.Total)
+	}
+	// 一个事务中写入的中奖记录，每条记录的id和抽奖记录对应
+	for _, data := range h.ServiceDrawLog.SearchByUser(c.uid(), 1, 10) {
+		if data.Code != 0 {
+			continue
+		}
+		if result := h.ServiceResult.Get(data.ResultId); result == nil || result.GiftId != data.GiftId || result.Uid != c.uid() {
+			return fmt.Errorf("draw log %+v points to result %+v", data, result)
+		}
+	}
+
+	// 需要积分的活动一次扣除，失败的抽奖退回积分
+	if _, err = h.ServicePoint.Earn(c.uid(), 50, "batch-test", "batch"); err != nil {
+		return err
+	}
+	if rs, err = batch(url.Values{"n": {"10"}, "campaign": {"paid"}}); err != nil {
+		return err
+	}
+	if rs.Code != 109 {
+		return fmt.Errorf("paid batch n=10 code=%d, want 109", rs.Code)
+	}
+	if rs, err = batch(url.Values{"n": {"5"}, "campaign": {"paid"}}); err != nil {
+		return err
+	}
+	if rs.Code != 0 || len(rs.DrawList) != 5 {
+		return fmt.Errorf("paid batch code=%d msg=%s draws=%d", rs.Code, rs.Msg, len(rs.DrawList))
+	}
+	refund := 0
+	for _, draw := range rs.DrawList {
+		if draw.Code >= 206 && draw.Code <= 209 {
+			refund += 10
+		}
+	}
+	if balance := h.ServicePoint.Get(c.uid()).Balance; balance != refund {
+		return fmt.Errorf("points after paid batch = %d, want %d", balance, refund)
+	}
+	// 需要积分的抽奖不占用免费的次数
+	if num := utils.GetUserLuckyNum(h.cache, c.uid()); num != 10 {
+		return fmt.Errorf("free draws after paid batch = %d, want 10", num)
+	}
+
+	// 免费的次数不够的时候拒绝，剩下的免费次数仍然可以使用
+	utils.InitUserLuckyNum(h.cache, c.uid(), int64(conf.UserPrizeMax-2))
+	for _, check := range []struct{ n, want int }{{5, 103}, {2, 0}, {1, 103}} {
+		if rs, err = batch(url.Values{"n": {fmt.Sprint(check.n)}}); err != nil {
+			return err
+		}
+		if rs.Code != check.want {
+			return fmt.Errorf("batch n=%d with 2 free draws left code=%d msg=%s, want %d", check.n, rs.Code, rs.Msg, check.want)
+		}
+	}
+	return nil
+}
+
+// 中奖之后受到风控限制，后面没有进行的抽奖退回积分、免费的次数和抽奖机会
+func testBatchCutShort(h *harness) error {
+	defer func(gtype int) { conf.LuckyBatchGuaranteeGtype = gtype }(conf.LuckyBatchGuaranteeGtype)
+	conf.LuckyBatchGuaranteeGtype = -1
+	defer delete(conf.DrawPointsCost, "paid")
+	conf.DrawPointsCost["paid"] = 10
+	if _, err := h.seedGift("coin", conf.GtypeVirtual, 0, "0-9999"); err != nil {
+		return err
+	}
+	// 获得虚拟币之后一天内拒绝抽奖
+	deny := &models.LtRule{Title: "deny", Rtype: conf.RuleTypeWinCooldown, Gtype: conf.GtypeVirtual,
+		Num: 1, Action: conf.RuleActionDeny}
+	if _, err := h.ServiceRule.Create(deny); err != nil {
+		return err
+	}
+	c := h.newClient()
+	if err := c.login(); err != nil {
+		return err
+	}
+	uid := c.uid()
+	batch := func(params string) error {
+		rs := struct {
+			Code     int                   `json:"code"`
+			Msg      string                `json:"msg"`
+			DrawList []models.ObjLuckyDraw `json:"draw_list"`
+		}{}
+		if err := c.getJSON("/lucky/batch?n=5"+params, &rs); err != nil {
+			return err
+		}
+		if rs.Code != 0 || len(rs.DrawList) != 5 {
+			return fmt.Errorf("batch n=5%s code=%d msg=%s draws=%d", params, rs.Code, rs.Msg, len(rs.DrawList))
+		}
+		for i, draw := range rs.DrawList {
+			if want := map[bool]int{true: 0, false: 105}[i == 0]; draw.Code != want {
+				return fmt.Errorf("batch n=5%s draw %d code=%d, want %d", params, i, draw.Code, want)
+			}
+		}
+		// 清除冷却期，下一次抽奖可以继续
+		for _, key := range h.redis.Keys() {
+			if strings.HasPrefix(key, "rule_cool_") {
+				h.redis.Del(key)
+			}
+		}
+		return nil
+	}
+
+	// 免费的次数只扣除进行了的抽奖
+	if err := batch(""); err != nil {
+		return err
+	}
+	if num := utils.GetUserLuckyNum(h.cache, uid); num != 1 {
+		return fmt.Errorf("free draws after cut short batch = %d, want 1", num)
+	}
+	if info := h.ServiceUserday.GetUserToday(uid); info == nil || info.Num != 1 {
+		return fmt.Errorf("userday after cut short batch = %+v, want num 1", info)
+	}
+
+	// 需要积分的活动退回没有进行的抽奖
+	if _, err := h.ServicePoint.Earn(uid, 50, "batch-cut", "batch"); err != nil {
+		return err
+	}
+	if err := batch("&campaign=paid"); err != nil {
+		return err
+	}
+	if balance := h.ServicePoint.Get(uid).Balance; balance != 40 {
+		return fmt.Errorf("points after cut short batch = %d, want 40", balance)
+	}
+
+	// 使用抽奖机会的时候只扣除进行了的抽奖
+	if _, err := h.ServiceChance.Grant(&models.LtChance{Uid: uid, Source: conf.ChanceSourceEvent,
+		Ref: "batch-cut", Num: 5, Expire: comm.NowUnix() + 86400}); err != nil {
+		return err
+	}
+	utils.InitUserLuckyNum(h.cache, uid, int64(conf.UserPrizeMax))
+	if err := batch(""); err != nil {
+		return err
+	}
+	if num := h.ServiceChance.LeftNum(uid); num != 4 {
+		return fmt.Errorf("chances after cut short batch = %d, want 4", num)
+	}
+	return nil
+}
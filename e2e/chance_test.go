@@ -0,0 +1,165 @@
+package e2e
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+	utils "github.com/iralance/go-lottery/uitls"
+)
+
+// 签到、分享、邀请和外部活动获得的抽奖机会，在每天免费的次数用完之后使用
+func testBonusChances(h *harness) error {
+	c := h.newClient()
+	if err := c.login(); err != nil {
+		return err
+	}
+	uid := c.uid()
+	if uid < 1 {
+		return errors.New("login uid not found")
+	}
+	post := func(c *client, path string, form url.Values, want int) error {
+		rs := struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}{}
+		if err := c.postJSON(path, form, &rs); err != nil {
+			return err
+		}
+		if rs.Code != want {
+			return fmt.Errorf("POST %s %v code=%d msg=%s, want %d", path, form, rs.Code, rs.Msg, want)
+		}
+		return nil
+	}
+	grant := func(body string, secret string) (int, error) {
+		req, _ := http.NewRequest(http.MethodPost, h.server.URL+"/chance/grant", strings.NewReader(body))
+		timestamp := strconv.Itoa(comm.NowUnix())
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Lottery-Timestamp", timestamp)
+		req.Header.Set("X-Lottery-Signature", comm.HmacSign(secret, timestamp, []byte(body)))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		var rs struct {
+			Code int `json:"code"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&rs)
+		return rs.Code, err
+	}
+
+	invitee := h.newClient()
+	if err := invitee.login(); err != nil {
+		return err
+	}
+	steps := []struct {
+		c    *client
+		path string
+		form url.Values
+		want int
+	}{
+		{c, "/checkin", nil, 0},
+		{c, "/checkin", nil, 501},
+		{c, "/share", nil, 0},
+		{c, "/share", nil, 502},
+		{c, "/invite", url.Values{"inviter": {strconv.Itoa(uid)}}, 503},
+		{invitee, "/invite", url.Values{"inviter": {strconv.Itoa(uid)}}, 0},
+		{invitee, "/invite", url.Values{"inviter": {strconv.Itoa(uid)}}, 504},
+	}
+	for _, step := range steps {
+		if err := post(step.c, step.path, step.form, step.want); err != nil {
+			return err
+		}
+	}
+	// 每天一次的机会按照系统时区的日期区分
+	today := fmt.Sprintf("%d-%s", uid, time.Now().In(conf.SysTimeLocation).Format(conf.SysTimeformShort))
+	for _, data := range h.ServiceChance.SearchValid(uid) {
+		if data.Source == conf.ChanceSourceCheckin && data.Ref != today {
+			return fmt.Errorf("checkin chance ref=%s, want %s", data.Ref, today)
+		}
+	}
+	event := fmt.Sprintf(`{"uid":%d,"num":3,"ref":"event-1","remark":"活动奖励"}`, uid)
+	for _, check := range []struct {
+		secret string
+		want   int
+	}{{"wrong-secret", 406}, {conf.ChancePartnerSecret, 0}, {conf.ChancePartnerSecret, 0}} {
+		code, err := grant(event, check.secret)
+		if err != nil {
+			return err
+		}
+		if code != check.want {
+			return fmt.Errorf("chance grant code=%d, want %d", code, check.want)
+		}
+	}
+
+	myprize := struct {
+		Code       int               `json:"code"`
+		PrizeNum   int               `json:"prize_num"`
+		FreeNum    int               `json:"free_num"`
+		ChanceNum  int               `json:"chance_num"`
+		ChanceList []models.LtChance `json:"chance_list"`
+	}{}
+	if err := c.getJSON("/myprize", &myprize); err != nil {
+		return err
+	}
+	chanceNum := conf.ChanceGrantNum[conf.ChanceSourceCheckin] + conf.ChanceGrantNum[conf.ChanceSourceShare] +
+		conf.ChanceGrantNum[conf.ChanceSourceInvite] + 3
+	if myprize.FreeNum != conf.UserPrizeMax || myprize.ChanceNum != chanceNum ||
+		myprize.PrizeNum != conf.UserPrizeMax+chanceNum || len(myprize.ChanceList) != 4 {
+		return fmt.Errorf("myprize with chances = %+v, want chance_num=%d", myprize, chanceNum)
+	}
+
+	// 过期的抽奖机会不能使用
+	now := comm.NowUnix()
+	if _, err := h.ServiceChance.Grant(&models.LtChance{Uid: uid, Source: conf.ChanceSourceEvent,
+		Ref: "event-expired", Num: 5, Expire: now - 1, SysCreated: now - 86400}); err != nil {
+		return err
+	}
+	// 免费的次数用完之后，每次抽奖使用一次额外的机会
+	utils.InitUserLuckyNum(h.cache, uid, int64(conf.UserPrizeMax))
+	// 风控规则拒绝的抽奖不扣除抽奖机会
+	deny := &models.LtRule{Title: "deny", Rtype: conf.RuleTypeUserLucky, Num: 0, Action: conf.RuleActionDeny}
+	if _, err := h.ServiceRule.Create(deny); err != nil {
+		return err
+	}
+	for _, path := range []string{"/lucky", "/lucky/batch?n=2"} {
+		rs := luckyResult{}
+		if err := c.getJSON(path, &rs); err != nil {
+			return err
+		}
+		if rs.Code != 105 {
+			return fmt.Errorf("GET %s with deny rule code=%d msg=%s, want 105", path, rs.Code, rs.Msg)
+		}
+	}
+	if num := h.ServiceChance.LeftNum(uid); num != chanceNum {
+		return fmt.Errorf("chances after denied draws = %d, want %d", num, chanceNum)
+	}
+	if err := h.ServiceRule.Delete(deny.Id); err != nil {
+		return err
+	}
+	for i := 0; i <= chanceNum; i++ {
+		rs, err := c.lucky()
+		if err != nil {
+			return err
+		}
+		if (rs.Code == 103) != (i == chanceNum) {
+			return fmt.Errorf("lucky %d with %d chances code=%d msg=%s", i+1, chanceNum, rs.Code, rs.Msg)
+		}
+	}
+	if err := c.getJSON("/myprize", &myprize); err != nil {
+		return err
+	}
+	if myprize.ChanceNum != 0 || len(myprize.ChanceList) != 0 {
+		return fmt.Errorf("myprize after using chances = %+v", myprize)
+	}
+	return nil
+}
@@ -0,0 +1,441 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+	utils "github.com/iralance/go-lottery/uitls"
+)
+
+// 优惠券带有效期导入，发放之后由合作方核销，过期的作废或者过期，统计核销率
+func testCodeLifecycle(h *harness) error {
+	gift, err := h.seedGift("coupon", conf.GtypeCodeDiff, 0, "0-9999")
+	if err != nil {
+		return err
+	}
+	admin := h.newAdmin()
+	today := time.Now().In(conf.SysTimeLocation).Format(conf.SysTimeformShort)
+	importPath := fmt.Sprintf("/admin/code/import?gift_id=%d", gift.Id)
+	form := url.Values{"codes": {"A1\nA2"}, "valid_from": {today}, "valid_to": {today}}
+	if _, _, err = admin.post(importPath, form); err != nil {
+		return err
+	}
+	a1 := h.ServiceCode.GetByCode("A1")
+	if a1 == nil || a1.ValidFrom <= 0 || a1.ValidTo-a1.ValidFrom != 86400-1 {
+		return fmt.Errorf("imported code = %+v, want a one day validity window", a1)
+	}
+	for i := 0; i < 2; i++ {
+		c := h.newClient()
+		if err = c.login(); err != nil {
+			return err
+		}
+		if rs, err := c.lucky(); err != nil || rs.Code != 0 {
+			return fmt.Errorf("lucky rs=%+v err=%v, want a coupon", rs, err)
+		}
+	}
+
+	redeem := func(code, ref, secret string) (int, error) {
+		body, _ := json.Marshal(map[string]string{"code": code, "ref": ref})
+		req, _ := http.NewRequest(http.MethodPost, h.server.URL+"/code/redeem", strings.NewReader(string(body)))
+		timestamp := strconv.Itoa(comm.NowUnix())
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Lottery-Timestamp", timestamp)
+		req.Header.Set("X-Lottery-Signature", comm.HmacSign(secret, timestamp, body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		var rs struct {
+			Code int `json:"code"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&rs)
+		return rs.Code, err
+	}
+	checks := []struct {
+		code, ref, secret string
+		want              int
+	}{
+		{"A1", "order-1", "wrong-secret", 406},
+		{"A1", "", conf.CodePartnerSecret, 400},
+		{"A1", strings.Repeat("x", 65), conf.CodePartnerSecret, 400},
+		{"A1", "order-1", conf.CodePartnerSecret, 0},
+		{"A1", "order-1", conf.CodePartnerSecret, 0},
+		{"A1", "order-2", conf.CodePartnerSecret, 403},
+		{"A3", "order-3", conf.CodePartnerSecret, 401},
+	}
+	for _, check := range checks {
+		code, err := redeem(check.code, check.ref, check.secret)
+		if err != nil {
+			return err
+		}
+		if code != check.want {
+			return fmt.Errorf("redeem %s ref=%s code=%d, want %d", check.code, check.ref, code, check.want)
+		}
+	}
+	if info := h.ServiceCode.GetByCode("A1"); info.SysStatus != conf.CodeStatusRedeemed || info.RedeemRef != "order-1" {
+		return fmt.Errorf("redeemed code = %+v", info)
+	}
+
+	// A2发放了没有核销，B1还没有发放，都过了有效期
+	yesterday := time.Now().In(conf.SysTimeLocation).AddDate(0, 0, -2).Format(conf.SysTimeformShort)
+	form = url.Values{"codes": {"B1"}, "valid_from": {yesterday}, "valid_to": {yesterday}}
+	if _, _, err = admin.post(importPath, form); err != nil {
+		return err
+	}
+	a2 := h.ServiceCode.GetByCode("A2")
+	h.ServiceCode.Update(&models.LtCode{Id: a2.Id, ValidTo: comm.NowUnix() - 1}, []string{"valid_to"})
+	if _, _, err = admin.get("/admin/code/expire"); err != nil {
+		return err
+	}
+	if info := h.ServiceCode.GetByCode("A2"); info.SysStatus != conf.CodeStatusExpired {
+		return fmt.Errorf("issued code A2 status=%d after expiry, want expired", info.SysStatus)
+	}
+	if info := h.ServiceCode.GetByCode("B1"); info.SysStatus != conf.CodeStatusVoid {
+		return fmt.Errorf("unissued code B1 status=%d after expiry, want void", info.SysStatus)
+	}
+	if _, cacheNum := utils.GetCacheCodeNum(h.cache, gift.Id, h.ServiceCode); cacheNum != 0 {
+		return fmt.Errorf("%d codes left in cache after expiry, want 0", cacheNum)
+	}
+	if code, err := redeem("A2", "order-4", conf.CodePartnerSecret); err != nil || code != 404 {
+		return fmt.Errorf("redeem expired code=%d err=%v, want 404", code, err)
+	}
+	stats := h.ServiceCode.Stats(gift.Id)
+	if stats.Redeemed != 1 || stats.Expired != 1 || stats.Void != 1 || stats.Rate != 50 {
+		return fmt.Errorf("code stats = %+v", stats)
+	}
+	status, body, err := admin.get("/admin/code/stats")
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK || !strings.Contains(string(body), "50.0%") {
+		return fmt.Errorf("code stats page status=%d body=%s", status, body)
+	}
+	return nil
+}
+
+// 上传文件导入优惠券，文件内和数据库中重复的跳过，大文件在后台导入
+func testCodeUpload(h *harness) error {
+	gift, err := h.seedGift("coupon", conf.GtypeCodeDiff, 0, "0-9999")
+	if err != nil {
+		return err
+	}
+	if err = h.seedCodes(gift.Id, 2); err != nil {
+		return err
+	}
+	admin := h.newAdmin()
+	form := url.Values{"codes": {fmt.Sprintf("X1\nX1\nG%d-000001\nbad code\n", gift.Id)}}
+	status, body, err := admin.post(fmt.Sprintf("/admin/code/import?gift_id=%d", gift.Id), form)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK || !strings.Contains(string(body), "成功导入 1 条，重复 2 条，格式错误 1 条") {
+		return fmt.Errorf("import status=%d body=%s", status, body)
+	}
+
+	num := 3000
+	var file strings.Builder
+	file.WriteString("code,note\n")
+	for i := 0; i < num; i++ {
+		fmt.Fprintf(&file, "U%06d,batch\n", i)
+	}
+	// 文件中重复、数据库中已经存在、格式错误
+	fmt.Fprintf(&file, "U000001,dup\nU000002,dup\nG%d-000000,db\nX1,db\n\"bad code\",invalid\n", gift.Id)
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, _ := mw.CreateFormFile("file", "codes.csv")
+	fw.Write([]byte(file.String()))
+	mw.Close()
+	asyncSize := conf.CodeImportAsyncSize
+	conf.CodeImportAsyncSize = 100
+	defer func() { conf.CodeImportAsyncSize = asyncSize }()
+	req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/admin/code/upload?gift_id=%d", h.server.URL, gift.Id), &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.SetBasicAuth("admin", "password")
+	resp, err := admin.http.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	location := resp.Header.Get("Location")
+	if !strings.HasPrefix(location, "/admin/code/job?id=") {
+		return fmt.Errorf("upload status=%d location=%s, want the job page", resp.StatusCode, location)
+	}
+
+	var job models.ObjImportJob
+	for i := 0; i < 500; i++ {
+		if err = admin.getJSON(location+"&json=1", &job); err != nil {
+			return err
+		}
+		if job.Status != "running" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if job.Status != "done" || job.Success != num || job.Dup != 4 || job.Invalid != 1 || job.Total != num+5 {
+		return fmt.Errorf("import job = %+v", job)
+	}
+	if n := h.ServiceCode.CountByGift(gift.Id); n != int64(num+3) {
+		return fmt.Errorf("%d codes in db after upload, want %d", n, num+3)
+	}
+	if _, cacheNum := utils.GetCacheCodeNum(h.cache, gift.Id, h.ServiceCode); cacheNum != num+3 {
+		return fmt.Errorf("%d codes in cache after upload, want %d", cacheNum, num+3)
+	}
+	status, body, err = admin.get(location)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK || !strings.Contains(string(body), "codes.csv") || !strings.Contains(string(body), "已完成") {
+		return fmt.Errorf("job page status=%d body=%s", status, body)
+	}
+	return nil
+}
+
+// 按照生成规则生成优惠券编码，剩余编码不足的时候自动补充
+func testCodeGenerator(h *harness) error {
+	gift, err := h.seedGift("coupon", conf.GtypeCodeDiff, 0, "0-9999")
+	if err != nil {
+		return err
+	}
+	admin := h.newAdmin()
+	form := url.Values{"gift_id": {strconv.Itoa(gift.Id)}, "prefix": {"GX-"}, "alphabet": {"ABCDEFGH23456789"},
+		"length": {"8"}, "checksum": {"1"}, "threshold": {"50"}, "topup_num": {"100"}}
+	if status, body, err := admin.post("/admin/code/gen", form); err != nil || status != http.StatusFound {
+		return fmt.Errorf("save gen status=%d body=%s err=%v", status, body, err)
+	}
+	gen := h.ServiceCodeGen.GetByGift(gift.Id)
+	if gen == nil || gen.Prefix != "GX-" || gen.Length != 8 || gen.Threshold != 50 {
+		return fmt.Errorf("saved gen = %+v", gen)
+	}
+	// 字符重复的规则不能保存
+	form.Set("alphabet", "AABC")
+	if _, body, _ := admin.post("/admin/code/gen", form); !strings.Contains(string(body), "重复") {
+		return fmt.Errorf("duplicate alphabet saved, body=%s", body)
+	}
+
+	genPath := fmt.Sprintf("/admin/code/generate?gift_id=%d", gift.Id)
+	if _, _, err = admin.post(genPath, url.Values{"num": {"200"}, "valid_to": {"2099-12-31"}}); err != nil {
+		return err
+	}
+	status, body, err := admin.get(fmt.Sprintf("/admin/code/gen?gift_id=%d", gift.Id))
+	if err != nil || status != http.StatusOK || !strings.Contains(string(body), "缓存中剩余编码 200 个") {
+		return fmt.Errorf("gen page status=%d body=%s err=%v", status, body, err)
+	}
+	list := h.ServiceCode.Search(gift.Id)
+	if len(list) != 200 {
+		return fmt.Errorf("%d codes generated, want 200", len(list))
+	}
+	seen := make(map[string]bool)
+	for _, data := range list {
+		plain := h.ServiceCode.PlainCode(&data)
+		if seen[plain] || !utils.CheckGenCode(gen, plain) || len(plain) != 12 || data.ValidTo == 0 {
+			return fmt.Errorf("bad generated code %s %+v", plain, data)
+		}
+		seen[plain] = true
+	}
+	// 输错一个字符，校验位可以发现
+	code := []byte(h.ServiceCode.PlainCode(&list[0]))
+	if code[3] == 'A' {
+		code[3] = 'B'
+	} else {
+		code[3] = 'A'
+	}
+	if utils.CheckGenCode(gen, string(code)) {
+		return fmt.Errorf("checksum accepted a mistyped code %s", code)
+	}
+
+	// 编码空间太小的时候拒绝生成
+	small := *gen
+	small.Alphabet, small.Length = "AB", 4
+	if _, err = utils.GenerateCodes(h.cache, h.ServiceCode, &small, 10, &utils.CodeImport{GiftId: gift.Id}); err == nil {
+		return errors.New("generated codes from a tiny code space")
+	}
+
+	if num := utils.TopupCodes(h.cache, h.ServiceGift, h.ServiceCode, h.ServiceCodeGen); num != 0 {
+		return fmt.Errorf("topped up %d codes above the threshold", num)
+	}
+	// 抽奖发放之后，剩余的编码少于阈值
+	key := fmt.Sprintf("gift_code_%d", gift.Id)
+	if _, err = h.cache.Do("SPOP", key, 160); err != nil {
+		return err
+	}
+	if num := utils.TopupCodes(h.cache, h.ServiceGift, h.ServiceCode, h.ServiceCodeGen); num != 100 {
+		return fmt.Errorf("topped up %d codes, want 100", num)
+	}
+	if num := utils.GetGiftCodeNum(h.cache, gift.Id); num != 140 {
+		return fmt.Errorf("%d codes in cache after topup, want 140", num)
+	}
+	if n := h.ServiceCode.CountByGift(gift.Id); n != 300 {
+		return fmt.Errorf("%d codes in db after topup, want 300", n)
+	}
+	return nil
+}
+
+// 优惠券编码加密保存，缓存中只有HMAC，轮换密钥之后旧的编码重新加密
+func testCodeEncryption(h *harness) error {
+	gift, err := h.seedGift("coupon", conf.GtypeCodeDiff, 0, "0-9999")
+	if err != nil {
+		return err
+	}
+	if err = h.seedCodes(gift.Id, 3); err != nil {
+		return err
+	}
+	plain := fmt.Sprintf("G%d-000000", gift.Id)
+	// 数据库和缓存中都没有明文
+	if n, _ := h.engine.Where("code=?", plain).Count(&models.LtCode{}); n != 0 {
+		return fmt.Errorf("plaintext code %s found in lt_code", plain)
+	}
+	key := fmt.Sprintf("gift_code_%d", gift.Id)
+	if ok, _ := redis.Bool(h.cache.Do("SISMEMBER", key, plain)); ok {
+		return fmt.Errorf("plaintext code %s found in %s", plain, key)
+	}
+	info := h.ServiceCode.GetByCode(plain)
+	if info == nil || info.KeyId != conf.CodeKeyCurrent || info.Code == plain || h.ServiceCode.PlainCode(info) != plain {
+		return fmt.Errorf("encrypted code = %+v", info)
+	}
+	admin := h.newAdmin()
+	status, body, err := admin.get(fmt.Sprintf("/admin/code?gift_id=%d", gift.Id))
+	if err != nil || status != http.StatusOK || !strings.Contains(string(body), plain) {
+		return fmt.Errorf("admin code page status=%d err=%v, want the decrypted code", status, err)
+	}
+	// 编码的HMAC是唯一索引，相同的编码不能重复写入
+	if _, err = h.ServiceCode.Create(&models.LtCode{GiftId: gift.Id, Code: plain}); err == nil {
+		return fmt.Errorf("duplicate code %s created", plain)
+	}
+
+	// 升级之前没有加密的旧数据，升级语句把code_hash设置成legacy-id
+	legacyCodes := []string{"LEGACY-1", "LEGACY-2", "LEGACY-3"}
+	for i, code := range legacyCodes {
+		legacy := &models.LtCode{Id: 1000 + i, GiftId: gift.Id, Code: code, SysCreated: comm.NowUnix()}
+		legacy.CodeHash = fmt.Sprintf("legacy-%d", legacy.Id)
+		if _, err = h.engine.Insert(legacy); err != nil {
+			return err
+		}
+		h.cache.Do("SADD", key, legacy.Code)
+		if info := h.ServiceCode.GetByCode(code); info == nil || info.Id != legacy.Id {
+			return fmt.Errorf("legacy code lookup = %+v", info)
+		}
+	}
+
+	// 增加新的密钥，轮换之后删除旧的密钥
+	keys, current := conf.CodeKeys, conf.CodeKeyCurrent
+	defer func() { conf.CodeKeys, conf.CodeKeyCurrent = keys, current }()
+	conf.CodeKeys = map[int]string{current: keys[current], current + 1: "1KDm+Q7SCkn3bYo7YQoeYDIn1A4uDq2oCKXSdwW5VBM="}
+	conf.CodeKeyCurrent = current + 1
+	status, body, err = admin.get("/admin/code/rotate")
+	if err != nil || !strings.Contains(string(body), "重新加密 6 条，失败 0 条") {
+		return fmt.Errorf("rotate status=%d body=%s err=%v", status, body, err)
+	}
+	if n := h.ServiceCode.CountOldKey(); n != 0 {
+		return fmt.Errorf("%d codes left with an old key after rotation", n)
+	}
+	for _, code := range legacyCodes {
+		if ok, _ := redis.Bool(h.cache.Do("SISMEMBER", key, code)); ok {
+			return fmt.Errorf("legacy plaintext code %s still in cache after rotation", code)
+		}
+		if info := h.ServiceCode.GetByHash(comm.CodeHash(code)); info == nil || info.KeyId != conf.CodeKeyCurrent {
+			return fmt.Errorf("legacy code %s after rotation = %+v", code, info)
+		}
+	}
+	delete(conf.CodeKeys, current)
+
+	// 只有新的密钥也可以正常发放
+	issued := make(map[string]bool)
+	for i := 0; i < 6; i++ {
+		c := h.newClient()
+		if err = c.login(); err != nil {
+			return err
+		}
+		rs, err := c.lucky()
+		if err != nil || rs.Code != 0 || rs.Gift == nil {
+			return fmt.Errorf("lucky rs=%+v err=%v, want a coupon", rs, err)
+		}
+		issued[rs.Gift.Gdata] = true
+	}
+	for _, code := range append([]string{plain, fmt.Sprintf("G%d-000001", gift.Id), fmt.Sprintf("G%d-000002", gift.Id)}, legacyCodes...) {
+		if !issued[code] {
+			return fmt.Errorf("code %s not issued, issued=%v", code, issued)
+		}
+		if n, _ := h.engine.Where("gift_data=?", code).Count(&models.LtResult{}); n != 0 {
+			return fmt.Errorf("plaintext code %s found in lt_result", code)
+		}
+	}
+	if code, msg := h.ServiceCode.Redeem("LEGACY-1", "order-legacy"); code != 0 {
+		return fmt.Errorf("redeem legacy code=%d msg=%s", code, msg)
+	}
+	return nil
+}
+
+// 优惠券列表按照状态筛选和分页，奖品池状态在缓存和数据库不一致时提醒
+func testCodePoolHealth(h *harness) error {
+	gift, err := h.seedGift("coupon", conf.GtypeCodeDiff, 0, "0-9999")
+	if err != nil {
+		return err
+	}
+	if err = h.seedCodes(gift.Id, 250); err != nil {
+		return err
+	}
+	// 作废的编码没有从缓存中移除
+	for _, data := range h.ServiceCode.SearchPage(gift.Id, conf.CodeStatusNormal, 1, 10) {
+		h.ServiceCode.Update(&models.LtCode{Id: data.Id, SysStatus: conf.CodeStatusVoid}, []string{"sys_status"})
+	}
+	admin := h.newAdmin()
+	rows := func(path string) (int, string, error) {
+		status, body, err := admin.get(path)
+		if err != nil || status != http.StatusOK {
+			return 0, "", fmt.Errorf("GET %s status=%d err=%v", path, status, err)
+		}
+		return strings.Count(string(body), `<th scope="row">`), string(body), nil
+	}
+	tests := []struct {
+		query string
+		rows  int
+		total int
+		next  bool
+	}{
+		{"", 100, 250, true},
+		{"&page=3", 50, 250, false},
+		{"&status=0&page=3", 40, 240, false},
+		{"&status=1", 10, 10, false},
+		{"&status=2", 0, 0, false},
+	}
+	for _, tc := range tests {
+		n, body, err := rows(fmt.Sprintf("/admin/code?gift_id=%d%s", gift.Id, tc.query))
+		if err != nil {
+			return err
+		}
+		if n != tc.rows || !strings.Contains(body, fmt.Sprintf("总共 %d 条记录", tc.total)) ||
+			strings.Contains(body, "下一页") != tc.next {
+			return fmt.Errorf("code list %s rows=%d, want %d rows of %d", tc.query, n, tc.rows, tc.total)
+		}
+	}
+
+	stats := utils.CodePoolHealth(h.cache, gift.Id, h.ServiceCode)
+	if stats.Normal != 240 || stats.Void != 10 || stats.CacheNum != 250 || !strings.Contains(stats.Alert, "10个编码已经不能发放") {
+		return fmt.Errorf("pool health = %+v", stats)
+	}
+	_, body, err := rows("/admin/code/stats")
+	if err != nil || !strings.Contains(body, stats.Alert) {
+		return fmt.Errorf("stats page without alert %q, err=%v", stats.Alert, err)
+	}
+	if _, _, err = admin.get(fmt.Sprintf("/admin/code/recache?id=%d", gift.Id)); err != nil {
+		return err
+	}
+	if stats = utils.CodePoolHealth(h.cache, gift.Id, h.ServiceCode); stats.CacheNum != 240 || stats.Alert != "" {
+		return fmt.Errorf("pool health after recache = %+v", stats)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+)
+
+// 实物奖品从填写地址、导出、导入快递单号到签收，没有填写地址的过期
+func testFulfillPipeline(h *harness) error {
+	if _, err := h.seedGift("small", conf.GtypeGiftSmall, 0, "0-9999"); err != nil {
+		return err
+	}
+	clients := make([]*client, 2)
+	for i := range clients {
+		c := h.newClient()
+		if err := c.login(); err != nil {
+			return err
+		}
+		rs, err := c.lucky()
+		if err != nil {
+			return err
+		}
+		if rs.Code != 0 {
+			return fmt.Errorf("lucky code=%d msg=%s, want a prize", rs.Code, rs.Msg)
+		}
+		clients[i] = c
+	}
+	var addr struct {
+		Code        int                `json:"code"`
+		Num         int                `json:"num"`
+		Realname    string             `json:"realname"`
+		FulfillList []models.LtFulfill `json:"fulfill_list"`
+	}
+	c := clients[0]
+	if err := c.getJSON("/address", &addr); err != nil {
+		return err
+	}
+	if len(addr.FulfillList) != 1 || addr.FulfillList[0].Status != conf.FulfillPendingAddress {
+		return fmt.Errorf("fulfill list before address = %+v", addr.FulfillList)
+	}
+	id := addr.FulfillList[0].Id
+	// 地址以公式开头，导出的时候需要转义
+	form := url.Values{"realname": {"张三"}, "mobile": {"abc"}, "address": {"=北京市海淀区"}}
+	if err := c.postJSON("/address", form, &addr); err != nil {
+		return err
+	}
+	if addr.Code != 302 {
+		return fmt.Errorf("address with bad mobile code=%d, want 302", addr.Code)
+	}
+	form.Set("mobile", "13800138000")
+	if err := c.postJSON("/address", form, &addr); err != nil {
+		return err
+	}
+	if addr.Code != 0 || addr.Num != 1 {
+		return fmt.Errorf("address code=%d num=%d, want 1 fulfillment ready", addr.Code, addr.Num)
+	}
+	if err := c.getJSON("/address", &addr); err != nil {
+		return err
+	}
+	if addr.Realname != "张三" || addr.FulfillList[0].Status != conf.FulfillReady {
+		return fmt.Errorf("address after save = %+v", addr)
+	}
+
+	admin := h.newAdmin()
+	_, body, err := admin.get("/admin/fulfill/export")
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], ",'=北京市海淀区,") {
+		return fmt.Errorf("export = %s, want header and 1 ready fulfillment with the address escaped", body)
+	}
+	// 填好快递信息之后导入导出的文件
+	tracking := lines[0] + "\n" + strings.TrimRight(lines[1], ",") + ",顺丰,SF1001\n"
+	if _, _, err = admin.post("/admin/fulfill/import", url.Values{"tracking": {tracking}}); err != nil {
+		return err
+	}
+	if info := h.ServiceFulfill.Get(id); info.Status != conf.FulfillShipped || info.TrackingNo != "SF1001" {
+		return fmt.Errorf("fulfillment after import = %+v", info)
+	}
+	if _, _, err = admin.get(fmt.Sprintf("/admin/fulfill/deliver?id=%d", id)); err != nil {
+		return err
+	}
+	if info := h.ServiceFulfill.Get(id); info.Status != conf.FulfillDelivered {
+		return fmt.Errorf("fulfillment status=%d after deliver", info.Status)
+	}
+
+	days := conf.FulfillAddressDays
+	conf.FulfillAddressDays = -1
+	defer func() { conf.FulfillAddressDays = days }()
+	if _, _, err = admin.get("/admin/fulfill/expire"); err != nil {
+		return err
+	}
+	if num := h.ServiceFulfill.CountAll(conf.FulfillExpired); num != 1 {
+		return fmt.Errorf("%d expired fulfillments, want the one without address", num)
+	}
+	status, body, err := admin.get("/admin/fulfill")
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK || !strings.Contains(string(body), "SF1001") {
+		return fmt.Errorf("fulfill page status=%d body=%s", status, body)
+	}
+	return nil
+}
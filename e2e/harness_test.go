@@ -0,0 +1,368 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-xorm/xorm"
+	"github.com/iralance/go-lottery/bootstrap"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/dao"
+	"github.com/iralance/go-lottery/datasource"
+	"github.com/iralance/go-lottery/models"
+	"github.com/iralance/go-lottery/services"
+	utils "github.com/iralance/go-lottery/uitls"
+	"github.com/iralance/go-lottery/web/app"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// 所有的数据表
+var allTables = []interface{}{
+	new(models.LtBlackip),
+	new(models.LtChance),
+	new(models.LtCode),
+	new(models.LtCodeGen),
+	new(models.LtDrawLog),
+	new(models.LtFulfill),
+	new(models.LtGift),
+	new(models.LtPity),
+	new(models.LtPoint),
+	new(models.LtPointLog),
+	new(models.LtResult),
+	new(models.LtReview),
+	new(models.LtRule),
+	new(models.LtUser),
+	new(models.LtUserday),
+}
+
+// 端到端测试环境，sqlite代替mysql，miniredis代替redis
+type harness struct {
+	tmpDir string
+	redis  *miniredis.Miniredis
+	engine *xorm.Engine
+	cache  datasource.Cache
+	server *httptest.Server
+
+	ServiceGift    services.GiftService
+	ServiceCode    services.CodeService
+	ServiceResult  services.ResultService
+	ServiceBlackip services.BlackipService
+	ServiceUser    services.UserService
+	ServiceReview  services.ReviewService
+	ServiceFulfill services.FulfillService
+	ServiceCodeGen services.CodeGenService
+	ServiceChance  services.ChanceService
+	ServicePoint   services.PointService
+	ServicePity    services.PityService
+	ServiceRule    services.RuleService
+	ServiceDrawLog services.DrawLogService
+	ServiceUserday services.UserdayService
+}
+
+func newHarness() (*harness, error) {
+	h := &harness{}
+	// 模版和静态文件都是相对web目录的路径
+	_, file, _, _ := runtime.Caller(0)
+	if err := os.Chdir(filepath.Join(filepath.Dir(file), "..", "web")); err != nil {
+		return nil, err
+	}
+	tmpDir, err := os.MkdirTemp("", "lottery-e2e")
+	if err != nil {
+		return nil, err
+	}
+	h.tmpDir = tmpDir
+
+	h.redis, err = miniredis.Run()
+	if err != nil {
+		h.Close()
+		return nil, err
+	}
+	conf.RdsCache.Host = h.redis.Host()
+	conf.RdsCache.Port, _ = strconv.Atoi(h.redis.Port())
+	rds := datasource.NewCache()
+	rds.ShowDebug(false)
+	h.cache = rds
+
+	dsn := filepath.Join(tmpDir, "lottery.db") + "?_busy_timeout=5000"
+	h.engine, err = xorm.NewEngine("sqlite3", dsn)
+	if err != nil {
+		h.Close()
+		return nil, err
+	}
+	// sqlite同一时间只能有一个写入
+	h.engine.SetMaxOpenConns(1)
+	if err = h.engine.Sync2(allTables...); err != nil {
+		h.Close()
+		return nil, err
+	}
+
+	h.ServiceGift = services.NewGiftService(dao.NewGiftDao(h.engine), h.cache)
+	h.ServiceCode = services.NewCodeService(dao.NewCodeDao(h.engine))
+	h.ServiceResult = services.NewResultService(dao.NewResultDao(h.engine))
+	h.ServiceBlackip = services.NewBlackipService(dao.NewBlackipDao(h.engine), h.cache)
+	h.ServiceUser = services.NewUserService(dao.NewUserDao(h.engine), h.cache)
+	h.ServiceReview = services.NewReviewService(dao.NewReviewDao(h.engine))
+	h.ServiceFulfill = services.NewFulfillService(dao.NewFulfillDao(h.engine))
+	h.ServiceCodeGen = services.NewCodeGenService(dao.NewCodeGenDao(h.engine))
+	h.ServiceChance = services.NewChanceService(dao.NewChanceDao(h.engine))
+	h.ServicePoint = services.NewPointService(dao.NewPointDao(h.engine))
+	h.ServicePity = services.NewPityService(dao.NewPityDao(h.engine), h.cache)
+	h.ServiceRule = services.NewRuleService(dao.NewRuleDao(h.engine), h.cache)
+	h.ServiceDrawLog = services.NewDrawLogService(dao.NewDrawLogDao(h.engine))
+	h.ServiceUserday = services.NewUserdayService(dao.NewUserdayDao(h.engine))
+
+	// 测试使用的密钥，线上启动的时候从环境变量读取
+	conf.CodeKeys = map[int]string{1: "5vDeuzAL4DMWnbGlMf/3ov+j3PakdM8M+yq7Hgx8sRU="}
+	conf.CodeKeyCurrent = 1
+	conf.CodeHashKey = "e2e-code-hash-key"
+	conf.CodePartnerSecret = "e2e-code-partner-secret"
+	conf.ChancePartnerSecret = "e2e-chance-partner-secret"
+
+	// 默认不限制访问频率，需要的场景单独启动应用
+	h.server, err = h.startApp(func(b *bootstrap.Bootstrapper) {
+		b.RateLimits = []conf.RateLimit{}
+	})
+	if err != nil {
+		h.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+// 使用相同的数据库和缓存启动一个应用，cfgs可以修改默认的配置
+func (h *harness) startApp(cfgs ...bootstrap.Configurator) (*httptest.Server, error) {
+	b := app.New(append([]bootstrap.Configurator{func(b *bootstrap.Bootstrapper) {
+		b.Engine = h.engine
+		b.Cache = h.cache
+		b.Logger().SetLevel("disable")
+	}}, cfgs...)...)
+	if err := b.Build(); err != nil {
+		return nil, err
+	}
+	return httptest.NewServer(b.Application), nil
+}
+
+func (h *harness) Close() {
+	if h.server != nil {
+		h.server.Close()
+	}
+	if h.engine != nil {
+		h.engine.Close()
+	}
+	if h.redis != nil {
+		h.redis.Close()
+	}
+	os.RemoveAll(h.tmpDir)
+}
+
+// 清空所有的数据和缓存，每个场景之间互不影响
+func (h *harness) reset() error {
+	for _, table := range allTables {
+		if _, err := h.engine.Where("1=1").Delete(table); err != nil {
+			return err
+		}
+	}
+	h.redis.FlushAll()
+	// 和第一次启动一样，写入默认的风控规则
+	return h.ServiceRule.SeedDefault()
+}
+
+// 新增一个奖品，并且按照库存设置好奖品池
+func (h *harness) seedGift(title string, gtype, prizeNum int, prizeCode string) (*models.LtGift, error) {
+	now := comm.NowUnix()
+	gift := &models.LtGift{
+		Title:      title,
+		PrizeNum:   prizeNum,
+		LeftNum:    prizeNum,
+		PrizeCode:  prizeCode,
+		Gtype:      gtype,
+		Gdata:      "",
+		TimeBegin:  now - 60,
+		TimeEnd:    now + 86400,
+		SysCreated: now,
+	}
+	if gtype == conf.GtypeVirtual {
+		gift.Gdata = "100"
+	}
+	if _, err := h.ServiceGift.Create(gift); err != nil {
+		return nil, err
+	}
+	utils.ResetGiftPrizeData(h.cache, gift, h.ServiceGift)
+	// 奖品列表的缓存需要重新加载
+	h.cache.Do("DEL", "allgift")
+	return gift, nil
+}
+
+// 给优惠券类的奖品导入编码，数据库和缓存同时导入
+func (h *harness) seedCodes(giftId, num int) error {
+	now := comm.NowUnix()
+	for i := 0; i < num; i++ {
+		code := fmt.Sprintf("G%d-%06d", giftId, i)
+		if _, err := h.ServiceCode.Create(&models.LtCode{GiftId: giftId, Code: code, SysCreated: now}); err != nil {
+			return err
+		}
+		if !utils.ImportCacheCodes(h.cache, giftId, code) {
+			return fmt.Errorf("import code %s into cache failed", code)
+		}
+	}
+	return nil
+}
+
+func (h *harness) poolNum(giftId int) int {
+	return utils.GetGiftPoolNum(h.cache, giftId)
+}
+
+// 模拟浏览器的客户端，保存登录的cookie
+type client struct {
+	h      *harness
+	http   *http.Client
+	admin  bool
+	header http.Header
+	server *httptest.Server
+}
+
+func (h *harness) newClient() *client {
+	jar, _ := cookiejar.New(nil)
+	return &client{
+		h:      h,
+		header: http.Header{},
+		server: h.server,
+		http: &http.Client{
+			Jar: jar,
+			// 登录、退出都是跳转，不需要跟随
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (h *harness) newAdmin() *client {
+	c := h.newClient()
+	c.admin = true
+	return c
+}
+
+func (c *client) do(method, path string, form url.Values) (int, []byte, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequest(method, c.server.URL+path, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
+	if c.admin {
+		req.SetBasicAuth("admin", "password")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, data, err
+}
+
+func (c *client) get(path string) (int, []byte, error) {
+	return c.do(http.MethodGet, path, nil)
+}
+
+func (c *client) post(path string, form url.Values) (int, []byte, error) {
+	return c.do(http.MethodPost, path, form)
+}
+
+// 读取json接口的返回
+func (c *client) getJSON(path string, v interface{}) error {
+	status, data, err := c.get(path)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("GET %s status=%d body=%s", path, status, data)
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("GET %s json error=%s body=%s", path, err, data)
+	}
+	return nil
+}
+
+func (c *client) postJSON(path string, form url.Values, v interface{}) error {
+	status, data, err := c.post(path, form)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("POST %s status=%d body=%s", path, status, data)
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("POST %s json error=%s body=%s", path, err, data)
+	}
+	return nil
+}
+
+func (c *client) login() error {
+	status, _, err := c.get("/login")
+	if err != nil {
+		return err
+	}
+	if status != http.StatusFound {
+		return fmt.Errorf("GET /login status=%d", status)
+	}
+	return nil
+}
+
+// 登录用户的uid，从登录的cookie中读取
+func (c *client) uid() int {
+	u, _ := url.Parse(c.server.URL)
+	req := &http.Request{Header: http.Header{}}
+	for _, cookie := range c.http.Jar.Cookies(u) {
+		req.AddCookie(cookie)
+	}
+	if loginuser := comm.GetLoginUser(req); loginuser != nil {
+		return loginuser.Uid
+	}
+	return 0
+}
+
+// 抽奖接口的返回
+type luckyResult struct {
+	Code int                  `json:"code"`
+	Msg  string               `json:"msg"`
+	Gift *models.ObjGiftPrize `json:"gift"`
+	// 需要验证的时候返回
+	Challenge *models.ObjChallenge `json:"challenge"`
+}
+
+func (c *client) lucky() (*luckyResult, error) {
+	return c.luckyWith(nil)
+}
+
+// 带上验证的答案等参数抽奖
+func (c *client) luckyWith(params url.Values) (*luckyResult, error) {
+	rs := &luckyResult{}
+	path := "/lucky"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+	err := c.getJSON(path, rs)
+	return rs, err
+}
@@ -0,0 +1,113 @@
+package e2e
+
+import (
+	"fmt"
+
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+)
+
+// 每次抽奖都有记录，用户分页查看自己的抽奖记录和中奖记录，包括券码和发货状态
+func testDrawHistory(h *harness) error {
+	codeGift, err := h.seedGift("code", conf.GtypeCodeDiff, 100, "0-2999")
+	if err != nil {
+		return err
+	}
+	if err = h.seedCodes(codeGift.Id, 100); err != nil {
+		return err
+	}
+	smallGift, err := h.seedGift("small", conf.GtypeGiftSmall, 100, "3000-5999")
+	if err != nil {
+		return err
+	}
+	c := h.newClient()
+	if err = c.login(); err != nil {
+		return err
+	}
+	draws := conf.UserListPageSize + 5
+	wins := 0
+	for i := 0; i < draws; i++ {
+		rs, err := c.lucky()
+		if err != nil {
+			return err
+		}
+		if rs.Code == 0 {
+			wins++
+		}
+	}
+
+	type drawPage struct {
+		Code     int                `json:"code"`
+		Page     int                `json:"page"`
+		Total    int                `json:"total"`
+		DrawList []models.LtDrawLog `json:"draw_list"`
+	}
+	drawWins := map[int]bool{}
+	for page, want := range []int{conf.UserListPageSize, 5, 0} {
+		rs := drawPage{}
+		if err = c.getJSON(fmt.Sprintf("/mydraws?page=%d", page+1), &rs); err != nil {
+			return err
+		}
+		if rs.Code != 0 || rs.Total != draws || len(rs.DrawList) != want {
+			return fmt.Errorf("mydraws page=%d total=%d num=%d, want total=%d num=%d",
+				page+1, rs.Total, len(rs.DrawList), draws, want)
+		}
+		for _, data := range rs.DrawList {
+			if (data.Code == 0) != (data.ResultId > 0) {
+				return fmt.Errorf("mydraws code=%d result_id=%d", data.Code, data.ResultId)
+			}
+			if data.Code == 0 {
+				drawWins[data.ResultId] = true
+			}
+		}
+	}
+	if len(drawWins) != wins {
+		return fmt.Errorf("mydraws lists %d wins, want %d", len(drawWins), wins)
+	}
+
+	prizes := 0
+	for page := 1; ; page++ {
+		rs := struct {
+			Code      int                 `json:"code"`
+			Total     int                 `json:"total"`
+			PrizeList []models.ObjMyPrize `json:"prize_list"`
+		}{}
+		if err = c.getJSON(fmt.Sprintf("/myprize?page=%d", page), &rs); err != nil {
+			return err
+		}
+		if rs.Total != wins || len(rs.PrizeList) > conf.UserListPageSize {
+			return fmt.Errorf("myprize page=%d total=%d num=%d, want total=%d", page, rs.Total, len(rs.PrizeList), wins)
+		}
+		if len(rs.PrizeList) == 0 {
+			break
+		}
+		for _, data := range rs.PrizeList {
+			prizes++
+			if !drawWins[data.Id] {
+				return fmt.Errorf("myprize result %d not in mydraws", data.Id)
+			}
+			switch data.GiftId {
+			case codeGift.Id:
+				// 用户自己可以看到券码
+				code := h.ServiceCode.GetByCode(data.GiftData)
+				if code == nil || code.SysStatus != conf.CodeStatusIssued {
+					return fmt.Errorf("myprize gift_data=%q is not an issued code", data.GiftData)
+				}
+				// 中奖记录中只保存优惠券的ID
+				if result := h.ServiceResult.Get(data.Id); result.GiftData != "" || result.CodeId != code.Id {
+					return fmt.Errorf("result gift_data=%q code_id=%d, want only code %d", result.GiftData, result.CodeId, code.Id)
+				}
+			case smallGift.Id:
+				if data.Fulfill == nil || data.Fulfill.Status != conf.FulfillPendingAddress {
+					return fmt.Errorf("myprize small gift fulfill=%+v", data.Fulfill)
+				}
+			default:
+				return fmt.Errorf("myprize unknown gift %+v", data)
+			}
+		}
+	}
+	if prizes != wins {
+		return fmt.Errorf("myprize lists %d prizes, want %d", prizes, wins)
+	}
+	return nil
+}
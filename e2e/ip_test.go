@@ -0,0 +1,223 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/iralance/go-lottery/bootstrap"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+	utils "github.com/iralance/go-lottery/uitls"
+)
+
+// 后台添加的网段黑名单，对网段内的IP生效，IPv6同样支持
+func testBlackipCidr(h *harness) error {
+	if _, err := h.seedGift("small", conf.GtypeGiftSmall, 0, "0-9999"); err != nil {
+		return err
+	}
+	admin := h.newAdmin()
+	saveBlackip := func(ip string, days int) error {
+		status, body, err := admin.post("/admin/blackip/save", url.Values{"ip": {ip}, "time": {fmt.Sprint(days)}})
+		if err != nil {
+			return err
+		}
+		if status != http.StatusFound && status != http.StatusSeeOther {
+			return fmt.Errorf("save blackip %s status=%d body=%s", ip, status, body)
+		}
+		return nil
+	}
+	c := h.newClient()
+	if err := c.login(); err != nil {
+		return err
+	}
+	expect := func(code int) error {
+		rs, err := c.lucky()
+		if err != nil {
+			return err
+		}
+		if rs.Code != code {
+			return fmt.Errorf("lucky code=%d msg=%s, want %d", rs.Code, rs.Msg, code)
+		}
+		return nil
+	}
+
+	if err := saveBlackip("127.0.0.0/8", 1); err != nil {
+		return err
+	}
+	// 黑名单中只能获得虚拟奖品
+	if err := expect(205); err != nil {
+		return err
+	}
+	if err := saveBlackip("127.0.0.0/8", 0); err != nil {
+		return err
+	}
+	if err := expect(0); err != nil {
+		return err
+	}
+
+	if err := saveBlackip("2001:DB8::/32", 1); err != nil {
+		return err
+	}
+	now := comm.NowUnix()
+	if info := h.ServiceBlackip.GetByIp("2001:db8:1::5"); info == nil || info.Blacktime <= now || info.Ip != "2001:db8::/32" {
+		return fmt.Errorf("GetByIp in IPv6 range = %+v", info)
+	}
+	if info := h.ServiceBlackip.GetByIp("2001:db9::5"); info != nil && info.Blacktime > now {
+		return fmt.Errorf("GetByIp outside IPv6 range = %+v", info)
+	}
+	if list := h.ServiceBlackip.Search("2001:db8::/32"); len(list) != 1 {
+		return fmt.Errorf("%d blackip rows for 2001:db8::/32, want 1", len(list))
+	}
+	return nil
+}
+
+// IP当天的计数，IPv4按照完整地址，IPv6按照/64网段
+func testIpDayCounters(h *harness) error {
+	for _, ip := range []string{"10.1.2.3", "10.1.2.3", "10.4.5.6"} {
+		utils.IncrIpLuckyNum(h.cache, ip)
+	}
+	if n := utils.IncrIpLuckyNum(h.cache, "10.4.5.6"); n != 2 {
+		return fmt.Errorf("10.4.5.6 counter=%d, want 2", n)
+	}
+	utils.IncrIpLuckyNum(h.cache, "2001:db8:0:1::1")
+	if n := utils.IncrIpLuckyNum(h.cache, "2001:db8:0:1:ffff::2"); n != 2 {
+		return fmt.Errorf("same /64 counter=%d, want 2", n)
+	}
+	if n := utils.IncrIpLuckyNum(h.cache, "2001:db8:0:2::1"); n != 1 {
+		return fmt.Errorf("other /64 counter=%d, want 1", n)
+	}
+	return nil
+}
+
+// 只有来自可信代理的请求才使用头信息中的客户端IP
+func testTrustedProxyClientIp(h *harness) error {
+	defer func(proxies []string) { conf.TrustedProxies = proxies }(conf.TrustedProxies)
+	if _, err := h.seedGift("small", conf.GtypeGiftSmall, 0, "0-9999"); err != nil {
+		return err
+	}
+	now := comm.NowUnix()
+	for _, ip := range []string{"203.0.113.9", "2001:db8::/32"} {
+		if _, err := h.ServiceBlackip.Create(&models.LtBlackip{Ip: ip, Blacktime: now + 86400, SysCreated: now}); err != nil {
+			return err
+		}
+	}
+	cases := []struct {
+		proxies []string
+		header  string
+		value   string
+		code    int
+	}{
+		// 不可信的来源，头信息被忽略
+		{nil, "X-Forwarded-For", "203.0.113.9", 0},
+		{[]string{"127.0.0.0/8"}, "X-Forwarded-For", "203.0.113.9", 205},
+		// 最左边伪造的地址不会被使用
+		{[]string{"127.0.0.0/8"}, "X-Forwarded-For", "203.0.113.9, 198.51.100.7", 0},
+		{[]string{"127.0.0.0/8", "10.0.0.0/8"}, "X-Forwarded-For", "198.51.100.7, 203.0.113.9, 10.0.0.2", 205},
+		{[]string{"127.0.0.0/8"}, "X-Real-IP", "203.0.113.9", 205},
+		{[]string{"127.0.0.0/8"}, "Forwarded", `for="[2001:db8:cafe::17]:4711";proto=https`, 205},
+	}
+	for i, tc := range cases {
+		conf.TrustedProxies = tc.proxies
+		c := h.newClient()
+		c.header.Set(tc.header, tc.value)
+		if err := c.login(); err != nil {
+			return err
+		}
+		rs, err := c.lucky()
+		if err != nil {
+			return err
+		}
+		if rs.Code != tc.code {
+			return fmt.Errorf("case %d %s: %s lucky code=%d msg=%s, want %d", i, tc.header, tc.value, rs.Code, rs.Msg, tc.code)
+		}
+	}
+	return nil
+}
+
+// 超过访问频率限制的时候返回429和Retry-After，不同的维度分别计数
+func testRateLimit(h *harness) error {
+	server, err := h.startApp(func(b *bootstrap.Bootstrapper) {
+		b.RateLimits = []conf.RateLimit{
+			{Path: "/lucky", Key: "uid", Limit: 2, Window: 60},
+			{Path: "/gifts", Key: "device", Limit: 3, Window: 60},
+		}
+	})
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+	if _, err = h.seedGift("coin", conf.GtypeVirtual, 0, "0-9999"); err != nil {
+		return err
+	}
+
+	users := []*client{h.newClient(), h.newClient()}
+	for _, c := range users {
+		c.server = server
+		if err = c.login(); err != nil {
+			return err
+		}
+		for i := 0; i < 2; i++ {
+			if _, err = c.lucky(); err != nil {
+				return err
+			}
+		}
+	}
+	status, body, err := users[0].get("/lucky")
+	if err != nil {
+		return err
+	}
+	if status != http.StatusTooManyRequests || !strings.Contains(string(body), `"code":429`) {
+		return fmt.Errorf("third /lucky status=%d body=%s, want 429", status, body)
+	}
+	resp, err := users[0].http.Get(server.URL + "/lucky")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if retry, _ := strconv.Atoi(resp.Header.Get("Retry-After")); retry < 1 || retry > 60 {
+		return fmt.Errorf("Retry-After=%q", resp.Header.Get("Retry-After"))
+	}
+	// 没有登录的请求没有uid，不受限制
+	anonymous := h.newClient()
+	anonymous.server = server
+	for i := 0; i < 3; i++ {
+		if rs, err := anonymous.lucky(); err != nil || rs.Code != 101 {
+			return fmt.Errorf("anonymous lucky rs=%+v err=%v", rs, err)
+		}
+	}
+
+	device := h.newClient()
+	device.server = server
+	device.header.Set("X-Device-Id", "device-1")
+	for i := 0; i < 4; i++ {
+		status, _, err = device.get("/gifts")
+		if err != nil {
+			return err
+		}
+		want := http.StatusOK
+		if i == 3 {
+			want = http.StatusTooManyRequests
+		}
+		if status != want {
+			return fmt.Errorf("/gifts request %d status=%d, want %d", i+1, status, want)
+		}
+	}
+
+	// 默认的配置中，抽奖的接口在每个维度上都有限制
+	for _, path := range []string{"/lucky", "/lucky/batch"} {
+		keys := make(map[string]bool)
+		for _, limit := range conf.RateLimits {
+			if limit.Path == path {
+				keys[limit.Key] = true
+			}
+		}
+		if !keys["uid"] || !keys["device"] || !keys["ip"] {
+			return fmt.Errorf("default rate limits for %s = %v", path, keys)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+package e2e
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+)
+
+func testLoginAndMyprize(h *harness) error {
+	c := h.newClient()
+	if err := c.login(); err != nil {
+		return err
+	}
+	rs := struct {
+		Code      int               `json:"code"`
+		PrizeNum  int               `json:"prize_num"`
+		PrizeList []models.LtResult `json:"prize_list"`
+	}{}
+	if err := c.getJSON("/myprize", &rs); err != nil {
+		return err
+	}
+	if rs.Code != 0 || rs.PrizeNum != conf.UserPrizeMax || len(rs.PrizeList) != 0 {
+		return fmt.Errorf("myprize before any draw = %+v", rs)
+	}
+	if _, err := c.lucky(); err != nil {
+		return err
+	}
+	if err := c.getJSON("/myprize", &rs); err != nil {
+		return err
+	}
+	if rs.PrizeNum != conf.UserPrizeMax-1 {
+		return fmt.Errorf("myprize prize_num=%d after one draw, want %d", rs.PrizeNum, conf.UserPrizeMax-1)
+	}
+	return nil
+}
+
+func testLuckyRequiresLogin(h *harness) error {
+	rs, err := h.newClient().lucky()
+	if err != nil {
+		return err
+	}
+	if rs.Code != 101 {
+		return fmt.Errorf("lucky without login code=%d, want 101", rs.Code)
+	}
+	return nil
+}
+
+// 并发抽奖之后，奖品池、库存、优惠券和中奖记录必须保持一致
+func testConcurrentDrawConsistency(h *harness) error {
+	large, err := h.seedGift("large", conf.GtypeGiftLarge, 2, "0-499")
+	if err != nil {
+		return err
+	}
+	small, err := h.seedGift("small", conf.GtypeGiftSmall, 10, "500-2999")
+	if err != nil {
+		return err
+	}
+	coupon, err := h.seedGift("coupon", conf.GtypeCodeDiff, 20, "3000-6999")
+	if err != nil {
+		return err
+	}
+	coin, err := h.seedGift("coin", conf.GtypeVirtual, 0, "7000-8999")
+	if err != nil {
+		return err
+	}
+	// 优惠券编码比库存多，发完库存之后还剩下一部分
+	codeNum := 30
+	if err = h.seedCodes(coupon.Id, codeNum); err != nil {
+		return err
+	}
+
+	users, draws := 40, 15
+	var mu sync.Mutex
+	wins := make(map[int]int)
+	codes := make(map[string]bool)
+	var wg sync.WaitGroup
+	errs := make(chan error, users)
+	for i := 0; i < users; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := h.newClient()
+			if err := c.login(); err != nil {
+				errs <- err
+				return
+			}
+			for j := 0; j < draws; j++ {
+				rs, err := c.lucky()
+				if err != nil {
+					errs <- err
+					return
+				}
+				if rs.Code != 0 {
+					continue
+				}
+				if rs.Gift == nil {
+					errs <- errors.New("lucky code=0 without gift")
+					return
+				}
+				mu.Lock()
+				wins[rs.Gift.Id]++
+				if rs.Gift.Gtype == conf.GtypeCodeDiff {
+					if codes[rs.Gift.Gdata] {
+						errs <- fmt.Errorf("coupon code %s issued twice", rs.Gift.Gdata)
+					}
+					codes[rs.Gift.Gdata] = true
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		return err
+	}
+
+	totalWins := 0
+	for _, gift := range []*models.LtGift{large, small, coupon, coin} {
+		num := int(h.ServiceResult.CountByGift(gift.Id))
+		if num != wins[gift.Id] {
+			return fmt.Errorf("gift %s has %d results, responses reported %d wins", gift.Title, num, wins[gift.Id])
+		}
+		totalWins += num
+		if gift.PrizeNum <= 0 {
+			continue
+		}
+		info := h.ServiceGift.Get(gift.Id, false)
+		if info.LeftNum < 0 {
+			return fmt.Errorf("gift %s left_num=%d oversold", gift.Title, info.LeftNum)
+		}
+		if gift.PrizeNum-info.LeftNum != num {
+			return fmt.Errorf("gift %s stock consumed %d, results %d", gift.Title, gift.PrizeNum-info.LeftNum, num)
+		}
+		if pool := h.poolNum(gift.Id); pool > info.LeftNum {
+			return fmt.Errorf("gift %s pool=%d more than left_num=%d", gift.Title, pool, info.LeftNum)
+		}
+	}
+	if int(h.ServiceResult.CountAll()) != totalWins {
+		return fmt.Errorf("lt_result has %d rows, want %d", h.ServiceResult.CountAll(), totalWins)
+	}
+
+	// 发放出去的优惠券和数据库、缓存中的状态一致
+	issued, unused := 0, 0
+	for _, code := range h.ServiceCode.Search(coupon.Id) {
+		switch code.SysStatus {
+		case 2:
+			issued++
+			if plain := h.ServiceCode.PlainCode(&code); !codes[plain] {
+				return fmt.Errorf("code %s marked issued but never returned to a user", plain)
+			}
+		case 0:
+			unused++
+		}
+	}
+	if issued != len(codes) {
+		return fmt.Errorf("%d codes marked issued, %d returned to users", issued, len(codes))
+	}
+	cacheNum, _ := h.cache.Do("SCARD", fmt.Sprintf("gift_code_%d", coupon.Id))
+	if int(cacheNum.(int64)) != unused {
+		return fmt.Errorf("gift_code set has %d codes, %d unused in db", cacheNum, unused)
+	}
+	return nil
+}
+
+func testNewprizeOnlyListsPublicGifts(h *harness) error {
+	small, err := h.seedGift("small", conf.GtypeGiftSmall, 5, "0-4999")
+	if err != nil {
+		return err
+	}
+	coin, err := h.seedGift("coin", conf.GtypeVirtual, 0, "5000-9999")
+	if err != nil {
+		return err
+	}
+	c := h.newClient()
+	if err = c.login(); err != nil {
+		return err
+	}
+	for i := 0; i < 20; i++ {
+		if _, err = c.lucky(); err != nil {
+			return err
+		}
+	}
+	rs := struct {
+		Code      int               `json:"code"`
+		PrizeList []models.LtResult `json:"prize_list"`
+	}{}
+	if err = h.newClient().getJSON("/newprize", &rs); err != nil {
+		return err
+	}
+	want := int(h.ServiceResult.CountByGift(small.Id))
+	if len(rs.PrizeList) != want {
+		return fmt.Errorf("newprize lists %d results, want %d", len(rs.PrizeList), want)
+	}
+	for _, data := range rs.PrizeList {
+		if data.GiftId == coin.Id {
+			return errors.New("newprize lists virtual coin results")
+		}
+	}
+	return nil
+}
+
+// 中奖次数达到上限之后不再中奖，redis中的统计丢失之后从中奖记录恢复
+func testWinCaps(h *harness) error {
+	defer func(max int) { conf.UserGiftWinMax = max }(conf.UserGiftWinMax)
+	conf.UserGiftWinMax = 2
+	gift, err := h.seedGift("coin", conf.GtypeVirtual, 0, "0-9999")
+	if err != nil {
+		return err
+	}
+	c := h.newClient()
+	if err = c.login(); err != nil {
+		return err
+	}
+	wins := 0
+	for i := 0; i < 6; i++ {
+		rs, err := c.lucky()
+		if err != nil {
+			return err
+		}
+		switch rs.Code {
+		case 0:
+			wins++
+		case 210:
+		default:
+			return fmt.Errorf("lucky code=%d msg=%s", rs.Code, rs.Msg)
+		}
+	}
+	if wins != 2 {
+		return fmt.Errorf("%d wins with UserGiftWinMax=2", wins)
+	}
+	keys := make([]string, 0)
+	for _, key := range h.redis.Keys() {
+		if strings.HasPrefix(key, "win_gift_") {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) != 1 {
+		return fmt.Errorf("win_gift keys=%v", keys)
+	}
+	h.redis.Del(keys[0])
+	rs, err := c.lucky()
+	if err != nil {
+		return err
+	}
+	if rs.Code != 210 {
+		return fmt.Errorf("lucky code=%d after win counter expired, want 210", rs.Code)
+	}
+	if num := h.ServiceResult.CountByGift(gift.Id); num != 2 {
+		return fmt.Errorf("%d results, want 2", num)
+	}
+	return nil
+}
+
+// 新登录一个用户抽奖，需要中奖
+func (h *harness) luckyWin() error {
+	c := h.newClient()
+	if err := c.login(); err != nil {
+		return err
+	}
+	return c.luckyWin()
+}
+
+func (c *client) luckyWin() error {
+	rs, err := c.lucky()
+	if err != nil {
+		return err
+	}
+	if rs.Code != 0 || rs.Gift == nil {
+		return fmt.Errorf("lucky code=%d msg=%s", rs.Code, rs.Msg)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+// 抽奖流程的端到端测试
+// 使用sqlite和miniredis启动完整的web应用，通过HTTP驱动前台和后台接口
+//
+//	go test ./e2e [-run TestScenarios/名称] [-applog]
+package e2e
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"testing"
+)
+
+var appLog = flag.Bool("applog", false, "输出应用和redis的日志")
+
+// 所有的场景共用一个测试环境，每个场景执行之前清空数据
+var testHarness *harness
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !*appLog {
+		log.SetOutput(io.Discard)
+	}
+	h, err := newHarness()
+	if err != nil {
+		fmt.Println("e2e: setup error", err)
+		os.Exit(2)
+	}
+	testHarness = h
+	code := m.Run()
+	h.Close()
+	os.Exit(code)
+}
+
+func TestScenarios(t *testing.T) {
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.name, func(t *testing.T) {
+			if err := testHarness.reset(); err != nil {
+				t.Fatal("reset error", err)
+			}
+			if err := s.run(testHarness); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
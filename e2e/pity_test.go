@@ -0,0 +1,149 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/iralance/go-lottery/conf"
+	utils "github.com/iralance/go-lottery/uitls"
+)
+
+// 连续没有中奖达到保底规则的次数之后必定中奖，中奖之后次数归零
+func testPityGuarantee(h *harness) error {
+	// 中奖编码范围只有一个，正常抽奖几乎不会中奖
+	gift, err := h.seedGift("pity gift", conf.GtypeGiftSmall, 5, "0-0")
+	if err != nil {
+		return err
+	}
+	admin := h.newAdmin()
+	status, body, err := admin.post("/admin/pity/save", url.Values{
+		"title": {"pity rule"}, "gtype": {fmt.Sprint(conf.GtypeGiftSmall)}, "num": {"3"},
+		"action": {fmt.Sprint(conf.PityActionGuarantee)}})
+	if err != nil {
+		return err
+	}
+	if status != http.StatusFound && status != http.StatusSeeOther {
+		return fmt.Errorf("POST /admin/pity/save status=%d body=%s", status, body)
+	}
+	// 提高概率的规则必须设置概率
+	if status, _, err = admin.post("/admin/pity/save", url.Values{
+		"title": {"bad rule"}, "gtype": {"0"}, "num": {"3"},
+		"action": {fmt.Sprint(conf.PityActionBoost)}}); err != nil {
+		return err
+	} else if status != http.StatusOK || h.ServicePity.CountAll() != 1 {
+		return fmt.Errorf("POST /admin/pity/save without boost status=%d, rules=%d", status, h.ServicePity.CountAll())
+	}
+
+	c := h.newClient()
+	if err = c.login(); err != nil {
+		return err
+	}
+	uid := c.uid()
+	utils.SetLossNum(h.cache, uid, 2)
+	lossNum := func() int {
+		return utils.GetLossNum(h.cache, uid)
+	}
+	// 每个用户单独保存并且会过期，后台的分布统计随着更新
+	if ttl := h.redis.TTL(fmt.Sprintf("pity_loss_%d", uid)); ttl <= 0 {
+		return fmt.Errorf("pity loss ttl = %v", ttl)
+	}
+	if counts := utils.GetLossCounts(h.cache); len(counts) != 1 || counts[2] != 1 {
+		return fmt.Errorf("loss counts = %v, want one user with 2", counts)
+	}
+	for _, want := range []int{205, 0} {
+		rs, err := c.lucky()
+		if err != nil {
+			return err
+		}
+		if rs.Code != want {
+			return fmt.Errorf("lucky code=%d msg=%s losses=%d, want %d", rs.Code, rs.Msg, lossNum(), want)
+		}
+		if want == 205 && lossNum() != 3 {
+			return fmt.Errorf("losses after 205 = %d, want 3", lossNum())
+		}
+	}
+	if lossNum() != 0 {
+		return fmt.Errorf("losses after win = %d, want 0", lossNum())
+	}
+	if counts := utils.GetLossCounts(h.cache); len(counts) != 0 {
+		return fmt.Errorf("loss counts after win = %v, want none", counts)
+	}
+	list := h.ServiceResult.GetAll(1, 10)
+	if len(list) != 1 || list[0].GiftId != gift.Id || list[0].PityId <= 0 {
+		return fmt.Errorf("results after pity = %+v", list)
+	}
+	if h.poolNum(gift.Id) != 4 {
+		return fmt.Errorf("pool after pity = %d, want 4", h.poolNum(gift.Id))
+	}
+	status, body, err = admin.get("/admin/pity")
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK || !strings.Contains(string(body), "pity rule") {
+		return fmt.Errorf("GET /admin/pity status=%d body=%s", status, body)
+	}
+	return nil
+}
+
+// 没有中奖的时候发放安慰奖，安慰奖有单独的每天次数限制，不算中奖
+func testConsolationPrizes(h *harness) error {
+	defer func(max int) { conf.UserDayConsolationMax = max }(conf.UserDayConsolationMax)
+	conf.UserDayConsolationMax = 2
+	// 只有安慰奖，正常抽奖不会中奖
+	status, body, err := h.newAdmin().post("/admin/gift/save", url.Values{
+		"title":        {"consolation coin"},
+		"prize_num":    {"0"},
+		"prize_code":   {"0-9999"},
+		"prize_time":   {"0"},
+		"gtype":        {fmt.Sprint(conf.GtypeVirtual)},
+		"gdata":        {"10"},
+		"fallback":     {"1"},
+		"time_begin":   {"2020-01-01 00:00:00"},
+		"time_end":     {"2099-01-01 00:00:00"},
+		"displayorder": {"1"},
+	})
+	if err != nil {
+		return err
+	}
+	if status != http.StatusFound && status != http.StatusSeeOther {
+		return fmt.Errorf("save gift status=%d body=%s", status, body)
+	}
+	gifts := h.ServiceGift.GetAll(false)
+	if len(gifts) != 1 || gifts[0].Fallback != 1 {
+		return fmt.Errorf("gifts after admin save = %+v", gifts)
+	}
+
+	c := h.newClient()
+	if err = c.login(); err != nil {
+		return err
+	}
+	for i, want := range []int{0, 0, 205} {
+		rs, err := c.lucky()
+		if err != nil {
+			return err
+		}
+		if rs.Code != want {
+			return fmt.Errorf("draw %d code=%d msg=%s, want %d", i, rs.Code, rs.Msg, want)
+		}
+		if want == 0 && (rs.Gift == nil || rs.Gift.Id != gifts[0].Id || rs.Gift.Fallback != 1) {
+			return fmt.Errorf("draw %d gift=%+v, want consolation", i, rs.Gift)
+		}
+	}
+	// 安慰奖和正常的中奖一样保存中奖记录
+	list := h.ServiceResult.GetAll(1, 10)
+	if len(list) != 2 || list[0].GiftId != gifts[0].Id || list[0].GiftData != "10" || list[0].Fallback != 1 {
+		return fmt.Errorf("results after consolation = %+v", list)
+	}
+	// 获得安慰奖仍然算没有中奖，不计算中奖次数
+	uid := c.uid()
+	if n := h.ServiceResult.CountByUserGift(uid, gifts[0].Id) + h.ServiceResult.CountByUserGtype(uid, conf.GtypeVirtual) +
+		h.ServiceResult.CountByUserSince(uid, 0); n != 0 {
+		return fmt.Errorf("win counts with only consolation results = %d, want 0", n)
+	}
+	if num := utils.GetLossNum(h.cache, c.uid()); num != 3 {
+		return fmt.Errorf("losses after consolation = %d, want 3", num)
+	}
+	return nil
+}
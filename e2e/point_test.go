@@ -0,0 +1,125 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+	utils "github.com/iralance/go-lottery/uitls"
+)
+
+// 需要积分的活动，抽奖之前扣除积分，系统原因没有中奖的时候退回积分
+func testPaidDraws(h *harness) error {
+	defer delete(conf.DrawPointsCost, "paid")
+	conf.DrawPointsCost["paid"] = 10
+	// 没有导入编码的优惠券，抽中之后发放失败
+	gift, err := h.seedGift("coupon", conf.GtypeCodeDiff, 0, "0-9999")
+	if err != nil {
+		return err
+	}
+	c := h.newClient()
+	if err = c.login(); err != nil {
+		return err
+	}
+	uid := c.uid()
+	// 今天只剩下一次免费的次数
+	utils.InitUserLuckyNum(h.cache, uid, int64(conf.UserPrizeMax-1))
+	draw := func(campaign string, want int) error {
+		rs, err := c.luckyWith(url.Values{"campaign": {campaign}})
+		if err != nil {
+			return err
+		}
+		if rs.Code != want {
+			return fmt.Errorf("lucky campaign=%s code=%d msg=%s, want %d", campaign, rs.Code, rs.Msg, want)
+		}
+		return nil
+	}
+	type pointsPage struct {
+		Code    int                 `json:"code"`
+		Balance int                 `json:"balance"`
+		Total   int                 `json:"total"`
+		LogList []models.LtPointLog `json:"log_list"`
+	}
+	checkPoints := func(balance, total int) (*pointsPage, error) {
+		rs := &pointsPage{}
+		if err := c.getJSON("/mypoints", rs); err != nil {
+			return nil, err
+		}
+		if rs.Code != 0 || rs.Balance != balance || rs.Total != total || len(rs.LogList) != total {
+			return nil, fmt.Errorf("mypoints = %+v, want balance=%d total=%d", rs, balance, total)
+		}
+		return rs, nil
+	}
+
+	if err = draw("unknown", 108); err != nil {
+		return err
+	}
+	if err = draw("paid", 109); err != nil {
+		return err
+	}
+	if _, err = checkPoints(0, 0); err != nil {
+		return err
+	}
+	status, body, err := h.newAdmin().post("/admin/point/earn", url.Values{
+		"uid": {strconv.Itoa(uid)}, "num": {"25"}, "remark": {"活动奖励"}})
+	if err != nil {
+		return err
+	}
+	if status != http.StatusFound && status != http.StatusSeeOther {
+		return fmt.Errorf("POST /admin/point/earn status=%d body=%s", status, body)
+	}
+	if status, body, err = h.newAdmin().get(fmt.Sprintf("/admin/point/log?uid=%d", uid)); err != nil {
+		return err
+	} else if status != http.StatusOK || !strings.Contains(string(body), "活动奖励") {
+		return fmt.Errorf("GET /admin/point/log status=%d body=%s", status, body)
+	}
+
+	// 优惠券发放失败，积分退回
+	if err = draw("paid", 208); err != nil {
+		return err
+	}
+	rs, err := checkPoints(25, 3)
+	if err != nil {
+		return err
+	}
+	if rs.LogList[0].Action != conf.PointActionRefund || rs.LogList[0].Num != 10 ||
+		rs.LogList[1].Action != conf.PointActionSpend || rs.LogList[1].Num != -10 {
+		return fmt.Errorf("points log after refund = %+v", rs.LogList)
+	}
+	if err = h.seedCodes(gift.Id, 5); err != nil {
+		return err
+	}
+	for _, want := range []int{0, 0, 109} {
+		if err = draw("paid", want); err != nil {
+			return err
+		}
+	}
+	if _, err = checkPoints(5, 5); err != nil {
+		return err
+	}
+	// 需要积分的抽奖不使用每天免费的次数
+	myprize := struct {
+		FreeNum int `json:"free_num"`
+	}{}
+	if err = c.getJSON("/myprize", &myprize); err != nil {
+		return err
+	}
+	if myprize.FreeNum != conf.UserPrizeMax {
+		return fmt.Errorf("myprize free_num=%d after paid draws, want %d", myprize.FreeNum, conf.UserPrizeMax)
+	}
+	// 积分抽奖之后，剩下的免费次数仍然可以使用
+	for i := 0; i < 2; i++ {
+		rs, err := c.lucky()
+		if err != nil {
+			return err
+		}
+		if (rs.Code == 103) != (i == 1) {
+			return fmt.Errorf("free lucky %d after paid draws code=%d msg=%s", i+1, rs.Code, rs.Msg)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	utils "github.com/iralance/go-lottery/uitls"
+)
+
+// 确认作弊之后撤销奖品，库存、奖品池和优惠券都退回，用户和IP进入黑名单
+func testReviewRevoke(h *harness) error {
+	gift, err := h.seedGift("coupon", conf.GtypeCodeDiff, 2, "0-9999")
+	if err != nil {
+		return err
+	}
+	if err = h.seedCodes(gift.Id, 2); err != nil {
+		return err
+	}
+	c := h.newClient()
+	if err = c.login(); err != nil {
+		return err
+	}
+	rs, err := c.lucky()
+	if err != nil {
+		return err
+	}
+	if rs.Code != 0 {
+		return fmt.Errorf("lucky code=%d msg=%s, want a prize", rs.Code, rs.Msg)
+	}
+	list := h.ServiceResult.GetAll(1, 10)
+	if len(list) != 1 {
+		return fmt.Errorf("%d results, want 1", len(list))
+	}
+	info := list[0]
+	admin := h.newAdmin()
+	review := func(action string, form url.Values) error {
+		form.Set("id", strconv.Itoa(info.Id))
+		form.Set("action", action)
+		_, _, err := admin.post("/admin/result/review", form)
+		return err
+	}
+	// 没有确认作弊之前不能撤销
+	if err = review("revoke", url.Values{}); err != nil {
+		return err
+	}
+	if h.ServiceGift.Get(gift.Id, false).LeftNum != 1 {
+		return fmt.Errorf("revoke before confirm returned the gift")
+	}
+	if err = review("confirm", url.Values{"reason": {"刷奖"}}); err != nil {
+		return err
+	}
+	revoke := url.Values{"code": {"requeue"}, "black_user": {"1"}, "black_ip": {"1"}, "black_days": {"7"}}
+	for i := 0; i < 2; i++ {
+		if err = review("revoke", revoke); err != nil {
+			return err
+		}
+	}
+	result := h.ServiceResult.Get(info.Id)
+	if result.ReviewStatus != conf.ReviewRevoked || result.SysStatus != conf.ResultStatusCheat {
+		return fmt.Errorf("result review_status=%d sys_status=%d after revoke", result.ReviewStatus, result.SysStatus)
+	}
+	if left := h.ServiceGift.Get(gift.Id, false).LeftNum; left != 2 {
+		return fmt.Errorf("gift left_num=%d after revoke, want 2", left)
+	}
+	if pool := h.poolNum(gift.Id); pool != 2 {
+		return fmt.Errorf("gift pool=%d after revoke, want 2", pool)
+	}
+	if num, cacheNum := utils.GetCacheCodeNum(h.cache, gift.Id, h.ServiceCode); num != 2 || cacheNum != 2 {
+		return fmt.Errorf("codes after requeue num=%d cache=%d, want 2", num, cacheNum)
+	}
+	now := comm.NowUnix()
+	if user := h.ServiceUser.Get(info.Uid); user.Blacktime <= now {
+		return fmt.Errorf("user %d blacktime=%d after revoke", info.Uid, user.Blacktime)
+	}
+	if black := h.ServiceBlackip.GetByIp(info.SysIp); black == nil || black.Blacktime <= now {
+		return fmt.Errorf("ip %s not blacklisted after revoke", info.SysIp)
+	}
+	reviews := h.ServiceReview.SearchByResult(info.Id)
+	if len(reviews) != 2 || reviews[0].Action != conf.ReviewConfirmed || reviews[1].Action != conf.ReviewRevoked {
+		return fmt.Errorf("reviews after revoke = %+v", reviews)
+	}
+	if reviews[1].Reviewer != "admin" || reviews[0].Reason != "刷奖" || reviews[1].Detail == "" {
+		return fmt.Errorf("review record = %+v", reviews)
+	}
+	status, body, err := admin.get(fmt.Sprintf("/admin/result/review?id=%d", info.Id))
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK || !strings.Contains(string(body), "已撤销") {
+		return fmt.Errorf("review page status=%d body=%s", status, body)
+	}
+	return nil
+}
+
+// 已经核销的优惠券撤销的时候不能放回奖品池
+func testReviewRevokeRedeemed(h *harness) error {
+	gift, err := h.seedGift("coupon", conf.GtypeCodeDiff, 2, "0-9999")
+	if err != nil {
+		return err
+	}
+	if err = h.seedCodes(gift.Id, 2); err != nil {
+		return err
+	}
+	c := h.newClient()
+	if err = c.login(); err != nil {
+		return err
+	}
+	rs, err := c.lucky()
+	if err != nil {
+		return err
+	}
+	if rs.Code != 0 || rs.Gift == nil || rs.Gift.Gdata == "" {
+		return fmt.Errorf("lucky code=%d msg=%s, want a coupon", rs.Code, rs.Msg)
+	}
+	if code, msg := h.ServiceCode.Redeem(rs.Gift.Gdata, "order-1"); code != 0 {
+		return fmt.Errorf("redeem code=%d msg=%s", code, msg)
+	}
+	info := h.ServiceResult.GetAll(1, 1)[0]
+	admin := h.newAdmin()
+	for _, form := range []url.Values{{"action": {"confirm"}}, {"action": {"revoke"}, "code": {"requeue"}}} {
+		form.Set("id", strconv.Itoa(info.Id))
+		if _, _, err = admin.post("/admin/result/review", form); err != nil {
+			return err
+		}
+	}
+	if data := h.ServiceCode.GetByCode(rs.Gift.Gdata); data == nil || data.SysStatus != conf.CodeStatusRedeemed {
+		return fmt.Errorf("redeemed code after revoke = %+v", data)
+	}
+	if num, cacheNum := utils.GetCacheCodeNum(h.cache, gift.Id, h.ServiceCode); num != 1 || cacheNum != 1 {
+		return fmt.Errorf("codes after revoke num=%d cache=%d, want 1", num, cacheNum)
+	}
+	reviews := h.ServiceReview.SearchByResult(info.Id)
+	if len(reviews) != 2 || !strings.Contains(reviews[1].Detail, "优惠券放回奖品池失败") {
+		return fmt.Errorf("reviews after revoke = %+v", reviews)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iralance/go-lottery/bootstrap"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+	"github.com/iralance/go-lottery/services"
+)
+
+// 虚拟币奖品通过签名的webhook入账，失败重试，重试失败之后人工重新发放
+func testRewardWebhook(h *harness) error {
+	var mu sync.Mutex
+	fails := 2
+	permanent := false
+	keys := make([]string, 0)
+	wallet := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sign := services.RewardSignature("e2e-secret", r.Header.Get("X-Lottery-Timestamp"), body)
+		if r.Header.Get("X-Lottery-Signature") != sign {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var payload services.RewardPayload
+		if err := json.Unmarshal(body, &payload); err != nil || payload.Amount != 100 ||
+			payload.IdempotencyKey != r.Header.Get("Idempotency-Key") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, payload.IdempotencyKey)
+		if permanent {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		} else if fails > 0 {
+			fails--
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer wallet.Close()
+
+	webhook := conf.RewardWebhook
+	conf.RewardWebhook = conf.Webhook{
+		Url:        wallet.URL,
+		Secret:     "e2e-secret",
+		Timeout:    time.Second,
+		MaxTries:   3,
+		RetryDelay: 10 * time.Millisecond,
+	}
+	server, err := h.startApp(func(b *bootstrap.Bootstrapper) {
+		b.RateLimits = []conf.RateLimit{}
+	})
+	conf.RewardWebhook = webhook
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+	if _, err = h.seedGift("coin", conf.GtypeVirtual, 0, "0-9999"); err != nil {
+		return err
+	}
+
+	// 等待异步发放结束
+	waitDeliver := func(id, status int) (*models.LtResult, error) {
+		for i := 0; i < 200; i++ {
+			info := h.ServiceResult.Get(id)
+			if info.DeliverStatus == status {
+				return info, nil
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		info := h.ServiceResult.Get(id)
+		return nil, fmt.Errorf("result %d deliver_status=%d tries=%d, want %d", id, info.DeliverStatus, info.DeliverTries, status)
+	}
+
+	c := h.newClient()
+	c.server = server
+	if err = c.login(); err != nil {
+		return err
+	}
+	if rs, err := c.lucky(); err != nil || rs.Code != 0 {
+		return fmt.Errorf("lucky rs=%+v err=%v, want coins", rs, err)
+	}
+	first := h.ServiceResult.GetAll(1, 1)[0]
+	info, err := waitDeliver(first.Id, conf.DeliverDone)
+	if err != nil {
+		return err
+	}
+	if info.DeliverTries != 3 {
+		return fmt.Errorf("delivered after %d tries, want 3", info.DeliverTries)
+	}
+	mu.Lock()
+	for _, key := range keys {
+		if key != services.RewardIdempotencyKey(first.Id) {
+			mu.Unlock()
+			return fmt.Errorf("retry used idempotency key %s", key)
+		}
+	}
+	permanent = true
+	keys = keys[:0]
+	mu.Unlock()
+
+	// 钱包拒绝的请求不再重试，直接进入发放失败
+	if rs, err := c.lucky(); err != nil || rs.Code != 0 {
+		return fmt.Errorf("lucky rs=%+v err=%v, want coins", rs, err)
+	}
+	second := h.ServiceResult.GetAll(1, 1)[0]
+	if info, err = waitDeliver(second.Id, conf.DeliverDead); err != nil {
+		return err
+	}
+	if info.DeliverTries != 1 {
+		return fmt.Errorf("rejected delivery tried %d times, want 1", info.DeliverTries)
+	}
+	admin := h.newAdmin()
+	admin.server = server
+	_, body, err := admin.get("/admin/result?deliver_status=3")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(string(body), fmt.Sprintf("/admin/result/redeliver?id=%d", second.Id)) {
+		return fmt.Errorf("dead letter page misses result %d", second.Id)
+	}
+	mu.Lock()
+	permanent = false
+	mu.Unlock()
+	if _, _, err = admin.get(fmt.Sprintf("/admin/result/redeliver?id=%d", second.Id)); err != nil {
+		return err
+	}
+	if _, err = waitDeliver(second.Id, conf.DeliverDone); err != nil {
+		return err
+	}
+	// 已经到账的不会重复发放
+	if _, _, err = admin.get(fmt.Sprintf("/admin/result/redeliver?id=%d", second.Id)); err != nil {
+		return err
+	}
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(keys) != 2 {
+		return fmt.Errorf("wallet received %d calls for the rejected result, want 2", len(keys))
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+	"github.com/iralance/go-lottery/services"
+	utils "github.com/iralance/go-lottery/uitls"
+)
+
+// 没有配置规则的时候，实物大奖之后用户和IP都进入冷却期，只能获得小奖
+func testDefaultRuleCooldown(h *harness) error {
+	if _, err := h.seedGift("large", conf.GtypeGiftLarge, 5, "0-9999"); err != nil {
+		return err
+	}
+	// 后台新增规则之后，默认规则仍然生效
+	status, body, err := h.newAdmin().post("/admin/rule/save", url.Values{
+		"title": {"flag"}, "rtype": {fmt.Sprint(conf.RuleTypeUserLucky)}, "num": {"1000"},
+		"action": {fmt.Sprint(conf.RuleActionFlag)}})
+	if err != nil {
+		return err
+	}
+	if status != http.StatusFound && status != http.StatusSeeOther {
+		return fmt.Errorf("save rule status=%d body=%s", status, body)
+	}
+	if num := len(h.ServiceRule.GetAllUse()); num != len(services.DefaultRules())+1 {
+		return fmt.Errorf("rules in use = %d, want defaults and the new rule", num)
+	}
+	first := h.newClient()
+	if err := first.login(); err != nil {
+		return err
+	}
+	rs, err := first.lucky()
+	if err != nil {
+		return err
+	}
+	if rs.Code != 0 {
+		return fmt.Errorf("first lucky code=%d msg=%s, want the large prize", rs.Code, rs.Msg)
+	}
+	// 降级的冷却期同时写入数据库的黑名单
+	result := h.ServiceResult.GetAll(1, 1)[0]
+	blacktime := comm.NowUnix() + 29*86400
+	if user := h.ServiceUser.Get(first.uid()); user.Blacktime < blacktime {
+		return fmt.Errorf("user blacktime after large prize = %d", user.Blacktime)
+	}
+	if info := h.ServiceBlackip.GetByIp(result.SysIp); info.Blacktime < blacktime {
+		return fmt.Errorf("ip %s blacktime after large prize = %d", result.SysIp, info.Blacktime)
+	}
+	// redis的冷却期丢失之后，相同IP的其他用户仍然只能获得小奖
+	h.redis.FlushAll()
+	second := h.newClient()
+	if err = second.login(); err != nil {
+		return err
+	}
+	rs, err = second.lucky()
+	if err != nil {
+		return err
+	}
+	if rs.Code != 205 {
+		return fmt.Errorf("lucky during cooldown code=%d msg=%s, want 205", rs.Code, rs.Msg)
+	}
+	return nil
+}
+
+// 后台配置的规则和默认规则一起生效，按照处理方式生效
+func testAdminRules(h *harness) error {
+	if _, err := h.seedGift("coin", conf.GtypeVirtual, 0, "0-9999"); err != nil {
+		return err
+	}
+	admin := h.newAdmin()
+	saveRule := func(id, rtype, num, action int) error {
+		form := url.Values{
+			"id":     {fmt.Sprint(id)},
+			"title":  {fmt.Sprintf("rule %d-%d-%d", rtype, num, action)},
+			"rtype":  {fmt.Sprint(rtype)},
+			"gtype":  {"0"},
+			"num":    {fmt.Sprint(num)},
+			"action": {fmt.Sprint(action)},
+		}
+		status, body, err := admin.post("/admin/rule/save", form)
+		if err != nil {
+			return err
+		}
+		if status != http.StatusFound && status != http.StatusSeeOther {
+			return fmt.Errorf("save rule status=%d body=%s", status, body)
+		}
+		return nil
+	}
+	expect := func(c *client, code int) error {
+		rs, err := c.lucky()
+		if err != nil {
+			return err
+		}
+		if rs.Code != code {
+			return fmt.Errorf("lucky code=%d msg=%s, want %d", rs.Code, rs.Msg, code)
+		}
+		return nil
+	}
+
+	// 用户第二次抽奖开始标记中奖记录
+	if err := saveRule(0, conf.RuleTypeUserLucky, 1, conf.RuleActionFlag); err != nil {
+		return err
+	}
+	c := h.newClient()
+	if err := c.login(); err != nil {
+		return err
+	}
+	for i := 0; i < 2; i++ {
+		if err := expect(c, 0); err != nil {
+			return err
+		}
+	}
+	list := h.ServiceResult.GetAll(1, 10)
+	if len(list) != 2 || list[0].RuleId <= 0 || list[1].RuleId != 0 {
+		return fmt.Errorf("results after flag rule = %+v", list)
+	}
+
+	// 修改成需要验证
+	if err := saveRule(list[0].RuleId, conf.RuleTypeUserLucky, 1, conf.RuleActionCaptcha); err != nil {
+		return err
+	}
+	if err := expect(c, 106); err != nil {
+		return err
+	}
+
+	// 同一个IP有两个用户参与就拒绝，严格的处理方式优先
+	if err := saveRule(0, conf.RuleTypeIpUsers, 1, conf.RuleActionDeny); err != nil {
+		return err
+	}
+	if err := expect(c, 106); err != nil {
+		return err
+	}
+	other := h.newClient()
+	if err := other.login(); err != nil {
+		return err
+	}
+	if err := expect(other, 105); err != nil {
+		return err
+	}
+	return nil
+}
+
+// 规则要求验证的时候返回验证，完成之后可以继续抽奖
+func testChallenge(h *harness) error {
+	defer func(ctype string) { conf.ChallengeType = ctype }(conf.ChallengeType)
+	if _, err := h.seedGift("coin", conf.GtypeVirtual, 0, "0-9999"); err != nil {
+		return err
+	}
+	// 用户第二次抽奖开始需要验证
+	form := url.Values{
+		"title":  {"captcha"},
+		"rtype":  {fmt.Sprint(conf.RuleTypeUserLucky)},
+		"num":    {"1"},
+		"action": {fmt.Sprint(conf.RuleActionCaptcha)},
+	}
+	if _, _, err := h.newAdmin().post("/admin/rule/save", form); err != nil {
+		return err
+	}
+	answer := func(id, value string) url.Values {
+		return url.Values{"challenge_id": {id}, "challenge_answer": {value}}
+	}
+	challenged := func(c *client) (*models.ObjChallenge, error) {
+		if rs, err := c.lucky(); err != nil || rs.Code != 0 {
+			return nil, fmt.Errorf("first lucky rs=%+v err=%v", rs, err)
+		}
+		rs, err := c.lucky()
+		if err != nil {
+			return nil, err
+		}
+		if rs.Code != 106 || rs.Challenge == nil || rs.Challenge.Id == "" {
+			return nil, fmt.Errorf("second lucky code=%d challenge=%+v, want 106", rs.Code, rs.Challenge)
+		}
+		return rs.Challenge, nil
+	}
+
+	conf.ChallengeType = "pow"
+	c := h.newClient()
+	if err := c.login(); err != nil {
+		return err
+	}
+	challenge, err := challenged(c)
+	if err != nil {
+		return err
+	}
+	if challenge.Type != "pow" || challenge.Prefix == "" || challenge.Difficulty != conf.ChallengePowBits {
+		return fmt.Errorf("pow challenge=%+v", challenge)
+	}
+	// 错误的答案返回新的验证，旧的验证不能再使用
+	rs, err := c.luckyWith(answer(challenge.Id, "wrong"))
+	if err != nil {
+		return err
+	}
+	if rs.Code != 107 || rs.Challenge == nil {
+		return fmt.Errorf("wrong answer code=%d challenge=%+v, want 107", rs.Code, rs.Challenge)
+	}
+	challenge = rs.Challenge
+	solution := ""
+	for i := 0; ; i++ {
+		if utils.CheckPowAnswer(challenge.Prefix, strconv.Itoa(i), challenge.Difficulty) {
+			solution = strconv.Itoa(i)
+			break
+		}
+	}
+	if rs, err = c.luckyWith(answer(challenge.Id, solution)); err != nil || rs.Code != 0 {
+		return fmt.Errorf("lucky with pow solution rs=%+v err=%v", rs, err)
+	}
+	// 验证之后一段时间内不需要再次验证
+	if rs, err = c.lucky(); err != nil || rs.Code != 0 {
+		return fmt.Errorf("lucky after challenge pass rs=%+v err=%v", rs, err)
+	}
+	if rs, err = c.luckyWith(answer(challenge.Id, solution)); err != nil || rs.Code != 107 {
+		return fmt.Errorf("reused challenge rs=%+v err=%v, want 107", rs, err)
+	}
+
+	conf.ChallengeType = "image"
+	other := h.newClient()
+	if err = other.login(); err != nil {
+		return err
+	}
+	if challenge, err = challenged(other); err != nil {
+		return err
+	}
+	if challenge.Type != "image" || challenge.Img == "" {
+		return fmt.Errorf("image challenge=%+v", challenge)
+	}
+	status, body, err := other.get(challenge.Img)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK || !strings.HasPrefix(string(body), "\x89PNG") {
+		return fmt.Errorf("challenge image status=%d size=%d", status, len(body))
+	}
+	digits := h.redis.HGet("challenge_"+challenge.Id, "answer")
+	if rs, err = other.luckyWith(answer(challenge.Id, digits)); err != nil || rs.Code != 0 {
+		return fmt.Errorf("lucky with image answer rs=%+v err=%v", rs, err)
+	}
+	return nil
+}
+
+// 同一个设备上的用户越来越多，默认规则先降级再拒绝，后台可以看到关联的用户
+func testDeviceLinkage(h *harness) error {
+	if _, err := h.seedGift("small", conf.GtypeGiftSmall, 0, "0-9999"); err != nil {
+		return err
+	}
+	// 拒绝只计算设备上的其他用户，第7个用户的20*6超过100
+	want := []int{0, 0, 0, 205, 205, 205, 105}
+	for i, code := range want {
+		c := h.newClient()
+		c.header.Set("X-Device-Id", "farm-1")
+		if err := c.login(); err != nil {
+			return err
+		}
+		rs, err := c.lucky()
+		if err != nil {
+			return err
+		}
+		if rs.Code != code {
+			return fmt.Errorf("user %d on shared device lucky code=%d msg=%s, want %d", i+1, rs.Code, rs.Msg, code)
+		}
+	}
+	list := h.ServiceResult.GetAll(1, 10)
+	if len(list) != 3 || list[0].Device != "farm-1" {
+		return fmt.Errorf("results on shared device = %+v", list)
+	}
+	status, body, err := h.newAdmin().get(fmt.Sprintf("/admin/result/link?id=%d", list[0].Id))
+	if err != nil {
+		return err
+	}
+	page := string(body)
+	if status != http.StatusOK || !strings.Contains(page, "farm-1") || !strings.Contains(page, "<strong>126</strong>") {
+		return fmt.Errorf("result link page status=%d body=%s", status, body)
+	}
+	for _, r := range list {
+		if !strings.Contains(page, fmt.Sprintf("/admin/result?uid=%d", r.Uid)) {
+			return fmt.Errorf("result link page misses uid %d", r.Uid)
+		}
+	}
+	return nil
+}
+
+// NAT后面同一个IP上的用户很多，关联风险分有上限，不会被降级或者拒绝
+func testSharedIpLinkage(h *harness) error {
+	if _, err := h.seedGift("small", conf.GtypeGiftSmall, 0, "0-9999"); err != nil {
+		return err
+	}
+	for uid := 100001; uid <= 100300; uid++ {
+		utils.RecordLink(h.cache, uid, "127.0.0.1", "")
+	}
+	for i := 0; i < 3; i++ {
+		c := h.newClient()
+		if err := c.login(); err != nil {
+			return err
+		}
+		rs, err := c.lucky()
+		if err != nil {
+			return err
+		}
+		if rs.Code != 0 {
+			return fmt.Errorf("user %d behind shared ip lucky code=%d msg=%s, want 0", i+1, rs.Code, rs.Msg)
+		}
+	}
+	if score := utils.LinkScore(h.cache, 100001, "127.0.0.1", ""); score != conf.LinkScoreIpUidMax {
+		return fmt.Errorf("link score behind shared ip = %d, want %d", score, conf.LinkScoreIpUidMax)
+	}
+	return nil
+}
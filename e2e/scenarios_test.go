@@ -0,0 +1,47 @@
+package e2e
+
+// 所有的场景，每个功能的场景在单独的文件中
+type scenario struct {
+	name string
+	run  func(h *harness) error
+}
+
+var scenarios = []scenario{
+	{"LoginAndMyprize", testLoginAndMyprize},
+	{"LuckyRequiresLogin", testLuckyRequiresLogin},
+	{"ConcurrentDrawConsistency", testConcurrentDrawConsistency},
+	{"NewprizeOnlyListsPublicGifts", testNewprizeOnlyListsPublicGifts},
+	{"WinCaps", testWinCaps},
+	{"DefaultRuleCooldown", testDefaultRuleCooldown},
+	{"AdminRules", testAdminRules},
+	{"BlackipCidr", testBlackipCidr},
+	{"IpDayCounters", testIpDayCounters},
+	{"TrustedProxyClientIp", testTrustedProxyClientIp},
+	{"RateLimit", testRateLimit},
+	{"Challenge", testChallenge},
+	{"DeviceLinkage", testDeviceLinkage},
+	{"SharedIpLinkage", testSharedIpLinkage},
+	{"AdminRequiresAuth", testAdminRequiresAuth},
+	{"AdminPages", testAdminPages},
+	{"AdminCodeImport", testAdminCodeImport},
+	{"AdminGiftCreate", testAdminGiftCreate},
+	{"AdminResultCheat", testAdminResultCheat},
+	{"ReviewRevoke", testReviewRevoke},
+	{"ReviewRevokeRedeemed", testReviewRevokeRedeemed},
+	{"FulfillPipeline", testFulfillPipeline},
+	{"RewardWebhook", testRewardWebhook},
+	{"CodeLifecycle", testCodeLifecycle},
+	{"CodeUpload", testCodeUpload},
+	{"CodeGenerator", testCodeGenerator},
+	{"CodeEncryption", testCodeEncryption},
+	{"CodePoolHealth", testCodePoolHealth},
+	{"WinnerFeed", testWinnerFeed},
+	{"WinnerPrivacy", testWinnerPrivacy},
+	{"DrawHistory", testDrawHistory},
+	{"BonusChances", testBonusChances},
+	{"PaidDraws", testPaidDraws},
+	{"PityGuarantee", testPityGuarantee},
+	{"ConsolationPrizes", testConsolationPrizes},
+	{"BatchDraws", testBatchDraws},
+	{"BatchCutShort", testBatchCutShort},
+}
@@ -0,0 +1,202 @@
+package e2e
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+)
+
+// 中奖之后通过websocket和SSE推送脱敏的中奖动态
+func testWinnerFeed(h *harness) error {
+	if _, err := h.seedGift("small", conf.GtypeGiftSmall, 100, "0-9999"); err != nil {
+		return err
+	}
+	// 先中奖一次，连接之后首先收到最新的中奖记录，说明已经开始订阅
+	if err := h.luckyWin(); err != nil {
+		return err
+	}
+	wsURL := "ws" + strings.TrimPrefix(h.server.URL, "http") + "/ws/winners"
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	readWs := func() ([]byte, error) {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, data, err := conn.ReadMessage()
+		return data, err
+	}
+	resp, err := http.Get(h.server.URL + "/sse/winners")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		return fmt.Errorf("sse content-type=%s", ct)
+	}
+	events := make(chan []byte, 10)
+	go func() {
+		defer close(events)
+		reader := bufio.NewReader(resp.Body)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if strings.HasPrefix(line, "data: ") {
+				events <- []byte(strings.TrimSpace(strings.TrimPrefix(line, "data: ")))
+			}
+		}
+	}()
+	readSse := func() ([]byte, error) {
+		select {
+		case data, ok := <-events:
+			if !ok {
+				return nil, errors.New("sse stream closed")
+			}
+			return data, nil
+		case <-time.After(5 * time.Second):
+			return nil, errors.New("sse read timeout")
+		}
+	}
+
+	check := func(from string, data []byte) (*models.ObjWinner, error) {
+		winner := &models.ObjWinner{}
+		if err := json.Unmarshal(data, winner); err != nil {
+			return nil, fmt.Errorf("%s winner json error=%s data=%s", from, err, data)
+		}
+		if !strings.Contains(winner.Username, "***") || strings.Contains(string(data), "admin-") ||
+			strings.Contains(string(data), "uid") {
+			return nil, fmt.Errorf("%s winner not masked data=%s", from, data)
+		}
+		return winner, nil
+	}
+	for _, read := range []func() ([]byte, error){readWs, readSse} {
+		data, err := read()
+		if err != nil {
+			return err
+		}
+		if _, err = check("backlog", data); err != nil {
+			return err
+		}
+	}
+	if err = h.luckyWin(); err != nil {
+		return err
+	}
+	var ids []int
+	for from, read := range map[string]func() ([]byte, error){"ws": readWs, "sse": readSse} {
+		data, err := read()
+		if err != nil {
+			return fmt.Errorf("%s %s", from, err)
+		}
+		winner, err := check(from, data)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, winner.Id)
+	}
+	if ids[0] != ids[1] {
+		return fmt.Errorf("ws and sse got different winners %v", ids)
+	}
+
+	rs := struct {
+		Code      int                `json:"code"`
+		PrizeList []models.ObjWinner `json:"prize_list"`
+	}{}
+	if err = h.newClient().getJSON("/newprize", &rs); err != nil {
+		return err
+	}
+	if len(rs.PrizeList) != 2 || rs.PrizeList[0].Id != ids[0] {
+		return fmt.Errorf("newprize list=%+v, want latest id=%d", rs.PrizeList, ids[0])
+	}
+	for _, winner := range rs.PrizeList {
+		if !strings.Contains(winner.Username, "***") {
+			return fmt.Errorf("newprize winner not masked %+v", winner)
+		}
+	}
+	return nil
+}
+
+// 中奖榜单按照配置打码，用户可以设置不在榜单中展示
+func testWinnerPrivacy(h *harness) error {
+	defer func(show bool) { conf.WinnerPublicGtypes[conf.GtypeGiftSmall] = show }(conf.WinnerPublicGtypes[conf.GtypeGiftSmall])
+	if _, err := h.seedGift("small", conf.GtypeGiftSmall, 100, "0-9999"); err != nil {
+		return err
+	}
+	hidden, shown := h.newClient(), h.newClient()
+	for _, c := range []*client{hidden, shown} {
+		if err := c.login(); err != nil {
+			return err
+		}
+	}
+	setHidewin := func(hidewin string) error {
+		rs := struct {
+			Code    int `json:"code"`
+			Hidewin int `json:"hidewin"`
+		}{}
+		if err := hidden.postJSON("/privacy", url.Values{"hidewin": {hidewin}}, &rs); err != nil {
+			return err
+		}
+		if rs.Code != 0 || strconv.Itoa(rs.Hidewin) != hidewin {
+			return fmt.Errorf("privacy hidewin=%s got %+v", hidewin, rs)
+		}
+		return nil
+	}
+	newprize := func() ([]models.ObjWinner, error) {
+		rs := struct {
+			Code      int                `json:"code"`
+			PrizeList []models.ObjWinner `json:"prize_list"`
+		}{}
+		err := h.newClient().getJSON("/newprize", &rs)
+		return rs.PrizeList, err
+	}
+	if err := setHidewin("1"); err != nil {
+		return err
+	}
+	for _, c := range []*client{hidden, shown} {
+		if err := c.luckyWin(); err != nil {
+			return err
+		}
+	}
+	list, err := newprize()
+	if err != nil {
+		return err
+	}
+	masked := regexp.MustCompile(`^adm\*\*\*[0-9]{1,2}$`)
+	if len(list) != 1 || !masked.MatchString(list[0].Username) {
+		return fmt.Errorf("newprize with hidden user = %+v", list)
+	}
+	// 重新展示之后，数据库中的中奖记录也会出现在榜单中
+	if err = setHidewin("0"); err != nil {
+		return err
+	}
+	if list, err = newprize(); err != nil {
+		return err
+	}
+	if len(list) != 2 {
+		return fmt.Errorf("newprize after showing again = %+v", list)
+	}
+	// 不需要展示的奖品类型不进入榜单
+	conf.WinnerPublicGtypes[conf.GtypeGiftSmall] = false
+	if err = h.luckyWin(); err != nil {
+		return err
+	}
+	if list, err = newprize(); err != nil {
+		return err
+	}
+	if len(list) != 2 {
+		return fmt.Errorf("newprize lists hidden gift type = %+v", list)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package models
+
+type LtChance struct {
+	Id         int    `xorm:"not null pk autoincr INT(10)" json:"id"`
+	Uid        int    `xorm:"not null default 0 comment('用户ID') index INT(10)" json:"-"`
+	Source     int    `xorm:"not null default 0 comment('来源，1 每日签到，2 邀请用户，3 分享，4 外部活动') unique(source_ref) SMALLINT(5)" json:"source"`
+	Ref        string `xorm:"not null default '' comment('来源的唯一标识，同一个来源不重复发放') unique(source_ref) VARCHAR(100)" json:"-"`
+	Num        int    `xorm:"not null default 0 comment('获得的抽奖次数') INT(10)" json:"num"`
+	LeftNum    int    `xorm:"not null default 0 comment('剩余的抽奖次数') INT(10)" json:"left_num"`
+	Expire     int    `xorm:"not null default 0 comment('到期时间') INT(10)" json:"expire"`
+	Remark     string `xorm:"not null default '' comment('说明') VARCHAR(255)" json:"remark"`
+	SysCreated int    `xorm:"not null default 0 comment('创建时间') INT(10)" json:"sys_created"`
+	SysUpdated int    `xorm:"not null default 0 comment('修改时间') INT(10)" json:"-"`
+}
@@ -3,8 +3,14 @@ package models
 type LtCode struct {
 	Id         int    `xorm:"not null pk autoincr INT(10)"`
 	GiftId     int    `xorm:"not null default 0 comment('奖品ID，关联lt_gift表') INT(10)"`
-	Code       string `xorm:"not null default '' comment('虚拟券编码') VARCHAR(255)"`
+	Code       string `xorm:"not null default '' comment('虚拟券编码，加密保存') VARCHAR(512)"`
+	CodeHash   string `xorm:"not null default '' comment('编码的HMAC，用来查找编码，没有加密的旧数据是legacy-id') unique VARCHAR(64)"`
+	KeyId      int    `xorm:"not null default 0 comment('加密密钥的版本，0 没有加密的旧数据') INT(10)"`
+	ValidFrom  int    `xorm:"not null default 0 comment('有效期开始时间，0 不限制') INT(10)"`
+	ValidTo    int    `xorm:"not null default 0 comment('有效期结束时间，0 不限制') INT(10)"`
+	RedeemedAt int    `xorm:"not null default 0 comment('核销时间') INT(10)"`
+	RedeemRef  string `xorm:"not null default '' comment('合作方的核销单号') VARCHAR(64)"`
 	SysCreated int    `xorm:"not null default 0 comment('创建时间') INT(10)"`
 	SysUpdated int    `xorm:"not null default 0 comment('更新时间') INT(10)"`
-	SysStatus  int    `xorm:"not null default 0 comment('状态，0正常，1作废，2已发放') SMALLINT(5)"`
+	SysStatus  int    `xorm:"not null default 0 comment('状态，0正常，1作废，2已发放，3已核销，4已过期') SMALLINT(5)"`
 }
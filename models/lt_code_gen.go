@@ -0,0 +1,14 @@
+package models
+
+type LtCodeGen struct {
+	Id         int    `xorm:"not null pk autoincr INT(10)"`
+	GiftId     int    `xorm:"not null default 0 comment('奖品ID，关联lt_gift表') unique INT(10)"`
+	Prefix     string `xorm:"not null default '' comment('编码前缀') VARCHAR(50)"`
+	Alphabet   string `xorm:"not null default '' comment('编码使用的字符') VARCHAR(100)"`
+	Length     int    `xorm:"not null default 0 comment('随机部分的长度，不包括前缀和校验位') INT(10)"`
+	Checksum   int    `xorm:"not null default 0 comment('是否添加校验位，0 否，1 是') SMALLINT(5)"`
+	Threshold  int    `xorm:"not null default 0 comment('剩余编码少于这个数量时自动补充，0 不自动补充') INT(10)"`
+	TopupNum   int    `xorm:"not null default 0 comment('每次自动补充的数量') INT(10)"`
+	SysCreated int    `xorm:"not null default 0 comment('创建时间') INT(10)"`
+	SysUpdated int    `xorm:"not null default 0 comment('修改时间') INT(10)"`
+}
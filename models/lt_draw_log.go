@@ -0,0 +1,14 @@
+package models
+
+type LtDrawLog struct {
+	Id         int    `xorm:"not null pk autoincr INT(10)" json:"id"`
+	Uid        int    `xorm:"not null default 0 comment('用户ID') index INT(10)" json:"-"`
+	Code       int    `xorm:"not null default 0 comment('抽奖结果，0 中奖，其他同抽奖接口的返回码') INT(10)" json:"code"`
+	Msg        string `xorm:"not null default '' comment('抽奖结果的说明') VARCHAR(255)" json:"msg"`
+	GiftId     int    `xorm:"not null default 0 comment('中奖的奖品ID，关联lt_gift表') INT(10)" json:"gift_id"`
+	GiftName   string `xorm:"not null default '' comment('中奖的奖品名称') VARCHAR(255)" json:"gift_name"`
+	ResultId   int    `xorm:"not null default 0 comment('中奖记录ID，关联lt_result表') INT(10)" json:"result_id"`
+	SysCreated int    `xorm:"not null default 0 comment('抽奖时间') INT(10)" json:"sys_created"`
+	SysIp      string `xorm:"not null default '' comment('用户抽奖的IP') VARCHAR(50)" json:"-"`
+	Device     string `xorm:"not null default '' comment('用户抽奖的设备标识') VARCHAR(64)" json:"-"`
+}
@@ -0,0 +1,19 @@
+package models
+
+type LtFulfill struct {
+	Id          int    `xorm:"not null pk autoincr INT(10)" json:"id"`
+	ResultId    int    `xorm:"not null default 0 comment('中奖记录ID，关联lt_result表') unique INT(10)" json:"result_id"`
+	Uid         int    `xorm:"not null default 0 comment('用户ID') index INT(10)" json:"-"`
+	GiftId      int    `xorm:"not null default 0 comment('奖品ID，关联lt_gift表') INT(10)" json:"gift_id"`
+	GiftName    string `xorm:"not null default '' comment('奖品名称') VARCHAR(255)" json:"gift_name"`
+	Status      int    `xorm:"not null default 0 comment('发货状态，1 等待填写地址，2 等待发货，3 已发货，4 已签收，5 已过期') SMALLINT(5)" json:"status"`
+	Realname    string `xorm:"not null default '' comment('联系人') VARCHAR(50)" json:"realname"`
+	Mobile      string `xorm:"not null default '' comment('手机号') VARCHAR(50)" json:"mobile"`
+	Address     string `xorm:"not null default '' comment('联系地址') VARCHAR(255)" json:"address"`
+	Carrier     string `xorm:"not null default '' comment('快递公司') VARCHAR(50)" json:"carrier"`
+	TrackingNo  string `xorm:"not null default '' comment('快递单号') VARCHAR(50)" json:"tracking_no"`
+	ShippedAt   int    `xorm:"not null default 0 comment('发货时间') INT(10)" json:"shipped_at"`
+	DeliveredAt int    `xorm:"not null default 0 comment('签收时间') INT(10)" json:"delivered_at"`
+	SysCreated  int    `xorm:"not null default 0 comment('创建时间') INT(10)" json:"sys_created"`
+	SysUpdated  int    `xorm:"not null default 0 comment('修改时间') INT(10)" json:"-"`
+}
@@ -11,6 +11,7 @@ type LtGift struct {
 	Displayorder int    `xorm:"not null default 0 comment('位置序号，小的排在前面') INT(10)" json:"displayorder"`
 	Gtype        int    `xorm:"not null default 0 comment('奖品类型，0 虚拟币，1 虚拟券，2 实物-小奖，3 实物-大奖') INT(10)" json:"gtype"`
 	Gdata        string `xorm:"not null default '' comment('扩展数据，如：虚拟币数量') VARCHAR(255)" json:"-"`
+	Fallback     int    `xorm:"not null default 0 comment('安慰奖，0 正常奖品，1 没有中奖时发放的安慰奖') SMALLINT(5)" json:"-"`
 	TimeBegin    int    `xorm:"not null default 0 comment('开始时间') INT(11)" json:"-"`
 	TimeEnd      int    `xorm:"not null default 0 comment('结束时间') INT(11)" json:"-"`
 	PrizeData    string `xorm:"comment('发奖计划，[[时间1,数量1],[时间2,数量2]]') MEDIUMTEXT" json:"-"`
@@ -0,0 +1,14 @@
+package models
+
+type LtPity struct {
+	Id         int    `xorm:"not null pk autoincr INT(10)"`
+	Title      string `xorm:"not null default '' comment('规则名称') VARCHAR(255)"`
+	Gtype      int    `xorm:"not null default 0 comment('保底的奖品类型，同lt_gift. gtype') INT(10)"`
+	Num        int    `xorm:"not null default 0 comment('连续没有中奖的次数达到N之后生效') INT(10)"`
+	Action     int    `xorm:"not null default 0 comment('处理方式，1 提高概率，2 必定中奖') SMALLINT(5)"`
+	Boost      int    `xorm:"not null default 0 comment('提高概率时，每多一次没有中奖增加的概率，万分之几') INT(10)"`
+	SysStatus  int    `xorm:"not null default 0 comment('状态，0 正常，1 删除') SMALLINT(5)"`
+	SysCreated int    `xorm:"not null default 0 comment('创建时间') INT(10)"`
+	SysUpdated int    `xorm:"not null default 0 comment('修改时间') INT(10)"`
+	SysIp      string `xorm:"not null default '' comment('操作人IP') VARCHAR(50)"`
+}
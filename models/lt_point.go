@@ -0,0 +1,9 @@
+package models
+
+type LtPoint struct {
+	Id         int `xorm:"not null pk autoincr INT(10)" json:"-"`
+	Uid        int `xorm:"not null default 0 comment('用户ID') unique INT(10)" json:"uid"`
+	Balance    int `xorm:"not null default 0 comment('积分余额') INT(10)" json:"balance"`
+	SysCreated int `xorm:"not null default 0 comment('创建时间') INT(10)" json:"-"`
+	SysUpdated int `xorm:"not null default 0 comment('修改时间') INT(10)" json:"sys_updated"`
+}
@@ -0,0 +1,12 @@
+package models
+
+type LtPointLog struct {
+	Id         int    `xorm:"not null pk autoincr INT(10)" json:"id"`
+	Uid        int    `xorm:"not null default 0 comment('用户ID') index INT(10)" json:"-"`
+	Action     int    `xorm:"not null default 0 comment('类型，1 获得，2 抽奖消耗，3 抽奖失败退回') unique(action_ref) SMALLINT(5)" json:"action"`
+	Ref        string `xorm:"not null default '' comment('唯一标识，退回的时候和消耗的相同') unique(action_ref) VARCHAR(100)" json:"-"`
+	Num        int    `xorm:"not null default 0 comment('积分变化，消耗的时候是负数') INT(10)" json:"num"`
+	Balance    int    `xorm:"not null default 0 comment('变化之后的积分余额') INT(10)" json:"balance"`
+	Remark     string `xorm:"not null default '' comment('说明') VARCHAR(255)" json:"remark"`
+	SysCreated int    `xorm:"not null default 0 comment('创建时间') INT(10)" json:"sys_created"`
+}
@@ -1,15 +1,24 @@
 package models
 
 type LtResult struct {
-	Id         int    `xorm:"not null pk autoincr INT(10)" json:"-"`
-	GiftId     int    `xorm:"not null default 0 comment('奖品ID，关联lt_gift表') INT(10)" json:"gift_id"`
-	GiftName   string `xorm:"not null default '' comment('奖品名称') VARCHAR(255)" json:"gift_name"`
-	GiftType   int    `xorm:"not null default 0 comment('奖品类型，同lt_gift. gtype') INT(10)" json:"gift_type"`
-	Uid        int    `xorm:"not null default 0 comment('用户ID') INT(10)" json:"uid"`
-	Username   string `xorm:"not null default '' comment('用户名') VARCHAR(50)" json:"username"`
-	PrizeCode  int    `xorm:"not null default 0 comment('抽奖编号（4位的随机数）') INT(10)" json:"-"`
-	GiftData   string `xorm:"not null default '' comment('获奖信息') VARCHAR(255)" json:"-"`
-	SysCreated int    `xorm:"not null default 0 comment('创建时间') INT(10)" json:"-"`
-	SysIp      string `xorm:"not null default '' comment('用户抽奖的IP') VARCHAR(50)" json:"-"`
-	SysStatus  int    `xorm:"not null default 0 comment('状态，0 正常，1删除，2作弊') SMALLINT(5)" json:"-"`
+	Id            int    `xorm:"not null pk autoincr INT(10)" json:"-"`
+	GiftId        int    `xorm:"not null default 0 comment('奖品ID，关联lt_gift表') INT(10)" json:"gift_id"`
+	GiftName      string `xorm:"not null default '' comment('奖品名称') VARCHAR(255)" json:"gift_name"`
+	GiftType      int    `xorm:"not null default 0 comment('奖品类型，同lt_gift. gtype') INT(10)" json:"gift_type"`
+	Uid           int    `xorm:"not null default 0 comment('用户ID') INT(10)" json:"uid"`
+	Username      string `xorm:"not null default '' comment('用户名') VARCHAR(50)" json:"username"`
+	PrizeCode     int    `xorm:"not null default 0 comment('抽奖编号（4位的随机数）') INT(10)" json:"-"`
+	GiftData      string `xorm:"not null default '' comment('获奖信息，不同编码的优惠券保存在code_id') VARCHAR(255)" json:"-"`
+	SysCreated    int    `xorm:"not null default 0 comment('创建时间') INT(10)" json:"-"`
+	SysIp         string `xorm:"not null default '' comment('用户抽奖的IP') VARCHAR(50)" json:"-"`
+	SysStatus     int    `xorm:"not null default 0 comment('状态，0 正常，1删除，2作弊') SMALLINT(5)" json:"-"`
+	Device        string `xorm:"not null default '' comment('用户抽奖的设备标识') VARCHAR(64)" json:"-"`
+	ReviewStatus  int    `xorm:"not null default 0 comment('审核状态，0 没有审核，1 标记，2 调查中，3 确认作弊，4 已撤销，5 审核通过') SMALLINT(5)" json:"-"`
+	RuleId        int    `xorm:"not null default 0 comment('标记这次中奖的风控规则ID，0 没有标记') INT(10)" json:"-"`
+	DeliverStatus int    `xorm:"not null default 0 comment('虚拟币到账状态，0 不需要，1 发放中，2 已到账，3 发放失败') SMALLINT(5)" json:"-"`
+	DeliverTries  int    `xorm:"not null default 0 comment('虚拟币发放的尝试次数') INT(10)" json:"-"`
+	DeliverTime   int    `xorm:"not null default 0 comment('虚拟币最后一次发放的时间') INT(10)" json:"-"`
+	PityId        int    `xorm:"not null default 0 comment('触发的保底规则ID，0 没有触发') INT(10)" json:"-"`
+	CodeId        int    `xorm:"not null default 0 comment('发放的优惠券ID，关联lt_code表，不保存明文的编码') INT(10)" json:"-"`
+	Fallback      int    `xorm:"not null default 0 comment('安慰奖，0 正常奖品，1 没有中奖时发放的安慰奖，不计算中奖次数') SMALLINT(5)" json:"-"`
 }
@@ -0,0 +1,12 @@
+package models
+
+type LtReview struct {
+	Id         int    `xorm:"not null pk autoincr INT(10)"`
+	ResultId   int    `xorm:"not null default 0 comment('中奖记录ID，关联lt_result表') INT(10)"`
+	Action     int    `xorm:"not null default 0 comment('审核操作，1 标记，2 调查，3 确认作弊，4 撤销奖品，5 审核通过') SMALLINT(5)"`
+	Reviewer   string `xorm:"not null default '' comment('审核人') VARCHAR(50)"`
+	Reason     string `xorm:"not null default '' comment('审核原因') VARCHAR(255)"`
+	Detail     string `xorm:"not null default '' comment('撤销奖品时的处理结果') VARCHAR(255)"`
+	SysCreated int    `xorm:"not null default 0 comment('创建时间') INT(10)"`
+	SysIp      string `xorm:"not null default '' comment('审核人IP') VARCHAR(50)"`
+}
@@ -0,0 +1,14 @@
+package models
+
+type LtRule struct {
+	Id         int    `xorm:"not null pk autoincr INT(10)"`
+	Title      string `xorm:"not null default '' comment('规则名称') VARCHAR(255)"`
+	Rtype      int    `xorm:"not null default 0 comment('规则类型，1 中奖后冷却，2 IP今日用户数，3 IP今日抽奖次数，4 用户今日抽奖次数') INT(10)"`
+	Gtype      int    `xorm:"not null default 0 comment('中奖后冷却的奖品类型，同lt_gift. gtype') INT(10)"`
+	Num        int    `xorm:"not null default 0 comment('中奖后冷却的天数，其他规则的次数上限') INT(10)"`
+	Action     int    `xorm:"not null default 0 comment('处理方式，1 标记，2 降级，3 验证，4 拒绝') SMALLINT(5)"`
+	SysStatus  int    `xorm:"not null default 0 comment('状态，0 正常，1 删除') SMALLINT(5)"`
+	SysCreated int    `xorm:"not null default 0 comment('创建时间') INT(10)"`
+	SysUpdated int    `xorm:"not null default 0 comment('修改时间') INT(10)"`
+	SysIp      string `xorm:"not null default '' comment('操作人IP') VARCHAR(50)"`
+}
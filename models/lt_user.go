@@ -7,6 +7,7 @@ type LtUser struct {
 	Realname   string `xorm:"not null default '' comment('联系人') VARCHAR(50)"`
 	Mobile     string `xorm:"not null default '' comment('手机号') VARCHAR(50)"`
 	Address    string `xorm:"not null default '' comment('联系地址') VARCHAR(255)"`
+	Hidewin    int    `xorm:"not null default 0 comment('不在公开的中奖榜单中展示，0 展示，1 隐藏') SMALLINT(5)"`
 	SysCreated int    `xorm:"not null default 0 comment('创建时间') INT(10)"`
 	SysUpdated int    `xorm:"not null default 0 comment('修改时间') INT(10)"`
 	SysIp      string `xorm:"not null default '' comment('IP地址') VARCHAR(50)"`
@@ -0,0 +1,12 @@
+package models
+
+// 抽奖前需要完成的验证
+// pow：找到answer，使sha256(prefix+answer)的前difficulty位都是0
+// image：识别图片中的数字
+type ObjChallenge struct {
+	Id         string `json:"id"`
+	Type       string `json:"type"`
+	Prefix     string `json:"prefix,omitempty"`
+	Difficulty int    `json:"difficulty,omitempty"`
+	Img        string `json:"img,omitempty"`
+}
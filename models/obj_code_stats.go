@@ -0,0 +1,17 @@
+package models
+
+// 一个奖品的优惠券编码按照状态统计，以及核销率
+type ObjCodeStats struct {
+	GiftId   int
+	Normal   int64
+	Void     int64
+	Issued   int64
+	Redeemed int64
+	Expired  int64
+	// 核销率的百分比，已核销 / (已发放 + 已核销 + 已过期) * 100
+	Rate float64
+	// 缓存中可以发放的编码数量，应该和Normal一致
+	CacheNum int64
+	// 缓存和数据库不一致时的提醒
+	Alert string
+}
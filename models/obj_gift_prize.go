@@ -11,4 +11,5 @@ type ObjGiftPrize struct {
 	Displayorder int    `json:"displayorder"`
 	Gtype        int    `json:"gtype"`
 	Gdata        string `json:"gdata"`
+	Fallback     int    `json:"fallback"`
 }
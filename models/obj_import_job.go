@@ -0,0 +1,17 @@
+package models
+
+// 优惠券编码的导入任务进度
+type ObjImportJob struct {
+	Id       string
+	GiftId   int
+	Filename string
+	Status   string // running 导入中，done 已完成，failed 失败
+	Total    int    // 已经读取的行数
+	Success  int
+	Dup      int // 文件中重复或者数据库中已经存在
+	Invalid  int
+	Failed   int
+	Error    string
+	Started  int
+	Finished int
+}
@@ -0,0 +1,12 @@
+package models
+
+// 用户、IP、设备之间的关联关系，以及计算出来的风险分
+type ObjLinkage struct {
+	Uid    int
+	Ip     string
+	Device string
+	// 用户使用过的设备和IP，以及每个设备、IP上的用户
+	DeviceUids map[string][]int
+	IpUids     map[string][]int
+	Score      int
+}
@@ -0,0 +1,8 @@
+package models
+
+// 一次抽奖多次，每一次抽奖的结果
+type ObjLuckyDraw struct {
+	Code int           `json:"code"`
+	Msg  string        `json:"msg"`
+	Gift *ObjGiftPrize `json:"gift"`
+}
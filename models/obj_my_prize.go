@@ -0,0 +1,14 @@
+package models
+
+// 用户自己的中奖记录，包括券码和发货状态
+type ObjMyPrize struct {
+	Id            int        `json:"id"`
+	GiftId        int        `json:"gift_id"`
+	GiftName      string     `json:"gift_name"`
+	GiftType      int        `json:"gift_type"`
+	GiftData      string     `json:"gift_data"`
+	SysStatus     int        `json:"sys_status"`
+	DeliverStatus int        `json:"deliver_status"`
+	SysCreated    int        `json:"sys_created"`
+	Fulfill       *LtFulfill `json:"fulfill"`
+}
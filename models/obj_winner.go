@@ -0,0 +1,11 @@
+package models
+
+// 中奖动态，推送给所有用户，用户名已经打码
+type ObjWinner struct {
+	Id         int    `json:"id"`
+	GiftId     int    `json:"gift_id"`
+	GiftName   string `json:"gift_name"`
+	GiftType   int    `json:"gift_type"`
+	Username   string `json:"username"`
+	SysCreated int    `json:"sys_created"`
+}
@@ -8,6 +8,9 @@ import (
 	"github.com/iralance/go-lottery/datasource"
 	"github.com/iralance/go-lottery/models"
 	"log"
+	"net"
+	"strings"
+	"sync"
 )
 
 type BlackipService interface {
@@ -22,12 +25,22 @@ type BlackipService interface {
 }
 
 type blackipService struct {
-	dao *dao.BlackipDao
+	dao   dao.BlackipDao
+	cache datasource.Cache
+	// CIDR网段黑名单的前缀树，redis中的版本号变化之后重新加载
+	cidrLock    sync.RWMutex
+	cidrVersion string
+	cidrTrie    *comm.IpTrie
+	cidrList    []models.LtBlackip
 }
 
-func NewBlackipService() BlackipService {
+// CIDR网段黑名单的版本号，网段数据变化的时候递增
+const blackipCidrVersionKey = "blackip_cidr_version"
+
+func NewBlackipService(blackipDao dao.BlackipDao, cache datasource.Cache) BlackipService {
 	return &blackipService{
-		dao: dao.NewBlackipDao(datasource.InstanceDbMaster()),
+		dao:   blackipDao,
+		cache: cache,
 	}
 }
 
@@ -40,7 +53,7 @@ func (s *blackipService) CountAll() int64 {
 }
 
 func (s *blackipService) Search(ip string) []models.LtBlackip {
-	return s.dao.Search(ip)
+	return s.dao.Search(normalizeBlackip(ip))
 }
 
 func (s *blackipService) Get(id int) *models.LtBlackip {
@@ -48,24 +61,56 @@ func (s *blackipService) Get(id int) *models.LtBlackip {
 }
 
 func (s *blackipService) Delete(id int) error {
-	return s.dao.Delete(id)
-
+	data := s.dao.Get(id)
+	if err := s.dao.Delete(id); err != nil {
+		return err
+	}
+	// 数据库删除成功之后再清理缓存
+	s.updateByCache(data, nil)
+	return nil
 }
 
 func (s *blackipService) Update(data *models.LtBlackip, columns []string) error {
-	// 先更新缓存的数据
+	if data.Ip == "" {
+		// 只有ID的时候，需要找到IP才能清理缓存
+		if info := s.dao.Get(data.Id); info != nil {
+			data.Ip = info.Ip
+		}
+	}
+	// 先更新数据库的数据
+	if err := s.dao.Update(data, columns); err != nil {
+		return err
+	}
+	// 再更新缓存的数据，网段的版本号在数据库写入之后递增，其他服务才能加载到新的数据
 	s.updateByCache(data, columns)
-	// 再更新数据的数据
-	return s.dao.Update(data, columns)
-
+	return nil
 }
 
 func (s *blackipService) Create(data *models.LtBlackip) (int64, error) {
-	return s.dao.Create(data)
+	data.Ip = normalizeBlackip(data.Ip)
+	num, err := s.dao.Create(data)
+	if err != nil {
+		return num, err
+	}
+	s.updateByCache(data, nil)
+	return num, nil
 }
 
 // 根据IP读取IP的黑名单数据
+// 单个IP和包含它的CIDR网段中，返回黑名单到期时间最晚的一条
 func (s *blackipService) GetByIp(ip string) *models.LtBlackip {
+	if str := comm.NormalizeIp(ip); str != "" {
+		ip = str
+	}
+	data := s.getByIp(ip)
+	cidr := s.getByCidr(ip)
+	if cidr != nil && cidr.Blacktime > data.Blacktime {
+		return cidr
+	}
+	return data
+}
+
+func (s *blackipService) getByIp(ip string) *models.LtBlackip {
 	// 先从缓存中读取数据
 	data := s.getByCache(ip)
 	if data == nil || data.Ip == "" {
@@ -82,7 +127,7 @@ func (s *blackipService) GetByIp(ip string) *models.LtBlackip {
 func (s *blackipService) getByCache(ip string) *models.LtBlackip {
 	// 集群模式，redis缓存
 	key := fmt.Sprintf("info_blackip_%s", ip)
-	rds := datasource.InstanceCache()
+	rds := s.cache
 	dataMap, err := redis.StringMap(rds.Do("HGETALL", key))
 	if err != nil {
 		log.Println("blackip_service.getByCache HGETALL key=", key, ", error=", err)
@@ -108,7 +153,7 @@ func (s *blackipService) setByCache(data *models.LtBlackip) {
 	}
 	// 集群模式，redis缓存
 	key := fmt.Sprintf("info_blackip_%s", data.Ip)
-	rds := datasource.InstanceCache()
+	rds := s.cache
 	// 数据更新到redis缓存
 	params := []interface{}{key}
 	params = append(params, "Ip", data.Ip)
@@ -128,9 +173,77 @@ func (s *blackipService) updateByCache(data *models.LtBlackip, columns []string)
 	if data == nil || data.Ip == "" {
 		return
 	}
+	if strings.Contains(data.Ip, "/") {
+		// 网段数据变化，所有的服务重新加载前缀树
+		s.cache.Do("INCR", blackipCidrVersionKey)
+		return
+	}
 	// 集群模式，redis缓存
 	key := fmt.Sprintf("info_blackip_%s", data.Ip)
-	rds := datasource.InstanceCache()
+	rds := s.cache
 	// 删除redis中的缓存
 	rds.Do("DEL", key)
 }
+
+// 从CIDR网段黑名单中找到包含这个IP的数据
+func (s *blackipService) getByCidr(ip string) *models.LtBlackip {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return nil
+	}
+	s.loadCidr()
+	s.cidrLock.RLock()
+	defer s.cidrLock.RUnlock()
+	var data *models.LtBlackip
+	for _, i := range s.cidrTrie.Lookup(addr) {
+		if data == nil || s.cidrList[i].Blacktime > data.Blacktime {
+			info := s.cidrList[i]
+			data = &info
+		}
+	}
+	return data
+}
+
+// 版本号没有变化的时候，继续使用已经加载的前缀树
+func (s *blackipService) loadCidr() {
+	rs, err := s.cache.Do("GET", blackipCidrVersionKey)
+	if err != nil {
+		log.Println("blackip_service.loadCidr GET key=", blackipCidrVersionKey, ", error=", err)
+	}
+	version := comm.GetString(rs, "")
+	s.cidrLock.RLock()
+	loaded := s.cidrTrie != nil && (err != nil || version == s.cidrVersion)
+	s.cidrLock.RUnlock()
+	if loaded {
+		return
+	}
+	trie := comm.NewIpTrie()
+	datalist := make([]models.LtBlackip, 0)
+	for _, data := range s.dao.GetAllCidr() {
+		ipNet, err := comm.ParseIpNet(data.Ip)
+		if err != nil {
+			log.Println("blackip_service.loadCidr ParseIpNet ip=", data.Ip, ", error=", err)
+			continue
+		}
+		trie.Insert(ipNet, len(datalist))
+		datalist = append(datalist, data)
+	}
+	s.cidrLock.Lock()
+	s.cidrVersion = version
+	s.cidrTrie = trie
+	s.cidrList = datalist
+	s.cidrLock.Unlock()
+}
+
+// 保存的IP统一格式，单个IP是标准的地址，网段是CIDR的写法
+func normalizeBlackip(ip string) string {
+	ipNet, err := comm.ParseIpNet(ip)
+	if err != nil {
+		return ip
+	}
+	ones, bits := ipNet.Mask.Size()
+	if ones == bits {
+		return ipNet.IP.String()
+	}
+	return ipNet.String()
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/dao"
+	"github.com/iralance/go-lottery/models"
+	"log"
+)
+
+type ChanceService interface {
+	SearchValid(uid int) []models.LtChance
+	LeftNum(uid int) int
+	CountBySource(uid, source, since int) int64
+	Grant(data *models.LtChance) (bool, error)
+	Consume(uid int) bool
+}
+
+type chanceService struct {
+	dao dao.ChanceDao
+}
+
+func NewChanceService(chanceDao dao.ChanceDao) ChanceService {
+	return &chanceService{
+		dao: chanceDao,
+	}
+}
+
+func (s *chanceService) SearchValid(uid int) []models.LtChance {
+	return s.dao.SearchValid(uid, comm.NowUnix())
+}
+
+// 用户剩余的额外抽奖次数
+func (s *chanceService) LeftNum(uid int) int {
+	num := 0
+	for _, data := range s.SearchValid(uid) {
+		num += data.LeftNum
+	}
+	return num
+}
+
+func (s *chanceService) CountBySource(uid, source, since int) int64 {
+	return s.dao.CountBySource(uid, source, since)
+}
+
+// 发放抽奖机会，同一个来源的ref已经发放过的时候返回false
+func (s *chanceService) Grant(data *models.LtChance) (bool, error) {
+	if s.dao.GetByRef(data.Source, data.Ref) != nil {
+		return false, nil
+	}
+	data.LeftNum = data.Num
+	if data.SysCreated == 0 {
+		data.SysCreated = comm.NowUnix()
+	}
+	if _, err := s.dao.Create(data); err != nil {
+		// 并发的发放已经写入了
+		if s.dao.GetByRef(data.Source, data.Ref) != nil {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// 使用一次额外的抽奖机会，先使用快要过期的
+func (s *chanceService) Consume(uid int) bool {
+	now := comm.NowUnix()
+	for _, data := range s.dao.SearchValid(uid, now) {
+		ok, err := s.dao.Consume(data.Id, now)
+		if err != nil {
+			log.Println("chance_service.Consume error=", err)
+			return false
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
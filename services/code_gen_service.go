@@ -0,0 +1,40 @@
+package services
+
+import (
+	"github.com/iralance/go-lottery/dao"
+	"github.com/iralance/go-lottery/models"
+)
+
+// 优惠券编码的生成规则，每个奖品一条
+type CodeGenService interface {
+	GetByGift(giftId int) *models.LtCodeGen
+	GetAll() []models.LtCodeGen
+	Create(data *models.LtCodeGen) (int64, error)
+	Update(data *models.LtCodeGen, columns []string) error
+}
+
+type codeGenService struct {
+	dao dao.CodeGenDao
+}
+
+func NewCodeGenService(codeGenDao dao.CodeGenDao) CodeGenService {
+	return &codeGenService{
+		dao: codeGenDao,
+	}
+}
+
+func (s *codeGenService) GetByGift(giftId int) *models.LtCodeGen {
+	return s.dao.GetByGift(giftId)
+}
+
+func (s *codeGenService) GetAll() []models.LtCodeGen {
+	return s.dao.GetAll()
+}
+
+func (s *codeGenService) Create(data *models.LtCodeGen) (int64, error) {
+	return s.dao.Create(data)
+}
+
+func (s *codeGenService) Update(data *models.LtCodeGen, columns []string) error {
+	return s.dao.Update(data, columns)
+}
@@ -1,9 +1,12 @@
 package services
 
 import (
+	"fmt"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
 	"github.com/iralance/go-lottery/dao"
-	"github.com/iralance/go-lottery/datasource"
 	"github.com/iralance/go-lottery/models"
+	"log"
 )
 
 type CodeService interface {
@@ -11,21 +14,35 @@ type CodeService interface {
 	CountAll() int64
 	CountByGift(giftId int) int64
 	Search(giftId int) []models.LtCode
+	SearchPage(giftId, status, page, size int) []models.LtCode
+	CountPage(giftId, status int) int64
 	Get(id int) *models.LtCode
 	Delete(id int) error
 	Update(data *models.LtCode, columns []string) error
 	Create(data *models.LtCode) (int64, error)
 	NextUsingCode(giftId, codeId int) *models.LtCode
 	UpdateByCode(data *models.LtCode, columns []string) error
+	GetByCode(code string) *models.LtCode
+	GetByHash(hash string) *models.LtCode
+	PlainCode(data *models.LtCode) string
+	SearchExpired(now, size int) []models.LtCode
+	UpdateStatus(data *models.LtCode, fromStatus []int, columns []string) (bool, error)
+	Redeem(code, ref string) (int, string)
+	Stats(giftId int) *models.ObjCodeStats
+	SearchExistCodes(codes []string) []string
+	CreateBatch(datalist []models.LtCode) (int64, error)
+	SearchOldKey(lastId, size int) []models.LtCode
+	CountOldKey() int64
+	Reencrypt(data *models.LtCode) error
 }
 
 type codeService struct {
-	dao *dao.CodeDao
+	dao dao.CodeDao
 }
 
-func NewCodeService() CodeService {
+func NewCodeService(codeDao dao.CodeDao) CodeService {
 	return &codeService{
-		dao: dao.NewCodeDao(datasource.InstanceDbMaster()),
+		dao: codeDao,
 	}
 }
 
@@ -46,6 +63,15 @@ func (s *codeService) Search(giftId int) []models.LtCode {
 	return s.dao.Search(giftId)
 }
 
+// 分页查询，giftId为0的时候不区分奖品，status小于0的时候不区分状态
+func (s *codeService) SearchPage(giftId, status, page, size int) []models.LtCode {
+	return s.dao.SearchPage(giftId, status, page, size)
+}
+
+func (s *codeService) CountPage(giftId, status int) int64 {
+	return s.dao.CountPage(giftId, status)
+}
+
 func (s *codeService) Get(id int) *models.LtCode {
 	return s.dao.Get(id)
 }
@@ -60,7 +86,11 @@ func (s *codeService) Update(data *models.LtCode, columns []string) error {
 
 }
 
+// 新增的编码是明文，加密之后保存
 func (s *codeService) Create(data *models.LtCode) (int64, error) {
+	if err := s.encrypt(data); err != nil {
+		return 0, err
+	}
 	return s.dao.Create(data)
 }
 
@@ -68,6 +98,171 @@ func (s *codeService) NextUsingCode(giftId, codeId int) *models.LtCode {
 	return s.dao.NextUsingCode(giftId, codeId)
 }
 
+// 根据明文的编码来更新，编码本身不会被修改
 func (s *codeService) UpdateByCode(data *models.LtCode, columns []string) error {
-	return s.dao.UpdateByCode(data, columns)
+	info := s.GetByCode(data.Code)
+	if info == nil {
+		return nil
+	}
+	update := *data
+	update.Id = info.Id
+	update.Code = ""
+	return s.dao.Update(&update, columns)
+}
+
+// 根据明文的编码查找，先查找加密的编码，再查找没有加密的旧数据
+func (s *codeService) GetByCode(code string) *models.LtCode {
+	if data := s.dao.GetByHash(comm.CodeHash(code)); data != nil {
+		return data
+	}
+	return s.dao.GetByCode(code)
+}
+
+func (s *codeService) GetByHash(hash string) *models.LtCode {
+	return s.dao.GetByHash(hash)
+}
+
+// 解密得到明文的编码，只在发放和后台显示的时候使用
+func (s *codeService) PlainCode(data *models.LtCode) string {
+	if data.KeyId == 0 {
+		return data.Code
+	}
+	code, err := comm.CodeDecrypt(data.Code, data.KeyId)
+	if err != nil {
+		log.Println("code_service.PlainCode id=", data.Id, ", error=", err)
+		return ""
+	}
+	return code
+}
+
+func (s *codeService) SearchExpired(now, size int) []models.LtCode {
+	return s.dao.SearchExpired(now, size)
+}
+
+func (s *codeService) UpdateStatus(data *models.LtCode, fromStatus []int, columns []string) (bool, error) {
+	return s.dao.UpdateStatus(data, fromStatus, columns)
+}
+
+// 合作方核销优惠券，同一个核销单号重复核销返回成功
+// 返回，错误码（0 成功），错误信息
+func (s *codeService) Redeem(code, ref string) (int, string) {
+	// 核销单号保存在redeem_ref，不能超过64个字符
+	if ref == "" || len(ref) > 64 {
+		return 400, "核销单号错误"
+	}
+	data := s.GetByCode(code)
+	if data == nil {
+		return 401, "优惠券不存在"
+	}
+	now := comm.NowUnix()
+	switch data.SysStatus {
+	case conf.CodeStatusIssued:
+	case conf.CodeStatusRedeemed:
+		if data.RedeemRef == ref {
+			return 0, ""
+		}
+		return 403, "优惠券已经核销"
+	case conf.CodeStatusExpired:
+		return 404, "优惠券已经过期"
+	default:
+		return 402, "优惠券还没有发放"
+	}
+	if data.ValidFrom > 0 && now < data.ValidFrom {
+		return 405, "优惠券还没有到使用时间"
+	}
+	if data.ValidTo > 0 && now > data.ValidTo {
+		return 404, "优惠券已经过期"
+	}
+	ok, err := s.dao.UpdateStatus(&models.LtCode{
+		Id:         data.Id,
+		SysStatus:  conf.CodeStatusRedeemed,
+		RedeemedAt: now,
+		RedeemRef:  ref,
+		SysUpdated: now,
+	}, []int{conf.CodeStatusIssued}, []string{"sys_status", "redeemed_at", "redeem_ref", "sys_updated"})
+	if err != nil {
+		log.Println("code_service.Redeem UpdateStatus error=", err)
+		return 500, "核销失败，请重试"
+	}
+	if !ok {
+		// 并发的核销或者过期已经修改了状态，重新判断一次
+		return s.Redeem(code, ref)
+	}
+	return 0, ""
+}
+
+func (s *codeService) Stats(giftId int) *models.ObjCodeStats {
+	nums := s.dao.CountGroupStatus(giftId)
+	stats := &models.ObjCodeStats{
+		GiftId:   giftId,
+		Normal:   nums[conf.CodeStatusNormal],
+		Void:     nums[conf.CodeStatusVoid],
+		Issued:   nums[conf.CodeStatusIssued],
+		Redeemed: nums[conf.CodeStatusRedeemed],
+		Expired:  nums[conf.CodeStatusExpired],
+	}
+	if total := stats.Issued + stats.Redeemed + stats.Expired; total > 0 {
+		stats.Rate = float64(stats.Redeemed) * 100 / float64(total)
+	}
+	return stats
+}
+
+// 已经存在的明文编码，包括加密的编码和没有加密的旧数据
+func (s *codeService) SearchExistCodes(codes []string) []string {
+	hashes := make(map[string]string)
+	list := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hash := comm.CodeHash(code)
+		hashes[hash] = code
+		list = append(list, hash)
+	}
+	exists := s.dao.SearchExistCodes(codes)
+	for _, hash := range s.dao.SearchExistHashes(list) {
+		exists = append(exists, hashes[hash])
+	}
+	return exists
+}
+
+// 批量新增明文的编码，datalist中的编码会被替换成密文
+func (s *codeService) CreateBatch(datalist []models.LtCode) (int64, error) {
+	for i := range datalist {
+		if err := s.encrypt(&datalist[i]); err != nil {
+			return 0, err
+		}
+	}
+	return s.dao.CreateBatch(datalist)
+}
+
+// 不是使用当前密钥加密的编码，包括没有加密的旧数据
+func (s *codeService) SearchOldKey(lastId, size int) []models.LtCode {
+	return s.dao.SearchOldKey(conf.CodeKeyCurrent, lastId, size)
+}
+
+func (s *codeService) CountOldKey() int64 {
+	return s.dao.CountOldKey(conf.CodeKeyCurrent)
+}
+
+// 使用当前的密钥重新加密
+func (s *codeService) Reencrypt(data *models.LtCode) error {
+	code := s.PlainCode(data)
+	if code == "" {
+		return fmt.Errorf("code %d decrypt failed", data.Id)
+	}
+	update := &models.LtCode{Id: data.Id, Code: code}
+	if err := s.encrypt(update); err != nil {
+		return err
+	}
+	return s.dao.Update(update, []string{"code", "code_hash", "key_id"})
+}
+
+// 明文的编码加密，同时计算查找用的HMAC
+func (s *codeService) encrypt(data *models.LtCode) error {
+	text, keyId, err := comm.CodeEncrypt(data.Code)
+	if err != nil {
+		return err
+	}
+	data.CodeHash = comm.CodeHash(data.Code)
+	data.Code = text
+	data.KeyId = keyId
+	return nil
 }
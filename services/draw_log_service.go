@@ -0,0 +1,39 @@
+package services
+
+import (
+	"github.com/iralance/go-lottery/dao"
+	"github.com/iralance/go-lottery/models"
+)
+
+type DrawLogService interface {
+	SearchByUser(uid, page, size int) []models.LtDrawLog
+	CountByUser(uid int) int64
+	Create(data *models.LtDrawLog) (int64, error)
+	CreateBatch(datalist []models.LtDrawLog) (int64, error)
+}
+
+type drawLogService struct {
+	dao dao.DrawLogDao
+}
+
+func NewDrawLogService(drawLogDao dao.DrawLogDao) DrawLogService {
+	return &drawLogService{
+		dao: drawLogDao,
+	}
+}
+
+func (s *drawLogService) SearchByUser(uid, page, size int) []models.LtDrawLog {
+	return s.dao.SearchByUser(uid, page, size)
+}
+
+func (s *drawLogService) CountByUser(uid int) int64 {
+	return s.dao.CountByUser(uid)
+}
+
+func (s *drawLogService) Create(data *models.LtDrawLog) (int64, error) {
+	return s.dao.Create(data)
+}
+
+func (s *drawLogService) CreateBatch(datalist []models.LtDrawLog) (int64, error) {
+	return s.dao.CreateBatch(datalist)
+}
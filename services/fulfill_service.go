@@ -0,0 +1,151 @@
+package services
+
+import (
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/dao"
+	"github.com/iralance/go-lottery/models"
+	"log"
+)
+
+type FulfillService interface {
+	Get(id int) *models.LtFulfill
+	GetByResult(resultId int) *models.LtFulfill
+	GetAll(status, page, size int) []models.LtFulfill
+	CountAll(status int) int64
+	SearchByUser(uid int) []models.LtFulfill
+	Create(data *models.LtFulfill) (int64, error)
+	SaveAddress(uid int, realname, mobile, address string) int
+	Ship(id int, carrier, trackingNo string) bool
+	Deliver(id int) bool
+	Cancel(resultId int) bool
+	ExpirePending(before int) int
+}
+
+type fulfillService struct {
+	dao dao.FulfillDao
+}
+
+func NewFulfillService(fulfillDao dao.FulfillDao) FulfillService {
+	return &fulfillService{
+		dao: fulfillDao,
+	}
+}
+
+func (s *fulfillService) Get(id int) *models.LtFulfill {
+	return s.dao.Get(id)
+}
+
+func (s *fulfillService) GetByResult(resultId int) *models.LtFulfill {
+	return s.dao.GetByResult(resultId)
+}
+
+func (s *fulfillService) GetAll(status, page, size int) []models.LtFulfill {
+	return s.dao.GetAll(status, page, size)
+}
+
+func (s *fulfillService) CountAll(status int) int64 {
+	return s.dao.CountAll(status)
+}
+
+func (s *fulfillService) SearchByUser(uid int) []models.LtFulfill {
+	return s.dao.SearchByUser(uid)
+}
+
+func (s *fulfillService) Create(data *models.LtFulfill) (int64, error) {
+	return s.dao.Create(data)
+}
+
+// 用户填写收货地址，还在等待发货的都使用新地址，返回更新的数量
+func (s *fulfillService) SaveAddress(uid int, realname, mobile, address string) int {
+	num := 0
+	for _, data := range s.dao.SearchByUser(uid) {
+		if data.Status != conf.FulfillPendingAddress && data.Status != conf.FulfillReady {
+			continue
+		}
+		ok, err := s.dao.UpdateStatus(&models.LtFulfill{
+			Id:         data.Id,
+			Status:     conf.FulfillReady,
+			Realname:   realname,
+			Mobile:     mobile,
+			Address:    address,
+			SysUpdated: comm.NowUnix(),
+		}, []int{conf.FulfillPendingAddress, conf.FulfillReady},
+			[]string{"status", "realname", "mobile", "address", "sys_updated"})
+		if err != nil {
+			log.Println("fulfill_service.SaveAddress UpdateStatus error=", err)
+		}
+		if ok {
+			num++
+		}
+	}
+	return num
+}
+
+// 发货，只有等待发货的才可以
+func (s *fulfillService) Ship(id int, carrier, trackingNo string) bool {
+	now := comm.NowUnix()
+	ok, err := s.dao.UpdateStatus(&models.LtFulfill{
+		Id:         id,
+		Status:     conf.FulfillShipped,
+		Carrier:    carrier,
+		TrackingNo: trackingNo,
+		ShippedAt:  now,
+		SysUpdated: now,
+	}, []int{conf.FulfillReady}, []string{"status", "carrier", "tracking_no", "shipped_at", "sys_updated"})
+	if err != nil {
+		log.Println("fulfill_service.Ship UpdateStatus error=", err)
+	}
+	return ok
+}
+
+// 签收，只有已发货的才可以
+func (s *fulfillService) Deliver(id int) bool {
+	now := comm.NowUnix()
+	ok, err := s.dao.UpdateStatus(&models.LtFulfill{
+		Id:          id,
+		Status:      conf.FulfillDelivered,
+		DeliveredAt: now,
+		SysUpdated:  now,
+	}, []int{conf.FulfillShipped}, []string{"status", "delivered_at", "sys_updated"})
+	if err != nil {
+		log.Println("fulfill_service.Deliver UpdateStatus error=", err)
+	}
+	return ok
+}
+
+// 中奖记录撤销之后，还没有发货的不再发货
+func (s *fulfillService) Cancel(resultId int) bool {
+	data := s.dao.GetByResult(resultId)
+	if data == nil {
+		return false
+	}
+	ok, err := s.dao.UpdateStatus(&models.LtFulfill{
+		Id:         data.Id,
+		Status:     conf.FulfillExpired,
+		SysUpdated: comm.NowUnix(),
+	}, []int{conf.FulfillPendingAddress, conf.FulfillReady}, []string{"status", "sys_updated"})
+	if err != nil {
+		log.Println("fulfill_service.Cancel UpdateStatus error=", err)
+	}
+	return ok
+}
+
+// 在before之前中奖，一直没有填写地址的都过期，返回过期的数量
+func (s *fulfillService) ExpirePending(before int) int {
+	num := 0
+	for _, data := range s.dao.SearchExpired(conf.FulfillPendingAddress, before) {
+		ok, err := s.dao.UpdateStatus(&models.LtFulfill{
+			Id:         data.Id,
+			Status:     conf.FulfillExpired,
+			SysUpdated: comm.NowUnix(),
+		}, []int{conf.FulfillPendingAddress}, []string{"status", "sys_updated"})
+		if err != nil {
+			log.Println("fulfill_service.ExpirePending UpdateStatus error=", err)
+		}
+		if ok {
+			num++
+		}
+	}
+	return num
+}
@@ -24,12 +24,14 @@ type GiftService interface {
 }
 
 type giftService struct {
-	dao *dao.GiftDao
+	dao   dao.GiftDao
+	cache datasource.Cache
 }
 
-func NewGiftService() GiftService {
+func NewGiftService(giftDao dao.GiftDao, cache datasource.Cache) GiftService {
 	return &giftService{
-		dao: dao.NewGiftDao(datasource.InstanceDbMaster()),
+		dao:   giftDao,
+		cache: cache,
 	}
 }
 
@@ -74,10 +76,11 @@ func (s *giftService) Update(data *models.LtGift, columns []string) error {
 }
 
 func (s *giftService) Create(data *models.LtGift) (int64, error) {
-	// 先更新缓存
+	// 先更新数据库，新增之后才有id
+	num, err := s.dao.Create(data)
+	// 再更新缓存
 	s.updateByCache(data, nil)
-	// 再更新数据库
-	return s.dao.Create(data)
+	return num, err
 }
 
 func (s *giftService) GetAllUse(useCache bool) []models.ObjGiftPrize {
@@ -121,6 +124,7 @@ func (s *giftService) GetAllUse(useCache bool) []models.ObjGiftPrize {
 						Displayorder: gift.Displayorder,
 						Gtype:        gift.Gtype,
 						Gdata:        gift.Gdata,
+						Fallback:     gift.Fallback,
 					}
 					gifts = append(gifts, data)
 				}
@@ -144,7 +148,7 @@ func (s *giftService) DecrLeftNum(id, num int) (int64, error) {
 func (s *giftService) getAllByCache() []models.LtGift {
 	// 集群模式，redis缓存
 	key := "allgift"
-	rds := datasource.InstanceCache()
+	rds := s.cache
 	// 读取缓存
 	rs, err := rds.Do("GET", key)
 	if err != nil {
@@ -181,6 +185,7 @@ func (s *giftService) getAllByCache() []models.LtGift {
 				Displayorder: int(comm.GetInt64FromMap(data, "Displayorder", 0)),
 				Gtype:        int(comm.GetInt64FromMap(data, "Gtype", 0)),
 				Gdata:        comm.GetStringFromMap(data, "Gdata", ""),
+				Fallback:     int(comm.GetInt64FromMap(data, "Fallback", 0)),
 				TimeBegin:    int(comm.GetInt64FromMap(data, "TimeBegin", 0)),
 				TimeEnd:      int(comm.GetInt64FromMap(data, "TimeEnd", 0)),
 				//PrizeData:    comm.GetStringFromMap(data, "PrizeData", ""),
@@ -217,6 +222,7 @@ func (s *giftService) setAllByCache(gifts []models.LtGift) {
 			data["Displayorder"] = gift.Displayorder
 			data["Gtype"] = gift.Gtype
 			data["Gdata"] = gift.Gdata
+			data["Fallback"] = gift.Fallback
 			data["TimeBegin"] = gift.TimeBegin
 			data["TimeEnd"] = gift.TimeEnd
 			//data["PrizeData"] = gift.PrizeData
@@ -235,7 +241,7 @@ func (s *giftService) setAllByCache(gifts []models.LtGift) {
 		strValue = string(str)
 	}
 	key := "allgift"
-	rds := datasource.InstanceCache()
+	rds := s.cache
 	// 更新缓存
 	_, err := rds.Do("SET", key, strValue)
 	if err != nil {
@@ -251,7 +257,7 @@ func (s *giftService) updateByCache(data *models.LtGift, columns []string) {
 	}
 	// 集群模式，redis缓存
 	key := "allgift"
-	rds := datasource.InstanceCache()
+	rds := s.cache
 	// 删除redis中的缓存
 	rds.Do("DEL", key)
 }
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"encoding/json"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/dao"
+	"github.com/iralance/go-lottery/datasource"
+	"github.com/iralance/go-lottery/models"
+	"log"
+	"sort"
+)
+
+type PityService interface {
+	GetAll() []models.LtPity
+	CountAll() int64
+	Get(id int) *models.LtPity
+	Delete(id int) error
+	Update(data *models.LtPity, columns []string) error
+	Create(data *models.LtPity) (int64, error)
+	GetAllUse() []models.LtPity
+}
+
+type pityService struct {
+	dao   dao.PityDao
+	cache datasource.Cache
+}
+
+func NewPityService(pityDao dao.PityDao, cache datasource.Cache) PityService {
+	return &pityService{
+		dao:   pityDao,
+		cache: cache,
+	}
+}
+
+func (s *pityService) GetAll() []models.LtPity {
+	return s.dao.GetAll()
+}
+
+func (s *pityService) CountAll() int64 {
+	return s.dao.CountAll()
+}
+
+func (s *pityService) Get(id int) *models.LtPity {
+	return s.dao.Get(id)
+}
+
+func (s *pityService) Delete(id int) error {
+	err := s.dao.Delete(id)
+	s.updateByCache()
+	return err
+}
+
+func (s *pityService) Update(data *models.LtPity, columns []string) error {
+	err := s.dao.Update(data, columns)
+	s.updateByCache()
+	return err
+}
+
+func (s *pityService) Create(data *models.LtPity) (int64, error) {
+	num, err := s.dao.Create(data)
+	s.updateByCache()
+	return num, err
+}
+
+// 抽奖时使用的保底规则，奖品类型大的排在前面，优先读取缓存
+func (s *pityService) GetAllUse() []models.LtPity {
+	pities := s.getAllByCache()
+	if pities != nil {
+		return pities
+	}
+	pities = make([]models.LtPity, 0)
+	for _, data := range s.dao.GetAll() {
+		if data.SysStatus == 0 {
+			pities = append(pities, data)
+		}
+	}
+	sort.SliceStable(pities, func(i, j int) bool { return pities[i].Gtype > pities[j].Gtype })
+	s.setAllByCache(pities)
+	return pities
+}
+
+// 缓存中没有数据的时候返回nil，没有可用的规则时返回空列表
+func (s *pityService) getAllByCache() []models.LtPity {
+	key := "allpity"
+	rs, err := s.cache.Do("GET", key)
+	if err != nil {
+		log.Println("pity_service.getAllByCache GET key=", key, ", error=", err)
+		return nil
+	}
+	str := comm.GetString(rs, "")
+	if str == "" {
+		return nil
+	}
+	pities := []models.LtPity{}
+	err = json.Unmarshal([]byte(str), &pities)
+	if err != nil {
+		log.Println("pity_service.getAllByCache json.Unmarshal error=", err)
+		return nil
+	}
+	return pities
+}
+
+func (s *pityService) setAllByCache(pities []models.LtPity) {
+	str, err := json.Marshal(pities)
+	if err != nil {
+		log.Println("pity_service.setAllByCache json.Marshal error=", err)
+		return
+	}
+	key := "allpity"
+	_, err = s.cache.Do("SET", key, string(str))
+	if err != nil {
+		log.Println("pity_service.setAllByCache SET key=", key, ", error=", err)
+	}
+}
+
+// 规则有变化，直接清空缓存
+func (s *pityService) updateByCache() {
+	key := "allpity"
+	s.cache.Do("DEL", key)
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/dao"
+	"github.com/iralance/go-lottery/models"
+)
+
+type PointService interface {
+	Get(uid int) *models.LtPoint
+	GetAll(page, size int) []models.LtPoint
+	CountAll() int64
+	SearchLog(uid, page, size int) []models.LtPointLog
+	CountLog(uid int) int64
+	Earn(uid, num int, ref, remark string) (bool, error)
+	Spend(uid, num int, ref, remark string) (bool, error)
+	Refund(uid, num int, ref, remark string) (bool, error)
+}
+
+type pointService struct {
+	dao dao.PointDao
+}
+
+func NewPointService(pointDao dao.PointDao) PointService {
+	return &pointService{
+		dao: pointDao,
+	}
+}
+
+// 用户的积分账户，还没有账户的时候余额是0
+func (s *pointService) Get(uid int) *models.LtPoint {
+	data := s.dao.Get(uid)
+	if data == nil {
+		data = &models.LtPoint{Uid: uid}
+	}
+	return data
+}
+
+func (s *pointService) GetAll(page, size int) []models.LtPoint {
+	return s.dao.GetAll(page, size)
+}
+
+func (s *pointService) CountAll() int64 {
+	return s.dao.CountAll()
+}
+
+func (s *pointService) SearchLog(uid, page, size int) []models.LtPointLog {
+	return s.dao.SearchLog(uid, page, size)
+}
+
+func (s *pointService) CountLog(uid int) int64 {
+	return s.dao.CountLog(uid)
+}
+
+// 获得积分，相同的ref只会增加一次
+func (s *pointService) Earn(uid, num int, ref, remark string) (bool, error) {
+	return s.change(uid, conf.PointActionEarn, num, ref, remark)
+}
+
+// 抽奖消耗积分，余额不足的时候返回false
+func (s *pointService) Spend(uid, num int, ref, remark string) (bool, error) {
+	return s.change(uid, conf.PointActionSpend, -num, ref, remark)
+}
+
+// 退回抽奖消耗的积分，ref和消耗的时候相同，只会退回一次
+func (s *pointService) Refund(uid, num int, ref, remark string) (bool, error) {
+	return s.change(uid, conf.PointActionRefund, num, ref, remark)
+}
+
+func (s *pointService) change(uid, action, num int, ref, remark string) (bool, error) {
+	if s.dao.GetLogByRef(action, ref) != nil {
+		return false, nil
+	}
+	ok, err := s.dao.Change(&models.LtPointLog{
+		Uid:        uid,
+		Action:     action,
+		Ref:        ref,
+		Num:        num,
+		Remark:     remark,
+		SysCreated: comm.NowUnix(),
+	})
+	if err != nil && s.dao.GetLogByRef(action, ref) != nil {
+		// 并发的请求已经写入了
+		return false, nil
+	}
+	return ok, err
+}
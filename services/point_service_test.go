@@ -0,0 +1,69 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/iralance/go-lottery/dao"
+)
+
+func TestPointService(t *testing.T) {
+	const (
+		earn = iota
+		spend
+		refund
+	)
+	tests := []struct {
+		name    string
+		op      int
+		num     int
+		ref     string
+		wantOk  bool
+		balance int
+	}{
+		{"没有账户的时候不能消耗", spend, 10, "s0", false, 0},
+		{"第一次获得积分新建账户", earn, 100, "e1", true, 100},
+		{"重复的获得只增加一次", earn, 100, "e1", false, 100},
+		{"消耗积分", spend, 30, "s1", true, 70},
+		{"余额不足", spend, 80, "s2", false, 70},
+		{"刚好用完", spend, 70, "s3", true, 0},
+		{"退回消耗的积分", refund, 30, "s1", true, 30},
+		{"重复的退回只退一次", refund, 30, "s1", false, 30},
+		{"消耗和获得的ref互不影响", earn, 5, "s3", true, 35},
+	}
+	s := NewPointService(dao.NewPointMemDao())
+	logNum := int64(0)
+	for _, tt := range tests {
+		var ok bool
+		var err error
+		switch tt.op {
+		case earn:
+			ok, err = s.Earn(1, tt.num, tt.ref, tt.name)
+		case spend:
+			ok, err = s.Spend(1, tt.num, tt.ref, tt.name)
+		case refund:
+			ok, err = s.Refund(1, tt.num, tt.ref, tt.name)
+		}
+		if err != nil || ok != tt.wantOk {
+			t.Errorf("%s: ok = %v, err = %v, want %v", tt.name, ok, err, tt.wantOk)
+		}
+		if balance := s.Get(1).Balance; balance != tt.balance {
+			t.Errorf("%s: balance = %d, want %d", tt.name, balance, tt.balance)
+		}
+		if ok {
+			logNum++
+		}
+		// 只有成功的修改才写入流水，流水中的余额和账户一致
+		if num := s.CountLog(1); num != logNum {
+			t.Errorf("%s: CountLog = %d, want %d", tt.name, num, logNum)
+		}
+		if ok {
+			if list := s.SearchLog(1, 1, 1); len(list) != 1 || list[0].Balance != tt.balance || list[0].Ref != tt.ref {
+				t.Errorf("%s: last log = %v", tt.name, list)
+			}
+		}
+	}
+	// 其他用户的账户不受影响
+	if balance := s.Get(2).Balance; balance != 0 {
+		t.Errorf("other user balance = %d, want 0", balance)
+	}
+}
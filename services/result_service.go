@@ -2,7 +2,6 @@ package services
 
 import (
 	"github.com/iralance/go-lottery/dao"
-	"github.com/iralance/go-lottery/datasource"
 	"github.com/iralance/go-lottery/models"
 )
 
@@ -14,19 +13,27 @@ type ResultService interface {
 	SearchByUser(uid, page, size int) []models.LtResult
 	CountByGift(giftId int) int64
 	CountByUser(uid int) int64
+	CountGroupPity() map[int]int64
+	CountByUserGift(uid, giftId int) int64
+	CountByUserGtype(uid, gtype int) int64
+	CountByUserSince(uid, since int) int64
 	Get(id int) *models.LtResult
 	Delete(id int) error
 	Update(data *models.LtResult, columns []string) error
 	Create(data *models.LtResult) (int64, error)
+	CreateBatch(datalist []models.LtResult) (int64, error)
+	UpdateReview(data *models.LtResult, fromStatus []int, columns []string) (bool, error)
+	UpdateDeliver(data *models.LtResult, fromStatus []int, columns []string) (bool, error)
+	SearchByDeliver(status, before, size int) []models.LtResult
 }
 
 type resultService struct {
-	dao *dao.ResultDao
+	dao dao.ResultDao
 }
 
-func NewResultService() ResultService {
+func NewResultService(resultDao dao.ResultDao) ResultService {
 	return &resultService{
-		dao: dao.NewResultDao(datasource.InstanceDbMaster()),
+		dao: resultDao,
 	}
 }
 
@@ -58,6 +65,22 @@ func (s *resultService) CountByUser(uid int) int64 {
 	return s.dao.CountByUser(uid)
 }
 
+func (s *resultService) CountGroupPity() map[int]int64 {
+	return s.dao.CountGroupPity()
+}
+
+func (s *resultService) CountByUserGift(uid, giftId int) int64 {
+	return s.dao.CountByUserGift(uid, giftId)
+}
+
+func (s *resultService) CountByUserGtype(uid, gtype int) int64 {
+	return s.dao.CountByUserGtype(uid, gtype)
+}
+
+func (s *resultService) CountByUserSince(uid, since int) int64 {
+	return s.dao.CountByUserSince(uid, since)
+}
+
 func (g *resultService) Get(id int) *models.LtResult {
 	return g.dao.Get(id)
 }
@@ -75,3 +98,19 @@ func (g *resultService) Update(data *models.LtResult, columns []string) error {
 func (g *resultService) Create(data *models.LtResult) (int64, error) {
 	return g.dao.Create(data)
 }
+
+func (g *resultService) CreateBatch(datalist []models.LtResult) (int64, error) {
+	return g.dao.CreateBatch(datalist)
+}
+
+func (g *resultService) UpdateReview(data *models.LtResult, fromStatus []int, columns []string) (bool, error) {
+	return g.dao.UpdateReview(data, fromStatus, columns)
+}
+
+func (g *resultService) UpdateDeliver(data *models.LtResult, fromStatus []int, columns []string) (bool, error) {
+	return g.dao.UpdateDeliver(data, fromStatus, columns)
+}
+
+func (g *resultService) SearchByDeliver(status, before, size int) []models.LtResult {
+	return g.dao.SearchByDeliver(status, before, size)
+}
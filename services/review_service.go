@@ -0,0 +1,39 @@
+package services
+
+import (
+	"github.com/iralance/go-lottery/dao"
+	"github.com/iralance/go-lottery/models"
+)
+
+type ReviewService interface {
+	GetAll(page, size int) []models.LtReview
+	CountAll() int64
+	SearchByResult(resultId int) []models.LtReview
+	Create(data *models.LtReview) (int64, error)
+}
+
+type reviewService struct {
+	dao dao.ReviewDao
+}
+
+func NewReviewService(reviewDao dao.ReviewDao) ReviewService {
+	return &reviewService{
+		dao: reviewDao,
+	}
+}
+
+func (s *reviewService) GetAll(page, size int) []models.LtReview {
+	return s.dao.GetAll(page, size)
+}
+
+func (s *reviewService) CountAll() int64 {
+	return s.dao.CountAll()
+}
+
+func (s *reviewService) SearchByResult(resultId int) []models.LtReview {
+	return s.dao.SearchByResult(resultId)
+}
+
+func (s *reviewService) Create(data *models.LtReview) (int64, error) {
+	return s.dao.Create(data)
+}
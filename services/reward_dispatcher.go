@@ -0,0 +1,199 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/datasource"
+	"github.com/iralance/go-lottery/models"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 中奖之后发放奖励，比如虚拟币入账到用户的钱包
+type RewardDispatcher interface {
+	// 异步发放，已经到账或者正在发放的不会重复发放
+	Dispatch(result *models.LtResult) bool
+}
+
+// 通知钱包服务的内容
+type RewardPayload struct {
+	IdempotencyKey string `json:"idempotency_key"`
+	ResultId       int    `json:"result_id"`
+	Uid            int    `json:"uid"`
+	Username       string `json:"username"`
+	GiftId         int    `json:"gift_id"`
+	Amount         int    `json:"amount"`
+	Created        int    `json:"created"`
+}
+
+// 根据中奖记录生成幂等键，重试和重新发放都使用同一个
+func RewardIdempotencyKey(resultId int) string {
+	return fmt.Sprintf("lottery-result-%d", resultId)
+}
+
+// 签名内容是 timestamp.body
+func RewardSignature(secret, timestamp string, body []byte) string {
+	return comm.HmacSign(secret, timestamp, body)
+}
+
+// 没有配置webhook的时候什么也不做
+type noopDispatcher struct{}
+
+func (d noopDispatcher) Dispatch(result *models.LtResult) bool {
+	return false
+}
+
+type webhookDispatcher struct {
+	cfg           conf.Webhook
+	client        *http.Client
+	cache         datasource.Cache
+	resultService ResultService
+}
+
+func NewRewardDispatcher(cfg conf.Webhook, cache datasource.Cache, resultService ResultService) RewardDispatcher {
+	if cfg.Url == "" {
+		return noopDispatcher{}
+	}
+	// 没有签名密钥的时候不通知钱包服务，应用启动的时候已经检查过
+	if cfg.Secret == "" {
+		log.Println("reward_dispatcher.NewRewardDispatcher webhook secret not set, url=", cfg.Url)
+		return noopDispatcher{}
+	}
+	return &webhookDispatcher{
+		cfg:           cfg,
+		client:        &http.Client{Timeout: cfg.Timeout},
+		cache:         cache,
+		resultService: resultService,
+	}
+}
+
+func (d *webhookDispatcher) Dispatch(result *models.LtResult) bool {
+	if result.GiftType != conf.GtypeVirtual || result.Id <= 0 {
+		return false
+	}
+	switch result.DeliverStatus {
+	case conf.DeliverDone:
+		return false
+	case conf.DeliverPending:
+		// 发放中断的记录，由计划任务重新发放
+	default:
+		// 新的中奖记录，或者人工重新发放失败的记录
+		ok, err := d.resultService.UpdateDeliver(&models.LtResult{
+			Id:            result.Id,
+			DeliverStatus: conf.DeliverPending,
+			DeliverTries:  0,
+			DeliverTime:   comm.NowUnix(),
+		}, []int{conf.DeliverNone, conf.DeliverDead}, []string{"deliver_status", "deliver_tries", "deliver_time"})
+		if err != nil || !ok {
+			log.Println("reward_dispatcher.Dispatch UpdateDeliver id=", result.Id, ", ok=", ok, ", error=", err)
+			return false
+		}
+		result.DeliverStatus = conf.DeliverPending
+		result.DeliverTries = 0
+	}
+	data := *result
+	go d.deliver(&data)
+	return true
+}
+
+// 发放，失败之后按照间隔翻倍重试，全部失败之后进入失败状态等待人工处理
+func (d *webhookDispatcher) deliver(result *models.LtResult) {
+	if !d.lock(result.Id) {
+		return
+	}
+	defer d.unlock(result.Id)
+
+	delay := d.cfg.RetryDelay
+	for tries := result.DeliverTries + 1; tries <= d.cfg.MaxTries; tries++ {
+		retry, err := d.post(result)
+		status := conf.DeliverPending
+		if err == nil {
+			status = conf.DeliverDone
+		} else if !retry || tries >= d.cfg.MaxTries {
+			status = conf.DeliverDead
+		}
+		ok, uerr := d.resultService.UpdateDeliver(&models.LtResult{
+			Id:            result.Id,
+			DeliverStatus: status,
+			DeliverTries:  tries,
+			DeliverTime:   comm.NowUnix(),
+		}, []int{conf.DeliverPending}, []string{"deliver_status", "deliver_tries", "deliver_time"})
+		if uerr != nil || !ok {
+			log.Println("reward_dispatcher.deliver UpdateDeliver id=", result.Id, ", ok=", ok, ", error=", uerr)
+			return
+		}
+		if status != conf.DeliverPending {
+			if err != nil {
+				log.Println("reward_dispatcher.deliver dead id=", result.Id, ", tries=", tries, ", error=", err)
+			}
+			return
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// 通知钱包服务，返回失败之后是否可以重试
+func (d *webhookDispatcher) post(result *models.LtResult) (bool, error) {
+	amount, _ := strconv.Atoi(result.GiftData)
+	body, err := json.Marshal(RewardPayload{
+		IdempotencyKey: RewardIdempotencyKey(result.Id),
+		ResultId:       result.Id,
+		Uid:            result.Uid,
+		Username:       result.Username,
+		GiftId:         result.GiftId,
+		Amount:         amount,
+		Created:        result.SysCreated,
+	})
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequest(http.MethodPost, d.cfg.Url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := strconv.Itoa(comm.NowUnix())
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", RewardIdempotencyKey(result.Id))
+	req.Header.Set("X-Lottery-Timestamp", timestamp)
+	req.Header.Set("X-Lottery-Signature", RewardSignature(d.cfg.Secret, timestamp, body))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	// 超时、限流和服务端错误可以重试，其他的错误重试也不会成功
+	retry := resp.StatusCode == http.StatusRequestTimeout ||
+		resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode >= 500
+	return retry, fmt.Errorf("wallet webhook status=%d", resp.StatusCode)
+}
+
+// 同一个中奖记录同时只能有一个发放过程
+func (d *webhookDispatcher) lock(id int) bool {
+	key := fmt.Sprintf("reward_dispatch_%d", id)
+	// 锁的时间覆盖全部重试的时间
+	total := time.Duration(d.cfg.MaxTries)*d.cfg.Timeout + d.cfg.RetryDelay*time.Duration(1<<uint(d.cfg.MaxTries))
+	ttl := int(total/time.Second) + 1
+	rs, err := d.cache.Do("SET", key, 1, "EX", ttl, "NX")
+	if err != nil {
+		log.Println("reward_dispatcher.lock error=", err)
+		return false
+	}
+	return rs == "OK"
+}
+
+func (d *webhookDispatcher) unlock(id int) {
+	key := fmt.Sprintf("reward_dispatch_%d", id)
+	d.cache.Do("DEL", key)
+}
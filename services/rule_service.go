@@ -0,0 +1,170 @@
+package services
+
+import (
+	"encoding/json"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/dao"
+	"github.com/iralance/go-lottery/datasource"
+	"github.com/iralance/go-lottery/models"
+	"log"
+)
+
+type RuleService interface {
+	GetAll() []models.LtRule
+	CountAll() int64
+	Get(id int) *models.LtRule
+	Delete(id int) error
+	Update(data *models.LtRule, columns []string) error
+	Create(data *models.LtRule) (int64, error)
+	GetAllUse() []models.LtRule
+	SeedDefault() error
+}
+
+type ruleService struct {
+	dao   dao.RuleDao
+	cache datasource.Cache
+}
+
+func NewRuleService(ruleDao dao.RuleDao, cache datasource.Cache) RuleService {
+	return &ruleService{
+		dao:   ruleDao,
+		cache: cache,
+	}
+}
+
+// 第一次启动，还没有任何规则的时候写入的默认规则
+// 与原来固定的逻辑一致：实物大奖之后冷却30天，同一个IP抽奖次数太多只能获得小奖
+// 另外关联风险分太高的时候降级或者拒绝，拒绝的时候不计算同一个IP上的其他用户
+func DefaultRules() []models.LtRule {
+	return []models.LtRule{
+		{
+			Title:  "获得实物大奖之后冷却30天",
+			Rtype:  conf.RuleTypeWinCooldown,
+			Gtype:  conf.GtypeGiftLarge,
+			Num:    30,
+			Action: conf.RuleActionDowngrade,
+		},
+		{
+			Title:  "同一个IP今天抽奖次数太多",
+			Rtype:  conf.RuleTypeIpLucky,
+			Num:    conf.IpPrizeMax,
+			Action: conf.RuleActionDowngrade,
+		},
+		{
+			Title:  "关联的用户和设备较多",
+			Rtype:  conf.RuleTypeLinkScore,
+			Num:    60,
+			Action: conf.RuleActionDowngrade,
+		},
+		{
+			Title:  "关联的用户和设备太多",
+			Rtype:  conf.RuleTypeLinkScore,
+			Num:    100,
+			Action: conf.RuleActionDeny,
+		},
+	}
+}
+
+func (s *ruleService) GetAll() []models.LtRule {
+	return s.dao.GetAll()
+}
+
+func (s *ruleService) CountAll() int64 {
+	return s.dao.CountAll()
+}
+
+func (s *ruleService) Get(id int) *models.LtRule {
+	return s.dao.Get(id)
+}
+
+func (s *ruleService) Delete(id int) error {
+	err := s.dao.Delete(id)
+	s.updateByCache()
+	return err
+}
+
+func (s *ruleService) Update(data *models.LtRule, columns []string) error {
+	err := s.dao.Update(data, columns)
+	s.updateByCache()
+	return err
+}
+
+func (s *ruleService) Create(data *models.LtRule) (int64, error) {
+	num, err := s.dao.Create(data)
+	s.updateByCache()
+	return num, err
+}
+
+// 抽奖时使用的规则列表，优先读取缓存
+func (s *ruleService) GetAllUse() []models.LtRule {
+	rules := s.getAllByCache()
+	if rules != nil {
+		return rules
+	}
+	all := s.dao.GetAll()
+	rules = make([]models.LtRule, 0, len(all))
+	for _, rule := range all {
+		if rule.SysStatus == 0 {
+			rules = append(rules, rule)
+		}
+	}
+	s.setAllByCache(rules)
+	return rules
+}
+
+// 规则表是空的时候写入默认规则，删除的规则也算，后台删除之后不会重新写入
+func (s *ruleService) SeedDefault() error {
+	if s.dao.CountAll() > 0 {
+		return nil
+	}
+	now := comm.NowUnix()
+	for _, rule := range DefaultRules() {
+		rule.SysCreated = now
+		if _, err := s.dao.Create(&rule); err != nil {
+			return err
+		}
+	}
+	s.updateByCache()
+	return nil
+}
+
+// 缓存中没有数据的时候返回nil，没有可用的规则时返回空列表
+func (s *ruleService) getAllByCache() []models.LtRule {
+	key := "allrule"
+	rs, err := s.cache.Do("GET", key)
+	if err != nil {
+		log.Println("rule_service.getAllByCache GET key=", key, ", error=", err)
+		return nil
+	}
+	str := comm.GetString(rs, "")
+	if str == "" {
+		return nil
+	}
+	rules := []models.LtRule{}
+	err = json.Unmarshal([]byte(str), &rules)
+	if err != nil {
+		log.Println("rule_service.getAllByCache json.Unmarshal error=", err)
+		return nil
+	}
+	return rules
+}
+
+func (s *ruleService) setAllByCache(rules []models.LtRule) {
+	str, err := json.Marshal(rules)
+	if err != nil {
+		log.Println("rule_service.setAllByCache json.Marshal error=", err)
+		return
+	}
+	key := "allrule"
+	_, err = s.cache.Do("SET", key, string(str))
+	if err != nil {
+		log.Println("rule_service.setAllByCache SET key=", key, ", error=", err)
+	}
+}
+
+// 规则有变化，直接清空缓存
+func (s *ruleService) updateByCache() {
+	key := "allrule"
+	s.cache.Do("DEL", key)
+}
@@ -20,12 +20,14 @@ type UserService interface {
 }
 
 type userService struct {
-	dao *dao.UserDao
+	dao   dao.UserDao
+	cache datasource.Cache
 }
 
-func NewUserService() UserService {
+func NewUserService(userDao dao.UserDao, cache datasource.Cache) UserService {
 	return &userService{
-		dao: dao.NewUserDao(datasource.InstanceDbMaster()),
+		dao:   userDao,
+		cache: cache,
 	}
 }
 
@@ -70,7 +72,7 @@ func (s *userService) Create(data *models.LtUser) (int64, error) {
 func (s *userService) getByCache(id int) *models.LtUser {
 	// 集群模式，redis缓存
 	key := fmt.Sprintf("info_user_%d", id)
-	rds := datasource.InstanceCache()
+	rds := s.cache
 	dataMap, err := redis.StringMap(rds.Do("HGETALL", key))
 	if err != nil {
 		log.Println("user_service.getByCache HGETALL key=", key, ", error=", err)
@@ -87,6 +89,7 @@ func (s *userService) getByCache(id int) *models.LtUser {
 		Realname:   comm.GetStringFromStringMap(dataMap, "Realname", ""),
 		Mobile:     comm.GetStringFromStringMap(dataMap, "Mobile", ""),
 		Address:    comm.GetStringFromStringMap(dataMap, "Address", ""),
+		Hidewin:    int(comm.GetInt64FromStringMap(dataMap, "Hidewin", 0)),
 		SysCreated: int(comm.GetInt64FromStringMap(dataMap, "SysCreated", 0)),
 		SysUpdated: int(comm.GetInt64FromStringMap(dataMap, "SysUpdated", 0)),
 		SysIp:      comm.GetStringFromStringMap(dataMap, "SysIp", ""),
@@ -102,7 +105,7 @@ func (s *userService) setByCache(data *models.LtUser) {
 	id := data.Id
 	// 集群模式，redis缓存
 	key := fmt.Sprintf("info_user_%d", id)
-	rds := datasource.InstanceCache()
+	rds := s.cache
 	// 数据更新到redis缓存
 	params := []interface{}{key}
 	params = append(params, "Id", id)
@@ -112,6 +115,7 @@ func (s *userService) setByCache(data *models.LtUser) {
 		params = append(params, "Realname", data.Realname)
 		params = append(params, "Mobile", data.Mobile)
 		params = append(params, "Address", data.Address)
+		params = append(params, "Hidewin", data.Hidewin)
 		params = append(params, "SysCreated", data.SysCreated)
 		params = append(params, "SysUpdated", data.SysUpdated)
 		params = append(params, "SysIp", data.SysIp)
@@ -129,7 +133,7 @@ func (s *userService) updateByCache(data *models.LtUser, columns []string) {
 	}
 	// 集群模式，redis缓存
 	key := fmt.Sprintf("info_user_%d", data.Id)
-	rds := datasource.InstanceCache()
+	rds := s.cache
 	// 删除redis中的缓存
 	rds.Do("DEL", key)
 }
@@ -3,7 +3,6 @@ package services
 import (
 	"fmt"
 	"github.com/iralance/go-lottery/dao"
-	"github.com/iralance/go-lottery/datasource"
 	"github.com/iralance/go-lottery/models"
 	"strconv"
 	"time"
@@ -22,12 +21,12 @@ type UserdayService interface {
 }
 
 type userdayService struct {
-	dao *dao.UserdayDao
+	dao dao.UserdayDao
 }
 
-func NewUserdayService() UserdayService {
+func NewUserdayService(userdayDao dao.UserdayDao) UserdayService {
 	return &userdayService{
-		dao: dao.NewUserdayDao(datasource.InstanceDbMaster()),
+		dao: userdayDao,
 	}
 }
 
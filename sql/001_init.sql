@@ -0,0 +1,81 @@
+-- 初始的数据表
+
+CREATE TABLE IF NOT EXISTS `lt_blackip` (
+  `id` INT(10) NOT NULL AUTO_INCREMENT,
+  `ip` VARCHAR(50) NOT NULL DEFAULT '' COMMENT 'IP地址',
+  `blacktime` INT(10) NOT NULL DEFAULT 0 COMMENT '黑名单限制到期时间',
+  `sys_created` INT(10) NOT NULL DEFAULT 0 COMMENT '创建时间',
+  `sys_updated` INT(10) NOT NULL DEFAULT 0 COMMENT '修改时间',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `lt_code` (
+  `id` INT(10) NOT NULL AUTO_INCREMENT,
+  `gift_id` INT(10) NOT NULL DEFAULT 0 COMMENT '奖品ID，关联lt_gift表',
+  `code` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '虚拟券编码',
+  `sys_created` INT(10) NOT NULL DEFAULT 0 COMMENT '创建时间',
+  `sys_updated` INT(10) NOT NULL DEFAULT 0 COMMENT '更新时间',
+  `sys_status` SMALLINT(5) NOT NULL DEFAULT 0 COMMENT '状态，0正常，1作废，2已发放',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `lt_gift` (
+  `id` INT(10) NOT NULL AUTO_INCREMENT,
+  `title` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '奖品名称',
+  `prize_num` INT(11) NOT NULL DEFAULT -1 COMMENT '奖品数量，0 无限量，>0限量，<0无奖品',
+  `left_num` INT(11) NOT NULL DEFAULT 0 COMMENT '剩余数量',
+  `prize_code` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '0-9999表示100%，0-0表示万分之一的中奖概率',
+  `prize_time` INT(10) NOT NULL DEFAULT 0 COMMENT '发奖周期，D天',
+  `img` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '奖品图片',
+  `displayorder` INT(10) NOT NULL DEFAULT 0 COMMENT '位置序号，小的排在前面',
+  `gtype` INT(10) NOT NULL DEFAULT 0 COMMENT '奖品类型，0 虚拟币，1 虚拟券，2 实物-小奖，3 实物-大奖',
+  `gdata` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '扩展数据，如：虚拟币数量',
+  `time_begin` INT(11) NOT NULL DEFAULT 0 COMMENT '开始时间',
+  `time_end` INT(11) NOT NULL DEFAULT 0 COMMENT '结束时间',
+  `prize_data` MEDIUMTEXT NULL COMMENT '发奖计划，[[时间1,数量1],[时间2,数量2]]',
+  `prize_begin` INT(11) NOT NULL DEFAULT 0 COMMENT '发奖计划周期的开始',
+  `prize_end` INT(11) NOT NULL DEFAULT 0 COMMENT '发奖计划周期的结束',
+  `sys_status` SMALLINT(5) NOT NULL DEFAULT 0 COMMENT '状态，0 正常，1 删除',
+  `sys_created` INT(10) NOT NULL DEFAULT 0 COMMENT '创建时间',
+  `sys_updated` INT(10) NOT NULL DEFAULT 0 COMMENT '修改时间',
+  `sys_ip` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '操作人IP',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `lt_result` (
+  `id` INT(10) NOT NULL AUTO_INCREMENT,
+  `gift_id` INT(10) NOT NULL DEFAULT 0 COMMENT '奖品ID，关联lt_gift表',
+  `gift_name` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '奖品名称',
+  `gift_type` INT(10) NOT NULL DEFAULT 0 COMMENT '奖品类型，同lt_gift. gtype',
+  `uid` INT(10) NOT NULL DEFAULT 0 COMMENT '用户ID',
+  `username` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '用户名',
+  `prize_code` INT(10) NOT NULL DEFAULT 0 COMMENT '抽奖编号（4位的随机数）',
+  `gift_data` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '获奖信息',
+  `sys_created` INT(10) NOT NULL DEFAULT 0 COMMENT '创建时间',
+  `sys_ip` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '用户抽奖的IP',
+  `sys_status` SMALLINT(5) NOT NULL DEFAULT 0 COMMENT '状态，0 正常，1删除，2作弊',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `lt_user` (
+  `id` INT(10) NOT NULL AUTO_INCREMENT,
+  `username` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '用户名',
+  `blacktime` INT(10) NOT NULL DEFAULT 0 COMMENT '黑名单限制到期时间',
+  `realname` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '联系人',
+  `mobile` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '手机号',
+  `address` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '联系地址',
+  `sys_created` INT(10) NOT NULL DEFAULT 0 COMMENT '创建时间',
+  `sys_updated` INT(10) NOT NULL DEFAULT 0 COMMENT '修改时间',
+  `sys_ip` VARCHAR(50) NOT NULL DEFAULT '' COMMENT 'IP地址',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `lt_userday` (
+  `id` INT(10) NOT NULL AUTO_INCREMENT,
+  `uid` INT(10) NOT NULL DEFAULT 0 COMMENT '用户ID',
+  `day` INT(10) NOT NULL DEFAULT 0 COMMENT '日期，如：20180725',
+  `num` INT(10) NOT NULL DEFAULT 0 COMMENT '次数',
+  `sys_created` INT(10) NOT NULL DEFAULT 0 COMMENT '创建时间',
+  `sys_updated` INT(10) NOT NULL DEFAULT 0 COMMENT '修改时间',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
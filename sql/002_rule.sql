@@ -0,0 +1,18 @@
+-- 风控规则，命中标记规则的中奖记录保存规则ID
+
+CREATE TABLE IF NOT EXISTS `lt_rule` (
+  `id` INT(10) NOT NULL AUTO_INCREMENT,
+  `title` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '规则名称',
+  `rtype` INT(10) NOT NULL DEFAULT 0 COMMENT '规则类型，1 中奖后冷却，2 IP今日用户数，3 IP今日抽奖次数，4 用户今日抽奖次数',
+  `gtype` INT(10) NOT NULL DEFAULT 0 COMMENT '中奖后冷却的奖品类型，同lt_gift. gtype',
+  `num` INT(10) NOT NULL DEFAULT 0 COMMENT '中奖后冷却的天数，其他规则的次数上限',
+  `action` SMALLINT(5) NOT NULL DEFAULT 0 COMMENT '处理方式，1 标记，2 降级，3 验证，4 拒绝',
+  `sys_status` SMALLINT(5) NOT NULL DEFAULT 0 COMMENT '状态，0 正常，1 删除',
+  `sys_created` INT(10) NOT NULL DEFAULT 0 COMMENT '创建时间',
+  `sys_updated` INT(10) NOT NULL DEFAULT 0 COMMENT '修改时间',
+  `sys_ip` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '操作人IP',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE `lt_result`
+  ADD COLUMN `rule_id` INT(10) NOT NULL DEFAULT 0 COMMENT '标记这次中奖的风控规则ID，0 没有标记';
@@ -0,0 +1,4 @@
+-- IP黑名单支持CIDR网段和IPv6，字段长度不变
+
+ALTER TABLE `lt_blackip`
+  MODIFY COLUMN `ip` VARCHAR(50) NOT NULL DEFAULT '' COMMENT 'IP地址或者CIDR网段，支持IPv6';
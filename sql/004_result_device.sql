@@ -0,0 +1,4 @@
+-- 中奖记录保存抽奖的设备标识，后台查看关联的用户
+
+ALTER TABLE `lt_result`
+  ADD COLUMN `device` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '用户抽奖的设备标识';
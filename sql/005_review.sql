@@ -0,0 +1,16 @@
+-- 中奖记录的作弊审核，审核的每一步都保存在lt_review
+
+CREATE TABLE IF NOT EXISTS `lt_review` (
+  `id` INT(10) NOT NULL AUTO_INCREMENT,
+  `result_id` INT(10) NOT NULL DEFAULT 0 COMMENT '中奖记录ID，关联lt_result表',
+  `action` SMALLINT(5) NOT NULL DEFAULT 0 COMMENT '审核操作，1 标记，2 调查，3 确认作弊，4 撤销奖品，5 审核通过',
+  `reviewer` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '审核人',
+  `reason` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '审核原因',
+  `detail` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '撤销奖品时的处理结果',
+  `sys_created` INT(10) NOT NULL DEFAULT 0 COMMENT '创建时间',
+  `sys_ip` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '审核人IP',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE `lt_result`
+  ADD COLUMN `review_status` SMALLINT(5) NOT NULL DEFAULT 0 COMMENT '审核状态，0 没有审核，1 标记，2 调查中，3 确认作弊，4 已撤销，5 审核通过';
@@ -0,0 +1,22 @@
+-- 实物奖品的收货地址和发货状态
+
+CREATE TABLE IF NOT EXISTS `lt_fulfill` (
+  `id` INT(10) NOT NULL AUTO_INCREMENT,
+  `result_id` INT(10) NOT NULL DEFAULT 0 COMMENT '中奖记录ID，关联lt_result表',
+  `uid` INT(10) NOT NULL DEFAULT 0 COMMENT '用户ID',
+  `gift_id` INT(10) NOT NULL DEFAULT 0 COMMENT '奖品ID，关联lt_gift表',
+  `gift_name` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '奖品名称',
+  `status` SMALLINT(5) NOT NULL DEFAULT 0 COMMENT '发货状态，1 等待填写地址，2 等待发货，3 已发货，4 已签收，5 已过期',
+  `realname` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '联系人',
+  `mobile` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '手机号',
+  `address` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '联系地址',
+  `carrier` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '快递公司',
+  `tracking_no` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '快递单号',
+  `shipped_at` INT(10) NOT NULL DEFAULT 0 COMMENT '发货时间',
+  `delivered_at` INT(10) NOT NULL DEFAULT 0 COMMENT '签收时间',
+  `sys_created` INT(10) NOT NULL DEFAULT 0 COMMENT '创建时间',
+  `sys_updated` INT(10) NOT NULL DEFAULT 0 COMMENT '修改时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `UQE_lt_fulfill_result_id` (`result_id`),
+  KEY `IDX_lt_fulfill_uid` (`uid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
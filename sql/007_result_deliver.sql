@@ -0,0 +1,6 @@
+-- 虚拟币奖品通过webhook入账的状态和重试
+
+ALTER TABLE `lt_result`
+  ADD COLUMN `deliver_status` SMALLINT(5) NOT NULL DEFAULT 0 COMMENT '虚拟币到账状态，0 不需要，1 发放中，2 已到账，3 发放失败',
+  ADD COLUMN `deliver_tries` INT(10) NOT NULL DEFAULT 0 COMMENT '虚拟币发放的尝试次数',
+  ADD COLUMN `deliver_time` INT(10) NOT NULL DEFAULT 0 COMMENT '虚拟币最后一次发放的时间';
@@ -0,0 +1,8 @@
+-- 优惠券的有效期和合作方核销
+
+ALTER TABLE `lt_code`
+  ADD COLUMN `valid_from` INT(10) NOT NULL DEFAULT 0 COMMENT '有效期开始时间，0 不限制',
+  ADD COLUMN `valid_to` INT(10) NOT NULL DEFAULT 0 COMMENT '有效期结束时间，0 不限制',
+  ADD COLUMN `redeemed_at` INT(10) NOT NULL DEFAULT 0 COMMENT '核销时间',
+  ADD COLUMN `redeem_ref` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '合作方的核销单号',
+  MODIFY COLUMN `sys_status` SMALLINT(5) NOT NULL DEFAULT 0 COMMENT '状态，0正常，1作废，2已发放，3已核销，4已过期';
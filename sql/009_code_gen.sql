@@ -0,0 +1,16 @@
+-- 系统生成优惠券编码的规则，每个奖品一条
+
+CREATE TABLE IF NOT EXISTS `lt_code_gen` (
+  `id` INT(10) NOT NULL AUTO_INCREMENT,
+  `gift_id` INT(10) NOT NULL DEFAULT 0 COMMENT '奖品ID，关联lt_gift表',
+  `prefix` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '编码前缀',
+  `alphabet` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '编码使用的字符',
+  `length` INT(10) NOT NULL DEFAULT 0 COMMENT '随机部分的长度，不包括前缀和校验位',
+  `checksum` SMALLINT(5) NOT NULL DEFAULT 0 COMMENT '是否添加校验位，0 否，1 是',
+  `threshold` INT(10) NOT NULL DEFAULT 0 COMMENT '剩余编码少于这个数量时自动补充，0 不自动补充',
+  `topup_num` INT(10) NOT NULL DEFAULT 0 COMMENT '每次自动补充的数量',
+  `sys_created` INT(10) NOT NULL DEFAULT 0 COMMENT '创建时间',
+  `sys_updated` INT(10) NOT NULL DEFAULT 0 COMMENT '修改时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `UQE_lt_code_gen_gift_id` (`gift_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,15 @@
+-- 优惠券编码加密保存，使用编码的HMAC查找，中奖记录只保存优惠券ID
+
+ALTER TABLE `lt_code`
+  MODIFY COLUMN `code` VARCHAR(512) NOT NULL DEFAULT '' COMMENT '虚拟券编码，加密保存',
+  ADD COLUMN `code_hash` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '编码的HMAC，用来查找编码，没有加密的旧数据是legacy-id',
+  ADD COLUMN `key_id` INT(10) NOT NULL DEFAULT 0 COMMENT '加密密钥的版本，0 没有加密的旧数据';
+
+-- 没有加密的旧数据不能计算HMAC，先使用不会和HMAC重复的占位值，重新加密的时候替换
+UPDATE `lt_code` SET `code_hash` = CONCAT('legacy-', `id`) WHERE `key_id` = 0;
+
+CREATE UNIQUE INDEX `UQE_lt_code_code_hash` ON `lt_code` (`code_hash`);
+
+ALTER TABLE `lt_result`
+  ADD COLUMN `code_id` INT(10) NOT NULL DEFAULT 0 COMMENT '发放的优惠券ID，关联lt_code表，不保存明文的编码',
+  MODIFY COLUMN `gift_data` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '获奖信息，不同编码的优惠券保存在code_id';
@@ -0,0 +1,4 @@
+-- 用户可以设置不在公开的中奖榜单中展示
+
+ALTER TABLE `lt_user`
+  ADD COLUMN `hidewin` SMALLINT(5) NOT NULL DEFAULT 0 COMMENT '不在公开的中奖榜单中展示，0 展示，1 隐藏' AFTER `address`;
@@ -0,0 +1,16 @@
+-- 每次抽奖的记录，用户查看自己的抽奖历史
+
+CREATE TABLE IF NOT EXISTS `lt_draw_log` (
+  `id` INT(10) NOT NULL AUTO_INCREMENT,
+  `uid` INT(10) NOT NULL DEFAULT 0 COMMENT '用户ID',
+  `code` INT(10) NOT NULL DEFAULT 0 COMMENT '抽奖结果，0 中奖，其他同抽奖接口的返回码',
+  `msg` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '抽奖结果的说明',
+  `gift_id` INT(10) NOT NULL DEFAULT 0 COMMENT '中奖的奖品ID，关联lt_gift表',
+  `gift_name` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '中奖的奖品名称',
+  `result_id` INT(10) NOT NULL DEFAULT 0 COMMENT '中奖记录ID，关联lt_result表',
+  `sys_created` INT(10) NOT NULL DEFAULT 0 COMMENT '抽奖时间',
+  `sys_ip` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '用户抽奖的IP',
+  `device` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '用户抽奖的设备标识',
+  PRIMARY KEY (`id`),
+  KEY `IDX_lt_draw_log_uid` (`uid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
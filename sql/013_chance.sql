@@ -0,0 +1,17 @@
+-- 签到、分享、邀请和外部活动获得的抽奖机会
+
+CREATE TABLE IF NOT EXISTS `lt_chance` (
+  `id` INT(10) NOT NULL AUTO_INCREMENT,
+  `uid` INT(10) NOT NULL DEFAULT 0 COMMENT '用户ID',
+  `source` SMALLINT(5) NOT NULL DEFAULT 0 COMMENT '来源，1 每日签到，2 邀请用户，3 分享，4 外部活动',
+  `ref` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '来源的唯一标识，同一个来源不重复发放',
+  `num` INT(10) NOT NULL DEFAULT 0 COMMENT '获得的抽奖次数',
+  `left_num` INT(10) NOT NULL DEFAULT 0 COMMENT '剩余的抽奖次数',
+  `expire` INT(10) NOT NULL DEFAULT 0 COMMENT '到期时间',
+  `remark` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '说明',
+  `sys_created` INT(10) NOT NULL DEFAULT 0 COMMENT '创建时间',
+  `sys_updated` INT(10) NOT NULL DEFAULT 0 COMMENT '修改时间',
+  PRIMARY KEY (`id`),
+  KEY `IDX_lt_chance_uid` (`uid`),
+  UNIQUE KEY `UQE_lt_chance_source_ref` (`source`, `ref`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,25 @@
+-- 积分账户和积分流水，需要积分的活动抽奖之前扣除
+
+CREATE TABLE IF NOT EXISTS `lt_point` (
+  `id` INT(10) NOT NULL AUTO_INCREMENT,
+  `uid` INT(10) NOT NULL DEFAULT 0 COMMENT '用户ID',
+  `balance` INT(10) NOT NULL DEFAULT 0 COMMENT '积分余额',
+  `sys_created` INT(10) NOT NULL DEFAULT 0 COMMENT '创建时间',
+  `sys_updated` INT(10) NOT NULL DEFAULT 0 COMMENT '修改时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `UQE_lt_point_uid` (`uid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `lt_point_log` (
+  `id` INT(10) NOT NULL AUTO_INCREMENT,
+  `uid` INT(10) NOT NULL DEFAULT 0 COMMENT '用户ID',
+  `action` SMALLINT(5) NOT NULL DEFAULT 0 COMMENT '类型，1 获得，2 抽奖消耗，3 抽奖失败退回',
+  `ref` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '唯一标识，退回的时候和消耗的相同',
+  `num` INT(10) NOT NULL DEFAULT 0 COMMENT '积分变化，消耗的时候是负数',
+  `balance` INT(10) NOT NULL DEFAULT 0 COMMENT '变化之后的积分余额',
+  `remark` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '说明',
+  `sys_created` INT(10) NOT NULL DEFAULT 0 COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  KEY `IDX_lt_point_log_uid` (`uid`),
+  UNIQUE KEY `UQE_lt_point_log_action_ref` (`action`, `ref`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
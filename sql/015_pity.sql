@@ -0,0 +1,18 @@
+-- 连续没有中奖的保底规则，触发保底的中奖记录保存规则ID
+
+CREATE TABLE IF NOT EXISTS `lt_pity` (
+  `id` INT(10) NOT NULL AUTO_INCREMENT,
+  `title` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '规则名称',
+  `gtype` INT(10) NOT NULL DEFAULT 0 COMMENT '保底的奖品类型，同lt_gift. gtype',
+  `num` INT(10) NOT NULL DEFAULT 0 COMMENT '连续没有中奖的次数达到N之后生效',
+  `action` SMALLINT(5) NOT NULL DEFAULT 0 COMMENT '处理方式，1 提高概率，2 必定中奖',
+  `boost` INT(10) NOT NULL DEFAULT 0 COMMENT '提高概率时，每多一次没有中奖增加的概率，万分之几',
+  `sys_status` SMALLINT(5) NOT NULL DEFAULT 0 COMMENT '状态，0 正常，1 删除',
+  `sys_created` INT(10) NOT NULL DEFAULT 0 COMMENT '创建时间',
+  `sys_updated` INT(10) NOT NULL DEFAULT 0 COMMENT '修改时间',
+  `sys_ip` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '操作人IP',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE `lt_result`
+  ADD COLUMN `pity_id` INT(10) NOT NULL DEFAULT 0 COMMENT '触发的保底规则ID，0 没有触发';
@@ -0,0 +1,7 @@
+-- 没有中奖时发放的安慰奖，安慰奖的中奖记录不计算中奖次数
+
+ALTER TABLE `lt_gift`
+  ADD COLUMN `fallback` SMALLINT(5) NOT NULL DEFAULT 0 COMMENT '安慰奖，0 正常奖品，1 没有中奖时发放的安慰奖' AFTER `gdata`;
+
+ALTER TABLE `lt_result`
+  ADD COLUMN `fallback` SMALLINT(5) NOT NULL DEFAULT 0 COMMENT '安慰奖，0 正常奖品，1 没有中奖时发放的安慰奖，不计算中奖次数';
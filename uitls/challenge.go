@@ -0,0 +1,133 @@
+/**
+ * 抽奖前的验证，工作量证明或者图片验证码
+ * 验证数据保存在redis中，每个验证只能使用一次
+ */
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/datasource"
+	"github.com/iralance/go-lottery/models"
+	"log"
+	"math/big"
+	"strings"
+)
+
+func getChallengeKey(id string) string {
+	return fmt.Sprintf("challenge_%s", id)
+}
+
+func getChallengePassKey(uid int) string {
+	return fmt.Sprintf("challenge_pass_%d", uid)
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+func randomDigits(n int) string {
+	digits := make([]byte, n)
+	for i := range digits {
+		num, _ := rand.Int(rand.Reader, big.NewInt(10))
+		digits[i] = byte('0' + num.Int64())
+	}
+	return string(digits)
+}
+
+// 给用户创建一个新的验证
+func NewChallenge(cacheObj datasource.Cache, uid int, ctype string) *models.ObjChallenge {
+	if ctype != "image" {
+		ctype = "pow"
+	}
+	challenge := &models.ObjChallenge{
+		Id:   randomHex(16),
+		Type: ctype,
+	}
+	params := []interface{}{getChallengeKey(challenge.Id), "uid", uid, "type", ctype}
+	if ctype == "image" {
+		challenge.Img = "/challenge/image?id=" + challenge.Id
+		params = append(params, "answer", randomDigits(conf.ChallengeImageLen))
+	} else {
+		challenge.Prefix = randomHex(8)
+		challenge.Difficulty = conf.ChallengePowBits
+		params = append(params, "prefix", challenge.Prefix, "difficulty", challenge.Difficulty)
+	}
+	key := getChallengeKey(challenge.Id)
+	_, err := cacheObj.Do("HMSET", params...)
+	if err != nil {
+		log.Println("challenge redis HMSET key=", key, ", err=", err)
+		return nil
+	}
+	cacheObj.Do("EXPIRE", key, conf.ChallengeExpire)
+	return challenge
+}
+
+// 验证用户提交的答案，通过之后一段时间内不需要再次验证
+// 不管是否通过，验证都会被删除，避免反复尝试
+func VerifyChallenge(cacheObj datasource.Cache, uid int, id, answer string) bool {
+	if id == "" || answer == "" {
+		return false
+	}
+	key := getChallengeKey(id)
+	dataMap, err := redis.StringMap(cacheObj.Do("HGETALL", key))
+	if err != nil || len(dataMap) == 0 {
+		return false
+	}
+	cacheObj.Do("DEL", key)
+	if comm.GetInt64FromStringMap(dataMap, "uid", 0) != int64(uid) {
+		return false
+	}
+	ok := false
+	switch dataMap["type"] {
+	case "image":
+		ok = strings.TrimSpace(answer) == dataMap["answer"]
+	case "pow":
+		difficulty := int(comm.GetInt64FromStringMap(dataMap, "difficulty", 0))
+		ok = CheckPowAnswer(dataMap["prefix"], answer, difficulty)
+	}
+	if ok {
+		cacheObj.Do("SET", getChallengePassKey(uid), comm.NowUnix(), "EX", conf.ChallengePassTime)
+	}
+	return ok
+}
+
+// 用户最近是否完成过验证
+func HasChallengePass(cacheObj datasource.Cache, uid int) bool {
+	rs, err := cacheObj.Do("EXISTS", getChallengePassKey(uid))
+	if err != nil {
+		log.Println("challenge redis EXISTS uid=", uid, ", err=", err)
+		return false
+	}
+	return comm.GetInt64(rs, 0) > 0
+}
+
+// 图片验证码的图片，验证不存在的时候返回nil
+func ChallengeImage(cacheObj datasource.Cache, id string) []byte {
+	rs, err := cacheObj.Do("HGET", getChallengeKey(id), "answer")
+	if err != nil || rs == nil {
+		return nil
+	}
+	return comm.CaptchaImage(comm.GetString(rs, ""))
+}
+
+// sha256(prefix+answer)的前difficulty位都是0
+func CheckPowAnswer(prefix, answer string, difficulty int) bool {
+	if difficulty <= 0 || difficulty > 256 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(prefix + answer))
+	for i := 0; i < difficulty; i++ {
+		if sum[i/8]&(0x80>>uint(i%8)) != 0 {
+			return false
+		}
+	}
+	return true
+}
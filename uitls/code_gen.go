@@ -0,0 +1,209 @@
+/**
+ * 优惠券编码的生成
+ * 按照奖品的生成规则随机生成编码，保证唯一之后写入数据库和缓存
+ */
+package utils
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/datasource"
+	"github.com/iralance/go-lottery/models"
+	"github.com/iralance/go-lottery/services"
+	"log"
+	"math"
+	"strings"
+)
+
+// 检查生成规则，返回错误信息
+func CheckCodeGen(gen *models.LtCodeGen) error {
+	if len(gen.Alphabet) < 2 || len(gen.Alphabet) > 100 {
+		return errors.New("编码字符的数量需要在2到100之间")
+	}
+	for i := 0; i < len(gen.Alphabet); i++ {
+		c := gen.Alphabet[i]
+		if c <= ' ' || c > '~' {
+			return errors.New("编码字符只能是可见的ASCII字符")
+		}
+		if strings.IndexByte(gen.Alphabet, c) != i {
+			return fmt.Errorf("编码字符 %c 重复", c)
+		}
+	}
+	if gen.Length < 4 || gen.Length > 64 {
+		return errors.New("编码长度需要在4到64之间")
+	}
+	if len(gen.Prefix) > 50 || !validImportCode(gen.Prefix) {
+		return errors.New("编码前缀不能超过50个字符，不能有空白字符")
+	}
+	if gen.Threshold < 0 || gen.TopupNum < 0 || gen.TopupNum > conf.CodeGenMax {
+		return fmt.Errorf("自动补充的数量需要在0到%d之间", conf.CodeGenMax)
+	}
+	return nil
+}
+
+// 按照奖品的生成规则，生成num个新的编码
+// 返回，成功生成的数量，错误信息
+func GenerateCodes(cacheObj datasource.Cache, codeService services.CodeService,
+	gen *models.LtCodeGen, num int, params *CodeImport) (int, error) {
+	if err := CheckCodeGen(gen); err != nil {
+		return 0, err
+	}
+	if num < 1 || num > conf.CodeGenMax {
+		return 0, fmt.Errorf("生成数量需要在1到%d之间", conf.CodeGenMax)
+	}
+	// 编码空间太小，随机生成的编码很难不重复
+	if math.Pow(float64(len(gen.Alphabet)), float64(gen.Length)) < float64(num)*100 {
+		return 0, errors.New("编码空间太小，请增加编码长度或者编码字符")
+	}
+	if !lockCodeGen(cacheObj, gen.GiftId) {
+		return 0, errors.New("这个奖品正在生成编码，请稍后再试")
+	}
+	defer unlockCodeGen(cacheObj, gen.GiftId)
+
+	job := &models.ObjImportJob{GiftId: gen.GiftId}
+	seen := make(map[string]bool)
+	// 和已有的编码重复的时候重新生成，连续几轮都没有新的编码就放弃
+	for round := 0; round < 10 && job.Success < num; round++ {
+		size := num - job.Success
+		if size > conf.CodeImportBatch {
+			size = conf.CodeImportBatch
+		}
+		batch := make([]string, 0, size)
+		for len(batch) < size {
+			code, err := newGenCode(gen)
+			if err != nil {
+				return job.Success, err
+			}
+			if seen[code] {
+				continue
+			}
+			seen[code] = true
+			batch = append(batch, code)
+		}
+		success, failed := job.Success, job.Failed
+		importCodeBatch(cacheObj, codeService, params, batch, job)
+		if job.Failed > failed {
+			return job.Success, errors.New("保存编码失败")
+		}
+		if job.Success > success {
+			round = -1
+		}
+	}
+	if job.Success < num {
+		return job.Success, errors.New("生成的编码重复太多，请增加编码长度或者编码字符")
+	}
+	return job.Success, nil
+}
+
+// 剩余的编码少于阈值的奖品，自动补充编码
+// 返回，补充的编码数量
+func TopupCodes(cacheObj datasource.Cache, giftService services.GiftService,
+	codeService services.CodeService, codeGenService services.CodeGenService) int {
+	gifts := make(map[int]bool)
+	for _, gift := range giftService.GetAll(true) {
+		if gift.SysStatus == 0 && gift.Gtype == conf.GtypeCodeDiff {
+			gifts[gift.Id] = true
+		}
+	}
+	total := 0
+	for _, gen := range codeGenService.GetAll() {
+		if !gifts[gen.GiftId] || gen.Threshold < 1 || gen.TopupNum < 1 {
+			continue
+		}
+		if num := GetGiftCodeNum(cacheObj, gen.GiftId); num >= gen.Threshold {
+			continue
+		}
+		num, err := GenerateCodes(cacheObj, codeService, &gen, gen.TopupNum, &CodeImport{GiftId: gen.GiftId})
+		if err != nil {
+			log.Println("code_gen.TopupCodes giftId=", gen.GiftId, ", num=", num, ", error=", err)
+		}
+		total += num
+	}
+	return total
+}
+
+// 生成一个编码，前缀+随机字符+校验位
+func newGenCode(gen *models.LtCodeGen) (string, error) {
+	n := len(gen.Alphabet)
+	// 拒绝采样，保证每个字符的概率相同
+	limit := 256 - 256%n
+	buf := make([]byte, gen.Length)
+	code := make([]byte, 0, gen.Length+1)
+	for len(code) < gen.Length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(code) < gen.Length {
+				code = append(code, gen.Alphabet[int(b)%n])
+			}
+		}
+	}
+	if gen.Checksum > 0 {
+		code = append(code, codeChecksum(gen.Alphabet, string(code)))
+	}
+	return gen.Prefix + string(code), nil
+}
+
+// 检查按照生成规则生成的编码，格式和校验位是否正确
+func CheckGenCode(gen *models.LtCodeGen, code string) bool {
+	if !strings.HasPrefix(code, gen.Prefix) {
+		return false
+	}
+	body := code[len(gen.Prefix):]
+	size := gen.Length
+	if gen.Checksum > 0 {
+		size++
+	}
+	if len(body) != size {
+		return false
+	}
+	for i := 0; i < len(body); i++ {
+		if strings.IndexByte(gen.Alphabet, body[i]) < 0 {
+			return false
+		}
+	}
+	if gen.Checksum > 0 {
+		return codeChecksum(gen.Alphabet, body[:gen.Length]) == body[gen.Length]
+	}
+	return true
+}
+
+// Luhn mod N 算法的校验位，可以发现输错一个字符和相邻字符颠倒
+func codeChecksum(alphabet, str string) byte {
+	n := len(alphabet)
+	factor := 2
+	sum := 0
+	for i := len(str) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(alphabet, str[i])
+		factor = 3 - factor
+		sum += addend/n + addend%n
+	}
+	return alphabet[(n-sum%n)%n]
+}
+
+// 缓存中剩余的编码数量，不需要读取数据库
+func GetGiftCodeNum(cacheObj datasource.Cache, id int) int {
+	key := fmt.Sprintf("gift_code_%d", id)
+	num, err := cacheObj.Do("SCARD", key)
+	if err != nil {
+		log.Println("code_gen.GetGiftCodeNum SCARD error=", err)
+		return 0
+	}
+	return int(comm.GetInt64(num, 0))
+}
+
+// 同一个奖品同时只能有一个生成过程
+func lockCodeGen(cacheObj datasource.Cache, id int) bool {
+	key := fmt.Sprintf("gift_code_gen_lock_%d", id)
+	rs, _ := cacheObj.Do("SET", key, 1, "EX", 300, "NX")
+	return rs == "OK"
+}
+
+func unlockCodeGen(cacheObj datasource.Cache, id int) {
+	key := fmt.Sprintf("gift_code_gen_lock_%d", id)
+	cacheObj.Do("DEL", key)
+}
@@ -0,0 +1,225 @@
+/**
+ * 优惠券编码的批量导入
+ * 流式读取文件，分批写入数据库和缓存，导入进度保存在redis中
+ */
+package utils
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/datasource"
+	"github.com/iralance/go-lottery/models"
+	"github.com/iralance/go-lottery/services"
+	"io"
+	"log"
+	"strings"
+	"unicode"
+)
+
+const importJobRunning = "running"
+const importJobDone = "done"
+const importJobFailed = "failed"
+
+// 导入任务的参数
+type CodeImport struct {
+	GiftId    int
+	Filename  string
+	Csv       bool // csv文件只读取第一列
+	ValidFrom int
+	ValidTo   int
+}
+
+func getImportJobKey(id string) string {
+	return fmt.Sprintf("code_import_job_%s", id)
+}
+
+// 新建一个导入任务，返回任务ID
+func NewCodeImportJob(cacheObj datasource.Cache, params *CodeImport) string {
+	id := fmt.Sprintf("%d%04d", comm.NowUnix(), comm.Random(10000))
+	key := getImportJobKey(id)
+	_, err := cacheObj.Do("HMSET", key,
+		"GiftId", params.GiftId,
+		"Filename", params.Filename,
+		"Status", importJobRunning,
+		"Started", comm.NowUnix())
+	if err != nil {
+		log.Println("code_import.NewCodeImportJob HMSET error=", err)
+	}
+	cacheObj.Do("EXPIRE", key, conf.CodeImportJobExpire)
+	return id
+}
+
+// 读取导入任务的进度
+func GetCodeImportJob(cacheObj datasource.Cache, id string) *models.ObjImportJob {
+	dataMap, err := redis.StringMap(cacheObj.Do("HGETALL", getImportJobKey(id)))
+	if err != nil || len(dataMap) == 0 {
+		return nil
+	}
+	return &models.ObjImportJob{
+		Id:       id,
+		GiftId:   int(comm.GetInt64FromStringMap(dataMap, "GiftId", 0)),
+		Filename: comm.GetStringFromStringMap(dataMap, "Filename", ""),
+		Status:   comm.GetStringFromStringMap(dataMap, "Status", ""),
+		Total:    int(comm.GetInt64FromStringMap(dataMap, "Total", 0)),
+		Success:  int(comm.GetInt64FromStringMap(dataMap, "Success", 0)),
+		Dup:      int(comm.GetInt64FromStringMap(dataMap, "Dup", 0)),
+		Invalid:  int(comm.GetInt64FromStringMap(dataMap, "Invalid", 0)),
+		Failed:   int(comm.GetInt64FromStringMap(dataMap, "Failed", 0)),
+		Error:    comm.GetStringFromStringMap(dataMap, "Error", ""),
+		Started:  int(comm.GetInt64FromStringMap(dataMap, "Started", 0)),
+		Finished: int(comm.GetInt64FromStringMap(dataMap, "Finished", 0)),
+	}
+}
+
+// 执行导入任务，每一批都更新一次进度
+func RunCodeImport(cacheObj datasource.Cache, codeService services.CodeService,
+	id string, params *CodeImport, r io.Reader) *models.ObjImportJob {
+	job := &models.ObjImportJob{Id: id, GiftId: params.GiftId, Filename: params.Filename, Status: importJobRunning}
+	// 文件中已经出现过的编码
+	seen := make(map[string]bool)
+	batch := make([]string, 0, conf.CodeImportBatch)
+	flush := func() {
+		importCodeBatch(cacheObj, codeService, params, batch, job)
+		batch = batch[:0]
+		saveImportJob(cacheObj, job)
+	}
+	err := readCodes(r, params.Csv, func(code string) {
+		job.Total++
+		if !validImportCode(code) {
+			job.Invalid++
+			return
+		}
+		if seen[code] {
+			job.Dup++
+			return
+		}
+		seen[code] = true
+		batch = append(batch, code)
+		if len(batch) >= conf.CodeImportBatch {
+			flush()
+		}
+	})
+	flush()
+	job.Status = importJobDone
+	if err != nil {
+		job.Status = importJobFailed
+		job.Error = err.Error()
+	}
+	job.Finished = comm.NowUnix()
+	saveImportJob(cacheObj, job)
+	return job
+}
+
+// 一行一个编码，csv文件读取第一列，第一行是表头的时候跳过
+func readCodes(r io.Reader, isCsv bool, fn func(code string)) error {
+	first := true
+	handle := func(code string) {
+		code = strings.TrimSpace(strings.TrimPrefix(code, "\xEF\xBB\xBF"))
+		if first {
+			first = false
+			if isCsv && (strings.EqualFold(code, "code") || code == "编码") {
+				return
+			}
+		}
+		if code != "" {
+			fn(code)
+		}
+	}
+	if isCsv {
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.ReuseRecord = true
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if len(record) > 0 {
+				handle(record[0])
+			}
+		}
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		handle(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// 编码不能超过255个字符，不能有空白和控制字符
+func validImportCode(code string) bool {
+	if len(code) > 255 {
+		return false
+	}
+	for _, c := range code {
+		if unicode.IsSpace(c) || unicode.IsControl(c) || c == unicode.ReplacementChar {
+			return false
+		}
+	}
+	return true
+}
+
+// 去掉数据库中已经存在的编码，剩下的在一个事务中写入数据库，然后一次写入缓存
+func importCodeBatch(cacheObj datasource.Cache, codeService services.CodeService,
+	params *CodeImport, codes []string, job *models.ObjImportJob) {
+	if len(codes) == 0 {
+		return
+	}
+	exists := make(map[string]bool)
+	for _, code := range codeService.SearchExistCodes(codes) {
+		exists[code] = true
+	}
+	now := comm.NowUnix()
+	datalist := make([]models.LtCode, 0, len(codes))
+	newCodes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		if exists[code] {
+			job.Dup++
+			continue
+		}
+		datalist = append(datalist, models.LtCode{
+			GiftId:     params.GiftId,
+			Code:       code,
+			ValidFrom:  params.ValidFrom,
+			ValidTo:    params.ValidTo,
+			SysCreated: now,
+		})
+		newCodes = append(newCodes, code)
+	}
+	if len(datalist) == 0 {
+		return
+	}
+	if _, err := codeService.CreateBatch(datalist); err != nil {
+		log.Println("code_import.importCodeBatch CreateBatch error=", err)
+		job.Failed += len(datalist)
+		return
+	}
+	// 成功导入数据库，还需要导入到缓存中
+	if !ImportCacheCodes(cacheObj, params.GiftId, newCodes...) {
+		job.Failed += len(newCodes)
+		return
+	}
+	job.Success += len(newCodes)
+}
+
+func saveImportJob(cacheObj datasource.Cache, job *models.ObjImportJob) {
+	_, err := cacheObj.Do("HMSET", getImportJobKey(job.Id),
+		"Status", job.Status,
+		"Total", job.Total,
+		"Success", job.Success,
+		"Dup", job.Dup,
+		"Invalid", job.Invalid,
+		"Failed", job.Failed,
+		"Error", job.Error,
+		"Finished", job.Finished)
+	if err != nil {
+		log.Println("code_import.saveImportJob HMSET error=", err)
+	}
+}
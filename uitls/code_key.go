@@ -0,0 +1,45 @@
+/**
+ * 优惠券编码的密钥轮换
+ * 修改conf.CodeKeyCurrent之后，使用新的密钥重新加密旧的编码
+ */
+package utils
+
+import (
+	"fmt"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/datasource"
+	"github.com/iralance/go-lottery/services"
+	"log"
+)
+
+// 使用当前的密钥重新加密所有旧密钥加密和没有加密的编码
+// 返回，成功数量，失败数量
+func RotateCodeKeys(cacheObj datasource.Cache, codeService services.CodeService) (sucNum, errNum int) {
+	lastId := 0
+	size := 500
+	for {
+		list := codeService.SearchOldKey(lastId, size)
+		for _, data := range list {
+			lastId = data.Id
+			if err := codeService.Reencrypt(&data); err != nil {
+				log.Println("code_key.RotateCodeKeys Reencrypt id=", data.Id, ", error=", err)
+				errNum++
+				continue
+			}
+			sucNum++
+			// 没有加密的旧数据，缓存中的明文换成HMAC
+			if data.KeyId == 0 && data.SysStatus == conf.CodeStatusNormal {
+				key := fmt.Sprintf("gift_code_%d", data.GiftId)
+				rs, err := cacheObj.Do("SREM", key, data.Code)
+				if err == nil && comm.GetInt64(rs, 0) > 0 {
+					ImportCacheCodes(cacheObj, data.GiftId, data.Code)
+				}
+			}
+		}
+		if len(list) < size {
+			break
+		}
+	}
+	return sucNum, errNum
+}
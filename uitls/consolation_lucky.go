@@ -0,0 +1,42 @@
+/**
+ * 同一个User每天获得安慰奖的次数，redis缓存
+ * 和中奖次数分开统计，安慰奖不占用中奖次数
+ */
+package utils
+
+import (
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/datasource"
+	"log"
+	"time"
+)
+
+func consolationDayKey(uid int) string {
+	y, m, d := time.Now().In(conf.SysTimeLocation).Date()
+	return fmt.Sprintf("consolation_day_%d%02d%02d_%d", y, m, d, uid)
+}
+
+// 用户今天获得安慰奖的次数
+func GetConsolationNum(cacheObj datasource.Cache, uid int) int {
+	key := consolationDayKey(uid)
+	num, err := redis.Int(cacheObj.Do("GET", key))
+	if err != nil && err != redis.ErrNil {
+		log.Println("consolation_lucky redis GET key=", key, ", err=", err)
+	}
+	return num
+}
+
+// 发放安慰奖之后，用户今天获得安慰奖的次数递增
+func IncrConsolationNum(cacheObj datasource.Cache, uid int) {
+	key := consolationDayKey(uid)
+	num, err := redis.Int(cacheObj.Do("INCR", key))
+	if err != nil {
+		log.Println("consolation_lucky redis INCR key=", key, ", err=", err)
+		return
+	}
+	if num == 1 {
+		cacheObj.Do("EXPIRE", key, 2*86400)
+	}
+}
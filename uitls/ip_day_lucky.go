@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"github.com/iralance/go-lottery/comm"
 	"github.com/iralance/go-lottery/datasource"
+	"hash/crc32"
 	"log"
 	"math"
 	"time"
@@ -14,19 +15,10 @@ import (
 
 const ipFrameSize = 2
 
-func init() {
-	//// IP当天的统计数，整点归零，设置定时器
-	//duration := comm.NextDayDuration()
-	//time.AfterFunc(duration, resetGroupIpList)
-
-	// 本地开发测试的时候，每次启动归零
-	resetGroupIpList()
-}
-
-// 重置单机IP今天次数
-func resetGroupIpList() {
+// 重置IP今天次数，每天零点再次归零
+// 本地开发测试的时候，每次启动归零
+func ResetGroupIpList(cacheObj datasource.Cache) {
 	log.Println("ip_day_lucky.resetGroupIpList start")
-	cacheObj := datasource.InstanceCache()
 	for i := 0; i < ipFrameSize; i++ {
 		key := fmt.Sprintf("day_ips_%d", i)
 		cacheObj.Do("DEL", key)
@@ -34,37 +26,32 @@ func resetGroupIpList() {
 	log.Println("ip_day_lucky.resetGroupIpList stop")
 	// IP当天的统计数，整点归零，设置定时器
 	duration := comm.NextDayDuration()
-	time.AfterFunc(duration, resetGroupIpList)
+	time.AfterFunc(duration, func() { ResetGroupIpList(cacheObj) })
 }
 
 // 今天的IP抽奖次数递增，返回递增后的数值
-func IncrIpLucyNum(strIp string) int64 {
-	ip := comm.Ip4toInt(strIp)
-	i := ip % ipFrameSize
-	// 集群的redis统计数递增
-	return incrServIpLucyNum(i, ip)
+func IncrIpLucyNum(cacheObj datasource.Cache, strIp string) int64 {
+	return IncrIpLuckyNum(cacheObj, strIp)
 }
 
-func incrServIpLucyNum(i, ip int64) int64 {
-	key := fmt.Sprintf("day_ips_%d", i)
-	cacheObj := datasource.InstanceCache()
-	rs, err := cacheObj.Do("HINCRBY", key, ip, 1)
-	if err != nil {
-		log.Println("ip_day_lucky redis HINCRBY err=", err)
-		return math.MaxInt32
-	} else {
-		return rs.(int64)
-	}
+// IPv4按照完整的地址统计，IPv6按照前缀网段统计
+func IncrIpLuckyNum(cacheObj datasource.Cache, strIp string) int64 {
+	return IncrIpLuckyNumBy(cacheObj, strIp, 1)
 }
 
-func IncrIpLuckyNum(strIp string) int64 {
-	ip := comm.Ip4toInt(strIp)
-	i := ip % ipFrameSize
+// 一次抽奖多次，今天的IP抽奖次数增加n
+func IncrIpLuckyNumBy(cacheObj datasource.Cache, strIp string, n int) int64 {
+	ip := comm.IpCounterKey(strIp)
+	i := crc32.ChecksumIEEE([]byte(ip)) % ipFrameSize
+	// 集群的redis统计数递增
+	return incrServIpLucyNum(cacheObj, i, ip, n)
+}
+
+func incrServIpLucyNum(cacheObj datasource.Cache, i uint32, ip string, n int) int64 {
 	key := fmt.Sprintf("day_ips_%d", i)
-	cacheObj := datasource.InstanceCache()
-	rs, err := cacheObj.Do("HINCRBY", key, ip, 1)
+	rs, err := cacheObj.Do("HINCRBY", key, ip, n)
 	if err != nil {
-		log.Println("ip_day_lucky redis HINCRBY error=", err)
+		log.Println("ip_day_lucky redis HINCRBY err=", err)
 		return math.MaxInt32
 	} else {
 		return rs.(int64)
@@ -0,0 +1,150 @@
+/**
+ * 用户、IP、设备之间的关联关系，redis集合保存
+ * 根据关联的数量计算风险分，识别换号、换IP的刷奖行为
+ */
+package utils
+
+import (
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/datasource"
+	"github.com/iralance/go-lottery/models"
+	"log"
+	"sort"
+	"strconv"
+)
+
+// 关联页面上每个集合最多展示的数量
+const linkShowMax = 100
+
+func getLinkDeviceKey(device string) string {
+	return fmt.Sprintf("link_device_%s", device)
+}
+
+func getLinkIpKey(ip string) string {
+	return fmt.Sprintf("link_ip_%s", comm.IpCounterKey(ip))
+}
+
+func getLinkUidDevicesKey(uid int) string {
+	return fmt.Sprintf("link_uid_devices_%d", uid)
+}
+
+func getLinkUidIpsKey(uid int) string {
+	return fmt.Sprintf("link_uid_ips_%d", uid)
+}
+
+// 记录这次抽奖的关联关系，没有设备标识的时候只记录用户和IP
+func RecordLink(cacheObj datasource.Cache, uid int, ip, device string) {
+	members := map[string]interface{}{
+		getLinkIpKey(ip):      uid,
+		getLinkUidIpsKey(uid): comm.IpCounterKey(ip),
+	}
+	if device != "" {
+		members[getLinkDeviceKey(device)] = uid
+		members[getLinkUidDevicesKey(uid)] = device
+	}
+	for key, member := range members {
+		_, err := cacheObj.Do("SADD", key, member)
+		if err != nil {
+			log.Println("link_lucky redis SADD key=", key, ", err=", err)
+			continue
+		}
+		cacheObj.Do("EXPIRE", key, conf.LinkExpire)
+	}
+}
+
+// 关联的风险分，关联的对象越多分数越高，每一项的分数有上限
+func LinkScore(cacheObj datasource.Cache, uid int, ip, device string) int {
+	return linkScore(cacheObj, uid, ip, device, true)
+}
+
+// 不包含同一个IP上其他用户的风险分，用于拒绝抽奖的规则
+// NAT、负载均衡后面的正常用户也会共用IP，这一项只用来降级
+func LinkScoreWithoutIp(cacheObj datasource.Cache, uid int, ip, device string) int {
+	return linkScore(cacheObj, uid, ip, device, false)
+}
+
+func linkScore(cacheObj datasource.Cache, uid int, ip, device string, withIp bool) int {
+	score := 0
+	if device != "" {
+		score += linkPart(cacheObj, getLinkDeviceKey(device), conf.LinkScoreDeviceUid, conf.LinkScoreDeviceUidMax)
+		score += linkPart(cacheObj, getLinkUidDevicesKey(uid), conf.LinkScoreUidDevice, conf.LinkScoreUidDeviceMax)
+	}
+	score += linkPart(cacheObj, getLinkUidIpsKey(uid), conf.LinkScoreUidIp, conf.LinkScoreUidIpMax)
+	if withIp {
+		score += linkPart(cacheObj, getLinkIpKey(ip), conf.LinkScoreIpUid, conf.LinkScoreIpUidMax)
+	}
+	return score
+}
+
+// 一项关联的分数，不超过上限
+func linkPart(cacheObj datasource.Cache, key string, score, max int) int {
+	num := linkOthers(cacheObj, key) * score
+	if num > max {
+		return max
+	}
+	return num
+}
+
+// 集合中除了自己之外的数量
+func linkOthers(cacheObj datasource.Cache, key string) int {
+	rs, err := cacheObj.Do("SCARD", key)
+	if err != nil {
+		log.Println("link_lucky redis SCARD key=", key, ", err=", err)
+		return 0
+	}
+	num := int(comm.GetInt64(rs, 0)) - 1
+	if num < 0 {
+		return 0
+	}
+	return num
+}
+
+// 用户使用过的设备、IP，以及这些设备、IP上的其他用户
+func GetLinkage(cacheObj datasource.Cache, uid int, ip, device string) *models.ObjLinkage {
+	data := &models.ObjLinkage{
+		Uid:        uid,
+		Ip:         ip,
+		Device:     device,
+		DeviceUids: make(map[string][]int),
+		IpUids:     make(map[string][]int),
+		Score:      LinkScore(cacheObj, uid, ip, device),
+	}
+	devices := linkMembers(cacheObj, getLinkUidDevicesKey(uid))
+	if device != "" {
+		devices = append(devices, device)
+	}
+	for _, d := range devices {
+		data.DeviceUids[d] = linkUids(cacheObj, getLinkDeviceKey(d))
+	}
+	for _, i := range append(linkMembers(cacheObj, getLinkUidIpsKey(uid)), ip) {
+		data.IpUids[comm.IpCounterKey(i)] = linkUids(cacheObj, getLinkIpKey(i))
+	}
+	return data
+}
+
+func linkMembers(cacheObj datasource.Cache, key string) []string {
+	list, err := redis.Strings(cacheObj.Do("SMEMBERS", key))
+	if err != nil {
+		log.Println("link_lucky redis SMEMBERS key=", key, ", err=", err)
+		return nil
+	}
+	sort.Strings(list)
+	if len(list) > linkShowMax {
+		list = list[:linkShowMax]
+	}
+	return list
+}
+
+func linkUids(cacheObj datasource.Cache, key string) []int {
+	uids := make([]int, 0)
+	for _, str := range linkMembers(cacheObj, key) {
+		if uid, err := strconv.Atoi(str); err == nil {
+			uids = append(uids, uid)
+		}
+	}
+	sort.Ints(uids)
+	return uids
+}
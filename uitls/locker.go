@@ -10,22 +10,21 @@ import (
 )
 
 // 加锁，抽奖的时候需要用到的锁，避免一个用户并发多次抽奖
-func LockLucky(uid int) bool {
-	return lockLuckyServ(uid)
+func LockLucky(cache datasource.Cache, uid int) bool {
+	return lockLuckyServ(cache, uid)
 }
 
 // 解锁，抽奖的时候需要用到的锁，避免一个用户并发多次抽奖
-func UnlockLucky(uid int) bool {
-	return unlockLuckyServ(uid)
+func UnlockLucky(cache datasource.Cache, uid int) bool {
+	return unlockLuckyServ(cache, uid)
 }
 
 func getLuckyLockKey(uid int) string {
 	return fmt.Sprintf("lucky_lock_%d", uid)
 }
 
-func lockLuckyServ(uid int) bool {
+func lockLuckyServ(cacheObj datasource.Cache, uid int) bool {
 	key := getLuckyLockKey(uid)
-	cacheObj := datasource.InstanceCache()
 	rs, _ := cacheObj.Do("SET", key, 1, "EX", 3, "NX")
 	if rs == "OK" {
 		return true
@@ -34,9 +33,8 @@ func lockLuckyServ(uid int) bool {
 	}
 }
 
-func unlockLuckyServ(uid int) bool {
+func unlockLuckyServ(cacheObj datasource.Cache, uid int) bool {
 	key := getLuckyLockKey(uid)
-	cacheObj := datasource.InstanceCache()
 	rs, _ := cacheObj.Do("DEL", key)
 	if rs == "OK" {
 		return true
@@ -0,0 +1,117 @@
+/**
+ * 同一个User连续没有中奖的次数，redis缓存
+ * 中奖之后归零，达到保底规则的次数之后提高中奖概率或者必定中奖
+ */
+package utils
+
+import (
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/datasource"
+	"log"
+	"strconv"
+)
+
+// 用户连续没有中奖的次数，以及最后更新的日期，一段时间没有抽奖之后过期
+func getPityLossKey(uid int) string {
+	return fmt.Sprintf("pity_loss_%d", uid)
+}
+
+// 每天更新的用户中，每个连续没有中奖次数的用户数，field是次数
+// 比用户的数据晚一天过期，用户的数据存在的时候，对应日期的统计一定存在
+func getPityLossNumsKey(day int) string {
+	return fmt.Sprintf("pity_loss_nums_%d", day)
+}
+
+func pityLossDay() int {
+	return comm.NowUnix() / 86400
+}
+
+// 用户连续没有中奖的次数
+func GetLossNum(cacheObj datasource.Cache, uid int) int {
+	num, err := redis.Int(cacheObj.Do("HGET", getPityLossKey(uid), "num"))
+	if err != nil && err != redis.ErrNil {
+		log.Println("pity_lucky.GetLossNum HGET uid=", uid, ", error=", err)
+	}
+	return num
+}
+
+// 设置连续没有中奖的次数，中奖之后归零，同时更新每天的统计
+func SetLossNum(cacheObj datasource.Cache, uid, num int) {
+	key := getPityLossKey(uid)
+	old, err := redis.Ints(cacheObj.Do("HMGET", key, "num", "day"))
+	if err != nil {
+		log.Println("pity_lucky.SetLossNum HMGET uid=", uid, ", error=", err)
+		return
+	}
+	if len(old) == 2 && old[0] == num && old[1] == pityLossDay() {
+		return
+	}
+	if len(old) == 2 && old[0] > 0 {
+		cacheObj.Do("HINCRBY", getPityLossNumsKey(old[1]), old[0], -1)
+	}
+	if num <= 0 {
+		if _, err = cacheObj.Do("DEL", key); err != nil {
+			log.Println("pity_lucky.SetLossNum DEL uid=", uid, ", error=", err)
+		}
+		return
+	}
+	day := pityLossDay()
+	expire := conf.PityLossExpireDays * 86400
+	if _, err = cacheObj.Do("HMSET", key, "num", num, "day", day); err != nil {
+		log.Println("pity_lucky.SetLossNum HMSET uid=", uid, ", error=", err)
+		return
+	}
+	cacheObj.Do("EXPIRE", key, expire)
+	numsKey := getPityLossNumsKey(day)
+	cacheObj.Do("HINCRBY", numsKey, num, 1)
+	cacheObj.Do("EXPIRE", numsKey, expire+86400)
+}
+
+// 每个连续没有中奖次数的用户数，后台统计使用
+func GetLossCounts(cacheObj datasource.Cache) map[int]int {
+	counts := make(map[int]int)
+	today := pityLossDay()
+	for day := today - conf.PityLossExpireDays; day <= today; day++ {
+		nums, err := redis.IntMap(cacheObj.Do("HGETALL", getPityLossNumsKey(day)))
+		if err != nil {
+			log.Println("pity_lucky.GetLossCounts HGETALL day=", day, ", error=", err)
+			continue
+		}
+		for str, userNum := range nums {
+			if num, err := strconv.Atoi(str); err == nil && userNum > 0 {
+				counts[num] += userNum
+			}
+		}
+	}
+	return counts
+}
+
+// 连续没有中奖次数的用户分布，rs[i]是次数在[buckets[i], buckets[i+1])之间的用户数
+// counts的key是连续没有中奖的次数，value是用户数
+func LossDistribution(counts map[int]int, buckets []int) []int {
+	rs := make([]int, len(buckets))
+	for num, userNum := range counts {
+		for i := len(buckets) - 1; i >= 0; i-- {
+			if num >= buckets[i] {
+				rs[i] += userNum
+				break
+			}
+		}
+	}
+	return rs
+}
+
+// 提高概率的保底规则，连续没有中奖lossNum次之后的中奖概率，万分之几
+func PityBoostRate(lossNum, num, boost int) int {
+	if lossNum < num {
+		return 0
+	}
+	rate := (lossNum - num + 1) * boost
+	if rate > 10000 {
+		rate = 10000
+	}
+	return rate
+}
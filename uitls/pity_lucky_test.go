@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/iralance/go-lottery/datasource"
+)
+
+func TestLossDistribution(t *testing.T) {
+	buckets := []int{0, 10, 20, 50}
+	got := LossDistribution(map[int]int{3: 2, 9: 1, 10: 1, 19: 1, 20: 1, 49: 1, 50: 1, 1000: 1}, buckets)
+	want := []int{3, 2, 2, 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LossDistribution = %v, want %v", got, want)
+	}
+	if got := LossDistribution(nil, buckets); !reflect.DeepEqual(got, []int{0, 0, 0, 0}) {
+		t.Errorf("LossDistribution(nil) = %v", got)
+	}
+}
+
+func TestPityBoostRate(t *testing.T) {
+	tests := []struct {
+		lossNum, num, boost int
+		want                int
+	}{
+		{9, 10, 500, 0},
+		{10, 10, 500, 500},
+		{12, 10, 500, 1500},
+		{100, 10, 500, 10000},
+		{10, 10, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := PityBoostRate(tt.lossNum, tt.num, tt.boost); got != tt.want {
+			t.Errorf("PityBoostRate(%d, %d, %d) = %d, want %d", tt.lossNum, tt.num, tt.boost, got, tt.want)
+		}
+	}
+}
+
+func TestSetLossNum(t *testing.T) {
+	tests := []struct {
+		uid, num int
+		want     map[int]int
+	}{
+		{1, 1, map[int]int{1: 1}},
+		{2, 1, map[int]int{1: 2}},
+		{1, 2, map[int]int{1: 1, 2: 1}},
+		// 次数没有变化，不重复统计
+		{1, 2, map[int]int{1: 1, 2: 1}},
+		{2, 3, map[int]int{2: 1, 3: 1}},
+		// 中奖之后归零，不在统计中
+		{1, 0, map[int]int{3: 1}},
+		{1, 0, map[int]int{3: 1}},
+		{2, 0, map[int]int{}},
+	}
+	cache := datasource.NewMemCache()
+	for i, tt := range tests {
+		SetLossNum(cache, tt.uid, tt.num)
+		if got := GetLossNum(cache, tt.uid); got != tt.num {
+			t.Errorf("step %d: GetLossNum(%d) = %d, want %d", i, tt.uid, got, tt.num)
+		}
+		if got := GetLossCounts(cache); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("step %d: GetLossCounts = %v, want %v", i, got, tt.want)
+		}
+	}
+}
@@ -13,17 +13,12 @@ import (
 	"time"
 )
 
-func init() {
-	// 本地开发测试的时候，每次重新启动，奖品池自动归零
-	resetServGiftPool()
-}
-
 // 重置一个奖品的发奖周期信息
 // 奖品剩余数量也会重新设置为当前奖品数量
 // 奖品的奖品池有效数量则会设置为空
 // 奖品数量、发放周期等设置有修改的时候，也需要重置
 // 【难点】根据发奖周期，重新更新发奖计划
-func ResetGiftPrizeData(giftInfo *models.LtGift, giftService services.GiftService) {
+func ResetGiftPrizeData(cacheObj datasource.Cache, giftInfo *models.LtGift, giftService services.GiftService) {
 	if giftInfo == nil || giftInfo.Id < 1 {
 		return
 	}
@@ -36,20 +31,20 @@ func ResetGiftPrizeData(giftInfo *models.LtGift, giftService services.GiftServic
 		giftInfo.LeftNum <= 0 || // 剩余数不足
 		giftInfo.PrizeNum <= 0 { // 总数不限制
 		if giftInfo.PrizeData != "" {
-			clearGiftPrizeData(giftInfo, giftService)
+			clearGiftPrizeData(cacheObj, giftInfo, giftService)
 		}
 		return
 	}
 	// 不限制发奖周期，直接把奖品数量全部设置上
 	dayNum := giftInfo.PrizeTime
 	if dayNum <= 0 {
-		setGiftPool(id, giftInfo.LeftNum)
+		setGiftPool(cacheObj, id, giftInfo.LeftNum)
 		return
 	}
 
 	// 重新计算出来合适的奖品发放节奏
 	// 奖品池的剩余数先设置为空
-	setGiftPool(id, 0)
+	setGiftPool(cacheObj, id, 0)
 
 	// 每天的概率一样
 	// 一天内24小时，每个小时的概率是不一样的
@@ -111,10 +106,9 @@ func ResetGiftPrizeData(giftInfo *models.LtGift, giftService services.GiftServic
  * 需要每分钟执行一次
  * 【难点】定时程序，根据奖品设置的数据，更新奖品池的数据
  */
-func DistributionGiftPool() int {
+func DistributionGiftPool(cacheObj datasource.Cache, giftService services.GiftService) int {
 	totalNum := 0
 	now := comm.NowUnix()
-	giftService := services.NewGiftService()
 	list := giftService.GetAll(false)
 	if list != nil && len(list) > 0 {
 		for _, gift := range list {
@@ -155,7 +149,7 @@ func DistributionGiftPool() int {
 				}
 				// 有奖品需要放入到奖品池
 				if giftNum > 0 {
-					incrGiftPool(gift.Id, giftNum)
+					incrGiftPool(cacheObj, gift.Id, giftNum)
 					totalNum += giftNum
 				}
 				// 有计划数据被执行过，需要更新到数据库
@@ -190,12 +184,11 @@ func DistributionGiftPool() int {
 }
 
 // 发奖，指定的奖品是否还可以发出来奖品
-func PrizeGift(id, leftNum int) bool {
+func PrizeGift(cacheObj datasource.Cache, id, leftNum int, giftService services.GiftService) bool {
 	ok := false
-	ok = prizeServGift(id)
+	ok = prizeServGift(cacheObj, id)
 	if ok {
 		// 更新数据库，减少奖品的库存
-		giftService := services.NewGiftService()
 		rows, err := giftService.DecrLeftNum(id, 1)
 		if rows < 1 || err != nil {
 			log.Println("prizedata.PrizeGift giftService.DecrLeftNum error=", err, ", rows=", rows)
@@ -206,54 +199,106 @@ func PrizeGift(id, leftNum int) bool {
 	return ok
 }
 
+// 撤销发奖，奖品放回奖品池，并且恢复库存
+func ReturnPrizeGift(cacheObj datasource.Cache, id int, giftService services.GiftService) bool {
+	rows, err := giftService.IncrLeftNum(id, 1)
+	if rows < 1 || err != nil {
+		log.Println("prizedata.ReturnPrizeGift giftService.IncrLeftNum error=", err, ", rows=", rows)
+		return false
+	}
+	incrGiftPool(cacheObj, id, 1)
+	return true
+}
+
+// 撤销优惠券的发放，requeue为true时重新放回缓存可以再次发放，否则作废
+func ReturnCodeDiff(cacheObj datasource.Cache, id, codeId int, requeue bool,
+	codeService services.CodeService) bool {
+	status := conf.CodeStatusVoid
+	if requeue {
+		status = conf.CodeStatusNormal
+	}
+	data := codeService.Get(codeId)
+	if data == nil {
+		log.Println("prizedata.ReturnCodeDiff code not found, giftId=", id, ", codeId=", codeId)
+		return false
+	}
+	// 只有已发放的优惠券才能退回，已经核销或者过期的不处理
+	ok, err := codeService.UpdateStatus(&models.LtCode{
+		Id:         data.Id,
+		SysStatus:  status,
+		SysUpdated: comm.NowUnix(),
+	}, []int{conf.CodeStatusIssued}, []string{"sys_status", "sys_updated"})
+	if err != nil {
+		log.Println("prizedata.ReturnCodeDiff codeService.UpdateStatus error=", err)
+		return false
+	}
+	if !ok {
+		log.Println("prizedata.ReturnCodeDiff code is not issued, id=", data.Id, ", status=", data.SysStatus)
+		return false
+	}
+	if requeue {
+		key := fmt.Sprintf("gift_code_%d", id)
+		if _, err = cacheObj.Do("SADD", key, codeCacheMember(data)); err != nil {
+			log.Println("prizedata.ReturnCodeDiff SADD error=", err)
+			return false
+		}
+	}
+	return true
+}
+
 // 获取当前奖品池中的奖品数量
-func GetGiftPoolNum(id int) int {
+func GetGiftPoolNum(cacheObj datasource.Cache, id int) int {
 	num := 0
-	num = getServGiftPoolNum(id)
+	num = getServGiftPoolNum(cacheObj, id)
 	return num
 }
 
-// 优惠券类的发放
-func PrizeCodeDiff(id int, codeService services.CodeService) string {
-	return prizeServCodeDiff(id, codeService)
+// 优惠券类的发放，返回优惠券的ID和明文的编码
+func PrizeCodeDiff(cacheObj datasource.Cache, id int, codeService services.CodeService) (int, string) {
+	return prizeServCodeDiff(cacheObj, id, codeService)
 }
 
 // 获取当前的缓存中编码数量
 // 返回，剩余编码数量，缓冲中编码数量
-func GetCacheCodeNum(id int, codeService services.CodeService) (int, int) {
-	num := 0
-	cacheNum := 0
+func GetCacheCodeNum(cacheObj datasource.Cache, id int, codeService services.CodeService) (int, int) {
 	// 统计数据库中有效编码数量
-	list := codeService.Search(id)
-	if len(list) > 0 {
-		for _, data := range list {
-			if data.SysStatus == 0 {
-				num++
-			}
-		}
-	}
+	num := int(codeService.CountPage(id, conf.CodeStatusNormal))
+	cacheNum := GetGiftCodeNum(cacheObj, id)
+	return num, cacheNum
+}
 
-	// redis中缓存的key值
-	key := fmt.Sprintf("gift_code_%d", id)
-	cacheObj := datasource.InstanceCache()
-	rs, err := cacheObj.Do("SCARD", key)
-	if err != nil {
-		log.Println("prizedata.RecacheCodes RENAME error=", err)
-	} else {
-		cacheNum = int(comm.GetInt64(rs, 0))
+// 奖品池的健康状况，编码的状态统计以及缓存中的数量
+// 缓存和数据库中可以发放的数量不一致的时候给出提醒
+func CodePoolHealth(cacheObj datasource.Cache, id int, codeService services.CodeService) *models.ObjCodeStats {
+	stats := codeService.Stats(id)
+	stats.CacheNum = int64(GetGiftCodeNum(cacheObj, id))
+	switch {
+	case stats.CacheNum > stats.Normal:
+		stats.Alert = fmt.Sprintf("缓存中有%d个编码已经不能发放，请重整缓存", stats.CacheNum-stats.Normal)
+	case stats.CacheNum < stats.Normal:
+		stats.Alert = fmt.Sprintf("有%d个可以发放的编码不在缓存中，请重整缓存", stats.Normal-stats.CacheNum)
+	case stats.Normal == 0:
+		stats.Alert = "没有可以发放的编码，请导入或者生成编码"
 	}
-
-	return num, cacheNum
+	return stats
 }
 
-// 导入新的优惠券编码
-func ImportCacheCodes(id int, code string) bool {
+// 导入新的优惠券编码，多个编码一次写入
+// 缓存中只保存编码的HMAC，发放的时候再从数据库中解密
+func ImportCacheCodes(cacheObj datasource.Cache, id int, codes ...string) bool {
+	if len(codes) == 0 {
+		return true
+	}
 	// 集群版本需要放入到redis中
 	// [暂时]本机版本的就直接从数据库中处理吧
 	// redis中缓存的key值
 	key := fmt.Sprintf("gift_code_%d", id)
-	cacheObj := datasource.InstanceCache()
-	_, err := cacheObj.Do("SADD", key, code)
+	params := make([]interface{}, 0, len(codes)+1)
+	params = append(params, key)
+	for _, code := range codes {
+		params = append(params, comm.CodeHash(code))
+	}
+	_, err := cacheObj.Do("SADD", params...)
 	if err != nil {
 		log.Println("prizedata.RecacheCodes SADD error=", err)
 		return false
@@ -262,22 +307,68 @@ func ImportCacheCodes(id int, code string) bool {
 	}
 }
 
+// 处理过了有效期的优惠券，没有发放的作废并且从缓存中移除，发放了没有核销的过期
+// 返回处理的数量
+func ExpireCodes(cacheObj datasource.Cache, codeService services.CodeService) int {
+	num := 0
+	now := comm.NowUnix()
+	size := 100
+	for {
+		list := codeService.SearchExpired(now, size)
+		for _, data := range list {
+			status := conf.CodeStatusExpired
+			if data.SysStatus == conf.CodeStatusNormal {
+				status = conf.CodeStatusVoid
+			}
+			ok, err := codeService.UpdateStatus(&models.LtCode{
+				Id:         data.Id,
+				SysStatus:  status,
+				SysUpdated: now,
+			}, []int{data.SysStatus}, []string{"sys_status"})
+			if err != nil {
+				log.Println("prizedata.ExpireCodes UpdateStatus error=", err)
+				return num
+			}
+			if !ok {
+				continue
+			}
+			num++
+			if status == conf.CodeStatusVoid {
+				key := fmt.Sprintf("gift_code_%d", data.GiftId)
+				cacheObj.Do("SREM", key, codeCacheMember(&data))
+			}
+		}
+		if len(list) < size {
+			break
+		}
+	}
+	return num
+}
+
 // 重新整理优惠券的编码到缓存中
-func RecacheCodes(id int, codeService services.CodeService) (sucNum, errNum int) {
+func RecacheCodes(cacheObj datasource.Cache, id int, codeService services.CodeService) (sucNum, errNum int) {
 	// 集群版本需要放入到redis中
 	// [暂时]本机版本的就直接从数据库中处理吧
-	list := codeService.Search(id)
-	if list == nil || len(list) <= 0 {
-		return 0, 0
-	}
 	// redis中缓存的key值
 	key := fmt.Sprintf("gift_code_%d", id)
-	cacheObj := datasource.InstanceCache()
 	tmpKey := "tmp_" + key
-	for _, data := range list {
-		if data.SysStatus == 0 {
-			code := data.Code
-			_, err := cacheObj.Do("SADD", tmpKey, code)
+	cacheObj.Do("DEL", tmpKey)
+	// 只读取可以发放的编码，分页读取
+	now := comm.NowUnix()
+	expired := make([]int, 0)
+	size := 1000
+	for page := 1; ; page++ {
+		list := codeService.SearchPage(id, conf.CodeStatusNormal, page, size)
+		for _, data := range list {
+			// 过了有效期的编码翻页结束之后作废，还没有到有效期的编码不放入缓存，到期之后再重新整理
+			if data.ValidTo > 0 && now > data.ValidTo {
+				expired = append(expired, data.Id)
+				continue
+			}
+			if data.ValidFrom > 0 && now < data.ValidFrom {
+				continue
+			}
+			_, err := cacheObj.Do("SADD", tmpKey, codeCacheMember(&data))
 			if err != nil {
 				log.Println("prizedata.RecacheCodes SADD error=", err)
 				errNum++
@@ -285,6 +376,24 @@ func RecacheCodes(id int, codeService services.CodeService) (sucNum, errNum int)
 				sucNum++
 			}
 		}
+		if len(list) < size {
+			break
+		}
+	}
+	for _, codeId := range expired {
+		_, err := codeService.UpdateStatus(&models.LtCode{
+			Id:         codeId,
+			SysStatus:  conf.CodeStatusVoid,
+			SysUpdated: now,
+		}, []int{conf.CodeStatusNormal}, []string{"sys_status"})
+		if err != nil {
+			log.Println("prizedata.RecacheCodes UpdateStatus error=", err)
+		}
+	}
+	if sucNum == 0 {
+		// 没有可以发放的编码，清空缓存
+		cacheObj.Do("DEL", key)
+		return sucNum, errNum
 	}
 	_, err := cacheObj.Do("RENAME", tmpKey, key)
 	if err != nil {
@@ -378,9 +487,9 @@ func formatGiftPrizeData(nowTime, dayNum int, prizeData map[int]map[int][60]int)
 }
 
 // 重置集群的奖品池
-func resetServGiftPool() {
+// 本地开发测试的时候，每次重新启动，奖品池自动归零
+func ResetGiftPool(cacheObj datasource.Cache) {
 	key := "gift_pool"
-	cacheObj := datasource.InstanceCache()
 	_, err := cacheObj.Do("DEL", key)
 	if err != nil {
 		log.Println("prizedata.resetServGiftPool DEL error=", err)
@@ -388,14 +497,13 @@ func resetServGiftPool() {
 }
 
 // 根据计划数据，往奖品池增加奖品数量
-func incrGiftPool(id, num int) int {
-	return incrServGiftPool(id, num)
+func incrGiftPool(cacheObj datasource.Cache, id, num int) int {
+	return incrServGiftPool(cacheObj, id, num)
 }
 
 // 往奖品池增加奖品数量，redis缓存，根据计划数据
-func incrServGiftPool(id, num int) int {
+func incrServGiftPool(cacheObj datasource.Cache, id, num int) int {
 	key := "gift_pool"
-	cacheObj := datasource.InstanceCache()
 	rtNum, err := redis.Int64(cacheObj.Do("HINCRBY", key, id, num))
 	if err != nil {
 		log.Println("prizedata.incrServGiftPool error=", err)
@@ -415,9 +523,8 @@ func incrServGiftPool(id, num int) int {
 }
 
 // 发奖，redis缓存
-func prizeServGift(id int) bool {
+func prizeServGift(cacheObj datasource.Cache, id int) bool {
 	key := "gift_pool"
-	cacheObj := datasource.InstanceCache()
 	rs, err := cacheObj.Do("HINCRBY", key, id, -1)
 	if err != nil {
 		log.Println("prizedata.prizeServGift error=", err)
@@ -432,37 +539,84 @@ func prizeServGift(id int) bool {
 }
 
 // 优惠券发放，使用redis的方式发放
-func prizeServCodeDiff(id int, codeService services.CodeService) string {
+func prizeServCodeDiff(cacheObj datasource.Cache, id int, codeService services.CodeService) (int, string) {
 	key := fmt.Sprintf("gift_code_%d", id)
-	cacheObj := datasource.InstanceCache()
-	rs, err := cacheObj.Do("SPOP", key)
-	if err != nil {
-		log.Println("prizedata.prizeServCodeDiff error=", err)
-		return ""
-	}
-	code := comm.GetString(rs, "")
-	if code == "" {
-		log.Printf("prizedata.prizeServCodeDiff rs=%s", rs)
-		return ""
-	}
-	// 更新数据库中的发放状态
-	codeService.UpdateByCode(&models.LtCode{
-		Code:       code,
-		SysStatus:  2,
-		SysUpdated: comm.NowUnix(),
-	}, nil)
-	return code
+	// 还没有到有效期的编码，发放结束之后放回缓存
+	waiting := make([]interface{}, 0)
+	defer func() {
+		if len(waiting) > 0 {
+			if _, err := cacheObj.Do("SADD", append([]interface{}{key}, waiting...)...); err != nil {
+				log.Println("prizedata.prizeServCodeDiff SADD error=", err)
+			}
+		}
+	}()
+	// 遇到不能发放的编码时重新取一个，最多尝试maxTry次
+	maxTry := 20
+	for i := 0; i < maxTry; i++ {
+		rs, err := cacheObj.Do("SPOP", key)
+		if err != nil {
+			log.Println("prizedata.prizeServCodeDiff error=", err)
+			return 0, ""
+		}
+		member := comm.GetString(rs, "")
+		if member == "" {
+			log.Printf("prizedata.prizeServCodeDiff rs=%s", rs)
+			return 0, ""
+		}
+		// 缓存中是编码的HMAC，还没有重新加密的旧数据是明文
+		data := codeService.GetByHash(member)
+		if data == nil {
+			data = codeService.GetByCode(member)
+		}
+		if data == nil {
+			log.Println("prizedata.prizeServCodeDiff code not found, giftId=", id)
+			continue
+		}
+		now := comm.NowUnix()
+		if data.ValidFrom > 0 && now < data.ValidFrom {
+			waiting = append(waiting, member)
+			continue
+		}
+		status := conf.CodeStatusIssued
+		if data.ValidTo > 0 && now > data.ValidTo {
+			// 过了有效期的编码作废，重新取一个
+			status = conf.CodeStatusVoid
+		}
+		// 更新数据库中的状态，已经作废的编码不能发放
+		ok, err := codeService.UpdateStatus(&models.LtCode{
+			Id:         data.Id,
+			SysStatus:  status,
+			SysUpdated: now,
+		}, []int{conf.CodeStatusNormal}, []string{"sys_status"})
+		if err != nil {
+			log.Println("prizedata.prizeServCodeDiff UpdateStatus id=", data.Id, ", error=", err)
+			return 0, ""
+		}
+		if !ok || status != conf.CodeStatusIssued {
+			log.Println("prizedata.prizeServCodeDiff skip id=", data.Id, ", ok=", ok, ", status=", status)
+			continue
+		}
+		return data.Id, codeService.PlainCode(data)
+	}
+	return 0, ""
+}
+
+// 缓存中保存的是编码的HMAC，没有加密的旧数据保存明文
+func codeCacheMember(data *models.LtCode) string {
+	if data.KeyId == 0 {
+		return data.Code
+	}
+	return data.CodeHash
 }
 
 // 设置奖品池的数量
-func setGiftPool(id, num int) {
-	setServGiftPool(id, num)
+func setGiftPool(cacheObj datasource.Cache, id, num int) {
+	setServGiftPool(cacheObj, id, num)
 }
 
 // 设置奖品池的数量，redis缓存
-func setServGiftPool(id, num int) {
+func setServGiftPool(cacheObj datasource.Cache, id, num int) {
 	key := "gift_pool"
-	cacheObj := datasource.InstanceCache()
 	_, err := cacheObj.Do("HSET", key, id, num)
 	if err != nil {
 		log.Println("prizedata.setServGiftPool error=", err)
@@ -470,7 +624,7 @@ func setServGiftPool(id, num int) {
 }
 
 // 清空奖品的发放计划
-func clearGiftPrizeData(giftInfo *models.LtGift, giftService services.GiftService) {
+func clearGiftPrizeData(cacheObj datasource.Cache, giftInfo *models.LtGift, giftService services.GiftService) {
 	info := &models.LtGift{
 		Id:        giftInfo.Id,
 		PrizeData: "",
@@ -480,13 +634,12 @@ func clearGiftPrizeData(giftInfo *models.LtGift, giftService services.GiftServic
 		log.Println("prizedata.clearGiftPrizeData giftService.Update",
 			info, ", error=", err)
 	}
-	setGiftPool(giftInfo.Id, 0)
+	setGiftPool(cacheObj, giftInfo.Id, 0)
 }
 
 // 获取当前奖品池中的奖品数量，从redis中
-func getServGiftPoolNum(id int) int {
+func getServGiftPoolNum(cacheObj datasource.Cache, id int) int {
 	key := "gift_pool"
-	cacheObj := datasource.InstanceCache()
 	rs, err := cacheObj.Do("HGET", key, id)
 	if err != nil {
 		log.Println("prizedata.getServGiftPoolNum error=", err)
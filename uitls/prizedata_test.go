@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/dao"
+	"github.com/iralance/go-lottery/datasource"
+	"github.com/iralance/go-lottery/models"
+	"github.com/iralance/go-lottery/services"
+)
+
+// 创建不同有效期的旧编码，返回编码对应的ID
+func seedValidityCodes(t *testing.T, giftId int) (services.CodeService, map[string]int) {
+	now := comm.NowUnix()
+	codes := []struct {
+		code               string
+		validFrom, validTo int
+	}{
+		{"NOLIMIT", 0, 0},
+		{"VALID", now - 3600, now + 3600},
+		{"EXPIRED", 0, now - 60},
+		{"NOTSTARTED", now + 3600, 0},
+	}
+	codeDao := dao.NewCodeMemDao()
+	ids := make(map[string]int)
+	for _, c := range codes {
+		data := &models.LtCode{
+			GiftId:    giftId,
+			Code:      c.code,
+			CodeHash:  "legacy-" + c.code,
+			ValidFrom: c.validFrom,
+			ValidTo:   c.validTo,
+		}
+		if _, err := codeDao.Create(data); err != nil {
+			t.Fatal(err)
+		}
+		ids[c.code] = data.Id
+	}
+	return services.NewCodeService(codeDao), ids
+}
+
+func cachedCodes(t *testing.T, cacheObj datasource.Cache, giftId int) []string {
+	rs, err := cacheObj.Do("SMEMBERS", fmt.Sprintf("gift_code_%d", giftId))
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := make([]string, 0)
+	for _, v := range rs.([]interface{}) {
+		list = append(list, comm.GetString(v, ""))
+	}
+	sort.Strings(list)
+	return list
+}
+
+func TestRecacheCodesValidity(t *testing.T) {
+	cacheObj := datasource.NewMemCache()
+	codeService, ids := seedValidityCodes(t, 1)
+	sucNum, errNum := RecacheCodes(cacheObj, 1, codeService)
+	if sucNum != 2 || errNum != 0 {
+		t.Errorf("RecacheCodes = %d, %d, want 2, 0", sucNum, errNum)
+	}
+	if got := fmt.Sprint(cachedCodes(t, cacheObj, 1)); got != "[NOLIMIT VALID]" {
+		t.Errorf("cached codes = %s, want [NOLIMIT VALID]", got)
+	}
+	tests := []struct {
+		code string
+		want int
+	}{
+		{"NOLIMIT", conf.CodeStatusNormal},
+		{"VALID", conf.CodeStatusNormal},
+		{"EXPIRED", conf.CodeStatusVoid},
+		{"NOTSTARTED", conf.CodeStatusNormal},
+	}
+	for _, tt := range tests {
+		if got := codeService.Get(ids[tt.code]).SysStatus; got != tt.want {
+			t.Errorf("RecacheCodes: %s status = %d, want %d", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestPrizeCodeDiffValidity(t *testing.T) {
+	tests := []struct {
+		name       string
+		cached     []string
+		wantCode   string
+		wantCached string // SPOP是随机的，空字符串不检查
+		wantVoid   []string
+	}{
+		{"有效期内", []string{"VALID"}, "VALID", "[]", nil},
+		{"过期的编码作废", []string{"EXPIRED"}, "", "[]", []string{"EXPIRED"}},
+		{"过期之后重新取一个", []string{"EXPIRED", "NOLIMIT"}, "NOLIMIT", "", nil},
+		{"还没有到有效期", []string{"NOTSTARTED"}, "", "[NOTSTARTED]", nil},
+		{"跳过还没有到有效期的编码", []string{"NOTSTARTED", "VALID"}, "VALID", "[NOTSTARTED]", nil},
+	}
+	for _, tt := range tests {
+		cacheObj := datasource.NewMemCache()
+		codeService, ids := seedValidityCodes(t, 1)
+		for _, code := range tt.cached {
+			cacheObj.Do("SADD", "gift_code_1", code)
+		}
+		codeId, code := PrizeCodeDiff(cacheObj, 1, codeService)
+		if code != tt.wantCode || (code != "" && codeId != ids[code]) {
+			t.Errorf("%s: PrizeCodeDiff = %d, %q, want %q", tt.name, codeId, code, tt.wantCode)
+		}
+		if code != "" && codeService.Get(codeId).SysStatus != conf.CodeStatusIssued {
+			t.Errorf("%s: %s is not issued", tt.name, code)
+		}
+		if got := fmt.Sprint(cachedCodes(t, cacheObj, 1)); tt.wantCached != "" && got != tt.wantCached {
+			t.Errorf("%s: cached codes = %s, want %s", tt.name, got, tt.wantCached)
+		}
+		for _, c := range tt.wantVoid {
+			if got := codeService.Get(ids[c]).SysStatus; got != conf.CodeStatusVoid {
+				t.Errorf("%s: %s status = %d, want void", tt.name, c, got)
+			}
+		}
+	}
+}
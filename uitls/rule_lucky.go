@@ -0,0 +1,73 @@
+/**
+ * 风控规则用到的redis状态
+ * 中奖后的冷却期，同一个IP今天参与的用户
+ */
+package utils
+
+import (
+	"fmt"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/datasource"
+	"log"
+	"math"
+	"time"
+)
+
+// 冷却期按照奖品类型和天数区分，相同参数的规则共用一个冷却期
+func getRuleCooldownKeys(gtype, days, uid int, ip string) []string {
+	ip = comm.IpCounterKey(ip)
+	return []string{
+		fmt.Sprintf("rule_cool_%d_%d_uid_%d", gtype, days, uid),
+		fmt.Sprintf("rule_cool_%d_%d_ip_%s", gtype, days, ip),
+	}
+}
+
+// 获得奖品之后，用户和IP同时进入冷却期
+func SetRuleCooldown(cacheObj datasource.Cache, gtype, days, uid int, ip string) {
+	if days <= 0 {
+		return
+	}
+	for _, key := range getRuleCooldownKeys(gtype, days, uid, ip) {
+		_, err := cacheObj.Do("SET", key, comm.NowUnix(), "EX", days*86400)
+		if err != nil {
+			log.Println("rule_lucky redis SET key=", key, ", err=", err)
+		}
+	}
+}
+
+// 用户或者IP是否还在冷却期
+func CheckRuleCooldown(cacheObj datasource.Cache, gtype, days, uid int, ip string) bool {
+	if days <= 0 {
+		return false
+	}
+	keys := getRuleCooldownKeys(gtype, days, uid, ip)
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = key
+	}
+	rs, err := cacheObj.Do("EXISTS", args...)
+	if err != nil {
+		log.Println("rule_lucky redis EXISTS keys=", keys, ", err=", err)
+		return false
+	}
+	return comm.GetInt64(rs, 0) > 0
+}
+
+// 记录IP今天参与的用户，返回今天参与的用户数
+func IncrIpDayUsers(cacheObj datasource.Cache, ip string, uid int) int64 {
+	y, m, d := time.Now().In(conf.SysTimeLocation).Date()
+	key := fmt.Sprintf("day_ip_uids_%d%02d%02d_%s", y, m, d, comm.IpCounterKey(ip))
+	_, err := cacheObj.Do("SADD", key, uid)
+	if err != nil {
+		log.Println("rule_lucky redis SADD key=", key, ", err=", err)
+		return math.MaxInt32
+	}
+	cacheObj.Do("EXPIRE", key, 2*86400)
+	rs, err := cacheObj.Do("SCARD", key)
+	if err != nil {
+		log.Println("rule_lucky redis SCARD key=", key, ", err=", err)
+		return math.MaxInt32
+	}
+	return comm.GetInt64(rs, 0)
+}
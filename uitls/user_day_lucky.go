@@ -14,19 +14,10 @@ import (
 
 const userFrameSize = 2
 
-func init() {
-	// User当天的统计数，整点归零，设置定时器
-	//duration := comm.NextDayDuration()
-	//time.AfterFunc(duration, resetGroupUserList)
-
-	// TODO: 本地开发测试的时候，每次启动归零
-	resetGroupUserList()
-}
-
-// 集群模式，重置用户今天次数
-func resetGroupUserList() {
+// 集群模式，重置用户今天次数，每天零点再次归零
+// TODO: 本地开发测试的时候，每次启动归零
+func ResetGroupUserList(cacheObj datasource.Cache) {
 	log.Println("user_day_lucky.resetGroupUserList start")
-	cacheObj := datasource.InstanceCache()
 	for i := 0; i < userFrameSize; i++ {
 		key := fmt.Sprintf("day_users_%d", i)
 		cacheObj.Do("DEL", key)
@@ -34,20 +25,24 @@ func resetGroupUserList() {
 	log.Println("user_day_lucky.resetGroupUserList stop")
 	// IP当天的统计数，整点归零，设置定时器
 	duration := comm.NextDayDuration()
-	time.AfterFunc(duration, resetGroupUserList)
+	time.AfterFunc(duration, func() { ResetGroupUserList(cacheObj) })
 }
 
 // 今天的用户抽奖次数递增，返回递增后的数值
-func IncrUserLuckyNum(uid int) int64 {
+func IncrUserLuckyNum(cacheObj datasource.Cache, uid int) int64 {
+	return IncrUserLuckyNumBy(cacheObj, uid, 1)
+}
+
+// 一次抽奖多次，今天的用户抽奖次数增加n
+func IncrUserLuckyNumBy(cacheObj datasource.Cache, uid, n int) int64 {
 	i := uid % userFrameSize
 	// 集群的redis统计数递增
-	return incrServUserLucyNum(i, uid)
+	return incrServUserLucyNum(cacheObj, i, uid, n)
 }
 
-func incrServUserLucyNum(i, uid int) int64 {
+func incrServUserLucyNum(cacheObj datasource.Cache, i, uid, n int) int64 {
 	key := fmt.Sprintf("day_users_%d", i)
-	cacheObj := datasource.InstanceCache()
-	rs, err := cacheObj.Do("HINCRBY", key, uid, 1)
+	rs, err := cacheObj.Do("HINCRBY", key, uid, n)
 	if err != nil {
 		log.Println("user_day_lucky redis HINCRBY key=", key,
 			", uid=", uid, ", err=", err)
@@ -58,19 +53,31 @@ func incrServUserLucyNum(i, uid int) int64 {
 	}
 }
 
+// 今天的用户抽奖次数，不递增
+func GetUserLuckyNum(cacheObj datasource.Cache, uid int) int64 {
+	i := uid % userFrameSize
+	key := fmt.Sprintf("day_users_%d", i)
+	rs, err := cacheObj.Do("HGET", key, uid)
+	if err != nil {
+		log.Println("user_day_lucky redis HGET key=", key,
+			", uid=", uid, ", err=", err)
+		return 0
+	}
+	return comm.GetInt64(rs, 0)
+}
+
 // 从给定的数据直接初始化用户的参与次数
-func InitUserLuckyNum(uid int, num int64) {
+func InitUserLuckyNum(cacheObj datasource.Cache, uid int, num int64) {
 	if num <= 1 {
 		return
 	}
 	i := uid % userFrameSize
 	// 集群
-	initServUserLuckyNum(i, uid, num)
+	initServUserLuckyNum(cacheObj, i, uid, num)
 }
 
-func initServUserLuckyNum(i, uid int, num int64) {
+func initServUserLuckyNum(cacheObj datasource.Cache, i, uid int, num int64) {
 	key := fmt.Sprintf("day_users_%d", i)
-	cacheObj := datasource.InstanceCache()
 	_, err := cacheObj.Do("HSET", key, uid, num)
 	if err != nil {
 		log.Println("user_day_lucky redis HSET key=", key,
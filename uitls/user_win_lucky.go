@@ -0,0 +1,109 @@
+/**
+ * 同一个User的中奖次数限制，redis缓存
+ * 缓存不存在的时候，从中奖记录中重新统计
+ */
+package utils
+
+import (
+	"fmt"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/datasource"
+	"github.com/iralance/go-lottery/models"
+	"github.com/iralance/go-lottery/services"
+	"log"
+	"time"
+)
+
+// 不限定周期的统计数，缓存过期之后从数据库重新统计
+const userWinExpire = 30 * 86400
+
+// 用户中奖次数的一个统计项
+type userWinCounter struct {
+	key    string
+	max    int
+	expire int
+	load   func() int64
+}
+
+func userWinCounters(uid int, gift *models.ObjGiftPrize, resultService services.ResultService) []userWinCounter {
+	now := time.Now().In(conf.SysTimeLocation)
+	y, m, d := now.Date()
+	dayBegin := time.Date(y, m, d, 0, 0, 0, 0, conf.SysTimeLocation)
+	// 每周从周一开始
+	weekday := (int(now.Weekday()) + 6) % 7
+	weekBegin := dayBegin.AddDate(0, 0, -weekday)
+	wy, wn := now.ISOWeek()
+	return []userWinCounter{
+		{
+			key:    fmt.Sprintf("win_gift_%d_%d", uid, gift.Id),
+			max:    conf.UserGiftWinMax,
+			expire: userWinExpire,
+			load:   func() int64 { return resultService.CountByUserGift(uid, gift.Id) },
+		},
+		{
+			key:    fmt.Sprintf("win_gtype_%d_%d", uid, gift.Gtype),
+			max:    conf.UserGtypeWinMax[gift.Gtype],
+			expire: userWinExpire,
+			load:   func() int64 { return resultService.CountByUserGtype(uid, gift.Gtype) },
+		},
+		{
+			key:    fmt.Sprintf("win_day_%d%02d%02d_%d", y, m, d, uid),
+			max:    conf.UserDayWinMax,
+			expire: 2 * 86400,
+			load:   func() int64 { return resultService.CountByUserSince(uid, int(dayBegin.Unix())) },
+		},
+		{
+			key:    fmt.Sprintf("win_week_%d%02d_%d", wy, wn, uid),
+			max:    conf.UserWeekWinMax,
+			expire: 8 * 86400,
+			load:   func() int64 { return resultService.CountByUserSince(uid, int(weekBegin.Unix())) },
+		},
+	}
+}
+
+// 用户是否还可以获得这个奖品，中奖次数都没有超过限制
+func CheckUserWinNum(cacheObj datasource.Cache, uid int, gift *models.ObjGiftPrize,
+	resultService services.ResultService) bool {
+	for _, counter := range userWinCounters(uid, gift, resultService) {
+		if counter.max <= 0 {
+			continue
+		}
+		if getServUserWinNum(cacheObj, counter) >= int64(counter.max) {
+			return false
+		}
+	}
+	return true
+}
+
+// 中奖之后，增加用户的中奖次数
+// 缓存中没有的统计项不需要处理，下次使用的时候会从数据库统计
+func IncrUserWinNum(cacheObj datasource.Cache, uid int, gift *models.ObjGiftPrize,
+	resultService services.ResultService) {
+	for _, counter := range userWinCounters(uid, gift, resultService) {
+		rs, err := cacheObj.Do("EXISTS", counter.key)
+		if err != nil || comm.GetInt64(rs, 0) < 1 {
+			continue
+		}
+		_, err = cacheObj.Do("INCR", counter.key)
+		if err != nil {
+			log.Println("user_win_lucky redis INCR key=", counter.key, ", err=", err)
+		}
+	}
+}
+
+func getServUserWinNum(cacheObj datasource.Cache, counter userWinCounter) int64 {
+	rs, err := cacheObj.Do("GET", counter.key)
+	if err != nil {
+		log.Println("user_win_lucky redis GET key=", counter.key, ", err=", err)
+	} else if rs != nil {
+		return comm.GetInt64(comm.GetString(rs, ""), 0)
+	}
+	// 缓存中没有，从中奖记录统计
+	num := counter.load()
+	_, err = cacheObj.Do("SET", counter.key, num, "EX", counter.expire, "NX")
+	if err != nil {
+		log.Println("user_win_lucky redis SET key=", counter.key, ", err=", err)
+	}
+	return num
+}
@@ -0,0 +1,92 @@
+/**
+ * 中奖动态
+ * 中奖之后发布到redis频道，同时保留最新的中奖记录给刚连接的用户
+ */
+package utils
+
+import (
+	"encoding/json"
+	"github.com/gomodule/redigo/redis"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/datasource"
+	"github.com/iralance/go-lottery/models"
+	"log"
+)
+
+const winnerRecentKey = "lottery_winners_recent"
+
+// 是否需要放到外部榜单中展示
+func IsPublicGift(gtype int) bool {
+	return conf.WinnerPublicGtypes[gtype]
+}
+
+// 中奖记录转换成对外展示的中奖动态
+func NewWinner(result *models.LtResult) *models.ObjWinner {
+	return &models.ObjWinner{
+		Id:         result.Id,
+		GiftId:     result.GiftId,
+		GiftName:   result.GiftName,
+		GiftType:   result.GiftType,
+		Username:   comm.MaskUsername(result.Username, conf.WinnerMaskPrefix, conf.WinnerMaskSuffix),
+		SysCreated: result.SysCreated,
+	}
+}
+
+// 发布一条中奖动态
+func PublishWinner(cacheObj datasource.Cache, result *models.LtResult) {
+	if !IsPublicGift(result.GiftType) {
+		return
+	}
+	data, err := json.Marshal(NewWinner(result))
+	if err != nil {
+		log.Println("winner_feed.PublishWinner json.Marshal error=", err)
+		return
+	}
+	cacheObj.Do("LPUSH", winnerRecentKey, data)
+	cacheObj.Do("LTRIM", winnerRecentKey, 0, conf.WinnerRecentSize-1)
+	if _, err = cacheObj.Do("PUBLISH", conf.WinnerChannel, data); err != nil {
+		log.Println("winner_feed.PublishWinner PUBLISH error=", err)
+	}
+}
+
+// 最新的中奖动态，按照时间倒序
+// 缓存中没有数据的时候返回nil
+func GetRecentWinners(cacheObj datasource.Cache) []models.ObjWinner {
+	list, err := redis.ByteSlices(cacheObj.Do("LRANGE", winnerRecentKey, 0, conf.WinnerRecentSize-1))
+	if err != nil || len(list) == 0 {
+		return nil
+	}
+	datalist := make([]models.ObjWinner, 0, len(list))
+	for _, data := range list {
+		winner := models.ObjWinner{}
+		if err = json.Unmarshal(data, &winner); err == nil {
+			datalist = append(datalist, winner)
+		}
+	}
+	return datalist
+}
+
+// 缓存中没有数据的时候，从数据库中的中奖记录重新生成，按照时间倒序
+func SetRecentWinners(cacheObj datasource.Cache, results []models.LtResult) []models.ObjWinner {
+	datalist := make([]models.ObjWinner, 0, len(results))
+	params := []interface{}{winnerRecentKey}
+	// LPUSH 之后是倒序，从最早的开始写入
+	for i := len(results) - 1; i >= 0; i-- {
+		winner := NewWinner(&results[i])
+		data, _ := json.Marshal(winner)
+		params = append(params, data)
+		datalist = append([]models.ObjWinner{*winner}, datalist...)
+	}
+	cacheObj.Do("DEL", winnerRecentKey)
+	if len(params) > 1 {
+		cacheObj.Do("LPUSH", params...)
+		cacheObj.Do("LTRIM", winnerRecentKey, 0, conf.WinnerRecentSize-1)
+	}
+	return datalist
+}
+
+// 清空最新的中奖动态，下次读取的时候从数据库重新生成
+func ClearRecentWinners(cacheObj datasource.Cache) {
+	cacheObj.Do("DEL", winnerRecentKey)
+}
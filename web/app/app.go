@@ -0,0 +1,17 @@
+package app
+
+import (
+	"github.com/iralance/go-lottery/bootstrap"
+	"github.com/iralance/go-lottery/web/middleware/identity"
+	"github.com/iralance/go-lottery/web/middleware/ratelimit"
+	"github.com/iralance/go-lottery/web/routes"
+)
+
+// 初始化应用，cfgs在Bootstrap之前执行，可以替换数据库和缓存
+func New(cfgs ...bootstrap.Configurator) *bootstrap.Bootstrapper {
+	b := bootstrap.New("抽奖系统", "iralance", cfgs...)
+	b.Bootstrap()
+	b.Configure(identity.Configure, ratelimit.Configure, routes.Configure)
+
+	return b
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"github.com/iralance/go-lottery/datasource"
 	"github.com/iralance/go-lottery/services"
 	"github.com/kataras/iris/v12"
 	"github.com/kataras/iris/v12/mvc"
@@ -14,6 +15,16 @@ type AdminController struct {
 	ServiceResult  services.ResultService
 	ServiceUserday services.UserdayService
 	ServiceBlackip services.BlackipService
+	ServiceRule    services.RuleService
+	ServiceReview  services.ReviewService
+	ServiceFulfill services.FulfillService
+	ServiceReward  services.RewardDispatcher
+	ServiceCodeGen services.CodeGenService
+	ServiceDrawLog services.DrawLogService
+	ServiceChance  services.ChanceService
+	ServicePoint   services.PointService
+	ServicePity    services.PityService
+	Cache          datasource.Cache
 }
 
 // http://localhost:8080/admin
@@ -3,6 +3,7 @@ package controllers
 import (
 	"fmt"
 	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/datasource"
 	"github.com/iralance/go-lottery/models"
 	"github.com/iralance/go-lottery/services"
 	"github.com/kataras/iris/v12"
@@ -17,6 +18,16 @@ type AdminBlackipController struct {
 	ServiceResult  services.ResultService
 	ServiceUserday services.UserdayService
 	ServiceBlackip services.BlackipService
+	ServiceRule    services.RuleService
+	ServiceReview  services.ReviewService
+	ServiceFulfill services.FulfillService
+	ServiceReward  services.RewardDispatcher
+	ServiceCodeGen services.CodeGenService
+	ServiceDrawLog services.DrawLogService
+	ServiceChance  services.ChanceService
+	ServicePoint   services.PointService
+	ServicePity    services.PityService
+	Cache          datasource.Cache
 }
 
 // GET /admin/blackip/
@@ -66,3 +77,28 @@ func (c *AdminBlackipController) GetBlack() mvc.Result {
 		Path: "/admin/blackip",
 	}
 }
+
+// POST /admin/blackip/save
+// 支持单个IP和CIDR网段，IPv4和IPv6都可以
+func (c *AdminBlackipController) PostSave() mvc.Result {
+	ip := c.Ctx.FormValue("ip")
+	t := c.Ctx.PostValueIntDefault("time", 0)
+	if _, err := comm.ParseIpNet(ip); err != nil {
+		return mvc.Response{
+			Text: fmt.Sprintf("IP或者网段的格式不正确, ip=%s, err=%s", ip, err),
+		}
+	}
+	if t > 0 {
+		t = t*86400 + comm.NowUnix()
+	}
+	datalist := c.ServiceBlackip.Search(ip)
+	if len(datalist) > 0 {
+		c.ServiceBlackip.Update(&models.LtBlackip{Id: datalist[0].Id, Ip: datalist[0].Ip, Blacktime: t, SysUpdated: comm.NowUnix()},
+			[]string{"blacktime"})
+	} else {
+		c.ServiceBlackip.Create(&models.LtBlackip{Ip: ip, Blacktime: t, SysCreated: comm.NowUnix()})
+	}
+	return mvc.Response{
+		Path: "/admin/blackip",
+	}
+}
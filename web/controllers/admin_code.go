@@ -2,14 +2,18 @@ package controllers
 
 import (
 	"fmt"
-	"github.com/iralance/go-lottery/comm"
 	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/datasource"
 	"github.com/iralance/go-lottery/models"
 	"github.com/iralance/go-lottery/services"
 	utils "github.com/iralance/go-lottery/uitls"
 	"github.com/kataras/iris/v12"
 	"github.com/kataras/iris/v12/mvc"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 type AdminCodeController struct {
@@ -20,49 +24,63 @@ type AdminCodeController struct {
 	ServiceResult  services.ResultService
 	ServiceUserday services.UserdayService
 	ServiceBlackip services.BlackipService
+	ServiceRule    services.RuleService
+	ServiceReview  services.ReviewService
+	ServiceFulfill services.FulfillService
+	ServiceReward  services.RewardDispatcher
+	ServiceCodeGen services.CodeGenService
+	ServiceDrawLog services.DrawLogService
+	ServiceChance  services.ChanceService
+	ServicePoint   services.PointService
+	ServicePity    services.PityService
+	Cache          datasource.Cache
 }
 
+// 分页显示优惠券，可以按照奖品和状态筛选 GET /admin/code?gift_id=1&status=0&page=1
 func (c *AdminCodeController) Get() mvc.Result {
 	giftId := c.Ctx.URLParamIntDefault("gift_id", 0)
+	status := c.Ctx.URLParamIntDefault("status", -1)
 	page := c.Ctx.URLParamIntDefault("page", 1)
+	if page < 1 {
+		page = 1
+	}
 	size := 100
 	pagePrev := ""
 	pageNext := ""
 	// 数据列表
-	var datalist []models.LtCode
+	datalist := c.ServiceCode.SearchPage(giftId, status, page, size)
 	var num int
 	var cacheNum int
 	if giftId > 0 {
-		datalist = c.ServiceCode.Search(giftId)
-		num, cacheNum = utils.GetCacheCodeNum(giftId, c.ServiceCode)
-	} else {
-		datalist = c.ServiceCode.GetAll(page, size)
+		num, cacheNum = utils.GetCacheCodeNum(c.Cache, giftId, c.ServiceCode)
 	}
-	total := (page - 1) + len(datalist)
 	// 数据总数
-	if len(datalist) >= size {
-		if giftId > 0 {
-			total = int(c.ServiceCode.CountByGift(giftId))
-		} else {
-			total = int(c.ServiceCode.CountAll())
-		}
+	total := int(c.ServiceCode.CountPage(giftId, status))
+	if page*size < total {
 		pageNext = fmt.Sprintf("%d", page+1)
 	}
 	if page > 1 {
 		pagePrev = fmt.Sprintf("%d", page-1)
 	}
+	// 编码是加密保存的，显示之前解密
+	for i := range datalist {
+		datalist[i].Code = c.ServiceCode.PlainCode(&datalist[i])
+	}
 	return mvc.View{
 		Name: "admin/code.html",
 		Data: iris.Map{
-			"Title":    "管理后台",
-			"Channel":  "code",
-			"GiftId":   giftId,
-			"Datalist": datalist,
-			"Total":    total,
-			"PagePrev": pagePrev,
-			"PageNext": pageNext,
-			"CodeNum":  num,
-			"CacheNum": cacheNum,
+			"Title":     "管理后台",
+			"Channel":   "code",
+			"GiftId":    giftId,
+			"Status":    status,
+			"Datalist":  datalist,
+			"Total":     total,
+			"PagePrev":  pagePrev,
+			"PageNext":  pageNext,
+			"CodeNum":   num,
+			"CacheNum":  cacheNum,
+			"OldKeyNum": c.ServiceCode.CountOldKey(),
+			"KeyId":     conf.CodeKeyCurrent,
 		},
 		Layout: "admin/layout.html",
 	}
@@ -71,43 +89,103 @@ func (c *AdminCodeController) Get() mvc.Result {
 func (c *AdminCodeController) PostImport() {
 	giftId := c.Ctx.URLParamIntDefault("gift_id", 0)
 	fmt.Println("PostImport giftId=", giftId)
+	params, ok := c.importParams(giftId)
+	if !ok {
+		return
+	}
+	codes := c.Ctx.PostValue("codes")
+	id := utils.NewCodeImportJob(c.Cache, params)
+	job := utils.RunCodeImport(c.Cache, c.ServiceCode, id, params, strings.NewReader(codes))
+	c.Ctx.HTML(fmt.Sprintf("成功导入 %d 条，重复 %d 条，格式错误 %d 条，导入失败 %d 条，<a href='/admin/code?gift_id=%d'>返回</a>",
+		job.Success, job.Dup, job.Invalid, job.Failed, giftId))
+}
+
+// 上传文件导入，txt文件每行一个券，csv文件读取第一列
+// 大文件在后台导入，跳转到进度页面 POST /admin/code/upload?gift_id=1
+func (c *AdminCodeController) PostUpload() {
+	giftId := c.Ctx.URLParamIntDefault("gift_id", 0)
+	params, ok := c.importParams(giftId)
+	if !ok {
+		return
+	}
+	file, header, err := c.Ctx.FormFile("file")
+	if err != nil {
+		c.Ctx.HTML("没有上传文件，<a href='' onclick='history.go(-1);return false;'>返回</a>")
+		return
+	}
+	defer file.Close()
+	params.Filename = header.Filename
+	params.Csv = strings.EqualFold(filepath.Ext(header.Filename), ".csv")
+	id := utils.NewCodeImportJob(c.Cache, params)
+	if header.Size <= conf.CodeImportAsyncSize {
+		utils.RunCodeImport(c.Cache, c.ServiceCode, id, params, file)
+	} else {
+		// 请求结束之后上传的文件会被删除，先复制一份
+		tmp, err := os.CreateTemp("", "code-import-*")
+		if err == nil {
+			_, err = io.Copy(tmp, file)
+		}
+		if err != nil {
+			c.Ctx.HTML(fmt.Sprintf("保存上传文件失败，err=%s", err))
+			return
+		}
+		tmp.Seek(0, io.SeekStart)
+		go func() {
+			defer os.Remove(tmp.Name())
+			defer tmp.Close()
+			utils.RunCodeImport(c.Cache, c.ServiceCode, id, params, tmp)
+		}()
+	}
+	c.Ctx.Redirect(fmt.Sprintf("/admin/code/job?id=%s", id))
+}
+
+// 导入任务的进度 GET /admin/code/job?id=xxx
+func (c *AdminCodeController) GetJob() mvc.Result {
+	job := utils.GetCodeImportJob(c.Cache, c.Ctx.URLParam("id"))
+	if c.Ctx.URLParamExists("json") {
+		return mvc.Response{
+			Object: job,
+		}
+	}
+	if job == nil {
+		return mvc.Response{
+			Text: "导入任务不存在或者已经过期",
+		}
+	}
+	return mvc.View{
+		Name: "admin/codeImport.html",
+		Data: iris.Map{
+			"Title":   "管理后台",
+			"Channel": "code",
+			"job":     job,
+		},
+		Layout: "admin/layout.html",
+	}
+}
+
+// 检查导入的奖品和有效期，不正确的时候直接输出错误信息
+func (c *AdminCodeController) importParams(giftId int) (*utils.CodeImport, bool) {
 	if giftId < 1 {
 		c.Ctx.Text("没有指定奖品ID，无法进行导入，<a href='' onclick='history.go(-1);return false;'>返回</a>")
-		return
+		return nil, false
 	}
 	gift := c.ServiceGift.Get(giftId, true)
 	if gift == nil || gift.Gtype != conf.GtypeCodeDiff {
 		c.Ctx.HTML("没有指定的优惠券类型的奖品，无法进行导入，<a href='' onclick='history.go(-1);return false;'>返回</a>")
-		return
+		return nil, false
 	}
-	codes := c.Ctx.PostValue("codes")
-	now := comm.NowUnix()
-	list := strings.Split(codes, "\n")
-	sucNum := 0
-	errNum := 0
-	for _, code := range list {
-		code := strings.TrimSpace(code)
-		if code != "" {
-			data := &models.LtCode{
-				GiftId:     giftId,
-				Code:       code,
-				SysCreated: now,
-			}
-			_, err := c.ServiceCode.Create(data)
-			if err != nil {
-				errNum++
-			} else {
-				// 成功导入数据库，还需要导入到缓存中
-				ok := utils.ImportCacheCodes(giftId, code)
-				if ok {
-					sucNum++
-				} else {
-					errNum++
-				}
-			}
-		}
+	// 有效期，按天设置，不填写就是不限制
+	validFrom, err1 := parseCodeDate(c.Ctx.PostValue("valid_from"), 0)
+	validTo, err2 := parseCodeDate(c.Ctx.PostValue("valid_to"), 86400-1)
+	if err1 != nil || err2 != nil || (validTo > 0 && validTo < validFrom) {
+		c.Ctx.HTML("有效期的格式不正确，<a href='' onclick='history.go(-1);return false;'>返回</a>")
+		return nil, false
 	}
-	c.Ctx.HTML(fmt.Sprintf("成功导入 %d 条，导入失败 %d 条，<a href='/admin/code?gift_id=%d'>返回</a>", sucNum, errNum, giftId))
+	return &utils.CodeImport{
+		GiftId:    giftId,
+		ValidFrom: validFrom,
+		ValidTo:   validTo,
+	}, true
 }
 
 func (c *AdminCodeController) GetDelete() mvc.Result {
@@ -150,8 +228,65 @@ func (c *AdminCodeController) GetRecache() {
 		c.Ctx.HTML(rs)
 		return
 	}
-	sucNum, errNum := utils.RecacheCodes(id, c.ServiceCode)
+	sucNum, errNum := utils.RecacheCodes(c.Cache, id, c.ServiceCode)
 
 	rs := fmt.Sprintf("sucNum=%d, errNum=%d, <a href='%s'>返回</a>", sucNum, errNum, refer)
 	c.Ctx.HTML(rs)
 }
+
+// 立即处理过了有效期的优惠券，不用等计划任务 GET /admin/code/expire
+func (c *AdminCodeController) GetExpire() {
+	refer := c.Ctx.GetHeader("Referer")
+	if refer == "" {
+		refer = "/admin/code"
+	}
+	num := utils.ExpireCodes(c.Cache, c.ServiceCode)
+	c.Ctx.HTML(fmt.Sprintf("处理过期优惠券 %d 条，<a href='%s'>返回</a>", num, refer))
+}
+
+// 修改了加密的密钥之后，使用新的密钥重新加密旧的编码 GET /admin/code/rotate
+func (c *AdminCodeController) GetRotate() {
+	refer := c.Ctx.GetHeader("Referer")
+	if refer == "" {
+		refer = "/admin/code"
+	}
+	sucNum, errNum := utils.RotateCodeKeys(c.Cache, c.ServiceCode)
+	c.Ctx.HTML(fmt.Sprintf("使用密钥版本 %d 重新加密 %d 条，失败 %d 条，<a href='%s'>返回</a>",
+		conf.CodeKeyCurrent, sucNum, errNum, refer))
+}
+
+// 每个优惠券奖品的编码状态、核销率和缓存中的数量 GET /admin/code/stats
+func (c *AdminCodeController) GetStats() mvc.Result {
+	datalist := make([]models.ObjCodeStats, 0)
+	titles := make(map[int]string)
+	for _, gift := range c.ServiceGift.GetAll(false) {
+		if gift.Gtype != conf.GtypeCodeDiff {
+			continue
+		}
+		titles[gift.Id] = gift.Title
+		datalist = append(datalist, *utils.CodePoolHealth(c.Cache, gift.Id, c.ServiceCode))
+	}
+	return mvc.View{
+		Name: "admin/codeStats.html",
+		Data: iris.Map{
+			"Title":    "管理后台",
+			"Channel":  "code",
+			"Datalist": datalist,
+			"Titles":   titles,
+		},
+		Layout: "admin/layout.html",
+	}
+}
+
+// 解析有效期的日期，offset是当天的秒数，空字符串返回0
+func parseCodeDate(str string, offset int) (int, error) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return 0, nil
+	}
+	t, err := time.ParseInLocation(conf.SysTimeformShort, str, conf.SysTimeLocation)
+	if err != nil {
+		return 0, err
+	}
+	return int(t.Unix()) + offset, nil
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+	utils "github.com/iralance/go-lottery/uitls"
+	"github.com/iralance/go-lottery/web/viewmodels"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/mvc"
+	"net/url"
+)
+
+// 奖品的编码生成规则，没有设置的时候使用默认规则
+func (c *AdminCodeController) getCodeGen(giftId int) *models.LtCodeGen {
+	gen := c.ServiceCodeGen.GetByGift(giftId)
+	if gen == nil {
+		gen = &models.LtCodeGen{
+			GiftId:   giftId,
+			Alphabet: conf.CodeGenAlphabet,
+			Length:   conf.CodeGenLength,
+			Checksum: 1,
+		}
+	}
+	return gen
+}
+
+// GET /admin/code/gen?gift_id=1
+func (c *AdminCodeController) GetGen() mvc.Result {
+	giftId := c.Ctx.URLParamIntDefault("gift_id", 0)
+	gift := c.ServiceGift.Get(giftId, true)
+	if gift == nil || gift.Gtype != conf.GtypeCodeDiff {
+		return mvc.Response{
+			Text: "没有指定的优惠券类型的奖品",
+		}
+	}
+	return mvc.View{
+		Name: "admin/codeGen.html",
+		Data: iris.Map{
+			"Title":    "管理后台",
+			"Channel":  "code",
+			"gift":     gift,
+			"info":     c.getCodeGen(giftId),
+			"CacheNum": utils.GetGiftCodeNum(c.Cache, giftId),
+			"Message":  c.Ctx.URLParam("message"),
+		},
+		Layout: "admin/layout.html",
+	}
+}
+
+// 保存生成规则 POST /admin/code/gen
+func (c *AdminCodeController) PostGen() mvc.Result {
+	data := viewmodels.ViewCodeGen{}
+	if err := c.Ctx.ReadForm(&data); err != nil {
+		return mvc.Response{
+			Text: fmt.Sprintf("ReadForm转换异常, err=%s", err),
+		}
+	}
+	gift := c.ServiceGift.Get(data.GiftId, true)
+	if gift == nil || gift.Gtype != conf.GtypeCodeDiff {
+		return mvc.Response{
+			Text: "没有指定的优惠券类型的奖品",
+		}
+	}
+	gen := c.getCodeGen(data.GiftId)
+	gen.Prefix = data.Prefix
+	gen.Alphabet = data.Alphabet
+	gen.Length = data.Length
+	gen.Checksum = data.Checksum
+	gen.Threshold = data.Threshold
+	gen.TopupNum = data.TopupNum
+	if err := utils.CheckCodeGen(gen); err != nil {
+		return mvc.Response{
+			Text: err.Error(),
+		}
+	}
+	var err error
+	if gen.Id > 0 {
+		gen.SysUpdated = comm.NowUnix()
+		err = c.ServiceCodeGen.Update(gen, []string{"prefix", "alphabet", "length", "checksum", "threshold", "topup_num", "sys_updated"})
+	} else {
+		gen.SysCreated = comm.NowUnix()
+		_, err = c.ServiceCodeGen.Create(gen)
+	}
+	message := "保存成功"
+	if err != nil {
+		message = fmt.Sprintf("保存失败，err=%s", err)
+	}
+	return mvc.Response{
+		Path: fmt.Sprintf("/admin/code/gen?gift_id=%d&message=%s", data.GiftId, url.QueryEscape(message)),
+	}
+}
+
+// 按照生成规则立即生成编码 POST /admin/code/generate?gift_id=1
+func (c *AdminCodeController) PostGenerate() {
+	giftId := c.Ctx.URLParamIntDefault("gift_id", 0)
+	params, ok := c.importParams(giftId)
+	if !ok {
+		return
+	}
+	num := c.Ctx.PostValueIntDefault("num", 0)
+	sucNum, err := utils.GenerateCodes(c.Cache, c.ServiceCode, c.getCodeGen(giftId), num, params)
+	message := fmt.Sprintf("成功生成 %d 条", sucNum)
+	if err != nil {
+		message += "，" + err.Error()
+	}
+	c.Ctx.Redirect(fmt.Sprintf("/admin/code/gen?gift_id=%d&message=%s", giftId, url.QueryEscape(message)))
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/datasource"
+	"github.com/iralance/go-lottery/services"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/mvc"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type AdminFulfillController struct {
+	Ctx            iris.Context
+	ServiceUser    services.UserService
+	ServiceGift    services.GiftService
+	ServiceCode    services.CodeService
+	ServiceResult  services.ResultService
+	ServiceUserday services.UserdayService
+	ServiceBlackip services.BlackipService
+	ServiceRule    services.RuleService
+	ServiceReview  services.ReviewService
+	ServiceFulfill services.FulfillService
+	ServiceReward  services.RewardDispatcher
+	ServiceCodeGen services.CodeGenService
+	ServiceDrawLog services.DrawLogService
+	ServiceChance  services.ChanceService
+	ServicePoint   services.PointService
+	ServicePity    services.PityService
+	Cache          datasource.Cache
+}
+
+// 导出文件的表头，导入快递单号的时候可以直接使用导出的文件
+var fulfillExportHeader = []string{"ID", "中奖记录ID", "用户ID", "奖品", "联系人", "手机号", "收货地址", "快递公司", "快递单号"}
+
+// GET /admin/fulfill/?status=2
+func (c *AdminFulfillController) Get() mvc.Result {
+	status := c.Ctx.URLParamIntDefault("status", 0)
+	page := c.Ctx.URLParamIntDefault("page", 1)
+	size := 100
+	pagePrev := ""
+	pageNext := ""
+	// 数据列表
+	datalist := c.ServiceFulfill.GetAll(status, page, size)
+	total := (page - 1) + len(datalist)
+	// 数据总数
+	if len(datalist) >= size {
+		total = int(c.ServiceFulfill.CountAll(status))
+		pageNext = fmt.Sprintf("%d", page+1)
+	}
+	if page > 1 {
+		pagePrev = fmt.Sprintf("%d", page-1)
+	}
+	return mvc.View{
+		Name: "admin/fulfill.html",
+		Data: iris.Map{
+			"Title":    "管理后台",
+			"Channel":  "fulfill",
+			"Status":   status,
+			"Datalist": datalist,
+			"Total":    total,
+			"PagePrev": pagePrev,
+			"PageNext": pageNext,
+		},
+		Layout: "admin/layout.html",
+	}
+}
+
+// 导出发货记录，默认导出等待发货的 GET /admin/fulfill/export?status=2
+func (c *AdminFulfillController) GetExport() {
+	status := c.Ctx.URLParamIntDefault("status", conf.FulfillReady)
+	c.Ctx.ContentType("text/csv; charset=utf-8")
+	c.Ctx.Header("Content-Disposition",
+		fmt.Sprintf("attachment; filename=fulfill-%d-%d.csv", status, comm.NowUnix()))
+	// 加上BOM，Excel打开的时候中文不会乱码
+	c.Ctx.WriteString("\xEF\xBB\xBF")
+	w := csv.NewWriter(c.Ctx.ResponseWriter())
+	w.Write(fulfillExportHeader)
+	size := 1000
+	for page := 1; ; page++ {
+		datalist := c.ServiceFulfill.GetAll(status, page, size)
+		for _, data := range datalist {
+			record := []string{
+				strconv.Itoa(data.Id),
+				strconv.Itoa(data.ResultId),
+				strconv.Itoa(data.Uid),
+				data.GiftName,
+				data.Realname,
+				data.Mobile,
+				data.Address,
+				data.Carrier,
+				data.TrackingNo,
+			}
+			// 用户填写的内容可能是公式
+			for i := range record {
+				record[i] = comm.CsvEscape(record[i])
+			}
+			w.Write(record)
+		}
+		if len(datalist) < size {
+			break
+		}
+	}
+	w.Flush()
+}
+
+// 导入快递单号，每行是 ID,快递公司,快递单号，或者是导出文件填好快递信息之后的格式
+// POST /admin/fulfill/import
+func (c *AdminFulfillController) PostImport() {
+	r := csv.NewReader(strings.NewReader(strings.TrimPrefix(c.Ctx.PostValue("tracking"), "\xEF\xBB\xBF")))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	sucNum := 0
+	errNum := 0
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errNum++
+			continue
+		}
+		var carrier, trackingNo string
+		switch len(record) {
+		case 3:
+			carrier, trackingNo = record[1], record[2]
+		case len(fulfillExportHeader):
+			carrier, trackingNo = record[7], record[8]
+		default:
+			errNum++
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(record[0]))
+		if err != nil {
+			// 表头
+			continue
+		}
+		carrier = strings.TrimSpace(comm.CsvUnescape(carrier))
+		trackingNo = strings.TrimSpace(comm.CsvUnescape(trackingNo))
+		if carrier == "" || trackingNo == "" || !c.ServiceFulfill.Ship(id, carrier, trackingNo) {
+			errNum++
+		} else {
+			sucNum++
+		}
+	}
+	c.Ctx.HTML(fmt.Sprintf("成功发货 %d 条，导入失败 %d 条，<a href='/admin/fulfill?status=%d'>返回</a>",
+		sucNum, errNum, conf.FulfillShipped))
+}
+
+// GET /admin/fulfill/deliver?id=1
+func (c *AdminFulfillController) GetDeliver() mvc.Result {
+	id, err := c.Ctx.URLParamInt("id")
+	if err == nil {
+		c.ServiceFulfill.Deliver(id)
+	}
+	refer := c.Ctx.GetHeader("Referer")
+	if refer == "" {
+		refer = "/admin/fulfill"
+	}
+	return mvc.Response{
+		Path: refer,
+	}
+}
+
+// 立即执行一次过期处理，不用等计划任务 GET /admin/fulfill/expire
+func (c *AdminFulfillController) GetExpire() mvc.Result {
+	before := comm.NowUnix() - conf.FulfillAddressDays*86400
+	c.ServiceFulfill.ExpirePending(before)
+	return mvc.Response{
+		Path: fmt.Sprintf("/admin/fulfill?status=%d", conf.FulfillExpired),
+	}
+}
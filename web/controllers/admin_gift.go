@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/datasource"
 	"github.com/iralance/go-lottery/models"
 	"github.com/iralance/go-lottery/services"
 	utils "github.com/iralance/go-lottery/uitls"
@@ -22,6 +23,16 @@ type AdminGiftController struct {
 	ServiceResult  services.ResultService
 	ServiceUserday services.UserdayService
 	ServiceBlackip services.BlackipService
+	ServiceRule    services.RuleService
+	ServiceReview  services.ReviewService
+	ServiceFulfill services.FulfillService
+	ServiceReward  services.RewardDispatcher
+	ServiceCodeGen services.CodeGenService
+	ServiceDrawLog services.DrawLogService
+	ServiceChance  services.ChanceService
+	ServicePoint   services.PointService
+	ServicePity    services.PityService
+	Cache          datasource.Cache
 }
 
 func (c *AdminGiftController) Get() mvc.Result {
@@ -47,7 +58,7 @@ func (c *AdminGiftController) Get() mvc.Result {
 			}
 		}
 		// 奖品当前的奖品池数量
-		num := utils.GetGiftPoolNum(giftInfo.Id)
+		num := utils.GetGiftPoolNum(c.Cache, giftInfo.Id)
 		datalist[i].Title = fmt.Sprintf("【%d】%s", num, datalist[i].Title)
 	}
 	total := len(datalist)
@@ -78,6 +89,7 @@ func (c *AdminGiftController) GetEdit() mvc.Result {
 			giftInfo.Displayorder = data.Displayorder
 			giftInfo.Gtype = data.Gtype
 			giftInfo.Gdata = data.Gdata
+			giftInfo.Fallback = data.Fallback
 			giftInfo.TimeBegin = comm.FormatFromUnixTime(int64(data.TimeBegin))
 			giftInfo.TimeEnd = comm.FormatFromUnixTime(int64(data.TimeEnd))
 		}
@@ -113,6 +125,7 @@ func (c *AdminGiftController) PostSave() mvc.Result {
 	giftInfo.Displayorder = data.Displayorder
 	giftInfo.Gtype = data.Gtype
 	giftInfo.Gdata = data.Gdata
+	giftInfo.Fallback = data.Fallback
 	t1, err1 := comm.ParseTime(data.TimeBegin)
 	t2, err2 := comm.ParseTime(data.TimeEnd)
 	if err1 != nil || err2 != nil {
@@ -135,27 +148,27 @@ func (c *AdminGiftController) PostSave() mvc.Result {
 					giftInfo.LeftNum = 0
 				}
 				giftInfo.SysStatus = datainfo.SysStatus
-				utils.ResetGiftPrizeData(&giftInfo, c.ServiceGift)
+				utils.ResetGiftPrizeData(c.Cache, &giftInfo, c.ServiceGift)
 			} else {
 				giftInfo.LeftNum = giftInfo.PrizeNum
 			}
 			if datainfo.PrizeTime != giftInfo.PrizeTime {
 				// 发奖周期发生了变化
-				utils.ResetGiftPrizeData(&giftInfo, c.ServiceGift)
+				utils.ResetGiftPrizeData(c.Cache, &giftInfo, c.ServiceGift)
 			}
 			c.ServiceGift.Update(&giftInfo, []string{"title", "prize_num", "left_num", "prize_code", "prize_time",
-				"img", "displayorder", "gtype", "gdata", "time_begin", "time_end", "sys_updated"})
+				"img", "displayorder", "gtype", "gdata", "fallback", "time_begin", "time_end", "sys_updated"})
 		} else {
 			giftInfo.Id = 0
 		}
 	}
-	if giftInfo.Id > 0 {
+	if giftInfo.Id == 0 {
 		giftInfo.LeftNum = giftInfo.PrizeNum
 		giftInfo.SysIp = comm.ClientIP(c.Ctx.Request())
 		giftInfo.SysCreated = int(time.Now().Unix())
 		c.ServiceGift.Create(&giftInfo)
 		// 更新奖品的发奖计划
-		utils.ResetGiftPrizeData(&giftInfo, c.ServiceGift)
+		utils.ResetGiftPrizeData(c.Cache, &giftInfo, c.ServiceGift)
 	}
 	return mvc.Response{
 		Path: "/admin/gift",
@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/datasource"
+	"github.com/iralance/go-lottery/models"
+	"github.com/iralance/go-lottery/services"
+	utils "github.com/iralance/go-lottery/uitls"
+	"github.com/iralance/go-lottery/web/viewmodels"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/mvc"
+)
+
+type AdminPityController struct {
+	Ctx            iris.Context
+	ServiceUser    services.UserService
+	ServiceGift    services.GiftService
+	ServiceCode    services.CodeService
+	ServiceResult  services.ResultService
+	ServiceUserday services.UserdayService
+	ServiceBlackip services.BlackipService
+	ServiceRule    services.RuleService
+	ServiceReview  services.ReviewService
+	ServiceFulfill services.FulfillService
+	ServiceReward  services.RewardDispatcher
+	ServiceCodeGen services.CodeGenService
+	ServiceDrawLog services.DrawLogService
+	ServiceChance  services.ChanceService
+	ServicePoint   services.PointService
+	ServicePity    services.PityService
+	Cache          datasource.Cache
+}
+
+// GET /admin/pity/
+func (c *AdminPityController) Get() mvc.Result {
+	// 每条规则的触发次数，以及当前达到规则次数的用户数
+	winNums := c.ServiceResult.CountGroupPity()
+	lossCounts := utils.GetLossCounts(c.Cache)
+	lossUser := 0
+	for _, count := range lossCounts {
+		lossUser += count
+	}
+	datalist := make([]viewmodels.ViewPityStat, 0)
+	for _, data := range c.ServicePity.GetAll() {
+		userNum := 0
+		for num, count := range lossCounts {
+			if num >= data.Num {
+				userNum += count
+			}
+		}
+		datalist = append(datalist, viewmodels.ViewPityStat{
+			Id:         data.Id,
+			Title:      data.Title,
+			Gtype:      data.Gtype,
+			Num:        data.Num,
+			Action:     data.Action,
+			Boost:      data.Boost,
+			SysStatus:  data.SysStatus,
+			SysUpdated: data.SysUpdated,
+			WinNum:     winNums[data.Id],
+			UserNum:    userNum,
+		})
+	}
+	// 连续没有中奖次数的分布
+	buckets := make([]viewmodels.ViewPityBucket, 0)
+	for i, userNum := range utils.LossDistribution(lossCounts, conf.PityLossBuckets) {
+		to := 0
+		if i+1 < len(conf.PityLossBuckets) {
+			to = conf.PityLossBuckets[i+1]
+		}
+		buckets = append(buckets, viewmodels.ViewPityBucket{
+			From:    conf.PityLossBuckets[i],
+			To:      to,
+			UserNum: userNum,
+		})
+	}
+	return mvc.View{
+		Name: "admin/pity.html",
+		Data: iris.Map{
+			"Title":    "管理后台",
+			"Channel":  "pity",
+			"Datalist": datalist,
+			"Total":    len(datalist),
+			"Buckets":  buckets,
+			"LossUser": lossUser,
+		},
+		Layout: "admin/layout.html",
+	}
+}
+
+// GET /admin/pity/edit?id=1
+func (c *AdminPityController) GetEdit() mvc.Result {
+	id := c.Ctx.URLParamIntDefault("id", 0)
+	pityInfo := viewmodels.ViewPity{Action: conf.PityActionGuarantee}
+	if id > 0 {
+		data := c.ServicePity.Get(id)
+		if data != nil {
+			pityInfo.Id = data.Id
+			pityInfo.Title = data.Title
+			pityInfo.Gtype = data.Gtype
+			pityInfo.Num = data.Num
+			pityInfo.Action = data.Action
+			pityInfo.Boost = data.Boost
+		}
+	}
+	return mvc.View{
+		Name: "admin/pityEdit.html",
+		Data: iris.Map{
+			"Title":   "管理后台",
+			"Channel": "pity",
+			"info":    pityInfo,
+		},
+		Layout: "admin/layout.html",
+	}
+}
+
+// POST /admin/pity/save
+func (c *AdminPityController) PostSave() mvc.Result {
+	data := viewmodels.ViewPity{}
+	err := c.Ctx.ReadForm(&data)
+	if err != nil {
+		fmt.Println("admin_pity.PostSave ReadForm error=", err)
+		return mvc.Response{
+			Text: fmt.Sprintf("ReadForm转换异常, err=%s", err),
+		}
+	}
+	if data.Action < conf.PityActionBoost || data.Action > conf.PityActionGuarantee ||
+		data.Gtype < conf.GtypeVirtual || data.Gtype > conf.GtypeGiftLarge {
+		return mvc.Response{
+			Text: fmt.Sprintf("奖品类型或者处理方式不正确, gtype=%d, action=%d", data.Gtype, data.Action),
+		}
+	}
+	if data.Num <= 0 {
+		return mvc.Response{
+			Text: fmt.Sprintf("连续没有中奖的次数必须大于0, num=%d", data.Num),
+		}
+	}
+	if data.Action == conf.PityActionBoost && (data.Boost <= 0 || data.Boost > 10000) {
+		return mvc.Response{
+			Text: fmt.Sprintf("提高的概率必须在1到10000之间, boost=%d", data.Boost),
+		}
+	}
+	pityInfo := models.LtPity{
+		Id:     data.Id,
+		Title:  data.Title,
+		Gtype:  data.Gtype,
+		Num:    data.Num,
+		Action: data.Action,
+		Boost:  data.Boost,
+		SysIp:  comm.ClientIP(c.Ctx.Request()),
+	}
+	if pityInfo.Id > 0 && c.ServicePity.Get(pityInfo.Id) != nil {
+		pityInfo.SysUpdated = comm.NowUnix()
+		c.ServicePity.Update(&pityInfo, []string{"title", "gtype", "num", "action", "boost", "sys_updated", "sys_ip"})
+	} else {
+		pityInfo.Id = 0
+		pityInfo.SysCreated = comm.NowUnix()
+		c.ServicePity.Create(&pityInfo)
+	}
+	return mvc.Response{
+		Path: "/admin/pity",
+	}
+}
+
+// GET /admin/pity/delete?id=1
+func (c *AdminPityController) GetDelete() mvc.Result {
+	id, err := c.Ctx.URLParamInt("id")
+	if err == nil {
+		c.ServicePity.Delete(id)
+	}
+	return mvc.Response{
+		Path: "/admin/pity",
+	}
+}
+
+// GET /admin/pity/reset?id=1
+func (c *AdminPityController) GetReset() mvc.Result {
+	id, err := c.Ctx.URLParamInt("id")
+	if err == nil {
+		c.ServicePity.Update(&models.LtPity{Id: id, SysStatus: 0}, []string{"sys_status"})
+	}
+	return mvc.Response{
+		Path: "/admin/pity",
+	}
+}
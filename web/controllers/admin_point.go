@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/iralance/go-lottery/datasource"
+	"github.com/iralance/go-lottery/services"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/mvc"
+	"log"
+	"strings"
+	"time"
+)
+
+type AdminPointController struct {
+	Ctx            iris.Context
+	ServiceUser    services.UserService
+	ServiceGift    services.GiftService
+	ServiceCode    services.CodeService
+	ServiceResult  services.ResultService
+	ServiceUserday services.UserdayService
+	ServiceBlackip services.BlackipService
+	ServiceRule    services.RuleService
+	ServiceReview  services.ReviewService
+	ServiceFulfill services.FulfillService
+	ServiceReward  services.RewardDispatcher
+	ServiceCodeGen services.CodeGenService
+	ServiceDrawLog services.DrawLogService
+	ServiceChance  services.ChanceService
+	ServicePoint   services.PointService
+	ServicePity    services.PityService
+	Cache          datasource.Cache
+}
+
+// 用户的积分余额 GET /admin/point/
+func (c *AdminPointController) Get() mvc.Result {
+	page := c.Ctx.URLParamIntDefault("page", 1)
+	size := 100
+	pagePrev := ""
+	pageNext := ""
+	// 数据列表
+	datalist := c.ServicePoint.GetAll(page, size)
+	total := (page - 1) + len(datalist)
+	// 数据总数
+	if len(datalist) >= size {
+		total = int(c.ServicePoint.CountAll())
+		pageNext = fmt.Sprintf("%d", page+1)
+	}
+	if page > 1 {
+		pagePrev = fmt.Sprintf("%d", page-1)
+	}
+	return mvc.View{
+		Name: "admin/point.html",
+		Data: iris.Map{
+			"Title":    "管理后台",
+			"Channel":  "point",
+			"Datalist": datalist,
+			"Total":    total,
+			"PagePrev": pagePrev,
+			"PageNext": pageNext,
+		},
+		Layout: "admin/layout.html",
+	}
+}
+
+// 积分流水，uid为0的时候是全部用户 GET /admin/point/log?uid=1
+func (c *AdminPointController) GetLog() mvc.Result {
+	uid := c.Ctx.URLParamIntDefault("uid", 0)
+	page := c.Ctx.URLParamIntDefault("page", 1)
+	size := 100
+	pagePrev := ""
+	pageNext := ""
+	// 数据列表
+	datalist := c.ServicePoint.SearchLog(uid, page, size)
+	total := (page - 1) + len(datalist)
+	// 数据总数
+	if len(datalist) >= size {
+		total = int(c.ServicePoint.CountLog(uid))
+		pageNext = fmt.Sprintf("%d", page+1)
+	}
+	if page > 1 {
+		pagePrev = fmt.Sprintf("%d", page-1)
+	}
+	var account interface{}
+	if uid > 0 {
+		account = c.ServicePoint.Get(uid)
+	}
+	return mvc.View{
+		Name: "admin/pointLog.html",
+		Data: iris.Map{
+			"Title":    "管理后台",
+			"Channel":  "point",
+			"Uid":      uid,
+			"Account":  account,
+			"Datalist": datalist,
+			"Total":    total,
+			"PagePrev": pagePrev,
+			"PageNext": pageNext,
+		},
+		Layout: "admin/layout.html",
+	}
+}
+
+// 给用户增加积分 POST /admin/point/earn
+func (c *AdminPointController) PostEarn() {
+	uid, err1 := c.Ctx.PostValueInt("uid")
+	num, err2 := c.Ctx.PostValueInt("num")
+	remark := strings.TrimSpace(c.Ctx.PostValue("remark"))
+	if err1 != nil || err2 != nil || uid < 1 || num < 1 {
+		c.Ctx.HTML("用户ID和积分都需要大于0，<a href='javascript:history.go(-1);'>返回</a>")
+		return
+	}
+	if remark == "" {
+		remark = "后台增加"
+	}
+	ref := fmt.Sprintf("admin-%d-%d", uid, time.Now().UnixNano())
+	if _, err := c.ServicePoint.Earn(uid, num, ref, remark); err != nil {
+		log.Println("admin_point.PostEarn ServicePoint.Earn uid=", uid, ", error=", err)
+		c.Ctx.HTML(fmt.Sprintf("增加积分失败：%s，<a href='javascript:history.go(-1);'>返回</a>", err))
+		return
+	}
+	c.Ctx.Redirect(fmt.Sprintf("/admin/point/log?uid=%d", uid))
+}
@@ -2,8 +2,12 @@ package controllers
 
 import (
 	"fmt"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/datasource"
 	"github.com/iralance/go-lottery/models"
 	"github.com/iralance/go-lottery/services"
+	utils "github.com/iralance/go-lottery/uitls"
 	"github.com/kataras/iris/v12"
 	"github.com/kataras/iris/v12/mvc"
 	"log"
@@ -17,18 +21,32 @@ type AdminResultController struct {
 	ServiceResult  services.ResultService
 	ServiceUserday services.UserdayService
 	ServiceBlackip services.BlackipService
+	ServiceRule    services.RuleService
+	ServiceReview  services.ReviewService
+	ServiceFulfill services.FulfillService
+	ServiceReward  services.RewardDispatcher
+	ServiceCodeGen services.CodeGenService
+	ServiceDrawLog services.DrawLogService
+	ServiceChance  services.ChanceService
+	ServicePoint   services.PointService
+	ServicePity    services.PityService
+	Cache          datasource.Cache
 }
 
 func (c *AdminResultController) Get() mvc.Result {
 	giftId := c.Ctx.URLParamIntDefault("gift_id", 0)
 	uid := c.Ctx.URLParamIntDefault("uid", 0)
+	deliverStatus := c.Ctx.URLParamIntDefault("deliver_status", 0)
 	page := c.Ctx.URLParamIntDefault("page", 1)
 	size := 100
 	pagePrev := ""
 	pageNext := ""
 	// 数据列表
 	var datalist []models.LtResult
-	if giftId > 0 {
+	if deliverStatus > 0 {
+		// 按照发放状态查看，比如发放失败等待人工处理的记录
+		datalist = c.ServiceResult.SearchByDeliver(deliverStatus, comm.NowUnix()+1, size)
+	} else if giftId > 0 {
 		datalist = c.ServiceResult.SearchByGift(giftId, page, size)
 	} else if uid > 0 {
 		datalist = c.ServiceResult.SearchByUser(uid, page, size)
@@ -39,7 +57,7 @@ func (c *AdminResultController) Get() mvc.Result {
 	log.Println("aaa")
 	total := (page - 1) + len(datalist)
 	// 数据总数
-	if len(datalist) >= size {
+	if len(datalist) >= size && deliverStatus == 0 {
 		if giftId > 0 {
 			total = int(c.ServiceResult.CountByGift(giftId))
 		} else if uid > 0 {
@@ -59,6 +77,7 @@ func (c *AdminResultController) Get() mvc.Result {
 			"Channel":  "result",
 			"GiftId":   giftId,
 			"Uid":      uid,
+			"Deliver":  deliverStatus,
 			"Datalist": datalist,
 			"Total":    total,
 			"PagePrev": pagePrev,
@@ -82,10 +101,11 @@ func (c *AdminResultController) GetDelete() mvc.Result {
 	}
 }
 
+// 标记作弊，等同于审核中的确认作弊
 func (c *AdminResultController) GetCheat() mvc.Result {
 	id, err := c.Ctx.URLParamInt("id")
 	if err == nil {
-		c.ServiceResult.Update(&models.LtResult{Id: id, SysStatus: 2}, []string{"sys_status"})
+		c.review(id, conf.ReviewConfirmed, "", reviewRevoke{})
 	}
 	refer := c.Ctx.GetHeader("Referer")
 	if refer == "" {
@@ -109,3 +129,43 @@ func (c *AdminResultController) GetReset() mvc.Result {
 		Path: refer,
 	}
 }
+
+// 发放失败的虚拟币奖品重新发放 GET /admin/result/redeliver?id=1
+func (c *AdminResultController) GetRedeliver() mvc.Result {
+	id, err := c.Ctx.URLParamInt("id")
+	if err == nil {
+		info := c.ServiceResult.Get(id)
+		if info != nil && info.DeliverStatus == conf.DeliverDead {
+			c.ServiceReward.Dispatch(info)
+		}
+	}
+	refer := c.Ctx.GetHeader("Referer")
+	if refer == "" {
+		refer = "/admin/result"
+	}
+	return mvc.Response{
+		Path: refer,
+	}
+}
+
+// GET /admin/result/link?id=1
+// 中奖记录的用户、IP、设备关联的其他用户
+func (c *AdminResultController) GetLink() mvc.Result {
+	id := c.Ctx.URLParamIntDefault("id", 0)
+	info := c.ServiceResult.Get(id)
+	if info == nil {
+		return mvc.Response{
+			Path: "/admin/result",
+		}
+	}
+	return mvc.View{
+		Name: "admin/resultLink.html",
+		Data: iris.Map{
+			"Title":   "管理后台",
+			"Channel": "result",
+			"info":    info,
+			"link":    utils.GetLinkage(c.Cache, info.Uid, info.SysIp, info.Device),
+		},
+		Layout: "admin/layout.html",
+	}
+}
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+	utils "github.com/iralance/go-lottery/uitls"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/mvc"
+	"net/url"
+	"strings"
+)
+
+// 审核操作允许的前置状态
+var reviewFrom = map[int][]int{
+	conf.ReviewFlagged:       {conf.ReviewNone, conf.ReviewCleared},
+	conf.ReviewInvestigating: {conf.ReviewFlagged},
+	conf.ReviewConfirmed:     {conf.ReviewNone, conf.ReviewFlagged, conf.ReviewInvestigating},
+	conf.ReviewRevoked:       {conf.ReviewConfirmed},
+	conf.ReviewCleared:       {conf.ReviewFlagged, conf.ReviewInvestigating},
+}
+
+var reviewActions = map[string]int{
+	"flag":        conf.ReviewFlagged,
+	"investigate": conf.ReviewInvestigating,
+	"confirm":     conf.ReviewConfirmed,
+	"revoke":      conf.ReviewRevoked,
+	"clear":       conf.ReviewCleared,
+}
+
+// 撤销奖品时的处理方式
+type reviewRevoke struct {
+	RequeueCode bool // 优惠券重新放回奖品池，否则作废
+	BlackUser   bool
+	BlackIp     bool
+	BlackDays   int
+}
+
+// GET /admin/result/review?id=1
+func (c *AdminResultController) GetReview() mvc.Result {
+	id := c.Ctx.URLParamIntDefault("id", 0)
+	info := c.ServiceResult.Get(id)
+	if info == nil {
+		return mvc.Response{
+			Path: "/admin/result",
+		}
+	}
+	return mvc.View{
+		Name: "admin/resultReview.html",
+		Data: iris.Map{
+			"Title":    "管理后台",
+			"Channel":  "result",
+			"info":     info,
+			"Datalist": c.ServiceReview.SearchByResult(id),
+			"Error":    c.Ctx.URLParam("error"),
+		},
+		Layout: "admin/layout.html",
+	}
+}
+
+// POST /admin/result/review
+func (c *AdminResultController) PostReview() mvc.Result {
+	id := c.Ctx.PostValueIntDefault("id", 0)
+	action, ok := reviewActions[c.Ctx.FormValue("action")]
+	if !ok {
+		return mvc.Response{
+			Text: fmt.Sprintf("不支持的审核操作, action=%s", c.Ctx.FormValue("action")),
+		}
+	}
+	revoke := reviewRevoke{
+		RequeueCode: c.Ctx.FormValue("code") == "requeue",
+		BlackUser:   c.Ctx.FormValue("black_user") == "1",
+		BlackIp:     c.Ctx.FormValue("black_ip") == "1",
+		BlackDays:   c.Ctx.PostValueIntDefault("black_days", 30),
+	}
+	path := fmt.Sprintf("/admin/result/review?id=%d", id)
+	if err := c.review(id, action, c.Ctx.FormValue("reason"), revoke); err != nil {
+		path += "&error=" + url.QueryEscape(err.Error())
+	}
+	return mvc.Response{
+		Path: path,
+	}
+}
+
+// 执行一次审核操作，状态不对的时候不执行，并且记录审核历史
+func (c *AdminResultController) review(id, action int, reason string, revoke reviewRevoke) error {
+	info := c.ServiceResult.Get(id)
+	if info == nil {
+		return fmt.Errorf("中奖记录不存在")
+	}
+	data := &models.LtResult{Id: id, ReviewStatus: action}
+	columns := []string{"review_status"}
+	if action == conf.ReviewConfirmed || action == conf.ReviewRevoked {
+		data.SysStatus = conf.ResultStatusCheat
+		columns = append(columns, "sys_status")
+	}
+	ok, err := c.ServiceResult.UpdateReview(data, reviewFrom[action], columns)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("当前审核状态不能执行该操作")
+	}
+	detail := ""
+	if action == conf.ReviewRevoked {
+		detail = c.revoke(info, revoke)
+	}
+	reviewer, _, _ := c.Ctx.Request().BasicAuth()
+	c.ServiceReview.Create(&models.LtReview{
+		ResultId:   id,
+		Action:     action,
+		Reviewer:   reviewer,
+		Reason:     reason,
+		Detail:     detail,
+		SysCreated: comm.NowUnix(),
+		SysIp:      comm.ClientIP(c.Ctx.Request()),
+	})
+	return nil
+}
+
+// 撤销奖品，退回库存和奖品池，处理优惠券，按需拉黑用户和IP
+func (c *AdminResultController) revoke(info *models.LtResult, revoke reviewRevoke) string {
+	detail := make([]string, 0)
+	gift := c.ServiceGift.Get(info.GiftId, true)
+	if gift != nil && gift.PrizeNum > 0 {
+		if utils.ReturnPrizeGift(c.Cache, gift.Id, c.ServiceGift) {
+			detail = append(detail, "退回库存")
+		} else {
+			detail = append(detail, "退回库存失败")
+		}
+	}
+	if info.GiftType == conf.GtypeCodeDiff && info.CodeId > 0 {
+		op := "作废优惠券"
+		if revoke.RequeueCode {
+			op = "优惠券放回奖品池"
+		}
+		if !utils.ReturnCodeDiff(c.Cache, info.GiftId, info.CodeId, revoke.RequeueCode, c.ServiceCode) {
+			op += "失败"
+		}
+		detail = append(detail, op)
+	}
+	if c.ServiceFulfill.Cancel(info.Id) {
+		detail = append(detail, "取消发货")
+	}
+	now := comm.NowUnix()
+	blacktime := now + revoke.BlackDays*86400
+	if revoke.BlackUser && info.Uid > 0 {
+		setBlackUser(c.ServiceUser, info.Uid, info.Username, info.SysIp, blacktime)
+		detail = append(detail, fmt.Sprintf("用户拉黑%d天", revoke.BlackDays))
+	}
+	if revoke.BlackIp && info.SysIp != "" {
+		setBlackIp(c.ServiceBlackip, info.SysIp, blacktime)
+		detail = append(detail, fmt.Sprintf("IP拉黑%d天", revoke.BlackDays))
+	}
+	return strings.Join(detail, "，")
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/datasource"
+	"github.com/iralance/go-lottery/models"
+	"github.com/iralance/go-lottery/services"
+	"github.com/iralance/go-lottery/web/viewmodels"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/mvc"
+)
+
+type AdminRuleController struct {
+	Ctx            iris.Context
+	ServiceUser    services.UserService
+	ServiceGift    services.GiftService
+	ServiceCode    services.CodeService
+	ServiceResult  services.ResultService
+	ServiceUserday services.UserdayService
+	ServiceBlackip services.BlackipService
+	ServiceRule    services.RuleService
+	ServiceReview  services.ReviewService
+	ServiceFulfill services.FulfillService
+	ServiceReward  services.RewardDispatcher
+	ServiceCodeGen services.CodeGenService
+	ServiceDrawLog services.DrawLogService
+	ServiceChance  services.ChanceService
+	ServicePoint   services.PointService
+	ServicePity    services.PityService
+	Cache          datasource.Cache
+}
+
+// GET /admin/rule/
+func (c *AdminRuleController) Get() mvc.Result {
+	datalist := c.ServiceRule.GetAll()
+	return mvc.View{
+		Name: "admin/rule.html",
+		Data: iris.Map{
+			"Title":    "管理后台",
+			"Channel":  "rule",
+			"Datalist": datalist,
+			"Total":    len(datalist),
+		},
+		Layout: "admin/layout.html",
+	}
+}
+
+// GET /admin/rule/edit?id=1
+func (c *AdminRuleController) GetEdit() mvc.Result {
+	id := c.Ctx.URLParamIntDefault("id", 0)
+	ruleInfo := viewmodels.ViewRule{Action: conf.RuleActionFlag}
+	if id > 0 {
+		data := c.ServiceRule.Get(id)
+		if data != nil {
+			ruleInfo.Id = data.Id
+			ruleInfo.Title = data.Title
+			ruleInfo.Rtype = data.Rtype
+			ruleInfo.Gtype = data.Gtype
+			ruleInfo.Num = data.Num
+			ruleInfo.Action = data.Action
+		}
+	}
+	return mvc.View{
+		Name: "admin/ruleEdit.html",
+		Data: iris.Map{
+			"Title":   "管理后台",
+			"Channel": "rule",
+			"info":    ruleInfo,
+		},
+		Layout: "admin/layout.html",
+	}
+}
+
+// POST /admin/rule/save
+func (c *AdminRuleController) PostSave() mvc.Result {
+	data := viewmodels.ViewRule{}
+	err := c.Ctx.ReadForm(&data)
+	if err != nil {
+		fmt.Println("admin_rule.PostSave ReadForm error=", err)
+		return mvc.Response{
+			Text: fmt.Sprintf("ReadForm转换异常, err=%s", err),
+		}
+	}
+	if data.Rtype < conf.RuleTypeWinCooldown || data.Rtype > conf.RuleTypeLinkScore ||
+		data.Action < conf.RuleActionFlag || data.Action > conf.RuleActionDeny {
+		return mvc.Response{
+			Text: fmt.Sprintf("规则类型或者处理方式不正确, rtype=%d, action=%d", data.Rtype, data.Action),
+		}
+	}
+	if data.Num <= 0 {
+		return mvc.Response{
+			Text: fmt.Sprintf("天数或者次数上限必须大于0, num=%d", data.Num),
+		}
+	}
+	ruleInfo := models.LtRule{
+		Id:     data.Id,
+		Title:  data.Title,
+		Rtype:  data.Rtype,
+		Gtype:  data.Gtype,
+		Num:    data.Num,
+		Action: data.Action,
+		SysIp:  comm.ClientIP(c.Ctx.Request()),
+	}
+	if ruleInfo.Id > 0 && c.ServiceRule.Get(ruleInfo.Id) != nil {
+		ruleInfo.SysUpdated = comm.NowUnix()
+		c.ServiceRule.Update(&ruleInfo, []string{"title", "rtype", "gtype", "num", "action", "sys_updated", "sys_ip"})
+	} else {
+		ruleInfo.Id = 0
+		ruleInfo.SysCreated = comm.NowUnix()
+		c.ServiceRule.Create(&ruleInfo)
+	}
+	return mvc.Response{
+		Path: "/admin/rule",
+	}
+}
+
+// GET /admin/rule/delete?id=1
+func (c *AdminRuleController) GetDelete() mvc.Result {
+	id, err := c.Ctx.URLParamInt("id")
+	if err == nil {
+		c.ServiceRule.Delete(id)
+	}
+	return mvc.Response{
+		Path: "/admin/rule",
+	}
+}
+
+// GET /admin/rule/reset?id=1
+func (c *AdminRuleController) GetReset() mvc.Result {
+	id, err := c.Ctx.URLParamInt("id")
+	if err == nil {
+		c.ServiceRule.Update(&models.LtRule{Id: id, SysStatus: 0}, []string{"sys_status"})
+	}
+	return mvc.Response{
+		Path: "/admin/rule",
+	}
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"fmt"
 	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/datasource"
 	"github.com/iralance/go-lottery/models"
 	"github.com/iralance/go-lottery/services"
 	"github.com/kataras/iris/v12"
@@ -17,6 +18,16 @@ type AdminUserController struct {
 	ServiceResult  services.ResultService
 	ServiceUserday services.UserdayService
 	ServiceBlackip services.BlackipService
+	ServiceRule    services.RuleService
+	ServiceReview  services.ReviewService
+	ServiceFulfill services.FulfillService
+	ServiceReward  services.RewardDispatcher
+	ServiceCodeGen services.CodeGenService
+	ServiceDrawLog services.DrawLogService
+	ServiceChance  services.ChanceService
+	ServicePoint   services.PointService
+	ServicePity    services.PityService
+	Cache          datasource.Cache
 }
 
 // GET /admin/user/
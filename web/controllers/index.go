@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"github.com/iralance/go-lottery/comm"
 	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/datasource"
 	"github.com/iralance/go-lottery/models"
 	"github.com/iralance/go-lottery/services"
+	utils "github.com/iralance/go-lottery/uitls"
+	"github.com/iralance/go-lottery/web/feed"
 	"github.com/kataras/iris/v12"
+	"github.com/kataras/neffos"
 	"log"
-	"strconv"
-	"time"
 )
 
 type IndexController struct {
@@ -20,6 +22,40 @@ type IndexController struct {
 	ServiceResult  services.ResultService
 	ServiceUserday services.UserdayService
 	ServiceBlackip services.BlackipService
+	ServiceRule    services.RuleService
+	ServiceReview  services.ReviewService
+	ServiceFulfill services.FulfillService
+	ServiceReward  services.RewardDispatcher
+	ServiceCodeGen services.CodeGenService
+	ServiceDrawLog services.DrawLogService
+	ServiceChance  services.ChanceService
+	ServicePoint   services.PointService
+	ServicePity    services.PityService
+	Cache          datasource.Cache
+	Hub            *feed.Hub
+	Ws             *neffos.Server
+}
+
+// 抽奖接口使用与控制器相同的服务和缓存
+func (c *IndexController) newLuckyApi() *LuckyApi {
+	return &LuckyApi{
+		ServiceUser:    c.ServiceUser,
+		ServiceGift:    c.ServiceGift,
+		ServiceCode:    c.ServiceCode,
+		ServiceResult:  c.ServiceResult,
+		ServiceUserday: c.ServiceUserday,
+		ServiceBlackip: c.ServiceBlackip,
+		ServiceRule:    c.ServiceRule,
+		ServiceReview:  c.ServiceReview,
+		ServiceFulfill: c.ServiceFulfill,
+		ServiceReward:  c.ServiceReward,
+		ServiceCodeGen: c.ServiceCodeGen,
+		ServiceDrawLog: c.ServiceDrawLog,
+		ServiceChance:  c.ServiceChance,
+		ServicePoint:   c.ServicePoint,
+		ServicePity:    c.ServicePity,
+		Cache:          c.Cache,
+	}
 }
 
 // http://localhost:8080/
@@ -51,20 +87,33 @@ func (c *IndexController) GetNewprize() map[string]interface{} {
 	rs := make(map[string]interface{})
 	rs["code"] = 0
 	rs["msg"] = ""
-	gifts := c.ServiceGift.GetAll(true)
-	giftIds := []int{}
-	for _, data := range gifts {
-		// 虚拟券或者实物奖才需要放到外部榜单中展示
-		if data.Gtype > 1 {
-			giftIds = append(giftIds, data.Id)
+	// 中奖的时候已经写入缓存，缓存中没有的时候才读取数据库
+	list := utils.GetRecentWinners(c.Cache)
+	if list == nil {
+		gifts := c.ServiceGift.GetAll(true)
+		giftIds := []int{}
+		for _, data := range gifts {
+			// 虚拟券或者实物奖才需要放到外部榜单中展示
+			// 安慰奖不放到榜单中展示
+			if utils.IsPublicGift(data.Gtype) && data.Fallback == 0 {
+				giftIds = append(giftIds, data.Id)
+			}
+		}
+		results := c.ServiceResult.GetNewPrize(conf.WinnerRecentSize, giftIds)
+		// 去掉设置了不在榜单中展示的用户
+		datalist := make([]models.LtResult, 0, len(results))
+		for _, data := range results {
+			if user := c.ServiceUser.Get(data.Uid); user.Hidewin == 0 {
+				datalist = append(datalist, data)
+			}
 		}
+		list = utils.SetRecentWinners(c.Cache, datalist)
 	}
-	list := c.ServiceResult.GetNewPrize(50, giftIds)
 	rs["prize_list"] = list
 	return rs
 }
 
-// http://localhost:8080/myprize
+// http://localhost:8080/myprize?page=1
 func (c *IndexController) GetMyprize() map[string]interface{} {
 	rs := make(map[string]interface{})
 	rs["code"] = 0
@@ -76,13 +125,83 @@ func (c *IndexController) GetMyprize() map[string]interface{} {
 		rs["msg"] = "请先登录，再来抽奖"
 		return rs
 	}
-	// 只读取出来最新的100次中奖记录
-	list := c.ServiceResult.SearchByUser(loginuser.Uid, 1, 100)
-	rs["prize_list"] = list
+	page := c.Ctx.URLParamIntDefault("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	list := c.ServiceResult.SearchByUser(loginuser.Uid, page, conf.UserListPageSize)
+	// 实物奖品的发货状态
+	fulfills := make(map[int]*models.LtFulfill)
+	for _, data := range c.ServiceFulfill.SearchByUser(loginuser.Uid) {
+		info := data
+		fulfills[data.ResultId] = &info
+	}
+	datalist := make([]models.ObjMyPrize, len(list))
+	for i, data := range list {
+		// 优惠券的编码加密保存，返回给用户的时候才解密
+		giftData := data.GiftData
+		if data.CodeId > 0 {
+			if code := c.ServiceCode.Get(data.CodeId); code != nil {
+				giftData = c.ServiceCode.PlainCode(code)
+			}
+		}
+		datalist[i] = models.ObjMyPrize{
+			Id:            data.Id,
+			GiftId:        data.GiftId,
+			GiftName:      data.GiftName,
+			GiftType:      data.GiftType,
+			GiftData:      giftData,
+			SysStatus:     data.SysStatus,
+			DeliverStatus: data.DeliverStatus,
+			SysCreated:    data.SysCreated,
+			Fulfill:       fulfills[data.Id],
+		}
+	}
+	rs["prize_list"] = datalist
+	rs["page"] = page
+	rs["total"] = c.ServiceResult.CountByUser(loginuser.Uid)
 	// 今天抽奖次数
-	day, _ := strconv.Atoi(comm.FormatFromUnixTimeShort(time.Now().Unix()))
-	num := c.ServiceUserday.Count(loginuser.Uid, day)
-	rs["prize_num"] = conf.UserPrizeMax - num
+	num := 0
+	userdayInfo := c.ServiceUserday.GetUserToday(loginuser.Uid)
+	if userdayInfo != nil {
+		num = userdayInfo.Num
+	}
+	freeNum := conf.UserPrizeMax - num
+	if freeNum < 0 {
+		freeNum = 0
+	}
+	// 额外获得的抽奖机会，按照到期时间排序
+	chances := c.ServiceChance.SearchValid(loginuser.Uid)
+	chanceNum := 0
+	for _, data := range chances {
+		chanceNum += data.LeftNum
+	}
+	rs["free_num"] = freeNum
+	rs["chance_num"] = chanceNum
+	rs["chance_list"] = chances
+	rs["prize_num"] = freeNum + chanceNum
+	return rs
+}
+
+// 用户自己的抽奖记录，包括没有中奖的
+// http://localhost:8080/mydraws?page=1
+func (c *IndexController) GetMydraws() map[string]interface{} {
+	rs := make(map[string]interface{})
+	rs["code"] = 0
+	rs["msg"] = ""
+	loginuser := comm.GetLoginUser(c.Ctx.Request())
+	if loginuser == nil || loginuser.Uid < 1 {
+		rs["code"] = 101
+		rs["msg"] = "请先登录"
+		return rs
+	}
+	page := c.Ctx.URLParamIntDefault("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	rs["draw_list"] = c.ServiceDrawLog.SearchByUser(loginuser.Uid, page, conf.UserListPageSize)
+	rs["page"] = page
+	rs["total"] = c.ServiceDrawLog.CountByUser(loginuser.Uid)
 	return rs
 }
 
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/models"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var mobileRegexp = regexp.MustCompile(`^\+?[0-9][0-9\- ]{4,19}$`)
+
+// 收货地址以及实物奖品的发货状态 GET /address
+func (c *IndexController) GetAddress() map[string]interface{} {
+	rs := make(map[string]interface{})
+	rs["code"] = 0
+	rs["msg"] = ""
+	loginuser := comm.GetLoginUser(c.Ctx.Request())
+	if loginuser == nil || loginuser.Uid < 1 {
+		rs["code"] = 101
+		rs["msg"] = "请先登录"
+		return rs
+	}
+	user := c.ServiceUser.Get(loginuser.Uid)
+	rs["realname"] = user.Realname
+	rs["mobile"] = user.Mobile
+	rs["address"] = user.Address
+	rs["fulfill_list"] = c.ServiceFulfill.SearchByUser(loginuser.Uid)
+	return rs
+}
+
+// 填写收货地址，等待填写地址的实物奖品进入待发货 POST /address
+func (c *IndexController) PostAddress() map[string]interface{} {
+	rs := make(map[string]interface{})
+	rs["code"] = 0
+	rs["msg"] = ""
+	loginuser := comm.GetLoginUser(c.Ctx.Request())
+	if loginuser == nil || loginuser.Uid < 1 {
+		rs["code"] = 101
+		rs["msg"] = "请先登录"
+		return rs
+	}
+	realname := strings.TrimSpace(c.Ctx.PostValue("realname"))
+	mobile := strings.TrimSpace(c.Ctx.PostValue("mobile"))
+	address := strings.TrimSpace(c.Ctx.PostValue("address"))
+	if realname == "" || utf8.RuneCountInString(realname) > 50 {
+		rs["code"] = 301
+		rs["msg"] = "请填写正确的联系人"
+		return rs
+	}
+	if !mobileRegexp.MatchString(mobile) {
+		rs["code"] = 302
+		rs["msg"] = "请填写正确的手机号"
+		return rs
+	}
+	if address == "" || utf8.RuneCountInString(address) > 255 {
+		rs["code"] = 303
+		rs["msg"] = "请填写正确的收货地址"
+		return rs
+	}
+	now := comm.NowUnix()
+	// 用户不在数据表中的时候先新增，再通过更新刷新缓存
+	if user := c.ServiceUser.Get(loginuser.Uid); user.SysCreated == 0 {
+		c.ServiceUser.Create(&models.LtUser{Id: loginuser.Uid, Username: loginuser.Username,
+			SysCreated: now, SysIp: comm.ClientIP(c.Ctx.Request())})
+	}
+	c.ServiceUser.Update(&models.LtUser{
+		Id:         loginuser.Uid,
+		Realname:   realname,
+		Mobile:     mobile,
+		Address:    address,
+		SysUpdated: now,
+	}, []string{"realname", "mobile", "address", "sys_updated"})
+	rs["num"] = c.ServiceFulfill.SaveAddress(loginuser.Uid, realname, mobile, address)
+	return rs
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 每日签到，获得额外的抽奖机会 POST /checkin
+func (c *IndexController) PostCheckin() map[string]interface{} {
+	return c.grantDaily(conf.ChanceSourceCheckin, "每日签到", 501, "今天已经签到过了")
+}
+
+// 分享之后获得额外的抽奖机会，每天一次 POST /share
+func (c *IndexController) PostShare() map[string]interface{} {
+	return c.grantDaily(conf.ChanceSourceShare, "分享", 502, "今天已经分享过了")
+}
+
+// 每天一次的抽奖机会，ref是用户和系统时区的日期
+func (c *IndexController) grantDaily(source int, remark string, dupCode int, dupMsg string) map[string]interface{} {
+	rs := make(map[string]interface{})
+	rs["code"] = 0
+	rs["msg"] = ""
+	loginuser := comm.GetLoginUser(c.Ctx.Request())
+	if loginuser == nil || loginuser.Uid < 1 {
+		rs["code"] = 101
+		rs["msg"] = "请先登录"
+		return rs
+	}
+	ref := fmt.Sprintf("%d-%s", loginuser.Uid, time.Now().In(conf.SysTimeLocation).Format(conf.SysTimeformShort))
+	ok, err := c.grantChance(loginuser.Uid, source, conf.ChanceGrantNum[source], ref, remark)
+	if err != nil {
+		rs["code"] = 500
+		rs["msg"] = "发放失败，请重试"
+		return rs
+	}
+	if !ok {
+		rs["code"] = dupCode
+		rs["msg"] = dupMsg
+		return rs
+	}
+	rs["num"] = conf.ChanceGrantNum[source]
+	rs["chance_num"] = c.ServiceChance.LeftNum(loginuser.Uid)
+	return rs
+}
+
+// 接受邀请，邀请人获得额外的抽奖机会 POST /invite
+// inviter 是邀请人的uid，每个用户只能接受一次邀请
+func (c *IndexController) PostInvite() map[string]interface{} {
+	rs := make(map[string]interface{})
+	rs["code"] = 0
+	rs["msg"] = ""
+	loginuser := comm.GetLoginUser(c.Ctx.Request())
+	if loginuser == nil || loginuser.Uid < 1 {
+		rs["code"] = 101
+		rs["msg"] = "请先登录"
+		return rs
+	}
+	inviter, err := c.Ctx.PostValueInt("inviter")
+	if err != nil || inviter < 1 || inviter == loginuser.Uid {
+		rs["code"] = 503
+		rs["msg"] = "邀请人错误"
+		return rs
+	}
+	if c.ServiceChance.CountBySource(inviter, conf.ChanceSourceInvite, dayBegin()) >= int64(conf.ChanceInviteDayMax) {
+		rs["code"] = 505
+		rs["msg"] = "邀请人今天获得的抽奖机会已达到上限"
+		return rs
+	}
+	ok, err := c.grantChance(inviter, conf.ChanceSourceInvite, conf.ChanceGrantNum[conf.ChanceSourceInvite],
+		strconv.Itoa(loginuser.Uid), fmt.Sprintf("邀请用户%d", loginuser.Uid))
+	if err != nil {
+		rs["code"] = 500
+		rs["msg"] = "发放失败，请重试"
+		return rs
+	}
+	if !ok {
+		rs["code"] = 504
+		rs["msg"] = "已经接受过邀请了"
+		return rs
+	}
+	return rs
+}
+
+// 外部活动发放抽奖机会 POST /chance/grant
+// 请求内容是JSON {"uid":1,"num":1,"ref":"活动的唯一单号","remark":"说明"}
+// 使用X-Lottery-Timestamp和X-Lottery-Signature两个头做HMAC签名验证
+func (c *IndexController) PostChanceGrant() map[string]interface{} {
+	rs := make(map[string]interface{})
+	rs["code"] = 0
+	rs["msg"] = ""
+	body, err := c.Ctx.GetBody()
+	if err != nil {
+		rs["code"] = 400
+		rs["msg"] = "请求内容错误"
+		return rs
+	}
+	timestamp := c.Ctx.GetHeader("X-Lottery-Timestamp")
+	t, _ := strconv.Atoi(timestamp)
+	now := comm.NowUnix()
+	if t < now-conf.CodeRedeemSkew || t > now+conf.CodeRedeemSkew ||
+		!comm.HmacVerify(conf.ChancePartnerSecret, timestamp, body, c.Ctx.GetHeader("X-Lottery-Signature")) {
+		rs["code"] = 406
+		rs["msg"] = "签名错误"
+		return rs
+	}
+	params := struct {
+		Uid    int    `json:"uid"`
+		Num    int    `json:"num"`
+		Ref    string `json:"ref"`
+		Remark string `json:"remark"`
+	}{}
+	if err = json.Unmarshal(body, &params); err != nil || params.Uid < 1 ||
+		params.Num < 1 || params.Num > conf.ChanceEventMax || strings.TrimSpace(params.Ref) == "" ||
+		len(params.Ref) > 100 || utf8.RuneCountInString(params.Remark) > 255 {
+		rs["code"] = 400
+		rs["msg"] = "请求内容错误"
+		return rs
+	}
+	// 相同单号重复请求的时候直接返回成功
+	_, err = c.grantChance(params.Uid, conf.ChanceSourceEvent, params.Num, strings.TrimSpace(params.Ref), params.Remark)
+	if err != nil {
+		rs["code"] = 500
+		rs["msg"] = "发放失败，请重试"
+		return rs
+	}
+	return rs
+}
+
+func (c *IndexController) grantChance(uid, source, num int, ref, remark string) (bool, error) {
+	now := comm.NowUnix()
+	ok, err := c.ServiceChance.Grant(&models.LtChance{
+		Uid:        uid,
+		Source:     source,
+		Ref:        ref,
+		Num:        num,
+		Expire:     now + conf.ChanceExpireDays*86400,
+		Remark:     remark,
+		SysCreated: now,
+	})
+	if err != nil {
+		log.Println("index_chance.grantChance ServiceChance.Grant uid=", uid, ", ref=", ref, ", error=", err)
+	}
+	return ok, err
+}
+
+// 今天零点的时间戳
+func dayBegin() int {
+	y, m, d := time.Now().In(conf.SysTimeLocation).Date()
+	return int(time.Date(y, m, d, 0, 0, 0, 0, conf.SysTimeLocation).Unix())
+}
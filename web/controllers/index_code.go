@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"strconv"
+	"strings"
+)
+
+// 合作方核销优惠券 POST /code/redeem
+// 请求内容是JSON {"code":"xxx","ref":"合作方的单号"}
+// 使用X-Lottery-Timestamp和X-Lottery-Signature两个头做HMAC签名验证
+func (c *IndexController) PostCodeRedeem() map[string]interface{} {
+	rs := make(map[string]interface{})
+	rs["code"] = 0
+	rs["msg"] = ""
+	body, err := c.Ctx.GetBody()
+	if err != nil {
+		rs["code"] = 400
+		rs["msg"] = "请求内容错误"
+		return rs
+	}
+	timestamp := c.Ctx.GetHeader("X-Lottery-Timestamp")
+	t, _ := strconv.Atoi(timestamp)
+	now := comm.NowUnix()
+	if t < now-conf.CodeRedeemSkew || t > now+conf.CodeRedeemSkew ||
+		!comm.HmacVerify(conf.CodePartnerSecret, timestamp, body, c.Ctx.GetHeader("X-Lottery-Signature")) {
+		rs["code"] = 406
+		rs["msg"] = "签名错误"
+		return rs
+	}
+	params := struct {
+		Code string `json:"code"`
+		Ref  string `json:"ref"`
+	}{}
+	if err = json.Unmarshal(body, &params); err != nil || strings.TrimSpace(params.Code) == "" {
+		rs["code"] = 400
+		rs["msg"] = "请求内容错误"
+		return rs
+	}
+	code, msg := c.ServiceCode.Redeem(strings.TrimSpace(params.Code), strings.TrimSpace(params.Ref))
+	rs["code"] = code
+	rs["msg"] = msg
+	return rs
+}
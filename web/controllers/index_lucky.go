@@ -1,24 +1,108 @@
 package controllers
 
-import "github.com/iralance/go-lottery/comm"
+import (
+	"fmt"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+	utils "github.com/iralance/go-lottery/uitls"
+	"net/http"
+)
 
 //localhost:8080/lucky
 func (c *IndexController) GetLucky() map[string]interface{} {
 	rs := make(map[string]interface{})
 	rs["code"] = 0
 	rs["msg"] = ""
-	// 1 验证登录用户
-	loginuser := comm.GetLoginUser(c.Ctx.Request())
-	if loginuser == nil || loginuser.Uid < 1 {
-		rs["code"] = 101
-		rs["msg"] = "请先登录，再来抽奖"
+	loginuser, campaign := c.checkLucky(rs)
+	if loginuser == nil {
 		return rs
 	}
 	ip := comm.ClientIP(c.Ctx.Request())
-	api := &LuckyApi{}
-	code, msg, gift := api.luckDo(loginuser.Uid, loginuser.Username, ip)
+	device := comm.ClientDevice(c.Ctx.Request())
+	api := c.newLuckyApi()
+	code, msg, gift := api.luckDo(loginuser.Uid, loginuser.Username, ip, device, campaign)
 	rs["code"] = code
 	rs["msg"] = msg
 	rs["gift"] = gift
+	if code == 106 {
+		// 需要验证，返回新的验证，完成之后带上challenge_id和challenge_answer重新抽奖
+		rs["challenge"] = utils.NewChallenge(c.Cache, loginuser.Uid, conf.ChallengeType)
+	}
+	return rs
+}
+
+// 一次抽奖多次
+// localhost:8080/lucky/batch?n=10
+func (c *IndexController) GetLuckyBatch() map[string]interface{} {
+	rs := make(map[string]interface{})
+	rs["code"] = 0
+	rs["msg"] = ""
+	n := c.Ctx.URLParamIntDefault("n", conf.LuckyBatchMax)
+	if n < 1 || n > conf.LuckyBatchMax {
+		rs["code"] = 111
+		rs["msg"] = fmt.Sprintf("一次最多抽奖%d次", conf.LuckyBatchMax)
+		return rs
+	}
+	loginuser, campaign := c.checkLucky(rs)
+	if loginuser == nil {
+		return rs
+	}
+	ip := comm.ClientIP(c.Ctx.Request())
+	device := comm.ClientDevice(c.Ctx.Request())
+	api := c.newLuckyApi()
+	code, msg, draws := api.luckBatchDo(loginuser.Uid, loginuser.Username, ip, device, campaign, n)
+	rs["code"] = code
+	rs["msg"] = msg
+	list := make([]models.ObjLuckyDraw, 0, len(draws))
+	for _, draw := range draws {
+		list = append(list, models.ObjLuckyDraw{Code: draw.code, Msg: draw.msg, Gift: draw.gift})
+	}
+	rs["draw_list"] = list
+	if code == 106 {
+		rs["challenge"] = utils.NewChallenge(c.Cache, loginuser.Uid, conf.ChallengeType)
+	}
 	return rs
 }
+
+// 抽奖之前的验证，登录用户、验证的答案、选择的活动
+// 验证没有通过的时候，rs中设置好返回的数据，返回nil
+func (c *IndexController) checkLucky(rs map[string]interface{}) (*models.ObjLoginuser, string) {
+	// 1 验证登录用户
+	loginuser := comm.GetLoginUser(c.Ctx.Request())
+	if loginuser == nil || loginuser.Uid < 1 {
+		rs["code"] = 101
+		rs["msg"] = "请先登录，再来抽奖"
+		return nil, ""
+	}
+	// 提交了验证的答案，先完成验证
+	challengeId := c.Ctx.URLParam("challenge_id")
+	if challengeId != "" &&
+		!utils.VerifyChallenge(c.Cache, loginuser.Uid, challengeId, c.Ctx.URLParam("challenge_answer")) {
+		rs["code"] = 107
+		rs["msg"] = "验证没有通过，请重新验证"
+		rs["challenge"] = utils.NewChallenge(c.Cache, loginuser.Uid, conf.ChallengeType)
+		return nil, ""
+	}
+	// 选择的活动，不同的活动每次抽奖消耗的积分不同
+	campaign := c.Ctx.URLParam("campaign")
+	if _, ok := conf.DrawPointsCost[campaign]; !ok {
+		rs["code"] = 108
+		rs["msg"] = "活动不存在"
+		return nil, ""
+	}
+	return loginuser, campaign
+}
+
+// 图片验证码
+// localhost:8080/challenge/image?id=xxx
+func (c *IndexController) GetChallengeImage() {
+	img := utils.ChallengeImage(c.Cache, c.Ctx.URLParam("id"))
+	if img == nil {
+		c.Ctx.StatusCode(http.StatusNotFound)
+		return
+	}
+	c.Ctx.Header("Cache-Control", "no-store")
+	c.Ctx.ContentType("image/png")
+	c.Ctx.Write(img)
+}
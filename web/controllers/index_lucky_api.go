@@ -1,49 +1,154 @@
 package controllers
 
 import (
+	"fmt"
 	"github.com/iralance/go-lottery/comm"
 	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/datasource"
 	"github.com/iralance/go-lottery/models"
 	"github.com/iralance/go-lottery/services"
 	utils "github.com/iralance/go-lottery/uitls"
 	"log"
+	"time"
 )
 
+// 抽奖的接口，依赖的服务和缓存由外部注入
 type LuckyApi struct {
+	ServiceUser    services.UserService
+	ServiceGift    services.GiftService
+	ServiceCode    services.CodeService
+	ServiceResult  services.ResultService
+	ServiceUserday services.UserdayService
+	ServiceBlackip services.BlackipService
+	ServiceRule    services.RuleService
+	ServiceReview  services.ReviewService
+	ServiceFulfill services.FulfillService
+	ServiceReward  services.RewardDispatcher
+	ServiceCodeGen services.CodeGenService
+	ServiceDrawLog services.DrawLogService
+	ServiceChance  services.ChanceService
+	ServicePoint   services.PointService
+	ServicePity    services.PityService
+	Cache          datasource.Cache
 }
 
-func (api *LuckyApi) luckDo(uid int, username, ip string) (int, string, *models.ObjGiftPrize) {
+// 一次抽奖的结果，中奖的时候带上还没有保存的中奖记录
+type luckyDraw struct {
+	code        int
+	msg         string
+	gift        *models.ObjGiftPrize
+	result      *models.LtResult
+	consolation bool // 安慰奖
+}
+
+// 中奖，不包括安慰奖
+func (d *luckyDraw) win() bool {
+	return d.code == 0 && !d.consolation
+}
+
+// 连续没有中奖的次数，中奖之后归零，获得安慰奖不算中奖
+func (d *luckyDraw) lossNum(lossNum int) int {
+	if d.win() {
+		return 0
+	} else if d.consolation || (d.code >= 205 && d.code <= 210) {
+		return lossNum + 1
+	}
+	return lossNum
+}
+
+func (api *LuckyApi) luckDo(uid int, username, ip, device, campaign string) (code int, msg string, gift *models.ObjGiftPrize) {
+	// 每次抽奖的结果都记录下来，用户可以查看自己的抽奖历史
+	resultId := 0
+	defer func() { api.drawLog(uid, ip, device, code, msg, gift, resultId) }()
 
 	// 2 用户抽奖分布式锁定
-	ok := utils.LockLucky(uid)
+	ok := utils.LockLucky(api.Cache, uid)
 	if ok {
-		defer utils.UnlockLucky(uid)
+		defer utils.UnlockLucky(api.Cache, uid)
 	} else {
 		return 102, "正在抽奖，请稍后重试", nil
 	}
 
-	//3 验证用户今日参与次数
-	userDayNum := utils.IncrUserLuckyNum(uid)
-	if userDayNum > conf.UserPrizeMax {
-		return 103, "今日的抽奖次数已用完，明天再来吧", nil
-	} else {
-		ok = api.checkUserDay(uid, userDayNum)
+	//3 需要积分的活动先扣除积分，免费的活动验证用户今日参与次数，免费的次数用完之后使用额外获得的抽奖机会
+	// 需要积分的抽奖不占用免费的次数，额外的抽奖机会在通过风控验证之后才扣除
+	var userDayNum int64
+	useChance := false
+	if cost := conf.DrawPointsCost[campaign]; cost > 0 {
+		userDayNum = utils.GetUserLuckyNum(api.Cache, uid)
+		ref := fmt.Sprintf("draw-%d-%d", uid, time.Now().UnixNano())
+		if code, msg = api.spendPoints(uid, cost, ref, campaign); code != 0 {
+			return code, msg, nil
+		}
+		defer func() { api.refundPoints(uid, cost, ref, campaign, code) }()
+	} else if userDayNum = utils.IncrUserLuckyNum(api.Cache, uid); userDayNum > conf.UserPrizeMax ||
+		!api.checkUserDay(uid, userDayNum, 1) {
+		if api.ServiceChance.LeftNum(uid) < 1 {
+			return 103, "今日的抽奖次数已用完，明天再来吧", nil
+		}
+		useChance = true
 	}
 
 	// 4 验证IP今日的参与次数
-	ipDayNum := utils.IncrIpLuckyNum(ip)
+	ipDayNum := utils.IncrIpLuckyNum(api.Cache, ip)
 	if ipDayNum > conf.IpLimitMax {
 		return 104, "相同IP参与次数太多，明天再来参与吧", nil
 	}
-	limitBlack := false // 黑名单
-	if ipDayNum > conf.IpPrizeMax {
+	// 记录用户、IP、设备的关联关系
+	utils.RecordLink(api.Cache, uid, ip, device)
+
+	// 风控规则和黑名单
+	code, msg, limitBlack, ruleId := api.checkLimit(uid, ip, device, userDayNum, ipDayNum)
+	if code != 0 {
+		return code, msg, nil
+	}
+	if useChance && !api.ServiceChance.Consume(uid) {
+		return 103, "今日的抽奖次数已用完，明天再来吧", nil
+	}
+
+	// 7 - 10 抽奖并且发放奖品
+	lossNum := utils.GetLossNum(api.Cache, uid)
+	draw := api.drawPrize(uid, username, ip, device, limitBlack, ruleId, lossNum, -1)
+	defer func() { utils.SetLossNum(api.Cache, uid, draw.lossNum(lossNum)) }()
+	if draw.code != 0 {
+		return draw.code, draw.msg, nil
+	}
+
+	// 11 记录中奖记录
+	_, err := api.ServiceResult.Create(draw.result)
+	if err != nil {
+		log.Println("index_lucky.GetLucky ServiceResult.Create ", draw.result,
+			", error=", err)
+		draw.code, draw.msg = 209, "很遗憾，没有中奖，请下次再试"
+		return draw.code, draw.msg, nil
+	}
+	resultId = draw.result.Id
+	api.afterPrize(uid, draw)
+	// 12 返回抽奖结果
+	return 0, draw.msg, draw.gift
+
+}
+
+// 风控规则和黑名单，返回0表示可以继续抽奖，以及是否只能获得小奖，需要标记的规则ID
+func (api *LuckyApi) checkLimit(uid int, ip, device string, userDayNum, ipDayNum int64) (code int, msg string, limitBlack bool, ruleId int) {
+	// 风控规则，只有标记的时候，中奖记录需要保存规则ID
+	action, rule := api.checkRule(uid, ip, device, userDayNum, ipDayNum)
+	switch action {
+	case conf.RuleActionDeny:
+		return 105, "抽奖受到限制，请稍后再试", false, 0
+	case conf.RuleActionCaptcha:
+		// 最近完成过验证的用户可以继续抽奖
+		if !utils.HasChallengePass(api.Cache, uid) {
+			return 106, "需要完成验证之后才能继续抽奖", false, 0
+		}
+	case conf.RuleActionDowngrade:
 		limitBlack = true
+	case conf.RuleActionFlag:
+		ruleId = rule.Id
 	}
 
 	// 5 验证IP黑名单
-	var blackipInfo *models.LtBlackip
 	if !limitBlack {
-		ok, blackipInfo = api.checkBlackip(ip)
+		ok, _ := api.checkBlackip(ip)
 		if !ok {
 			log.Println("黑名单中的IP", ip, limitBlack)
 			limitBlack = true
@@ -51,68 +156,136 @@ func (api *LuckyApi) luckDo(uid int, username, ip string) (int, string, *models.
 	}
 
 	// 6 验证用户黑名单
-	var userInfo *models.LtUser
 	if !limitBlack {
-		ok, userInfo = api.checkBlackUser(uid)
+		ok, _ := api.checkBlackUser(uid)
 		if !ok {
 			limitBlack = true
 		}
 	}
+	return 0, "", limitBlack, ruleId
+}
 
+// 抽奖一次，中奖的时候扣除奖品库存，返回还没有保存的中奖记录
+// guaranteeGtype大于等于0的时候，必定获得该类型的奖品，没有库存的时候正常抽奖
+func (api *LuckyApi) drawPrize(uid int, username, ip, device string, limitBlack bool,
+	ruleId, lossNum, guaranteeGtype int) *luckyDraw {
 	// 7 获得抽奖编码
 	prizeCode := comm.Random(10000)
 	// 8 匹配奖品是否中奖
-	prizeGift := api.prize(prizeCode, limitBlack)
+	var prizeGift *models.ObjGiftPrize
+	if guaranteeGtype >= 0 && (!limitBlack || guaranteeGtype < conf.GtypeGiftSmall) {
+		prizeGift = api.pityGift(guaranteeGtype)
+	}
+	if prizeGift == nil {
+		prizeGift = api.prize(prizeCode, limitBlack)
+	}
+	// 没有中奖的时候，连续没有中奖的次数达到保底规则之后提高概率或者必定中奖
+	pityId := 0
+	if prizeGift == nil ||
+		prizeGift.PrizeNum < 0 ||
+		(prizeGift.PrizeNum > 0 && prizeGift.LeftNum <= 0) {
+		if pityGift, id := api.pityPrize(lossNum, limitBlack); pityGift != nil {
+			prizeGift, pityId = pityGift, id
+		}
+	}
+	// 还是没有中奖的时候，发放安慰奖
+	draw := &luckyDraw{}
+	if prizeGift == nil ||
+		prizeGift.PrizeNum < 0 ||
+		(prizeGift.PrizeNum > 0 && prizeGift.LeftNum <= 0) {
+		if consolationGift := api.consolationPrize(uid, limitBlack); consolationGift != nil {
+			prizeGift, draw.consolation = consolationGift, true
+		}
+	}
 	if prizeGift == nil ||
 		prizeGift.PrizeNum < 0 ||
 		(prizeGift.PrizeNum > 0 && prizeGift.LeftNum <= 0) {
-		return 205, "很遗憾，没有中奖，请下次再试", nil
+		draw.code, draw.msg = 205, "很遗憾，没有中奖，请下次再试"
+		return draw
+	}
+	// 用户在该奖品、奖品类型、每天、每周的中奖次数限制，安慰奖有单独的每天次数限制
+	if !draw.consolation && !utils.CheckUserWinNum(api.Cache, uid, prizeGift, api.ServiceResult) {
+		draw.code, draw.msg = 210, "很遗憾，没有中奖，请下次再试"
+		return draw
 	}
 
 	// 9 有限制奖品发放
 	if prizeGift.PrizeNum > 0 {
-		if utils.GetGiftPoolNum(prizeGift.Id) <= 0 {
-			return 206, "很遗憾，没有中奖，请下次再试", nil
+		if utils.GetGiftPoolNum(api.Cache, prizeGift.Id) <= 0 {
+			draw.code, draw.msg = 206, "很遗憾，没有中奖，请下次再试"
+			return draw
 		}
-		ok = utils.PrizeGift(prizeGift.Id, prizeGift.LeftNum)
+		ok := utils.PrizeGift(api.Cache, prizeGift.Id, prizeGift.LeftNum, api.ServiceGift)
 		if !ok {
-			return 207, "很遗憾，没有中奖，请下次再试", nil
+			draw.code, draw.msg = 207, "很遗憾，没有中奖，请下次再试"
+			return draw
 		}
 	}
 
-	// 10 不同编码的优惠券的发放
+	// 10 不同编码的优惠券的发放，中奖记录只保存优惠券的ID
+	giftData, codeId := prizeGift.Gdata, 0
 	if prizeGift.Gtype == conf.GtypeCodeDiff {
-		code := utils.PrizeCodeDiff(prizeGift.Id, services.NewCodeService())
-		if code == "" {
-			return 208, "很遗憾，没有中奖，请下次再试", nil
+		id, giftCode := utils.PrizeCodeDiff(api.Cache, prizeGift.Id, api.ServiceCode)
+		if giftCode == "" {
+			draw.code, draw.msg = 208, "很遗憾，没有中奖，请下次再试"
+			return draw
 		}
-		prizeGift.Gdata = code
+		prizeGift.Gdata = giftCode
+		giftData, codeId = "", id
 	}
 
-	// 11 记录中奖记录
-	result := models.LtResult{
-		GiftId:     prizeGift.Id,
-		GiftName:   prizeGift.Title,
-		GiftType:   prizeGift.Gtype,
-		Uid:        uid,
-		Username:   username,
-		PrizeCode:  prizeCode,
-		GiftData:   prizeGift.Gdata,
-		SysCreated: comm.NowUnix(),
-		SysIp:      ip,
-		SysStatus:  0,
-	}
-	_, err := services.NewResultService().Create(&result)
-	if err != nil {
-		log.Println("index_lucky.GetLucky ServiceResult.Create ", result,
-			", error=", err)
-		return 209, "很遗憾，没有中奖，请下次再试", nil
+	// 中奖之后马上更新中奖次数和冷却期，一次抽奖多次的时候，后面的抽奖需要使用
+	if draw.consolation {
+		utils.IncrConsolationNum(api.Cache, uid)
+		draw.msg = "很遗憾，没有中奖，送您一份安慰奖"
+	} else {
+		utils.IncrUserWinNum(api.Cache, uid, prizeGift, api.ServiceResult)
+		// 中奖之后，用户和IP进入规则设置的冷却期
+		api.ruleCooldown(uid, username, ip, prizeGift.Gtype)
 	}
-	if prizeGift.Gtype == conf.GtypeGiftLarge {
-		// 如果获得了实物大奖，需要将用户、IP设置成黑名单一段时间
-		api.prizeLarge(ip, uid, username, userInfo, blackipInfo)
+
+	// 命中标记规则的中奖记录需要人工审核
+	reviewStatus := conf.ReviewNone
+	if ruleId > 0 {
+		reviewStatus = conf.ReviewFlagged
 	}
-	// 12 返回抽奖结果
-	return 0, "", prizeGift
+	fallback := 0
+	if draw.consolation {
+		fallback = 1
+	}
+	draw.gift = prizeGift
+	draw.result = &models.LtResult{
+		GiftId:       prizeGift.Id,
+		GiftName:     prizeGift.Title,
+		GiftType:     prizeGift.Gtype,
+		Uid:          uid,
+		Username:     username,
+		PrizeCode:    prizeCode,
+		GiftData:     giftData,
+		CodeId:       codeId,
+		Fallback:     fallback,
+		SysCreated:   comm.NowUnix(),
+		SysIp:        ip,
+		Device:       device,
+		SysStatus:    0,
+		RuleId:       ruleId,
+		PityId:       pityId,
+		ReviewStatus: reviewStatus,
+	}
+	return draw
+}
 
+// 中奖记录保存之后，发货、入账以及推送中奖动态
+func (api *LuckyApi) afterPrize(uid int, draw *luckyDraw) {
+	// 实物奖品进入发货流程
+	api.createFulfill(draw.result)
+	// 虚拟币奖品通知钱包服务入账
+	api.ServiceReward.Dispatch(draw.result)
+	// 推送中奖动态，安慰奖和用户设置了不在榜单中展示的时候不推送
+	if draw.consolation {
+		return
+	}
+	if user := api.ServiceUser.Get(uid); user.Hidewin == 0 {
+		utils.PublishWinner(api.Cache, draw.result)
+	}
 }
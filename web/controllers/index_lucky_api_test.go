@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/dao"
+	"github.com/iralance/go-lottery/datasource"
+	"github.com/iralance/go-lottery/models"
+	"github.com/iralance/go-lottery/services"
+	utils "github.com/iralance/go-lottery/uitls"
+)
+
+// 使用内存版本的数据和缓存，不需要mysql和redis
+func newMemLuckyApi() *LuckyApi {
+	cache := datasource.NewMemCache()
+	resultService := services.NewResultService(dao.NewResultMemDao())
+	return &LuckyApi{
+		ServiceUser:    services.NewUserService(dao.NewUserMemDao(), cache),
+		ServiceGift:    services.NewGiftService(dao.NewGiftMemDao(), cache),
+		ServiceCode:    services.NewCodeService(dao.NewCodeMemDao()),
+		ServiceResult:  resultService,
+		ServiceUserday: services.NewUserdayService(dao.NewUserdayMemDao()),
+		ServiceBlackip: services.NewBlackipService(dao.NewBlackipMemDao(), cache),
+		ServiceRule:    services.NewRuleService(dao.NewRuleMemDao(), cache),
+		ServiceReview:  services.NewReviewService(dao.NewReviewMemDao()),
+		ServiceFulfill: services.NewFulfillService(dao.NewFulfillMemDao()),
+		ServiceReward:  services.NewRewardDispatcher(conf.Webhook{}, cache, resultService),
+		ServiceCodeGen: services.NewCodeGenService(dao.NewCodeGenMemDao()),
+		ServiceDrawLog: services.NewDrawLogService(dao.NewDrawLogMemDao()),
+		ServiceChance:  services.NewChanceService(dao.NewChanceMemDao()),
+		ServicePoint:   services.NewPointService(dao.NewPointMemDao()),
+		ServicePity:    services.NewPityService(dao.NewPityMemDao(), cache),
+		Cache:          cache,
+	}
+}
+
+// 写入默认的风控规则，和应用第一次启动的时候一样
+func seedMemRules(t *testing.T, api *LuckyApi) {
+	if err := api.ServiceRule.SeedDefault(); err != nil {
+		t.Fatal("SeedDefault error", err)
+	}
+}
+
+// 新增一个必定中奖的奖品，并且设置好奖品池
+func seedMemGift(t *testing.T, api *LuckyApi, gtype, prizeNum int) *models.LtGift {
+	now := comm.NowUnix()
+	gift := &models.LtGift{
+		Title:      "test",
+		PrizeNum:   prizeNum,
+		LeftNum:    prizeNum,
+		PrizeCode:  "0-9999",
+		Gtype:      gtype,
+		Gdata:      "100",
+		TimeBegin:  now - 60,
+		TimeEnd:    now + 86400,
+		SysCreated: now,
+	}
+	if _, err := api.ServiceGift.Create(gift); err != nil {
+		t.Fatal("ServiceGift.Create error", err)
+	}
+	utils.ResetGiftPrizeData(api.Cache, gift, api.ServiceGift)
+	return gift
+}
+
+func TestLuckDoMem(t *testing.T) {
+	api := newMemLuckyApi()
+	seedMemRules(t, api)
+	gift := seedMemGift(t, api, conf.GtypeVirtual, 1)
+
+	code, msg, prize := api.luckDo(1, "u1", "10.0.0.1", "d1", "")
+	if code != 0 || prize == nil || prize.Id != gift.Id {
+		t.Fatalf("luckDo code=%d msg=%s prize=%v", code, msg, prize)
+	}
+	if num := api.ServiceResult.CountByUserGift(1, gift.Id); num != 1 {
+		t.Fatalf("CountByUserGift=%d, want 1", num)
+	}
+	if left := api.ServiceGift.Get(gift.Id, false).LeftNum; left != 0 {
+		t.Fatalf("LeftNum=%d, want 0", left)
+	}
+
+	// 库存已经发完，第二个用户不能中奖
+	code, _, prize = api.luckDo(2, "u2", "10.0.0.2", "d2", "")
+	if code == 0 || prize != nil {
+		t.Fatalf("luckDo without stock code=%d prize=%v", code, prize)
+	}
+}
+
+func TestLuckBatchDoMem(t *testing.T) {
+	api := newMemLuckyApi()
+	seedMemRules(t, api)
+	gift := seedMemGift(t, api, conf.GtypeVirtual, 0)
+
+	code, msg, draws := api.luckBatchDo(1, "u1", "10.0.0.1", "d1", "", 3)
+	if code != 0 || len(draws) != 3 {
+		t.Fatalf("luckBatchDo code=%d msg=%s draws=%d", code, msg, len(draws))
+	}
+	ids := map[int]bool{}
+	for _, draw := range draws {
+		if !draw.win() || draw.result.Id <= 0 || ids[draw.result.Id] {
+			t.Fatalf("draw code=%d result=%v", draw.code, draw.result)
+		}
+		ids[draw.result.Id] = true
+	}
+	if num := api.ServiceResult.CountByUserGift(1, gift.Id); num != 3 {
+		t.Fatalf("CountByUserGift=%d, want 3", num)
+	}
+}
+
+func TestLuckyDrawLossNum(t *testing.T) {
+	tests := []struct {
+		draw    luckyDraw
+		lossNum int
+		want    int
+	}{
+		{luckyDraw{code: 0}, 5, 0},
+		{luckyDraw{code: 0, consolation: true}, 5, 6},
+		{luckyDraw{code: 205}, 5, 6},
+		{luckyDraw{code: 210}, 5, 6},
+		// 奖品池、库存不足没有中奖的也计算
+		{luckyDraw{code: 207}, 5, 6},
+		{luckyDraw{code: 209}, 5, 6},
+		// 没有抽奖的时候不计算
+		{luckyDraw{code: 105}, 5, 5},
+		{luckyDraw{code: 211}, 5, 5},
+	}
+	for _, tt := range tests {
+		if got := tt.draw.lossNum(tt.lossNum); got != tt.want {
+			t.Errorf("luckyDraw{code: %d, consolation: %v}.lossNum(%d) = %d, want %d",
+				tt.draw.code, tt.draw.consolation, tt.lossNum, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+	utils "github.com/iralance/go-lottery/uitls"
+	"log"
+	"time"
+)
+
+// 一次抽奖n次，只锁定一次，参与次数和积分一次验证，中奖记录一次写入
+// 返回0的时候，draws中是每一次抽奖的结果
+func (api *LuckyApi) luckBatchDo(uid int, username, ip, device, campaign string, n int) (code int, msg string, draws []*luckyDraw) {
+	// 抽奖记录一起写入，没有进行抽奖的时候只记录一次
+	defer func() {
+		if code == 0 {
+			api.drawLogBatch(uid, ip, device, draws)
+		} else {
+			api.drawLog(uid, ip, device, code, msg, nil, 0)
+		}
+	}()
+
+	// 2 用户抽奖分布式锁定
+	ok := utils.LockLucky(api.Cache, uid)
+	if ok {
+		defer utils.UnlockLucky(api.Cache, uid)
+	} else {
+		return 102, "正在抽奖，请稍后重试", nil
+	}
+
+	// 3 需要积分的活动一次扣除n次的积分，免费的活动一次验证n次的参与次数
+	// 免费的次数不够n次的时候，全部使用额外获得的抽奖机会，每次抽奖之前才扣除
+	// 先验证再增加免费的次数，需要积分或者使用抽奖机会的时候不占用免费的次数
+	// 中奖之后受到限制没有进行的抽奖，退回积分和免费的次数
+	userDayNum := utils.GetUserLuckyNum(api.Cache, uid) + int64(n)
+	useChance, useUserDay := false, false
+	if cost := conf.DrawPointsCost[campaign]; cost > 0 {
+		ref := fmt.Sprintf("draw-batch-%d-%d", uid, time.Now().UnixNano())
+		if code, msg = api.spendPoints(uid, cost*n, ref, campaign); code != 0 {
+			return code, msg, nil
+		}
+		defer func() { api.refundBatchPoints(uid, cost, n, ref, campaign, code, draws) }()
+	} else if userDayNum > conf.UserPrizeMax || !api.checkUserDay(uid, userDayNum, n) {
+		if api.ServiceChance.LeftNum(uid) < n {
+			return 103, "今日的抽奖次数已用完，明天再来吧", nil
+		}
+		useChance = true
+	} else {
+		useUserDay = true
+		if utils.GetUserLuckyNum(api.Cache, uid) < userDayNum {
+			// 验证的时候没有按照数据库中的次数重新设置缓存，才需要增加
+			utils.IncrUserLuckyNumBy(api.Cache, uid, n)
+		}
+	}
+
+	// 4 验证IP今日的参与次数
+	ipDayNum := utils.IncrIpLuckyNumBy(api.Cache, ip, n)
+	if ipDayNum > conf.IpLimitMax {
+		return 104, "相同IP参与次数太多，明天再来参与吧", nil
+	}
+	// 记录用户、IP、设备的关联关系
+	utils.RecordLink(api.Cache, uid, ip, device)
+
+	// 风控规则和黑名单
+	code, msg, limitBlack, ruleId := api.checkLimit(uid, ip, device, userDayNum, ipDayNum)
+	if code != 0 {
+		return code, msg, nil
+	}
+
+	// 7 - 10 抽奖n次，最后一次还没有获得保底类型的奖品时，必定获得该类型的奖品
+	lossNum := utils.GetLossNum(api.Cache, uid)
+	guaranteed := conf.LuckyBatchGuaranteeGtype < 0 || n < conf.LuckyBatchGuaranteeNum
+	draws = make([]*luckyDraw, 0, n)
+	limitCode, limitMsg, skipNum := 0, "", 0
+	for i := 0; i < n; i++ {
+		// 中奖之后进入冷却期等，后面的抽奖受到限制
+		if limitCode != 0 {
+			draws = append(draws, &luckyDraw{code: limitCode, msg: limitMsg})
+			skipNum++
+			continue
+		}
+		if useChance && !api.ServiceChance.Consume(uid) {
+			// 抽奖机会刚好过期，只进行已经扣除的抽奖
+			break
+		}
+		guaranteeGtype := -1
+		if i == n-1 && !guaranteed {
+			guaranteeGtype = conf.LuckyBatchGuaranteeGtype
+		}
+		draw := api.drawPrize(uid, username, ip, device, limitBlack, ruleId, lossNum, guaranteeGtype)
+		draws = append(draws, draw)
+		lossNum = draw.lossNum(lossNum)
+		if draw.win() {
+			if draw.gift.Gtype == conf.LuckyBatchGuaranteeGtype {
+				guaranteed = true
+			}
+			limitCode, limitMsg, limitBlack, ruleId = api.checkLimit(uid, ip, device, userDayNum, ipDayNum)
+		}
+	}
+	if len(draws) == 0 {
+		return 103, "今日的抽奖次数已用完，明天再来吧", nil
+	}
+	if useUserDay {
+		api.refundUserDay(uid, skipNum)
+	}
+
+	// 11 中奖记录一次写入
+	results := make([]models.LtResult, 0, n)
+	for _, draw := range draws {
+		if draw.code == 0 {
+			results = append(results, *draw.result)
+		}
+	}
+	if len(results) > 0 {
+		if _, err := api.ServiceResult.CreateBatch(results); err != nil {
+			log.Println("index_lucky_batch.luckBatchDo ServiceResult.CreateBatch uid=", uid,
+				", error=", err)
+			for _, draw := range draws {
+				if draw.code == 0 {
+					draw.code, draw.msg, draw.gift = 209, "很遗憾，没有中奖，请下次再试", nil
+				}
+			}
+		} else {
+			i := 0
+			for _, draw := range draws {
+				if draw.code == 0 {
+					draw.result.Id = results[i].Id
+					i++
+					api.afterPrize(uid, draw)
+				}
+			}
+		}
+	}
+	// 按照最终的结果计算连续没有中奖的次数，写入失败的中奖算没有中奖
+	lossNum = utils.GetLossNum(api.Cache, uid)
+	for _, draw := range draws {
+		lossNum = draw.lossNum(lossNum)
+	}
+	utils.SetLossNum(api.Cache, uid, lossNum)
+	return 0, "", draws
+}
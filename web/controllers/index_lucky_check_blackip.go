@@ -2,12 +2,11 @@ package controllers
 
 import (
 	"github.com/iralance/go-lottery/models"
-	"github.com/iralance/go-lottery/services"
 	"time"
 )
 
 func (api *LuckyApi) checkBlackip(ip string) (bool, *models.LtBlackip) {
-	info := services.NewBlackipService().GetByIp(ip)
+	info := api.ServiceBlackip.GetByIp(ip)
 	if info == nil || info.Ip == "" {
 		return true, nil
 	}
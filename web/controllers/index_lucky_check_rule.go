@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+	"github.com/iralance/go-lottery/services"
+	utils "github.com/iralance/go-lottery/uitls"
+	"log"
+)
+
+// 验证风控规则，返回命中规则中最严格的处理方式，以及对应的规则
+func (api *LuckyApi) checkRule(uid int, ip, device string, userDayNum, ipDayNum int64) (int, *models.LtRule) {
+	action := 0
+	var hitRule *models.LtRule
+	var ipUsers int64 = -1
+	linkScore, linkScoreWithoutIp := -1, -1
+	rules := api.ServiceRule.GetAllUse()
+	for i := range rules {
+		rule := &rules[i]
+		hit := false
+		switch rule.Rtype {
+		case conf.RuleTypeWinCooldown:
+			hit = utils.CheckRuleCooldown(api.Cache, rule.Gtype, rule.Num, uid, ip)
+		case conf.RuleTypeIpUsers:
+			// 多条规则只需要统计一次
+			if ipUsers < 0 {
+				ipUsers = utils.IncrIpDayUsers(api.Cache, ip, uid)
+			}
+			hit = ipUsers > int64(rule.Num)
+		case conf.RuleTypeIpLucky:
+			hit = ipDayNum > int64(rule.Num)
+		case conf.RuleTypeUserLucky:
+			hit = userDayNum > int64(rule.Num)
+		case conf.RuleTypeLinkScore:
+			if rule.Action < conf.RuleActionDeny {
+				if linkScore < 0 {
+					linkScore = utils.LinkScore(api.Cache, uid, ip, device)
+				}
+				hit = linkScore > rule.Num
+				break
+			}
+			// 拒绝抽奖的时候不计算同一个IP上的其他用户，避免误伤NAT后面的用户
+			if linkScoreWithoutIp < 0 {
+				linkScoreWithoutIp = utils.LinkScoreWithoutIp(api.Cache, uid, ip, device)
+			}
+			hit = linkScoreWithoutIp > rule.Num
+		}
+		if hit && rule.Action > action {
+			action = rule.Action
+			hitRule = rule
+		}
+	}
+	if hitRule != nil {
+		log.Println("index_lucky_check_rule hit rule=", hitRule.Id, hitRule.Title,
+			", uid=", uid, ", ip=", ip, ", action=", action)
+	}
+	return action, hitRule
+}
+
+// 中奖之后，按照规则设置用户和IP的冷却期
+// 降级的冷却期同时写入数据库的黑名单，redis数据丢失之后仍然有效
+func (api *LuckyApi) ruleCooldown(uid int, username, ip string, gtype int) {
+	rules := api.ServiceRule.GetAllUse()
+	for _, rule := range rules {
+		if rule.Rtype != conf.RuleTypeWinCooldown || rule.Gtype != gtype {
+			continue
+		}
+		utils.SetRuleCooldown(api.Cache, rule.Gtype, rule.Num, uid, ip)
+		if rule.Action != conf.RuleActionDowngrade || rule.Num <= 0 {
+			continue
+		}
+		blacktime := comm.NowUnix() + rule.Num*86400
+		// 已经在黑名单中的时候，不缩短黑名单的时间
+		if user := api.ServiceUser.Get(uid); user.Blacktime < blacktime {
+			setBlackUser(api.ServiceUser, uid, username, ip, blacktime)
+		}
+		if info := api.ServiceBlackip.GetByIp(ip); info.Blacktime < blacktime {
+			setBlackIp(api.ServiceBlackip, ip, blacktime)
+		}
+	}
+}
+
+// 设置用户的黑名单时间，用户不在数据表中的时候先新增，再通过更新刷新缓存
+func setBlackUser(serviceUser services.UserService, uid int, username, ip string, blacktime int) {
+	now := comm.NowUnix()
+	if user := serviceUser.Get(uid); user.SysCreated == 0 {
+		serviceUser.Create(&models.LtUser{Id: uid, Username: username, Blacktime: blacktime,
+			SysCreated: now, SysIp: ip})
+	}
+	serviceUser.Update(&models.LtUser{Id: uid, Blacktime: blacktime, SysUpdated: now},
+		[]string{"blacktime"})
+}
+
+// 设置IP的黑名单时间，IP不在黑名单中的时候新增
+func setBlackIp(serviceBlackip services.BlackipService, ip string, blacktime int) {
+	now := comm.NowUnix()
+	datalist := serviceBlackip.Search(ip)
+	if len(datalist) > 0 {
+		serviceBlackip.Update(&models.LtBlackip{Id: datalist[0].Id, Ip: datalist[0].Ip, Blacktime: blacktime, SysUpdated: now},
+			[]string{"blacktime"})
+	} else {
+		serviceBlackip.Create(&models.LtBlackip{Ip: ip, Blacktime: blacktime, SysCreated: now})
+	}
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+	utils "github.com/iralance/go-lottery/uitls"
+)
+
+func TestCheckRule(t *testing.T) {
+	tests := []struct {
+		name                 string
+		rules                []models.LtRule
+		setup                func(api *LuckyApi)
+		userDayNum, ipDayNum int64
+		want                 int
+	}{
+		{
+			name: "没有规则",
+			want: 0,
+		},
+		{
+			name:       "用户抽奖次数没有超过",
+			rules:      []models.LtRule{{Rtype: conf.RuleTypeUserLucky, Num: 5, Action: conf.RuleActionDeny}},
+			userDayNum: 5,
+			want:       0,
+		},
+		{
+			name:       "用户抽奖次数超过",
+			rules:      []models.LtRule{{Rtype: conf.RuleTypeUserLucky, Num: 5, Action: conf.RuleActionDeny}},
+			userDayNum: 6,
+			want:       conf.RuleActionDeny,
+		},
+		{
+			name:     "IP抽奖次数超过",
+			rules:    []models.LtRule{{Rtype: conf.RuleTypeIpLucky, Num: 10, Action: conf.RuleActionDowngrade}},
+			ipDayNum: 11,
+			want:     conf.RuleActionDowngrade,
+		},
+		{
+			name:  "IP今天的用户数超过",
+			rules: []models.LtRule{{Rtype: conf.RuleTypeIpUsers, Num: 1, Action: conf.RuleActionCaptcha}},
+			setup: func(api *LuckyApi) {
+				utils.IncrIpDayUsers(api.Cache, "10.0.0.1", 2)
+			},
+			want: conf.RuleActionCaptcha,
+		},
+		{
+			name:  "冷却期中",
+			rules: []models.LtRule{{Rtype: conf.RuleTypeWinCooldown, Gtype: conf.GtypeGiftLarge, Num: 30, Action: conf.RuleActionDowngrade}},
+			setup: func(api *LuckyApi) {
+				utils.SetRuleCooldown(api.Cache, conf.GtypeGiftLarge, 30, 1, "10.0.0.1")
+			},
+			want: conf.RuleActionDowngrade,
+		},
+		{
+			name:  "其他类型奖品的冷却期",
+			rules: []models.LtRule{{Rtype: conf.RuleTypeWinCooldown, Gtype: conf.GtypeGiftLarge, Num: 30, Action: conf.RuleActionDowngrade}},
+			setup: func(api *LuckyApi) {
+				utils.SetRuleCooldown(api.Cache, conf.GtypeGiftSmall, 30, 1, "10.0.0.1")
+			},
+			want: 0,
+		},
+		{
+			name: "多条规则命中，使用最严格的处理方式",
+			rules: []models.LtRule{
+				{Rtype: conf.RuleTypeUserLucky, Num: 1, Action: conf.RuleActionFlag},
+				{Rtype: conf.RuleTypeIpLucky, Num: 1, Action: conf.RuleActionCaptcha},
+				{Rtype: conf.RuleTypeUserLucky, Num: 100, Action: conf.RuleActionDeny},
+			},
+			userDayNum: 2,
+			ipDayNum:   2,
+			want:       conf.RuleActionCaptcha,
+		},
+		{
+			name:       "删除的规则不生效",
+			rules:      []models.LtRule{{Rtype: conf.RuleTypeUserLucky, Num: 1, Action: conf.RuleActionDeny, SysStatus: 1}},
+			userDayNum: 2,
+			want:       0,
+		},
+	}
+	for _, tt := range tests {
+		api := newMemLuckyApi()
+		for i := range tt.rules {
+			if _, err := api.ServiceRule.Create(&tt.rules[i]); err != nil {
+				t.Fatal("ServiceRule.Create error", err)
+			}
+		}
+		if tt.setup != nil {
+			tt.setup(api)
+		}
+		action, rule := api.checkRule(1, "10.0.0.1", "d1", tt.userDayNum, tt.ipDayNum)
+		if action != tt.want {
+			t.Errorf("%s: checkRule action = %d, want %d", tt.name, action, tt.want)
+		}
+		if (action == 0) != (rule == nil) || (rule != nil && rule.Action != action) {
+			t.Errorf("%s: checkRule action = %d, rule = %v", tt.name, action, rule)
+		}
+	}
+}
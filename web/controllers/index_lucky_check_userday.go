@@ -4,27 +4,27 @@ import (
 	"fmt"
 	"github.com/iralance/go-lottery/conf"
 	"github.com/iralance/go-lottery/models"
-	"github.com/iralance/go-lottery/services"
 	utils "github.com/iralance/go-lottery/uitls"
 	"log"
 	"strconv"
 	"time"
 )
 
-func (api *LuckyApi) checkUserDay(uid int, num int64) bool {
-	userdayService := services.NewUserdayService()
+// 验证并且增加用户今天的参与次数，一次抽奖多次的时候n大于1
+func (api *LuckyApi) checkUserDay(uid int, num int64, n int) bool {
+	userdayService := api.ServiceUserday
 	userdayInfo := userdayService.GetUserToday(uid)
 	if userdayInfo != nil && userdayInfo.Uid == uid {
 		//今天存在抽奖记录
-		if userdayInfo.Num >= conf.UserPrizeMax {
+		if userdayInfo.Num+n > conf.UserPrizeMax {
 			if int(num) < userdayInfo.Num {
-				utils.InitUserLuckyNum(uid, int64(userdayInfo.Num))
+				utils.InitUserLuckyNum(api.Cache, uid, int64(userdayInfo.Num))
 			}
 			return false
 		} else {
-			userdayInfo.Num++
+			userdayInfo.Num += n
 			if int(num) < userdayInfo.Num {
-				utils.InitUserLuckyNum(uid, int64(userdayInfo.Num))
+				utils.InitUserLuckyNum(api.Cache, uid, int64(userdayInfo.Num))
 			}
 			err103 := userdayService.Update(userdayInfo, nil)
 			if err103 != nil {
@@ -40,7 +40,7 @@ func (api *LuckyApi) checkUserDay(uid int, num int64) bool {
 		userdayInfo = &models.LtUserday{
 			Uid:        uid,
 			Day:        day,
-			Num:        1,
+			Num:        n,
 			SysCreated: int(time.Now().Unix()),
 		}
 		_, err103 := userdayService.Create(userdayInfo)
@@ -48,7 +48,27 @@ func (api *LuckyApi) checkUserDay(uid int, num int64) bool {
 			log.Println("index_lucky_check_userday ServiceUserDay.Create "+
 				"err103=", err103)
 		}
-		utils.InitUserLuckyNum(uid, 1)
+		utils.InitUserLuckyNum(api.Cache, uid, int64(n))
 	}
 	return true
 }
+
+// 一次抽奖多次的时候，没有进行的抽奖退回今天的参与次数
+func (api *LuckyApi) refundUserDay(uid, n int) {
+	if n < 1 {
+		return
+	}
+	utils.IncrUserLuckyNumBy(api.Cache, uid, -n)
+	userdayInfo := api.ServiceUserday.GetUserToday(uid)
+	if userdayInfo == nil || userdayInfo.Uid != uid {
+		return
+	}
+	userdayInfo.Num -= n
+	if userdayInfo.Num < 0 {
+		userdayInfo.Num = 0
+	}
+	if err := api.ServiceUserday.Update(userdayInfo, []string{"num"}); err != nil {
+		log.Println("index_lucky_check_userday.refundUserDay ServiceUserday.Update uid=", uid,
+			", error=", err)
+	}
+}
@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+	utils "github.com/iralance/go-lottery/uitls"
+)
+
+// 没有中奖的时候发放安慰奖，中奖编码是发放安慰奖的概率
+// 超过每天的次数限制，或者没有库存的时候返回nil
+func (api *LuckyApi) consolationPrize(uid int, limitBlack bool) *models.ObjGiftPrize {
+	if conf.UserDayConsolationMax > 0 &&
+		utils.GetConsolationNum(api.Cache, uid) >= conf.UserDayConsolationMax {
+		return nil
+	}
+	prizeCode := comm.Random(10000)
+	giftList := api.ServiceGift.GetAllUse(true)
+	for _, gift := range giftList {
+		if gift.Fallback == 0 ||
+			gift.PrizeCodeA > prizeCode || gift.PrizeCodeB < prizeCode {
+			continue
+		}
+		if limitBlack && gift.Gtype >= conf.GtypeGiftSmall {
+			continue
+		}
+		if gift.PrizeNum < 0 || (gift.PrizeNum > 0 && gift.LeftNum <= 0) {
+			continue
+		}
+		return &gift
+	}
+	return nil
+}
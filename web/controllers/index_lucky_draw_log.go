@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/models"
+	"log"
+)
+
+// 记录一次抽奖的结果，没有中奖的也需要记录
+func (api *LuckyApi) drawLog(uid int, ip, device string, code int, msg string,
+	gift *models.ObjGiftPrize, resultId int) {
+	data := newDrawLog(uid, ip, device, code, msg, gift, resultId)
+	if _, err := api.ServiceDrawLog.Create(data); err != nil {
+		log.Println("index_lucky_draw_log.drawLog ServiceDrawLog.Create ", data, ", error=", err)
+	}
+}
+
+// 一次抽奖多次，抽奖记录一起写入
+func (api *LuckyApi) drawLogBatch(uid int, ip, device string, draws []*luckyDraw) {
+	datalist := make([]models.LtDrawLog, 0, len(draws))
+	for _, draw := range draws {
+		resultId := 0
+		if draw.code == 0 {
+			resultId = draw.result.Id
+		}
+		datalist = append(datalist, *newDrawLog(uid, ip, device, draw.code, draw.msg, draw.gift, resultId))
+	}
+	if _, err := api.ServiceDrawLog.CreateBatch(datalist); err != nil {
+		log.Println("index_lucky_draw_log.drawLogBatch ServiceDrawLog.CreateBatch uid=", uid, ", error=", err)
+	}
+}
+
+func newDrawLog(uid int, ip, device string, code int, msg string,
+	gift *models.ObjGiftPrize, resultId int) *models.LtDrawLog {
+	data := &models.LtDrawLog{
+		Uid:        uid,
+		Code:       code,
+		Msg:        msg,
+		ResultId:   resultId,
+		SysCreated: comm.NowUnix(),
+		SysIp:      ip,
+		Device:     device,
+	}
+	if gift != nil {
+		data.GiftId = gift.Id
+		data.GiftName = gift.Title
+	}
+	return data
+}
@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+	"log"
+)
+
+// 实物奖品需要发货，用户已经填写过收货地址的直接等待发货
+func (api *LuckyApi) createFulfill(result *models.LtResult) {
+	if result.GiftType != conf.GtypeGiftSmall && result.GiftType != conf.GtypeGiftLarge {
+		return
+	}
+	data := &models.LtFulfill{
+		ResultId:   result.Id,
+		Uid:        result.Uid,
+		GiftId:     result.GiftId,
+		GiftName:   result.GiftName,
+		Status:     conf.FulfillPendingAddress,
+		SysCreated: result.SysCreated,
+		SysUpdated: result.SysCreated,
+	}
+	user := api.ServiceUser.Get(result.Uid)
+	if user != nil && user.Address != "" {
+		data.Status = conf.FulfillReady
+		data.Realname = user.Realname
+		data.Mobile = user.Mobile
+		data.Address = user.Address
+	}
+	if _, err := api.ServiceFulfill.Create(data); err != nil {
+		log.Println("index_lucky_fulfill.createFulfill ServiceFulfill.Create ", data, ", error=", err)
+	}
+}
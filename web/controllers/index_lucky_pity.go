@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/models"
+	utils "github.com/iralance/go-lottery/uitls"
+)
+
+// 保底规则，用户连续没有中奖lossNum次之后，提高概率或者必定获得某类奖品
+// 返回保底的奖品和规则ID，没有满足条件的规则时返回nil
+func (api *LuckyApi) pityPrize(lossNum int, limitBlack bool) (*models.ObjGiftPrize, int) {
+	for _, pity := range api.ServicePity.GetAllUse() {
+		if lossNum < pity.Num {
+			continue
+		}
+		if pity.Action == conf.PityActionBoost &&
+			comm.Random(10000) >= utils.PityBoostRate(lossNum, pity.Num, pity.Boost) {
+			continue
+		}
+		// 黑名单用户只能获得小奖
+		if limitBlack && pity.Gtype >= conf.GtypeGiftSmall {
+			continue
+		}
+		if gift := api.pityGift(pity.Gtype); gift != nil {
+			return gift, pity.Id
+		}
+	}
+	return nil, 0
+}
+
+// 某类奖品中还有库存的第一个奖品
+func (api *LuckyApi) pityGift(gtype int) *models.ObjGiftPrize {
+	giftList := api.ServiceGift.GetAllUse(true)
+	for _, gift := range giftList {
+		if gift.Gtype != gtype || gift.Fallback != 0 || gift.PrizeNum < 0 ||
+			(gift.PrizeNum > 0 && gift.LeftNum <= 0) {
+			continue
+		}
+		return &gift
+	}
+	return nil
+}
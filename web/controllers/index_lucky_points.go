@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+)
+
+// 抽奖之前扣除积分，返回0表示扣除成功
+func (api *LuckyApi) spendPoints(uid, cost int, ref, campaign string) (int, string) {
+	ok, err := api.ServicePoint.Spend(uid, cost, ref, fmt.Sprintf("抽奖消耗，活动%s", campaign))
+	if err != nil {
+		log.Println("index_lucky_points.spendPoints ServicePoint.Spend uid=", uid, ", ref=", ref, ", error=", err)
+		return 110, "扣除积分失败，请重试"
+	}
+	if !ok {
+		return 109, "积分不足，不能参与抽奖"
+	}
+	return 0, ""
+}
+
+// 扣除积分之后抽奖没有进行，或者因为系统原因失败的时候，退回积分
+func (api *LuckyApi) refundPoints(uid, cost int, ref, campaign string, code int) {
+	if !isRefundCode(code) {
+		return
+	}
+	ok, err := api.ServicePoint.Refund(uid, cost, ref, fmt.Sprintf("抽奖失败退回，活动%s，返回码%d", campaign, code))
+	if err != nil || !ok {
+		log.Println("index_lucky_points.refundPoints ServicePoint.Refund uid=", uid, ", ref=", ref,
+			", ok=", ok, ", error=", err)
+	}
+}
+
+// 一次抽奖多次，只扣除和退回一次积分，退回没有进行或者因为系统原因失败的次数
+func (api *LuckyApi) refundBatchPoints(uid, cost, n int, ref, campaign string, code int, draws []*luckyDraw) {
+	num := 0
+	if isRefundCode(code) {
+		num = n
+	} else if code == 0 {
+		for _, draw := range draws {
+			if isRefundCode(draw.code) {
+				num++
+			}
+		}
+	}
+	if num == 0 {
+		return
+	}
+	ok, err := api.ServicePoint.Refund(uid, cost*num, ref, fmt.Sprintf("抽奖失败退回，活动%s，%d次", campaign, num))
+	if err != nil || !ok {
+		log.Println("index_lucky_points.refundBatchPoints ServicePoint.Refund uid=", uid, ", ref=", ref,
+			", ok=", ok, ", error=", err)
+	}
+}
+
+func isRefundCode(code int) bool {
+	switch code {
+	case 104, 105, 106, 206, 207, 208, 209:
+		return true
+	}
+	return false
+}
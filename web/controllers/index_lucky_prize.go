@@ -3,14 +3,15 @@ package controllers
 import (
 	"github.com/iralance/go-lottery/conf"
 	"github.com/iralance/go-lottery/models"
-	"github.com/iralance/go-lottery/services"
 )
 
 func (api *LuckyApi) prize(prizeCode int, limitBlack bool) *models.ObjGiftPrize {
 	var prizeGift *models.ObjGiftPrize
-	giftList := services.NewGiftService().GetAllUse(true)
+	giftList := api.ServiceGift.GetAllUse(true)
 	for _, gift := range giftList {
-		if gift.PrizeCodeA <= prizeCode &&
+		// 安慰奖只在没有中奖的时候发放
+		if gift.Fallback == 0 &&
+			gift.PrizeCodeA <= prizeCode &&
 			gift.PrizeCodeB >= prizeCode {
 			// 中奖编码区间满足条件，说明可以中奖
 			if !limitBlack || gift.Gtype < conf.GtypeGiftSmall {
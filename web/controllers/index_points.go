@@ -0,0 +1,28 @@
+package controllers
+
+import (
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+)
+
+// 用户的积分余额和积分流水 GET /mypoints?page=1
+func (c *IndexController) GetMypoints() map[string]interface{} {
+	rs := make(map[string]interface{})
+	rs["code"] = 0
+	rs["msg"] = ""
+	loginuser := comm.GetLoginUser(c.Ctx.Request())
+	if loginuser == nil || loginuser.Uid < 1 {
+		rs["code"] = 101
+		rs["msg"] = "请先登录"
+		return rs
+	}
+	page := c.Ctx.URLParamIntDefault("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	rs["balance"] = c.ServicePoint.Get(loginuser.Uid).Balance
+	rs["log_list"] = c.ServicePoint.SearchLog(loginuser.Uid, page, conf.UserListPageSize)
+	rs["page"] = page
+	rs["total"] = c.ServicePoint.CountLog(loginuser.Uid)
+	return rs
+}
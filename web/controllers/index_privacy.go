@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/models"
+	utils "github.com/iralance/go-lottery/uitls"
+)
+
+// 隐私设置 GET /privacy
+func (c *IndexController) GetPrivacy() map[string]interface{} {
+	rs := make(map[string]interface{})
+	rs["code"] = 0
+	rs["msg"] = ""
+	loginuser := comm.GetLoginUser(c.Ctx.Request())
+	if loginuser == nil || loginuser.Uid < 1 {
+		rs["code"] = 101
+		rs["msg"] = "请先登录"
+		return rs
+	}
+	user := c.ServiceUser.Get(loginuser.Uid)
+	rs["hidewin"] = user.Hidewin
+	return rs
+}
+
+// 设置是否在公开的中奖榜单中展示 POST /privacy
+// hidewin=1 不展示，hidewin=0 展示
+func (c *IndexController) PostPrivacy() map[string]interface{} {
+	rs := make(map[string]interface{})
+	rs["code"] = 0
+	rs["msg"] = ""
+	loginuser := comm.GetLoginUser(c.Ctx.Request())
+	if loginuser == nil || loginuser.Uid < 1 {
+		rs["code"] = 101
+		rs["msg"] = "请先登录"
+		return rs
+	}
+	hidewin, err := c.Ctx.PostValueInt("hidewin")
+	if err != nil || (hidewin != 0 && hidewin != 1) {
+		rs["code"] = 304
+		rs["msg"] = "参数错误"
+		return rs
+	}
+	now := comm.NowUnix()
+	// 用户不在数据表中的时候先新增，再通过更新刷新缓存
+	user := c.ServiceUser.Get(loginuser.Uid)
+	if user.SysCreated == 0 {
+		c.ServiceUser.Create(&models.LtUser{Id: loginuser.Uid, Username: loginuser.Username,
+			SysCreated: now, SysIp: comm.ClientIP(c.Ctx.Request())})
+	} else if user.Hidewin == hidewin {
+		rs["hidewin"] = hidewin
+		return rs
+	}
+	c.ServiceUser.Update(&models.LtUser{Id: loginuser.Uid, Hidewin: hidewin, SysUpdated: now},
+		[]string{"hidewin", "sys_updated"})
+	// 榜单中已经展示的记录也需要去掉，重新生成
+	utils.ClearRecentWinners(c.Cache)
+	rs["hidewin"] = hidewin
+	return rs
+}
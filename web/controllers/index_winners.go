@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	utils "github.com/iralance/go-lottery/uitls"
+	"github.com/iralance/go-lottery/web/feed"
+	"github.com/kataras/iris/v12/websocket"
+	"log"
+	"net/http"
+	"time"
+)
+
+const winnerPingPeriod = 30 * time.Second
+
+// 中奖动态的实时推送，先推送最新的中奖记录，之后推送新的中奖
+// ws://localhost:8080/ws/winners
+func (c *IndexController) GetWsWinners() {
+	conn := websocket.Upgrade(c.Ctx, websocket.DefaultIDGenerator, c.Ws)
+	if conn == nil {
+		log.Println("index_winners.GetWsWinners Upgrade failed")
+		return
+	}
+	defer conn.Close()
+	ch := c.Hub.Join()
+	defer c.Hub.Leave(ch)
+
+	closed := feed.WsClosed(conn)
+	for _, data := range c.recentWinners() {
+		if !feed.WsWrite(conn, data) {
+			return
+		}
+	}
+	for {
+		select {
+		case data := <-ch:
+			if !feed.WsWrite(conn, data) {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// 不支持websocket的时候使用SSE推送
+// http://localhost:8080/sse/winners
+func (c *IndexController) GetSseWinners() {
+	w := c.Ctx.ResponseWriter()
+	if _, ok := w.Flusher(); !ok {
+		c.Ctx.StatusCode(http.StatusNotImplemented)
+		return
+	}
+	c.Ctx.ContentType("text/event-stream")
+	c.Ctx.Header("Cache-Control", "no-cache")
+	c.Ctx.Header("X-Accel-Buffering", "no")
+	ch := c.Hub.Join()
+	defer c.Hub.Leave(ch)
+
+	write := func(data []byte) bool {
+		_, err := fmt.Fprintf(w, "event: winner\ndata: %s\n\n", data)
+		w.Flush()
+		return err == nil
+	}
+	for _, data := range c.recentWinners() {
+		if !write(data) {
+			return
+		}
+	}
+	w.Flush()
+	ticker := time.NewTicker(winnerPingPeriod)
+	defer ticker.Stop()
+	done := c.Ctx.Request().Context().Done()
+	for {
+		select {
+		case data := <-ch:
+			if !write(data) {
+				return
+			}
+		case <-ticker.C:
+			// 注释行，保持连接
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			w.Flush()
+		case <-done:
+			return
+		}
+	}
+}
+
+// 最新的中奖动态，按照时间正序
+func (c *IndexController) recentWinners() [][]byte {
+	list := utils.GetRecentWinners(c.Cache)
+	datalist := make([][]byte, 0, len(list))
+	for i := len(list) - 1; i >= 0; i-- {
+		data, _ := json.Marshal(list[i])
+		datalist = append(datalist, data)
+	}
+	return datalist
+}
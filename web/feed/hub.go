@@ -0,0 +1,75 @@
+package feed
+
+import (
+	"github.com/iralance/go-lottery/datasource"
+	"log"
+	"sync"
+	"time"
+)
+
+// 中奖动态的推送中心
+// 订阅redis的频道，把收到的消息转发给这个应用上所有连接的用户
+type Hub struct {
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		clients: make(map[chan []byte]struct{}),
+	}
+}
+
+// 新的连接加入，返回接收消息的通道
+func (h *Hub) Join() chan []byte {
+	ch := make(chan []byte, 64)
+	h.mu.Lock()
+	h.clients[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+// 连接断开之后离开
+func (h *Hub) Leave(ch chan []byte) {
+	h.mu.Lock()
+	delete(h.clients, ch)
+	h.mu.Unlock()
+}
+
+// 当前连接的数量
+func (h *Hub) Count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// 转发给所有的连接，接收太慢的连接丢弃这条消息
+func (h *Hub) Broadcast(data []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.clients {
+		select {
+		case ch <- data:
+		default:
+		}
+	}
+}
+
+// 订阅频道，连接断开之后等待一秒重新订阅，直到stop被关闭
+func (h *Hub) Listen(cache datasource.Cache, channel string, stop <-chan struct{}) {
+	sub, ok := cache.(datasource.Subscriber)
+	if !ok {
+		log.Println("feed.Hub.Listen cache does not support subscribe")
+		return
+	}
+	for {
+		err := sub.Subscribe(channel, stop, h.Broadcast)
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		log.Println("feed.Hub.Listen Subscribe error=", err)
+		time.Sleep(time.Second)
+	}
+}
@@ -0,0 +1,53 @@
+package feed
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/kataras/iris/v12/websocket"
+	"github.com/kataras/neffos"
+)
+
+const wsWriteWait = 10 * time.Second
+
+// 连接上保存断开通知的key
+const wsClosedKey = "feed.closed"
+
+// 中奖动态是公开的数据，不需要检查来源
+func wsUpgrader(w http.ResponseWriter, r *http.Request) (neffos.Socket, error) {
+	r.Header.Del("Origin")
+	return websocket.DefaultGorillaUpgrader(w, r)
+}
+
+// 中奖动态的websocket服务，只推送不接收
+// 只注册原生消息的事件，浏览器直接使用WebSocket连接，不需要neffos的客户端
+func NewWsServer() *neffos.Server {
+	ws := websocket.New(wsUpgrader, websocket.WithTimeout{
+		WriteTimeout: wsWriteWait,
+		Events: websocket.Events{
+			websocket.OnNativeMessage: func(*websocket.NSConn, websocket.Message) error { return nil },
+		},
+	})
+	// 连接准备好之前保存通知的通道，断开的时候关闭
+	ws.OnConnect = func(conn *websocket.Conn) error {
+		conn.Set(wsClosedKey, make(chan struct{}))
+		return nil
+	}
+	ws.OnDisconnect = func(conn *websocket.Conn) {
+		if ch, ok := conn.Get(wsClosedKey).(chan struct{}); ok {
+			close(ch)
+		}
+	}
+	return ws
+}
+
+// 连接断开的时候关闭的通道
+func WsClosed(conn *websocket.Conn) <-chan struct{} {
+	ch, _ := conn.Get(wsClosedKey).(chan struct{})
+	return ch
+}
+
+// 发送原生的文本消息
+func WsWrite(conn *websocket.Conn, data []byte) bool {
+	return conn.Write(websocket.Message{Body: data, IsNative: true})
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"fmt"
+	"github.com/iralance/go-lottery/bootstrap"
+	"github.com/iralance/go-lottery/comm"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/datasource"
+	"github.com/kataras/iris/v12"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// New 按照配置的接口和维度限制访问频率
+// 使用redis中两个相邻固定窗口的计数，按照时间比例估算滑动窗口内的请求数
+// 超过限制的时候返回429，并且通过Retry-After告诉客户端多久之后重试
+func New(cache datasource.Cache, limits []conf.RateLimit) iris.Handler {
+	return func(ctx iris.Context) {
+		path := ctx.Path()
+		for _, limit := range limits {
+			if limit.Path != path || limit.Limit <= 0 || limit.Window <= 0 {
+				continue
+			}
+			key := limitKey(ctx, limit.Key)
+			if key == "" {
+				continue
+			}
+			ok, retryAfter := allow(cache, limit, key)
+			if !ok {
+				ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+				ctx.StatusCode(http.StatusTooManyRequests)
+				ctx.JSON(iris.Map{
+					"code": http.StatusTooManyRequests,
+					"msg":  "请求太频繁，请稍后再试",
+				})
+				ctx.StopExecution()
+				return
+			}
+		}
+		ctx.Next()
+	}
+}
+
+// Configure 注册到应用中，配置来自bootstrap.Bootstrapper
+func Configure(b *bootstrap.Bootstrapper) {
+	if len(b.RateLimits) == 0 {
+		return
+	}
+	b.UseGlobal(New(b.Cache, b.RateLimits))
+}
+
+// 请求在这个维度上的标识，没有标识的时候不做限制
+func limitKey(ctx iris.Context, keyType string) string {
+	switch keyType {
+	case "ip":
+		return comm.IpCounterKey(comm.ClientIP(ctx.Request()))
+	case "uid":
+		loginuser := comm.GetLoginUser(ctx.Request())
+		if loginuser == nil || loginuser.Uid < 1 {
+			return ""
+		}
+		return strconv.Itoa(loginuser.Uid)
+	case "device":
+		return comm.ClientDevice(ctx.Request())
+	}
+	return ""
+}
+
+// 返回是否允许访问，不允许的时候同时返回需要等待的秒数
+func allow(cache datasource.Cache, limit conf.RateLimit, key string) (bool, int) {
+	windowMs := int64(limit.Window) * 1000
+	nowMs := time.Now().UnixNano() / int64(time.Millisecond)
+	index := nowMs / windowMs
+	elapsed := nowMs - index*windowMs
+	prefix := fmt.Sprintf("ratelimit_%s_%s_%s_", limit.Path, limit.Key, key)
+	currKey := prefix + strconv.FormatInt(index, 10)
+	prevKey := prefix + strconv.FormatInt(index-1, 10)
+
+	rs, err := cache.Do("INCR", currKey)
+	if err != nil {
+		// redis异常的时候不影响正常访问
+		log.Println("ratelimit redis INCR key=", currKey, ", error=", err)
+		return true, 0
+	}
+	curr := comm.GetInt64(rs, 0)
+	if curr == 1 {
+		cache.Do("EXPIRE", currKey, limit.Window*2)
+	}
+	rs, err = cache.Do("GET", prevKey)
+	if err != nil {
+		log.Println("ratelimit redis GET key=", prevKey, ", error=", err)
+	}
+	prev := comm.GetInt64(comm.GetString(rs, "0"), 0)
+
+	// 上一个窗口的请求，按照还在滑动窗口中的时间比例计算
+	weight := float64(windowMs-elapsed) / float64(windowMs)
+	count := float64(prev)*weight + float64(curr)
+	if count <= float64(limit.Limit) {
+		return true, 0
+	}
+	// 至少要等到当前窗口结束
+	retryAfter := int(math.Ceil(float64(windowMs-elapsed) / 1000))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	return false, retryAfter
+}
@@ -2,9 +2,11 @@ $(function () {
     DomeWebController.init();
 });
 
-function LuckyDo(wheel, type) {
+function LuckyDo(wheel, type, params) {
     $.ajax({
         url:"/lucky",
+        data:params || {},
+        headers:{"X-Device-Id": DeviceId()},
         cache:false,
         dataType:"json",
         timeout:1000,
@@ -25,6 +27,8 @@ function LuckyDo(wheel, type) {
             } else if (data.code == 101) {
                 alert(data.msg);
                 location.href = "/public/index.html";
+            } else if ((data.code == 106 || data.code == 107) && data.challenge) {
+                LuckyChallenge(wheel, type, data.challenge);
             } else if (data.code < 200) {
                 alert(data.msg);
             } else {
@@ -36,6 +40,51 @@ function LuckyDo(wheel, type) {
     });
 }
 
+// 浏览器的设备标识，第一次使用的时候生成并保存
+function DeviceId() {
+    var id = localStorage.getItem("lottery_device");
+    if (!id) {
+        id = "web-" + Date.now().toString(36) + "-" + Math.random().toString(36).substr(2, 10);
+        localStorage.setItem("lottery_device", id);
+    }
+    return id;
+}
+
+// 完成验证之后，带上答案重新抽奖
+function LuckyChallenge(wheel, type, challenge) {
+    var retry = function (answer) {
+        LuckyDo(wheel, type, {challenge_id: challenge.id, challenge_answer: answer});
+    };
+    if (challenge.type == "image") {
+        var $img = $('#challenge_img');
+        if ($img.length == 0) {
+            $img = $('<img id="challenge_img"/>').appendTo('body');
+        }
+        $img.one('load', function () {
+            var answer = prompt("请输入图片中的数字");
+            if (answer) {
+                retry(answer);
+            }
+        }).attr('src', challenge.img);
+    } else {
+        SolvePow(challenge.prefix, challenge.difficulty, 0).then(retry);
+    }
+}
+
+// 找到answer，使sha256(prefix+answer)的前difficulty位都是0
+function SolvePow(prefix, difficulty, answer) {
+    var bytes = new TextEncoder().encode(prefix + answer);
+    return crypto.subtle.digest("SHA-256", bytes).then(function (buf) {
+        var sum = new Uint8Array(buf);
+        for (var i = 0; i < difficulty; i++) {
+            if (sum[i >> 3] & (0x80 >> (i % 8))) {
+                return SolvePow(prefix, difficulty, answer + 1);
+            }
+        }
+        return "" + answer;
+    });
+}
+
 function LuckyShow(wheel) {
     alert(wheel["LuckyMsg"])
 }
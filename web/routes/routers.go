@@ -2,27 +2,48 @@ package routes
 
 import (
 	"github.com/iralance/go-lottery/bootstrap"
+	"github.com/iralance/go-lottery/conf"
+	"github.com/iralance/go-lottery/dao"
 	"github.com/iralance/go-lottery/services"
 	"github.com/iralance/go-lottery/web/controllers"
+	"github.com/iralance/go-lottery/web/feed"
 	"github.com/iralance/go-lottery/web/middleware"
 	"github.com/kataras/iris/v12/mvc"
+	"log"
 )
 
 func Configure(b *bootstrap.Bootstrapper) {
-	userService := services.NewUserService()
-	giftService := services.NewGiftService()
-	codeService := services.NewCodeService()
-	resultService := services.NewResultService()
-	userdayService := services.NewUserdayService()
-	blackipService := services.NewBlackipService()
+	userService := services.NewUserService(dao.NewUserDao(b.Engine), b.Cache)
+	giftService := services.NewGiftService(dao.NewGiftDao(b.Engine), b.Cache)
+	codeService := services.NewCodeService(dao.NewCodeDao(b.Engine))
+	resultService := services.NewResultService(dao.NewResultDao(b.Engine))
+	userdayService := services.NewUserdayService(dao.NewUserdayDao(b.Engine))
+	blackipService := services.NewBlackipService(dao.NewBlackipDao(b.Engine), b.Cache)
+	ruleService := services.NewRuleService(dao.NewRuleDao(b.Engine), b.Cache)
+	// 第一次启动的时候写入默认的风控规则
+	if err := ruleService.SeedDefault(); err != nil {
+		log.Println("routes.Configure ruleService.SeedDefault error=", err)
+	}
+	reviewService := services.NewReviewService(dao.NewReviewDao(b.Engine))
+	fulfillService := services.NewFulfillService(dao.NewFulfillDao(b.Engine))
+	codeGenService := services.NewCodeGenService(dao.NewCodeGenDao(b.Engine))
+	drawLogService := services.NewDrawLogService(dao.NewDrawLogDao(b.Engine))
+	chanceService := services.NewChanceService(dao.NewChanceDao(b.Engine))
+	pointService := services.NewPointService(dao.NewPointDao(b.Engine))
+	pityService := services.NewPityService(dao.NewPityDao(b.Engine), b.Cache)
+	rewardDispatcher := services.NewRewardDispatcher(conf.RewardWebhook, b.Cache, resultService)
+
+	// 中奖动态，订阅redis频道之后推送给连接的用户
+	hub := feed.NewHub()
+	go hub.Listen(b.Cache, conf.WinnerChannel, nil)
 
 	index := mvc.New(b.Party("/"))
-	index.Register(userService, giftService, codeService, resultService, userdayService, blackipService)
+	index.Register(userService, giftService, codeService, resultService, userdayService, blackipService, ruleService, reviewService, fulfillService, rewardDispatcher, codeGenService, drawLogService, chanceService, pointService, pityService, b.Cache, hub, feed.NewWsServer())
 	index.Handle(new(controllers.IndexController))
 
 	admin := mvc.New(b.Party("/admin"))
 	admin.Router.Use(middleware.BasicAuth)
-	admin.Register(userService, giftService, codeService, resultService, userdayService, blackipService)
+	admin.Register(userService, giftService, codeService, resultService, userdayService, blackipService, ruleService, reviewService, fulfillService, rewardDispatcher, codeGenService, drawLogService, chanceService, pointService, pityService, b.Cache)
 	admin.Handle(new(controllers.AdminController))
 
 	adminUser := admin.Party("/user")
@@ -34,15 +55,31 @@ func Configure(b *bootstrap.Bootstrapper) {
 	adminGift.Handle(new(controllers.AdminGiftController))
 
 	adminCode := admin.Party("/code")
-	adminCode.Register(codeService)
+	adminCode.Register(codeService, codeGenService)
 	adminCode.Handle(new(controllers.AdminCodeController))
 
 	adminResult := admin.Party("/result")
-	adminResult.Register(resultService)
+	adminResult.Register(resultService, reviewService)
 	adminResult.Handle(new(controllers.AdminResultController))
 
 	adminBlackip := admin.Party("/blackip")
 	adminBlackip.Register(blackipService)
 	adminBlackip.Handle(new(controllers.AdminBlackipController))
 
+	adminFulfill := admin.Party("/fulfill")
+	adminFulfill.Register(fulfillService)
+	adminFulfill.Handle(new(controllers.AdminFulfillController))
+
+	adminPoint := admin.Party("/point")
+	adminPoint.Register(pointService)
+	adminPoint.Handle(new(controllers.AdminPointController))
+
+	adminRule := admin.Party("/rule")
+	adminRule.Register(ruleService)
+	adminRule.Handle(new(controllers.AdminRuleController))
+
+	adminPity := admin.Party("/pity")
+	adminPity.Register(pityService, resultService, b.Cache)
+	adminPity.Handle(new(controllers.AdminPityController))
+
 }
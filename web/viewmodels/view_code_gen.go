@@ -0,0 +1,11 @@
+package viewmodels
+
+type ViewCodeGen struct {
+	GiftId    int    `form:"gift_id"`
+	Prefix    string `form:"prefix"`
+	Alphabet  string `form:"alphabet"`
+	Length    int    `form:"length"`
+	Checksum  int    `form:"checksum"`
+	Threshold int    `form:"threshold"`
+	TopupNum  int    `form:"topup_num"`
+}
@@ -10,6 +10,7 @@ type ViewGift struct {
 	Displayorder int    `form:"displayorder"`
 	Gtype        int    `form:"gtype"`
 	Gdata        string `form:"gdata"`
+	Fallback     int    `form:"fallback"`
 	TimeBegin    string `form:"time_begin"`
 	TimeEnd      string `form:"time_end"`
 }
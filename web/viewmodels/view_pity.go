@@ -0,0 +1,31 @@
+package viewmodels
+
+type ViewPity struct {
+	Id     int    `form:"id"`
+	Title  string `form:"title"`
+	Gtype  int    `form:"gtype"`
+	Num    int    `form:"num"`
+	Action int    `form:"action"`
+	Boost  int    `form:"boost"`
+}
+
+// 后台展示的保底规则，带上触发次数和达到次数的用户数
+type ViewPityStat struct {
+	Id         int
+	Title      string
+	Gtype      int
+	Num        int
+	Action     int
+	Boost      int
+	SysStatus  int
+	SysUpdated int
+	WinNum     int64
+	UserNum    int
+}
+
+// 连续没有中奖次数的用户分布
+type ViewPityBucket struct {
+	From    int
+	To      int
+	UserNum int
+}
@@ -0,0 +1,10 @@
+package viewmodels
+
+type ViewRule struct {
+	Id     int    `form:"id"`
+	Title  string `form:"title"`
+	Rtype  int    `form:"rtype"`
+	Gtype  int    `form:"gtype"`
+	Num    int    `form:"num"`
+	Action int    `form:"action"`
+}